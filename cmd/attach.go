@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/syno-docker/pkg/deploy"
+)
+
+var (
+	attachNoStdin    bool
+	attachDetachKeys string
+)
+
+var attachCmd = &cobra.Command{
+	Use:   "attach [OPTIONS] <container>",
+	Short: "Attach local standard input, output, and error streams to a running container",
+	Long: `Attach your terminal to the main process of a running container on your Synology NAS.
+If the container was started with a TTY, a pseudo-terminal is allocated and resized with your terminal.`,
+	Args: cobra.ExactArgs(1),
+	RunE: attachContainer,
+}
+
+func attachContainer(cmd *cobra.Command, args []string) error {
+	containerNameOrID := args[0]
+
+	// Connect to Synology NAS
+	_, conn, err := connectNAS(cmd, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Attach to container
+	opts := &deploy.AttachOptions{
+		NoStdin:    attachNoStdin,
+		DetachKeys: attachDetachKeys,
+	}
+
+	fmt.Printf("Attaching to container %s...\n", containerNameOrID)
+	err = deploy.AttachContainerContext(cmd.Context(), conn, containerNameOrID, opts)
+	return remoteExitError(cmd, err, true)
+}
+
+func init() {
+	attachCmd.Flags().BoolVar(&attachNoStdin, "no-stdin", false, "Do not attach STDIN")
+	attachCmd.Flags().StringVar(&attachDetachKeys, "detach-keys", "", "Override the key sequence for detaching a container")
+}
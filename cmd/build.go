@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/syno-docker/pkg/deploy"
+)
+
+var buildCmd = &cobra.Command{
+	Use:   "build [OPTIONS] PATH",
+	Short: "Build an image on the NAS from a local build context",
+	Long: `Build an image on your Synology NAS from a build context on your local machine.
+The context directory is packed, honoring .dockerignore, streamed to the NAS
+and built there with "docker build"; the build output is shown as it runs.
+
+As with docker build, --file is relative to the current directory and defaults
+to PATH/Dockerfile. A --build-arg without a value takes it from your local
+environment.`,
+	Example: `  syno-docker build -t my-app:1.0 .
+  syno-docker build -f docker/Dockerfile.prod -t my-app:prod --build-arg VERSION=1.0 .`,
+	Args: cobra.ExactArgs(1),
+	RunE: buildImage,
+}
+
+var (
+	buildFile      string
+	buildTags      []string
+	buildArgs      []string
+	buildTarget    string
+	buildNoCache   bool
+	buildPullImage bool
+)
+
+func buildImage(cmd *cobra.Command, args []string) error {
+	// Connect to Synology NAS
+	_, conn, err := connectNAS(cmd, os.Stdout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Build image
+	opts := &deploy.BuildOptions{
+		Context:    args[0],
+		Dockerfile: buildFile,
+		Tags:       buildTags,
+		BuildArgs:  buildArgs,
+		Target:     buildTarget,
+		NoCache:    buildNoCache,
+		Pull:       buildPullImage,
+	}
+
+	fmt.Printf("Building image from %s...\n", args[0])
+	if err := deploy.BuildImageContext(cmd.Context(), conn, opts); err != nil {
+		return fmt.Errorf("failed to build image: %w", err)
+	}
+
+	fmt.Println("✅ Image built successfully!")
+	return nil
+}
+
+func init() {
+	buildCmd.Flags().StringVarP(&buildFile, "file", "f", "", "Name of the Dockerfile (default \"PATH/Dockerfile\")")
+	buildCmd.Flags().StringArrayVarP(&buildTags, "tag", "t", nil, "Name and optionally a tag in the \"name:tag\" format")
+	buildCmd.Flags().StringArrayVar(&buildArgs, "build-arg", nil, "Set build-time variables")
+	buildCmd.Flags().StringVar(&buildTarget, "target", "", "Set the target build stage to build")
+	buildCmd.Flags().BoolVar(&buildNoCache, "no-cache", false, "Do not use cache when building the image")
+	buildCmd.Flags().BoolVar(&buildPullImage, "pull", false, "Always attempt to pull a newer version of the base images")
+}
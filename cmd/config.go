@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/syno-docker/pkg/config"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Show the syno-docker configuration",
+	Long: `Show the syno-docker configuration. Settings come from the profile in use
+in ~/.syno-docker/config.yaml, with the nearest ` + config.ProjectConfigFile + ` found from the
+working directory upwards and then the connection flags and environment
+variables applied over it.
+
+A ` + config.ProjectConfigFile + ` file in a repository can set:
+
+  profile: office             # unless --profile or $` + config.ProfileEnv + ` is set
+  defaults:
+    volume_path: /volume2/apps
+    network: apps
+  labels:                     # added to the containers run and deployed
+    com.example.team: web
+  compose_files:              # what deploy looks for, relative to this file
+    - deploy/compose.yaml`,
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the configuration files in use",
+	Long: `Show the configuration files in use, or with --resolved the settings commands
+use and the file, flag or environment variable each one came from.`,
+	Example: `  syno-docker config show
+  syno-docker config show --resolved
+  syno-docker --profile office config show --resolved`,
+	Args: cobra.NoArgs,
+	RunE: showConfig,
+}
+
+var configShowResolved bool
+
+func showConfig(cmd *cobra.Command, args []string) error {
+	if configShowResolved {
+		return showResolvedConfig()
+	}
+
+	f, err := config.LoadFile()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	configPath, err := config.GetConfigPath()
+	if err != nil {
+		return err
+	}
+	paths := []string{configPath}
+	if f.Project != nil {
+		paths = append(paths, f.Project.Path)
+	}
+
+	for i, path := range paths {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("# %s\n%s", path, data)
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			fmt.Println()
+		}
+	}
+	return nil
+}
+
+// showResolvedConfig lists the settings in use and where each came from
+func showResolvedConfig() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	profile := cfg.Profile
+	if profile == "" {
+		profile = "(none)"
+	}
+	values := map[string]string{
+		config.SettingProfile:      profile,
+		config.SettingHost:         cfg.Host,
+		config.SettingPort:         strconv.Itoa(cfg.Port),
+		config.SettingUser:         cfg.User,
+		config.SettingSSHKeyPath:   cfg.SSHKeyPath,
+		config.SettingVolumePath:   cfg.Defaults.VolumePath,
+		config.SettingNetwork:      cfg.Defaults.Network,
+		config.SettingLabels:       strings.Join(formatLabels(cfg.Labels), ", "),
+		config.SettingComposeFiles: strings.Join(cfg.ComposeFiles, ", "),
+	}
+	if len(cfg.ComposeFiles) == 0 {
+		values[config.SettingComposeFiles] = strings.Join(config.DefaultComposeFiles, ", ")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SETTING\tVALUE\tSOURCE")
+	for _, key := range append([]string{config.SettingProfile}, config.SettingKeys...) {
+		value := values[key]
+		if value == "" {
+			value = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", key, value, cfg.Sources[key])
+	}
+	return w.Flush()
+}
+
+func init() {
+	configCmd.AddCommand(configShowCmd)
+
+	configShowCmd.Flags().BoolVar(&configShowResolved, "resolved", false, "Show the settings in use and where each came from")
+}
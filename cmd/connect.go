@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/scttfrdmn/syno-docker/pkg/config"
+	"github.com/scttfrdmn/syno-docker/pkg/synology"
+)
+
+// connection is what commands need from a NAS connection
+type connection interface {
+	synology.Executor
+	ConnectContext(ctx context.Context) error
+	Close() error
+}
+
+// newConnection creates the connection commands use. Tests replace it to run
+// commands against a synologytest.FakeNAS.
+var newConnection = func(cfg *config.Config) connection {
+	return newSSHConnection(cfg)
+}
+
+// connectNAS connects to the NAS of the active profile, with the connection
+// flags and environment variables applied over it. When status is set, where
+// it connects to is reported there.
+func connectNAS(cmd *cobra.Command, status io.Writer) (*config.Config, connection, error) {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Connect to Synology NAS
+	if status != nil {
+		fmt.Fprintf(status, "Connecting to %s@%s:%d...\n", cfg.User, cfg.Host, cfg.Port)
+	}
+	conn := newConnection(cfg)
+	if err := conn.ConnectContext(cmd.Context()); err != nil {
+		return nil, nil, fmt.Errorf("connection failed: %w", err)
+	}
+	return cfg, conn, nil
+}
+
+// newSSHConnection creates an SSH connection that can prompt for key
+// passphrases, passwords and 2-step verification codes on the terminal
+func newSSHConnection(cfg *config.Config) *synology.Connection {
+	conn := synology.NewConnection(cfg)
+	conn.SetCredentialPrompt(promptCredential)
+	return conn
+}
+
+// promptMu keeps the prompts of concurrent connections, as in a fan-out
+// across profiles, from interleaving
+var promptMu sync.Mutex
+
+// promptCredential reads an answer from the terminal, hiding it unless echo is set
+func promptCredential(prompt string, echo bool) (string, error) {
+	promptMu.Lock()
+	defer promptMu.Unlock()
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("cannot prompt for %q: stdin is not a terminal", strings.TrimSpace(prompt))
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+
+	if echo {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	secret, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/docker/go-units"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/scttfrdmn/syno-docker/pkg/deploy"
+)
+
+var cpCmd = &cobra.Command{
+	Use:   "cp [OPTIONS] SRC DEST",
+	Short: "Copy files between your machine, the NAS and containers",
+	Long: `Copy files or directories between your machine, your Synology NAS and containers.
+
+Locations are written as:
+  PATH or local:PATH     a path on this machine
+  nas:PATH               an absolute path on the NAS
+  CONTAINER:PATH         an absolute path inside a container
+
+Files are sent over SFTP, which must be enabled in DSM under Control Panel >
+File Services > FTP. Permissions and modification times are preserved and
+every file is verified with a SHA-256 checksum. Copies to and from containers
+are staged on the NAS and moved with docker cp.`,
+	Example: `  syno-docker cp ./nginx.conf nas:/volume1/docker/nginx/
+  syno-docker cp nas:/volume1/docker/backups/db.sql.gz .
+  syno-docker cp ./site web:/usr/share/nginx/html
+  syno-docker cp db:/var/lib/postgresql/data/postgresql.conf ./`,
+	Args: cobra.ExactArgs(2),
+	RunE: copyFiles,
+}
+
+var (
+	cpStagingDir string
+	cpQuiet      bool
+)
+
+func copyFiles(cmd *cobra.Command, args []string) error {
+	src, err := deploy.ParseCopyLocation(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid source: %w", err)
+	}
+	dst, err := deploy.ParseCopyLocation(args[1])
+	if err != nil {
+		return fmt.Errorf("invalid destination: %w", err)
+	}
+
+	// Connect to Synology NAS
+	cfg, conn, err := connectNAS(cmd, os.Stdout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	opts := &deploy.CopyOptions{
+		StagingDir: cpStagingDir,
+	}
+	if opts.StagingDir == "" && cfg.Defaults.VolumePath != "" {
+		opts.StagingDir = path.Join(cfg.Defaults.VolumePath, ".syno-docker-cp")
+	}
+	if !cpQuiet && term.IsTerminal(int(os.Stderr.Fd())) {
+		opts.Progress = os.Stderr
+	}
+
+	// Copy files
+	fmt.Printf("Copying %s to %s...\n", src, dst)
+	result, err := deploy.CopyContext(cmd.Context(), conn, src, dst, opts)
+	if err != nil {
+		return fmt.Errorf("failed to copy: %w", err)
+	}
+
+	if result.Files > 0 {
+		fmt.Printf("✅ Copied %d file(s), %s, to %s successfully!\n", result.Files, units.HumanSize(float64(result.Bytes)), dst)
+	} else {
+		fmt.Printf("✅ Copied %s to %s successfully!\n", src, dst)
+	}
+	return nil
+}
+
+func init() {
+	cpCmd.Flags().StringVar(&cpStagingDir, "staging-dir", "", "NAS directory for staging container copies (default: <volume path>/.syno-docker-cp)")
+	cpCmd.Flags().BoolVarP(&cpQuiet, "quiet", "q", false, "Suppress progress output")
+}
@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/syno-docker/pkg/deploy"
+	"github.com/scttfrdmn/syno-docker/pkg/synology"
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Manage the Container Manager Docker daemon",
+	Long:  `Manage the Docker daemon that Container Manager runs on your Synology NAS.`,
+}
+
+var daemonConfigCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Read and change the daemon configuration (dockerd.json)",
+	Long: `Read and change the Container Manager daemon configuration in
+` + synology.ConfigPath + `, such as registry mirrors,
+insecure registries, log driver defaults or the data-root.
+
+Changes are validated, the current file is backed up beside it with a
+timestamp, and Container Manager is restarted, which restarts running
+containers. If the daemon doesn't come back healthy, the previous
+configuration is restored and Container Manager restarted again.
+
+Changing the configuration needs an SSH user that can sudo without a
+password prompt.`,
+}
+
+var daemonConfigGetCmd = &cobra.Command{
+	Use:   "get [KEY]",
+	Short: "Show the daemon configuration or one setting",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  getDaemonConfig,
+}
+
+var daemonConfigSetCmd = &cobra.Command{
+	Use:   "set KEY VALUE",
+	Short: "Change one setting and restart the daemon",
+	Long: `Change one setting and restart the daemon. VALUE is JSON when it parses as
+JSON and a string otherwise; list settings such as registry-mirrors also take
+a comma-separated list, and null removes the setting.`,
+	Example: `  syno-docker daemon config set registry-mirrors https://mirror.gcr.io
+  syno-docker daemon config set insecure-registries '["nas.local:5000"]'
+  syno-docker daemon config set log-opts '{"max-size": "10m", "max-file": "3"}'
+  syno-docker daemon config set debug null`,
+	Args: cobra.ExactArgs(2),
+	RunE: setDaemonConfig,
+}
+
+var daemonConfigEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Edit the daemon configuration in $EDITOR and restart the daemon",
+	Args:  cobra.NoArgs,
+	RunE:  editDaemonConfig,
+}
+
+var (
+	daemonConfigForce         bool
+	daemonConfigHealthTimeout time.Duration
+)
+
+func getDaemonConfig(cmd *cobra.Command, args []string) error {
+	_, conn, err := connectNAS(cmd, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	cfg, err := deploy.ReadDaemonConfigContext(cmd.Context(), conn)
+	if err != nil {
+		return err
+	}
+
+	var value any = cfg
+	if len(args) == 1 {
+		var ok bool
+		if value, ok = cfg[args[0]]; !ok {
+			return fmt.Errorf("%s is not set in %s", args[0], synology.ConfigPath)
+		}
+	}
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+func setDaemonConfig(cmd *cobra.Command, args []string) error {
+	_, conn, err := connectNAS(cmd, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	cfg, err := deploy.ReadDaemonConfigContext(cmd.Context(), conn)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Set(args[0], args[1]); err != nil {
+		return err
+	}
+	return applyDaemonConfig(cmd, conn, cfg)
+}
+
+func editDaemonConfig(cmd *cobra.Command, args []string) error {
+	_, conn, err := connectNAS(cmd, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	cfg, err := deploy.ReadDaemonConfigContext(cmd.Context(), conn)
+	if err != nil {
+		return err
+	}
+	original, err := cfg.Marshal()
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp("", "dockerd-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(file.Name())
+	_, err = file.Write(original)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	if err := runEditor(file.Name()); err != nil {
+		return err
+	}
+	edited, err := os.ReadFile(file.Name())
+	if err != nil {
+		return fmt.Errorf("failed to read edited file: %w", err)
+	}
+	if bytes.Equal(edited, original) {
+		fmt.Println("No changes.")
+		return nil
+	}
+
+	cfg, err = deploy.ParseDaemonConfig(edited)
+	if err != nil {
+		return err
+	}
+	return applyDaemonConfig(cmd, conn, cfg)
+}
+
+// applyDaemonConfig confirms the restart unless --force is set, then writes
+// cfg and restarts the daemon
+func applyDaemonConfig(cmd *cobra.Command, conn connection, cfg deploy.DaemonConfig) error {
+	if !daemonConfigForce {
+		fmt.Print("WARNING! Container Manager will restart, which restarts running containers.\n")
+		fmt.Print("Are you sure you want to continue? [y/N] ")
+
+		var response string
+		fmt.Scanln(&response)
+		if response != "y" && response != "Y" {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+
+	opts := &deploy.DaemonConfigOptions{HealthTimeout: daemonConfigHealthTimeout}
+	result, err := deploy.ApplyDaemonConfigContext(cmd.Context(), conn, cfg, opts)
+	if err != nil {
+		return fmt.Errorf("failed to apply daemon configuration: %w", err)
+	}
+
+	fmt.Printf("✅ Container Manager restarted with the new configuration (Docker %s)\n", result.DockerVersion)
+	if result.Backup != "" {
+		fmt.Printf("Previous configuration saved as %s\n", result.Backup)
+	}
+	return nil
+}
+
+// runEditor opens file in $VISUAL or $EDITOR, or vi (notepad on Windows)
+func runEditor(file string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+
+	args := strings.Fields(editor)
+	editorCmd := exec.Command(args[0], append(args[1:], file)...)
+	editorCmd.Stdin, editorCmd.Stdout, editorCmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := editorCmd.Run(); err != nil {
+		return fmt.Errorf("editor %s failed: %w", editor, err)
+	}
+	return nil
+}
+
+func init() {
+	daemonCmd.AddCommand(daemonConfigCmd)
+	daemonConfigCmd.AddCommand(daemonConfigGetCmd)
+	daemonConfigCmd.AddCommand(daemonConfigSetCmd)
+	daemonConfigCmd.AddCommand(daemonConfigEditCmd)
+
+	for _, c := range []*cobra.Command{daemonConfigSetCmd, daemonConfigEditCmd} {
+		c.Flags().BoolVarP(&daemonConfigForce, "force", "f", false, "Do not prompt for confirmation")
+		c.Flags().DurationVar(&daemonConfigHealthTimeout, "health-timeout", deploy.DefaultDaemonHealthTimeout, "How long to wait for the daemon to come back before rolling back")
+	}
+}
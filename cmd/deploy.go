@@ -2,51 +2,62 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"path"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/scttfrdmn/syno-docker/pkg/config"
 	"github.com/scttfrdmn/syno-docker/pkg/deploy"
-	"github.com/scttfrdmn/syno-docker/pkg/synology"
 )
 
 var (
 	deployProject string
 	deployEnvFile string
+	deployAdopt   bool
 )
 
 var deployCmd = &cobra.Command{
-	Use:   "deploy <compose-file>",
+	Use:   "deploy [compose-file]",
 	Short: "Deploy from docker-compose.yml",
 	Long: `Deploy containers from a docker-compose.yml file to your Synology NAS.
-This command parses the compose file and creates individual containers for each service.`,
-	Args: cobra.ExactArgs(1),
+This command parses the compose file and creates individual containers for each service.
+Services with a build section are built on the NAS from their local build context
+first, as "syno-docker build" does.
+
+The deployment is a Container Manager project: the compose file (and the env file,
+as .env) is uploaded to <volume-path>/<project>, relative bind mounts such as ./data
+resolve inside that folder, and the containers carry the docker compose labels.
+Deploying the same project again replaces its containers. A project created in
+Container Manager is only replaced with --adopt.
+
+Without a compose file, the first of compose_files from .syno-docker.yaml or
+the configuration that exists is deployed, by default compose.yaml,
+compose.yml, docker-compose.yaml or docker-compose.yml in the working directory.`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: deployCompose,
 }
 
 func deployCompose(cmd *cobra.Command, args []string) error {
-	composeFile := args[0]
-
-	// Check if compose file exists
-	absPath, err := filepath.Abs(composeFile)
+	// Connect to Synology NAS
+	cfg, conn, err := connectNAS(cmd, os.Stdout)
 	if err != nil {
-		return fmt.Errorf("failed to resolve compose file path: %w", err)
+		return err
 	}
+	defer conn.Close()
 
-	// Load configuration
-	cfg, err := config.Load()
+	composeFile, err := findComposeFile(cfg, args)
 	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
+		return err
 	}
 
-	// Connect to Synology NAS
-	fmt.Printf("Connecting to %s@%s:%d...\n", cfg.User, cfg.Host, cfg.Port)
-	conn := synology.NewConnection(cfg)
-	if err := conn.Connect(); err != nil {
-		return fmt.Errorf("connection failed: %w", err)
+	// Check if compose file exists
+	absPath, err := filepath.Abs(composeFile)
+	if err != nil {
+		return fmt.Errorf("failed to resolve compose file path: %w", err)
 	}
-	defer conn.Close()
 
 	// Generate project name if not specified
 	projectName := deployProject
@@ -59,10 +70,16 @@ func deployCompose(cmd *cobra.Command, args []string) error {
 		ComposeFile: absPath,
 		ProjectName: projectName,
 		EnvFile:     deployEnvFile,
+		Adopt:       deployAdopt,
+		Network:     cfg.Defaults.Network,
+		Labels:      cfg.Labels,
+	}
+	if cfg.Defaults.VolumePath != "" {
+		opts.ProjectDir = path.Join(cfg.Defaults.VolumePath, projectName)
 	}
 
 	// Deploy compose
-	if err := deploy.Compose(conn, opts); err != nil {
+	if err := deploy.ComposeContext(cmd.Context(), conn, opts); err != nil {
 		return fmt.Errorf("deployment failed: %w", err)
 	}
 
@@ -70,7 +87,27 @@ func deployCompose(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// findComposeFile returns the compose file given in args, or else the first
+// of the configured compose files that exists
+func findComposeFile(cfg *config.Config, args []string) (string, error) {
+	if len(args) > 0 {
+		return args[0], nil
+	}
+
+	names := cfg.ComposeFiles
+	if len(names) == 0 {
+		names = config.DefaultComposeFiles
+	}
+	for _, name := range names {
+		if _, err := os.Stat(name); err == nil {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("no compose file given and none of %s found", strings.Join(names, ", "))
+}
+
 func init() {
 	deployCmd.Flags().StringVarP(&deployProject, "project", "p", "", "Project name (auto-generated from directory if not specified)")
 	deployCmd.Flags().StringVar(&deployEnvFile, "env-file", "", "Environment file path")
+	deployCmd.Flags().BoolVar(&deployAdopt, "adopt", false, "Take over an existing Container Manager project of the same name")
 }
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/syno-docker/pkg/synology"
+)
+
+// ExitError asks main to exit with Code. If Err is nil nothing is printed,
+// because the remote process has already written its own output.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+// Error returns the underlying error message, or the exit status
+func (e *ExitError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// Unwrap returns the underlying error
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// remoteExitError converts err into an *ExitError carrying the remote exit
+// status when err came from a remote command that exited with a status.
+// Other errors are returned unchanged.
+func remoteExitError(cmd *cobra.Command, err error, silent bool) error {
+	var remoteErr *synology.RemoteCommandError
+	if !errors.As(err, &remoteErr) {
+		return err
+	}
+
+	// The failure is the remote process's, not a usage mistake
+	cmd.SilenceUsage = true
+
+	if silent {
+		cmd.SilenceErrors = true
+		return &ExitError{Code: remoteErr.ExitStatus}
+	}
+	return &ExitError{Code: remoteErr.ExitStatus, Err: err}
+}
@@ -1,11 +1,11 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/spf13/cobra"
 
-	"github.com/scttfrdmn/syno-docker/pkg/config"
 	"github.com/scttfrdmn/syno-docker/pkg/deploy"
 	"github.com/scttfrdmn/syno-docker/pkg/synology"
 )
@@ -30,16 +30,10 @@ func executeCommand(cmd *cobra.Command, args []string) error {
 	containerNameOrID := args[0]
 	command := args[1:]
 
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
-	}
-
 	// Connect to Synology NAS
-	conn := synology.NewConnection(cfg)
-	if err := conn.Connect(); err != nil {
-		return fmt.Errorf("connection failed: %w", err)
+	_, conn, err := connectNAS(cmd, nil)
+	if err != nil {
+		return err
 	}
 	defer conn.Close()
 
@@ -52,15 +46,21 @@ func executeCommand(cmd *cobra.Command, args []string) error {
 		Env:         execEnv,
 	}
 
-	if execInteractive {
+	if execInteractive || execTTY {
 		fmt.Printf("Executing interactive command in container %s...\n", containerNameOrID)
-		return deploy.ExecInteractive(conn, containerNameOrID, command, opts)
+		err := deploy.ExecInteractiveContext(cmd.Context(), conn, containerNameOrID, command, opts)
+		return remoteExitError(cmd, err, true)
 	} else {
-		output, err := deploy.ExecCommand(conn, containerNameOrID, command, opts)
+		output, err := deploy.ExecCommandContext(cmd.Context(), conn, containerNameOrID, command, opts)
+		fmt.Print(output)
 		if err != nil {
+			// Pass the process's exit status through like docker exec
+			var remoteErr *synology.RemoteCommandError
+			if errors.As(err, &remoteErr) {
+				return remoteExitError(cmd, err, true)
+			}
 			return fmt.Errorf("failed to execute command: %w", err)
 		}
-		fmt.Print(output)
 		return nil
 	}
 }
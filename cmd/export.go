@@ -2,61 +2,99 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 
-	"github.com/scttfrdmn/syno-docker/pkg/config"
 	"github.com/scttfrdmn/syno-docker/pkg/deploy"
-	"github.com/scttfrdmn/syno-docker/pkg/synology"
 )
 
 var exportCmd = &cobra.Command{
 	Use:   "export [OPTIONS] CONTAINER",
 	Short: "Export a container's filesystem as a tar archive",
-	Long:  `Export a container's filesystem as a tar archive to your local machine.`,
-	Args:  cobra.ExactArgs(1),
-	RunE:  exportContainer,
+	Long: `Export a container's filesystem as a tar archive to your local machine.
+
+The archive is streamed from the NAS straight to the output file, or to
+STDOUT, so containers of any size can be exported. With --compress the
+archive is compressed on the NAS before it is sent; an output file ending in
+.gz, .tgz or .zst selects the matching compression automatically.`,
+	Example: `  syno-docker export web -o web.tar
+  syno-docker export web -o web.tar.gz
+  syno-docker export web --compress zstd > web.tar.zst`,
+	Args: cobra.ExactArgs(1),
+	RunE: exportContainer,
 }
 
 var (
-	exportOutput string
+	exportOutput   string
+	exportCompress string
+	exportQuiet    bool
 )
 
 func exportContainer(cmd *cobra.Command, args []string) error {
 	containerNameOrID := args[0]
 
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
+	// Status messages must not mix with an archive written to STDOUT
+	status := io.Writer(os.Stdout)
+	if exportOutput == "" || exportOutput == "-" {
+		exportOutput = ""
+		if term.IsTerminal(int(os.Stdout.Fd())) {
+			return fmt.Errorf("refusing to write the archive to a terminal; use --output or redirect STDOUT")
+		}
+		status = os.Stderr
+	}
+
+	compression := exportCompress
+	if !cmd.Flags().Changed("compress") {
+		compression = compressionForFile(exportOutput)
 	}
 
 	// Connect to Synology NAS
-	fmt.Printf("Connecting to %s@%s:%d...\n", cfg.User, cfg.Host, cfg.Port)
-	conn := synology.NewConnection(cfg)
-	if err := conn.Connect(); err != nil {
-		return fmt.Errorf("connection failed: %w", err)
+	_, conn, err := connectNAS(cmd, status)
+	if err != nil {
+		return err
 	}
 	defer conn.Close()
 
 	// Export container
 	opts := &deploy.ExportOptions{
-		Output: exportOutput,
+		Output:      exportOutput,
+		Compression: compression,
+	}
+	if !exportQuiet && term.IsTerminal(int(os.Stderr.Fd())) {
+		opts.Progress = os.Stderr
 	}
 
-	fmt.Printf("Exporting container %s...\n", containerNameOrID)
-	if err := deploy.ExportContainer(conn, containerNameOrID, opts); err != nil {
+	fmt.Fprintf(status, "Exporting container %s...\n", containerNameOrID)
+	if err := deploy.ExportContainerContext(cmd.Context(), conn, containerNameOrID, opts); err != nil {
 		return fmt.Errorf("failed to export container: %w", err)
 	}
 
 	if exportOutput != "" {
-		fmt.Printf("✅ Container %s exported to %s successfully!\n", containerNameOrID, exportOutput)
+		fmt.Fprintf(status, "✅ Container %s exported to %s successfully!\n", containerNameOrID, exportOutput)
 	} else {
-		fmt.Printf("✅ Container %s exported successfully!\n", containerNameOrID)
+		fmt.Fprintf(status, "✅ Container %s exported successfully!\n", containerNameOrID)
 	}
 	return nil
 }
 
+// compressionForFile picks the export compression matching the extension
+// of path
+func compressionForFile(path string) string {
+	switch {
+	case strings.HasSuffix(path, ".gz"), strings.HasSuffix(path, ".tgz"):
+		return "gzip"
+	case strings.HasSuffix(path, ".zst"):
+		return "zstd"
+	}
+	return ""
+}
+
 func init() {
-	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "Write to a file, instead of STDOUT")
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "Write to a local file, instead of STDOUT")
+	exportCmd.Flags().StringVar(&exportCompress, "compress", "", "Compress the archive on the NAS: gzip or zstd")
+	exportCmd.Flags().BoolVarP(&exportQuiet, "quiet", "q", false, "Suppress progress output")
 }
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/syno-docker/pkg/config"
+)
+
+// hostFunc runs a command against one NAS, writing its output to w
+type hostFunc func(ctx context.Context, cfg *config.Config, conn connection, w io.Writer) error
+
+// hostOutput collects the output of a command run against one NAS of a
+// fan-out
+type hostOutput struct {
+	bytes.Buffer
+	host string
+	err  error
+}
+
+// forEachHost connects to the NAS of the active profile with connectNAS and
+// runs fn, writing to cmd's output. When the profile is a pattern, such as
+// --profile '*' or 'edge-*', fn runs concurrently against the NAS of every
+// matching profile; table rows are merged into one table with a HOST column,
+// other output lines are prefixed with the host, and a failure on one NAS
+// doesn't stop the others.
+func forEachHost(cmd *cobra.Command, fn hostFunc) error {
+	f, err := config.LoadFile()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	stdout, stderr := cmd.OutOrStdout(), cmd.ErrOrStderr()
+	pattern := f.ActiveProfile()
+	if !config.IsProfilePattern(pattern) {
+		cfg, conn, err := connectNAS(cmd, nil)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		return fn(cmd.Context(), cfg, conn, stdout)
+	}
+
+	overrides, err := config.ConnectionOverrides()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if overrides.Host != "" {
+		return fmt.Errorf("a host set with --host or %s can't be combined with the profile pattern %q", config.HostEnv, pattern)
+	}
+	names, err := f.Match(pattern)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	outputs := make([]*hostOutput, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		cfg, err := f.Resolve(name)
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+		outputs[i] = &hostOutput{host: cfg.Host}
+		wg.Add(1)
+		go func(out *hostOutput) {
+			defer wg.Done()
+			out.err = runOnHost(cmd.Context(), cfg, fn, out)
+		}(outputs[i])
+	}
+	wg.Wait()
+
+	if err := printHostOutputs(stdout, outputs); err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, out := range outputs {
+		if out.err != nil {
+			failed++
+			fmt.Fprintf(stderr, "Error: %s: %v\n", out.host, out.err)
+		}
+	}
+	if failed > 0 {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%d of %d hosts failed", failed, len(outputs))
+	}
+	return nil
+}
+
+// runOnHost connects to the NAS of cfg and runs fn, writing to out
+func runOnHost(ctx context.Context, cfg *config.Config, fn hostFunc, out *hostOutput) error {
+	conn := newConnection(cfg)
+	if err := conn.ConnectContext(ctx); err != nil {
+		return fmt.Errorf("connection failed: %w", err)
+	}
+	defer conn.Close()
+
+	return fn(ctx, cfg, conn, out)
+}
+
+// printHostOutputs writes the output of a fan-out to w: the tables, whose
+// first line is the header, merged into one with a HOST column, followed by
+// the other lines, prefixed with their host
+func printHostOutputs(w io.Writer, outputs []*hostOutput) error {
+	var header string
+	var rows, lines []string
+	for _, out := range outputs {
+		text := strings.TrimRight(out.String(), "\n")
+		if text == "" {
+			continue
+		}
+		seenHeader := false
+		for _, line := range strings.Split(text, "\n") {
+			switch {
+			case !strings.Contains(line, "\t"):
+				lines = append(lines, out.host+"\t"+line)
+			case !seenHeader:
+				seenHeader = true
+				if header == "" {
+					header = line
+				}
+			default:
+				rows = append(rows, out.host+"\t"+line)
+			}
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if header != "" {
+		fmt.Fprintln(tw, "HOST\t"+header)
+		for _, row := range rows {
+			fmt.Fprintln(tw, row)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	for _, line := range lines {
+		fmt.Fprintln(tw, line)
+	}
+	return tw.Flush()
+}
+
+// table is a writer for tab-separated columns
+type table interface {
+	io.Writer
+	Flush() error
+}
+
+// unalignedTable leaves the columns of a fan-out's tables to printHostOutputs
+type unalignedTable struct {
+	io.Writer
+}
+
+// Flush does nothing, as nothing is buffered
+func (unalignedTable) Flush() error {
+	return nil
+}
+
+// newTable returns a writer that aligns the tab-separated columns written to
+// it. Within a fan-out the columns are left for printHostOutputs to align
+// across hosts.
+func newTable(w io.Writer) table {
+	if isFanOut(w) {
+		return unalignedTable{w}
+	}
+	return tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+}
+
+// isFanOut reports whether w collects the output of one NAS of a fan-out
+func isFanOut(w io.Writer) bool {
+	_, ok := w.(*hostOutput)
+	return ok
+}
@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/syno-docker/pkg/config"
+	"github.com/scttfrdmn/syno-docker/pkg/synology/synologytest"
+)
+
+func TestPrintHostOutputs(t *testing.T) {
+	tests := []struct {
+		name    string
+		outputs map[string]string
+		failed  []string
+		want    string
+	}{
+		{
+			name: "tables merged",
+			outputs: map[string]string{
+				"nas1": "DRIVER\tVOLUME NAME\nlocal\tdata\n",
+				"nas2": "DRIVER\tVOLUME NAME\nlocal\tlogs\nlocal\tmedia\n",
+			},
+			want: "HOST  DRIVER  VOLUME NAME\n" +
+				"nas1  local   data\n" +
+				"nas2  local   logs\n" +
+				"nas2  local   media\n",
+		},
+		{
+			name: "mixed table and plain lines",
+			outputs: map[string]string{
+				"nas1": "Pulling nginx:latest...\nREPOSITORY\tTAG\nnginx\tlatest\n",
+				"nas2": "No images found.\n",
+			},
+			want: "HOST  REPOSITORY  TAG\n" +
+				"nas1  nginx       latest\n" +
+				"nas1  Pulling nginx:latest...\n" +
+				"nas2  No images found.\n",
+		},
+		{
+			name: "plain lines only",
+			outputs: map[string]string{
+				"nas1": "abc123\n",
+				"nas2": "def456\n",
+			},
+			want: "nas1  abc123\nnas2  def456\n",
+		},
+		{
+			name: "one host failing",
+			outputs: map[string]string{
+				"nas1": "NAME\tDRIVER\nbridge\tbridge\n",
+				"nas2": "",
+			},
+			failed: []string{"nas2"},
+			want:   "HOST  NAME    DRIVER\nnas1  bridge  bridge\n",
+		},
+		{
+			name:    "every host failing",
+			outputs: map[string]string{"nas1": "", "nas2": ""},
+			failed:  []string{"nas1", "nas2"},
+			want:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var outputs []*hostOutput
+			for _, host := range []string{"nas1", "nas2"} {
+				out := &hostOutput{host: host}
+				out.WriteString(tt.outputs[host])
+				if slices.Contains(tt.failed, host) {
+					out.err = errors.New("connection failed")
+				}
+				outputs = append(outputs, out)
+			}
+
+			var buf bytes.Buffer
+			if err := printHostOutputs(&buf, outputs); err != nil {
+				t.Fatalf("printHostOutputs failed: %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.want, buf.String())
+			}
+		})
+	}
+}
+
+// setupProfiles saves the named profiles, each with host NAME.local, and
+// makes newConnection return a synologytest.FakeNAS per host
+func setupProfiles(t *testing.T, names ...string) map[string]*synologytest.FakeNAS {
+	t.Setenv("HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+	for _, env := range []string{config.ProfileEnv, config.HostEnv, config.UserEnv, config.PortEnv, config.KeyEnv} {
+		t.Setenv(env, "")
+	}
+	config.SetOverrides(config.Overrides{})
+	t.Cleanup(func() { config.SelectProfile("") })
+
+	f := &config.File{}
+	nas := map[string]*synologytest.FakeNAS{}
+	for _, name := range names {
+		cfg := config.New()
+		cfg.Host = name + ".local"
+		if err := f.SetProfile(name, cfg); err != nil {
+			t.Fatal(err)
+		}
+		nas[cfg.Host] = synologytest.New()
+	}
+	if err := f.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := newConnection
+	newConnection = func(cfg *config.Config) connection {
+		return nas[cfg.Host]
+	}
+	t.Cleanup(func() { newConnection = saved })
+	return nas
+}
+
+func TestForEachHost(t *testing.T) {
+	tests := []struct {
+		name       string
+		profile    string
+		failing    []string
+		wantOut    string
+		wantStderr []string
+		wantErr    string
+	}{
+		{
+			name:    "single profile",
+			profile: "edge-1",
+			wantOut: "DRIVER  VOLUME NAME\nlocal   data-edge-1\n",
+		},
+		{
+			name:    "every host succeeding",
+			profile: "edge-*",
+			wantOut: "HOST          DRIVER  VOLUME NAME\nedge-1.local  local   data-edge-1\nedge-2.local  local   data-edge-2\n",
+		},
+		{
+			name:       "one host failing",
+			profile:    "edge-*",
+			failing:    []string{"edge-2.local"},
+			wantOut:    "HOST          DRIVER  VOLUME NAME\nedge-1.local  local   data-edge-1\n",
+			wantStderr: []string{"Error: edge-2.local: failed to list volumes", "Cannot connect to the Docker daemon"},
+			wantErr:    "1 of 2 hosts failed",
+		},
+		{
+			name:       "every host failing",
+			profile:    "edge-*",
+			failing:    []string{"edge-1.local", "edge-2.local"},
+			wantStderr: []string{"Error: edge-1.local:", "Error: edge-2.local:"},
+			wantErr:    "2 of 2 hosts failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nas := setupProfiles(t, "edge-1", "edge-2", "office")
+			for host, fake := range nas {
+				name := strings.TrimSuffix(host, ".local")
+				fake.OnDocker("volume", "ls").Return("DRIVER\tVOLUME NAME\nlocal\tdata-" + name + "\n")
+			}
+			for _, host := range tt.failing {
+				nas[host].OnDocker("volume", "ls").Fail(1, "Cannot connect to the Docker daemon")
+			}
+			config.SelectProfile(tt.profile)
+
+			var stdout, stderr bytes.Buffer
+			cmd := &cobra.Command{}
+			cmd.SetContext(context.Background())
+			cmd.SetOut(&stdout)
+			cmd.SetErr(&stderr)
+
+			err := forEachHost(cmd, printVolumes)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("forEachHost failed: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Fatalf("Expected error %q, got %v", tt.wantErr, err)
+			}
+
+			if stdout.String() != tt.wantOut {
+				t.Errorf("Expected output:\n%s\ngot:\n%s", tt.wantOut, stdout.String())
+			}
+			for _, want := range tt.wantStderr {
+				if !strings.Contains(stderr.String(), want) {
+					t.Errorf("Expected %q in stderr:\n%s", want, stderr.String())
+				}
+			}
+			if nas["office.local"].Connected() {
+				t.Error("Expected the profile not matching the pattern to be left alone")
+			}
+			for host, fake := range nas {
+				if fake.Connected() && !fake.Closed() {
+					t.Errorf("Expected the connection to %s to be closed", host)
+				}
+			}
+		})
+	}
+}
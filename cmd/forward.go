@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/syno-docker/pkg/deploy"
+)
+
+var forwardCmd = &cobra.Command{
+	Use:   "forward [OPTIONS] CONTAINER:PORT [LOCAL_PORT]",
+	Short: "Forward a local port to a container port on the NAS",
+	Long: `Forward a local port to a port of a running container on your Synology NAS,
+tunnelled over the SSH connection. The port doesn't need to be published: the
+container is reached at its IP address on its Docker network, looked up with
+"docker inspect", so admin UIs and databases can stay private to the NAS.
+
+LOCAL_PORT defaults to the container port; use 0 to pick a free port. The
+forward runs until interrupted with Ctrl+C.`,
+	Example: `  syno-docker forward postgres:5432
+  syno-docker forward grafana:3000 8080
+  syno-docker forward --address 0.0.0.0 web:80 8080`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: forward,
+}
+
+var forwardAddress string
+
+func forward(cmd *cobra.Command, args []string) error {
+	containerName, port, err := deploy.ParseForwardTarget(args[0])
+	if err != nil {
+		return err
+	}
+	localPort := port
+	if len(args) == 2 {
+		if localPort, err = strconv.Atoi(args[1]); err != nil || localPort < 0 || localPort > 65535 {
+			return fmt.Errorf("invalid local port %q", args[1])
+		}
+	}
+
+	// Connect to Synology NAS
+	_, conn, err := connectNAS(cmd, os.Stdout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	addr, err := deploy.ContainerAddressContext(cmd.Context(), conn, containerName, port)
+	if err != nil {
+		return fmt.Errorf("failed to resolve container address: %w", err)
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(forwardAddress, strconv.Itoa(localPort)))
+	if err != nil {
+		return fmt.Errorf("failed to listen on local port %d: %w", localPort, err)
+	}
+	warnIfExposed(listener.Addr())
+
+	fmt.Printf("Forwarding %s -> %s:%d (%s on the NAS)\n", listener.Addr(), containerName, port, addr)
+	fmt.Println("Press Ctrl+C to stop")
+	if err := deploy.Forward(cmd.Context(), conn, listener, "tcp", addr, &deploy.ForwardOptions{Log: os.Stderr}); err != nil {
+		return fmt.Errorf("forwarding failed: %w", err)
+	}
+
+	fmt.Println("✅ Forwarding stopped")
+	return nil
+}
+
+// warnIfExposed warns when a listener accepts connections from other hosts
+func warnIfExposed(addr net.Addr) {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if ok && !tcpAddr.IP.IsLoopback() {
+		fmt.Printf("Warning: %s is reachable from other hosts on your network\n", addr)
+	}
+}
+
+func init() {
+	forwardCmd.Flags().StringVar(&forwardAddress, "address", "127.0.0.1", "Local address to listen on")
+}
@@ -1,15 +1,14 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
-	"os"
-	"text/tabwriter"
+	"io"
 
 	"github.com/spf13/cobra"
 
 	"github.com/scttfrdmn/syno-docker/pkg/config"
 	"github.com/scttfrdmn/syno-docker/pkg/deploy"
-	"github.com/scttfrdmn/syno-docker/pkg/synology"
 )
 
 var (
@@ -30,19 +29,13 @@ var imagesCmd = &cobra.Command{
 }
 
 func listImages(cmd *cobra.Command, args []string) error {
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
-	}
-
-	// Connect to Synology NAS
-	conn := synology.NewConnection(cfg)
-	if err := conn.Connect(); err != nil {
-		return fmt.Errorf("connection failed: %w", err)
-	}
-	defer conn.Close()
+	return forEachHost(cmd, func(ctx context.Context, cfg *config.Config, conn connection, out io.Writer) error {
+		return printImages(ctx, conn, args, out)
+	})
+}
 
+// printImages lists the images on one NAS
+func printImages(ctx context.Context, conn connection, args []string, out io.Writer) error {
 	// List images
 	opts := &deploy.ImagesOptions{
 		All:      imagesAll,
@@ -59,28 +52,28 @@ func listImages(cmd *cobra.Command, args []string) error {
 	}
 
 	if imagesQuiet {
-		imageIDs, err := deploy.ListImageIDs(conn, repository, opts)
+		imageIDs, err := deploy.ListImageIDsContext(ctx, conn, repository, opts)
 		if err != nil {
 			return fmt.Errorf("failed to list images: %w", err)
 		}
 		for _, id := range imageIDs {
-			fmt.Println(id)
+			fmt.Fprintln(out, id)
 		}
 		return nil
 	}
 
-	images, err := deploy.ListImages(conn, repository, opts)
+	images, err := deploy.ListImagesContext(ctx, conn, repository, opts)
 	if err != nil {
 		return fmt.Errorf("failed to list images: %w", err)
 	}
 
 	if len(images) == 0 {
-		fmt.Println("No images found.")
+		fmt.Fprintln(out, "No images found.")
 		return nil
 	}
 
 	// Display images in a table format
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	w := newTable(out)
 	if imagesDigests {
 		fmt.Fprintln(w, "REPOSITORY\tTAG\tDIGEST\tIMAGE ID\tCREATED\tSIZE")
 	} else {
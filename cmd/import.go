@@ -2,26 +2,33 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 
-	"github.com/scttfrdmn/syno-docker/pkg/config"
 	"github.com/scttfrdmn/syno-docker/pkg/deploy"
-	"github.com/scttfrdmn/syno-docker/pkg/synology"
 )
 
 var importCmd = &cobra.Command{
 	Use:   "import [OPTIONS] file|URL|- [REPOSITORY[:TAG]]",
 	Short: "Import the contents from a tarball to create a filesystem image",
-	Long:  `Import the contents from a tarball to create a filesystem image on your Synology NAS.`,
-	Args:  cobra.RangeArgs(1, 2),
-	RunE:  importImage,
+	Long: `Import the contents from a tarball to create a filesystem image on your Synology NAS.
+
+A local file, or STDIN for "-", is streamed to the NAS. Use a URL to have the
+NAS download the tarball, or nas:PATH for a tarball already on the NAS.`,
+	Example: `  syno-docker import rootfs.tar.gz my-image:latest
+  cat rootfs.tar | syno-docker import - my-image:latest
+  syno-docker import nas:/volume1/backups/rootfs.tar my-image:latest`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: importImage,
 }
 
 var (
 	importChange   []string
 	importMessage  string
 	importPlatform string
+	importQuiet    bool
 )
 
 func importImage(cmd *cobra.Command, args []string) error {
@@ -31,17 +38,14 @@ func importImage(cmd *cobra.Command, args []string) error {
 		repository = args[1]
 	}
 
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
+	if source == "-" && term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("requested import from STDIN, but STDIN is a terminal; redirect STDIN or name a file")
 	}
 
 	// Connect to Synology NAS
-	fmt.Printf("Connecting to %s@%s:%d...\n", cfg.User, cfg.Host, cfg.Port)
-	conn := synology.NewConnection(cfg)
-	if err := conn.Connect(); err != nil {
-		return fmt.Errorf("connection failed: %w", err)
+	_, conn, err := connectNAS(cmd, os.Stdout)
+	if err != nil {
+		return err
 	}
 	defer conn.Close()
 
@@ -51,9 +55,12 @@ func importImage(cmd *cobra.Command, args []string) error {
 		Message:  importMessage,
 		Platform: importPlatform,
 	}
+	if !importQuiet && term.IsTerminal(int(os.Stderr.Fd())) {
+		opts.Progress = os.Stderr
+	}
 
 	fmt.Printf("Importing image from %s...\n", source)
-	imageID, err := deploy.ImportImage(conn, source, repository, opts)
+	imageID, err := deploy.ImportImageContext(cmd.Context(), conn, source, repository, opts)
 	if err != nil {
 		return fmt.Errorf("failed to import image: %w", err)
 	}
@@ -70,4 +77,5 @@ func init() {
 	importCmd.Flags().StringSliceVarP(&importChange, "change", "c", []string{}, "Apply Dockerfile instruction to the created image")
 	importCmd.Flags().StringVarP(&importMessage, "message", "m", "", "Set commit message for imported image")
 	importCmd.Flags().StringVar(&importPlatform, "platform", "", "Set platform if server is multi-platform capable")
+	importCmd.Flags().BoolVarP(&importQuiet, "quiet", "q", false, "Suppress progress output")
 }
@@ -2,8 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
 
 	"github.com/spf13/cobra"
 
@@ -12,31 +10,84 @@ import (
 )
 
 var (
-	initUser       string
-	initPort       int
-	initSSHKey     string
-	initVolumePath string
+	initUser        string
+	initPort        int
+	initSSHKey      string
+	initVolumePath  string
+	initInsecure    bool
+	initAuth        []string
+	initTransport   string
+	initDSMURL      string
+	initDSMInsecure bool
 )
 
 var initCmd = &cobra.Command{
 	Use:   "init <host>",
 	Short: "Setup connection to Synology NAS",
 	Long: `Initialize syno-docker configuration for connecting to your Synology NAS.
-This command sets up SSH connection details and tests the connection.`,
+This command sets up SSH connection details and tests the connection.
+The settings are saved to the active profile, "default" unless --profile or
+` + config.ProfileEnv + ` names another; see 'syno-docker profile' to manage several NAS.
+
+For a NAS that doesn't allow SSH, use --transport dsm to go through the DSM
+Web API (HTTPS) instead, which supports listing, starting, stopping and
+restarting containers and reading logs. With --dsm-url set, the default
+transport also falls back to the DSM Web API whenever SSH is unavailable.`,
+	Example: `  syno-docker init nas.local --user admin
+  syno-docker init nas.local --user admin --transport dsm --dsm-insecure
+  syno-docker init nas.local --dsm-url https://nas.local:5001`,
 	Args: cobra.ExactArgs(1),
 	RunE: runInit,
 }
 
 func runInit(cmd *cobra.Command, args []string) error {
-	host := args[0]
+	return initProfile(cmd, "", args[0])
+}
 
+// initProfile tests the connection to host and saves it as the named
+// profile, or the active profile when name is empty
+func initProfile(cmd *cobra.Command, name, host string) error {
 	// Create new config
 	cfg := config.New()
+	cfg.Profile = name
 	cfg.Host = host
 	cfg.User = initUser
 	cfg.Port = initPort
 	cfg.SSHKeyPath = initSSHKey
 	cfg.Defaults.VolumePath = initVolumePath
+	cfg.InsecureSkipHostKeyCheck = initInsecure
+	cfg.AuthMethods = initAuth
+	cfg.Transport = initTransport
+	cfg.DSM.URL = initDSMURL
+	cfg.DSM.InsecureSkipVerify = initDSMInsecure
+
+	// A port or user not passed is left out of the profile, so later
+	// changes to ~/.ssh/config still apply
+	cfg.Sources = map[string]string{}
+	if !cmd.Flags().Changed("user") {
+		cfg.Sources[config.SettingUser] = config.SourceDefault
+	}
+	if !cmd.Flags().Changed("port") {
+		cfg.Sources[config.SettingPort] = config.SourceDefault
+	}
+
+	// Fill in settings the user did not pass from ~/.ssh/config
+	if sshHost, err := synology.ResolveSSHHost(host); err == nil && sshHost.Found {
+		if !cmd.Flags().Changed("user") && sshHost.User != "" {
+			cfg.User = sshHost.User
+		}
+		if !cmd.Flags().Changed("port") && sshHost.Port != 0 {
+			cfg.Port = sshHost.Port
+		}
+		if !cmd.Flags().Changed("key") && sshHost.IdentityFile != "" {
+			cfg.SSHKeyPath = sshHost.IdentityFile
+		}
+		fmt.Printf("Using ~/.ssh/config entry for %s", host)
+		if sshHost.ProxyJump != "" {
+			fmt.Printf(" (via %s)", sshHost.ProxyJump)
+		}
+		fmt.Println()
+	}
 
 	// Validate configuration
 	if err := cfg.Validate(); err != nil {
@@ -45,14 +96,15 @@ func runInit(cmd *cobra.Command, args []string) error {
 
 	// Test connection
 	fmt.Printf("Testing connection to %s@%s:%d...\n", cfg.User, cfg.Host, cfg.Port)
-	conn := synology.NewConnection(cfg)
-	if err := conn.Connect(); err != nil {
+	conn := newSSHConnection(cfg)
+	conn.SetHostKeyPrompt(promptHostKey)
+	if err := conn.ConnectContext(cmd.Context()); err != nil {
 		return fmt.Errorf("connection test failed: %w\n\nTry:\n  1. Verify host is reachable: ping %s\n  2. Check SSH service is enabled on your NAS\n  3. Verify username and SSH key path\n  4. Ensure your user has admin privileges", err, cfg.Host)
 	}
 	defer conn.Close()
 
 	// Additional connection tests
-	if err := conn.TestConnection(); err != nil {
+	if err := conn.TestConnectionContext(cmd.Context()); err != nil {
 		return fmt.Errorf("docker connection test failed: %w\n\nTry:\n  1. Ensure Container Manager is installed and running\n  2. Verify your user is in the docker group\n  3. Check if Docker service is running: systemctl status pkg-ContainerManager-dockerd", err)
 	}
 
@@ -62,19 +114,40 @@ func runInit(cmd *cobra.Command, args []string) error {
 	}
 
 	configPath, _ := config.GetConfigPath()
-	fmt.Printf("✅ Connection successful!\nConfiguration saved to %s\n", configPath)
+	fmt.Printf("✅ Connection successful!\nConfiguration saved to profile %s in %s\n", cfg.Profile, configPath)
+	if cfg.HostKeyFingerprint != "" {
+		fmt.Printf("Host key fingerprint: %s\n", cfg.HostKeyFingerprint)
+	}
 	fmt.Printf("You can now deploy containers using 'syno-docker run' or 'syno-docker deploy'\n")
 
 	return nil
 }
 
+// promptHostKey asks the user to trust a host key seen for the first time
+func promptHostKey(host, fingerprint string) (bool, error) {
+	fmt.Printf("The authenticity of host '%s' can't be established.\n", host)
+	fmt.Printf("Host key fingerprint is %s.\n", fingerprint)
+	fmt.Print("Are you sure you want to trust this host? [y/N] ")
+
+	var response string
+	fmt.Scanln(&response)
+	return response == "y" || response == "Y" || response == "yes", nil
+}
+
 func init() {
-	// Default SSH key path
-	homeDir, _ := os.UserHomeDir()
-	defaultSSHKey := filepath.Join(homeDir, ".ssh", "id_rsa")
-
-	initCmd.Flags().StringVarP(&initUser, "user", "u", config.DefaultUser, "SSH username")
-	initCmd.Flags().IntVarP(&initPort, "port", "p", config.DefaultPort, "SSH port")
-	initCmd.Flags().StringVarP(&initSSHKey, "key", "k", defaultSSHKey, "SSH private key path")
-	initCmd.Flags().StringVar(&initVolumePath, "volume-path", config.DefaultVolumePath, "Default volume path on NAS")
+	addInitFlags(initCmd)
+}
+
+// addInitFlags adds the connection settings flags shared by init and
+// profile add
+func addInitFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&initUser, "user", "u", config.DefaultUser, "SSH username")
+	cmd.Flags().IntVarP(&initPort, "port", "p", config.DefaultPort, "SSH port")
+	cmd.Flags().StringVarP(&initSSHKey, "key", "k", config.DefaultSSHKeyPath(), "SSH private key path")
+	cmd.Flags().StringVar(&initVolumePath, "volume-path", config.DefaultVolumePath, "Default volume path on NAS")
+	cmd.Flags().StringSliceVar(&initAuth, "auth", nil, "SSH auth methods to try, in order (agent, publickey, password, keyboard-interactive; default agent,publickey)")
+	cmd.Flags().BoolVar(&initInsecure, "insecure-skip-host-key-check", false, "Disable SSH host key verification (not recommended)")
+	cmd.Flags().StringVar(&initTransport, "transport", "", "How to reach the NAS: auto, ssh or dsm (default auto: SSH, falling back to the DSM Web API when --dsm-url is set)")
+	cmd.Flags().StringVar(&initDSMURL, "dsm-url", "", "DSM Web API address, e.g. https://nas.local:5001 (default https://<host>:5001 with --transport dsm)")
+	cmd.Flags().BoolVar(&initDSMInsecure, "dsm-insecure", false, "Accept the DSM self-signed TLS certificate")
 }
@@ -5,9 +5,7 @@ import (
 
 	"github.com/spf13/cobra"
 
-	"github.com/scttfrdmn/syno-docker/pkg/config"
 	"github.com/scttfrdmn/syno-docker/pkg/deploy"
-	"github.com/scttfrdmn/syno-docker/pkg/synology"
 )
 
 var (
@@ -25,16 +23,10 @@ var inspectCmd = &cobra.Command{
 }
 
 func inspectObjects(cmd *cobra.Command, args []string) error {
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
-	}
-
 	// Connect to Synology NAS
-	conn := synology.NewConnection(cfg)
-	if err := conn.Connect(); err != nil {
-		return fmt.Errorf("connection failed: %w", err)
+	_, conn, err := connectNAS(cmd, nil)
+	if err != nil {
+		return err
 	}
 	defer conn.Close()
 
@@ -46,7 +38,7 @@ func inspectObjects(cmd *cobra.Command, args []string) error {
 	}
 
 	for _, objectName := range args {
-		info, err := deploy.InspectObject(conn, objectName, opts)
+		info, err := deploy.InspectObjectContext(cmd.Context(), conn, objectName, opts)
 		if err != nil {
 			return fmt.Errorf("failed to inspect object %s: %w", objectName, err)
 		}
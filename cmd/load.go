@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/scttfrdmn/syno-docker/pkg/deploy"
+)
+
+var loadCmd = &cobra.Command{
+	Use:   "load [OPTIONS]",
+	Short: "Load images from a tar archive",
+	Long: `Load images from a tar archive created by "docker save" or "syno-docker save"
+onto your Synology NAS. The archive is read from a local file, or STDIN, and
+streamed over SSH, so images can be sideloaded onto a NAS without internet
+access. Archives compressed with gzip, bzip2 or xz are accepted.`,
+	Example: `  syno-docker load -i nginx.tar
+  docker save my-app:1.0 | syno-docker load`,
+	Args: cobra.NoArgs,
+	RunE: loadImages,
+}
+
+var (
+	loadInput string
+	loadQuiet bool
+)
+
+func loadImages(cmd *cobra.Command, args []string) error {
+	if (loadInput == "" || loadInput == "-") && term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("requested load from STDIN, but STDIN is a terminal; use --input or redirect STDIN")
+	}
+
+	// Connect to Synology NAS
+	_, conn, err := connectNAS(cmd, os.Stdout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Load images
+	opts := &deploy.LoadOptions{
+		Input: loadInput,
+		Quiet: loadQuiet,
+	}
+	if loadInput == "-" {
+		opts.Input = ""
+	}
+	if !loadQuiet && term.IsTerminal(int(os.Stderr.Fd())) {
+		opts.Progress = os.Stderr
+	}
+
+	fmt.Println("Loading images...")
+	output, err := deploy.LoadImagesContext(cmd.Context(), conn, opts)
+	if err != nil {
+		return fmt.Errorf("failed to load images: %w", err)
+	}
+
+	fmt.Print(output)
+	fmt.Println("✅ Images loaded successfully!")
+	return nil
+}
+
+func init() {
+	loadCmd.Flags().StringVarP(&loadInput, "input", "i", "", "Read from a local tar archive file, instead of STDIN")
+	loadCmd.Flags().BoolVarP(&loadQuiet, "quiet", "q", false, "Suppress the load output")
+}
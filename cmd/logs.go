@@ -1,14 +1,14 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 
 	"github.com/spf13/cobra"
 
-	"github.com/scttfrdmn/syno-docker/pkg/config"
 	"github.com/scttfrdmn/syno-docker/pkg/deploy"
-	"github.com/scttfrdmn/syno-docker/pkg/synology"
 )
 
 var (
@@ -29,26 +29,25 @@ var logsCmd = &cobra.Command{
 func showContainerLogs(cmd *cobra.Command, args []string) error {
 	containerNameOrID := args[0]
 
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
-	}
-
 	// Connect to Synology NAS
-	conn := synology.NewConnection(cfg)
-	if err := conn.Connect(); err != nil {
-		return fmt.Errorf("connection failed: %w", err)
+	_, conn, err := connectNAS(cmd, nil)
+	if err != nil {
+		return err
 	}
 	defer conn.Close()
 
 	// Show container logs
 	if logsFollow {
 		fmt.Printf("Following logs for container %s (press Ctrl+C to stop)...\n", containerNameOrID)
-		return deploy.FollowContainerLogs(conn, containerNameOrID, logsTail, logsSince, logsTimestamps, os.Stdout, os.Stderr)
+		err := deploy.FollowContainerLogsContext(cmd.Context(), conn, containerNameOrID, logsTail, logsSince, logsTimestamps, os.Stdout, os.Stderr)
+		if errors.Is(err, context.Canceled) {
+			// Ctrl+C is the normal way to stop following
+			return nil
+		}
+		return err
 	} else {
 		fmt.Printf("Fetching logs for container %s...\n", containerNameOrID)
-		logs, err := deploy.GetContainerLogs(conn, containerNameOrID, logsTail, logsSince, logsTimestamps)
+		logs, err := deploy.GetContainerLogsContext(cmd.Context(), conn, containerNameOrID, logsTail, logsSince, logsTimestamps)
 		if err != nil {
 			return fmt.Errorf("failed to get container logs: %w", err)
 		}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/template"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/syno-docker/pkg/config"
+	"github.com/scttfrdmn/syno-docker/pkg/synology"
+)
+
+var nasCmd = &cobra.Command{
+	Use:   "nas",
+	Short: "Inspect the Synology NAS",
+	Long:  `Inspect the Synology NAS itself rather than its containers.`,
+}
+
+var nasInfoCmd = &cobra.Command{
+	Use:   "info",
+	Short: "Show the DSM release, model, CPU architecture and Docker version",
+	Long: `Show what the NAS runs: its DSM release, model, CPU architecture and the
+Container Manager Docker version. The result is cached per host for a day in
+~/.syno-docker/nas-info.json; --refresh probes the NAS again.`,
+	Example: `  syno-docker nas info
+  syno-docker nas info --format '{{.Platform}}'
+  syno-docker nas info --format '{{json .}}'`,
+	Args: cobra.NoArgs,
+	RunE: showNASInfo,
+}
+
+var (
+	nasInfoFormat  string
+	nasInfoRefresh bool
+)
+
+func showNASInfo(cmd *cobra.Command, args []string) error {
+	var tmpl *template.Template
+	if nasInfoFormat != "" {
+		var err error
+		tmpl, err = template.New("format").Funcs(template.FuncMap{
+			"json": func(v any) (string, error) {
+				data, err := json.Marshal(v)
+				return string(data), err
+			},
+		}).Parse(nasInfoFormat)
+		if err != nil {
+			return fmt.Errorf("invalid format: %w", err)
+		}
+	}
+
+	return forEachHost(cmd, func(ctx context.Context, cfg *config.Config, conn connection, out io.Writer) error {
+		info, err := probeNAS(ctx, conn, nasInfoRefresh)
+		if err != nil {
+			return fmt.Errorf("failed to probe the NAS: %w", err)
+		}
+
+		if tmpl != nil {
+			if err := tmpl.Execute(out, info); err != nil {
+				return fmt.Errorf("failed to format NAS info: %w", err)
+			}
+			fmt.Fprintln(out)
+			return nil
+		}
+		return printNASInfo(cfg, info, out)
+	})
+}
+
+// printNASInfo shows what one NAS runs, as a list, or as a table row when
+// the command runs against several NAS
+func printNASInfo(cfg *config.Config, info *synology.NASInfo, out io.Writer) error {
+	model := info.Model
+	if model == "" {
+		model = "unknown"
+	}
+	docker := "not running"
+	if info.DockerVersion != "" {
+		docker = fmt.Sprintf("%s (API %s)", info.DockerVersion, info.DockerAPIVersion)
+	}
+
+	if isFanOut(out) {
+		fmt.Fprintln(out, "MODEL\tDSM\tARCHITECTURE\tDOCKER")
+		fmt.Fprintf(out, "%s\t%s\t%s (%s)\t%s\n", model, info.DSMRelease(), info.Arch, info.Platform(), docker)
+		return nil
+	}
+
+	w := newTable(out)
+	fmt.Fprintf(w, "Host:\t%s\n", cfg.Host)
+	fmt.Fprintf(w, "Model:\t%s\n", model)
+	fmt.Fprintf(w, "DSM:\t%s\n", info.DSMRelease())
+	fmt.Fprintf(w, "Architecture:\t%s (%s)\n", info.Arch, info.Platform())
+	fmt.Fprintf(w, "Docker:\t%s\n", docker)
+	fmt.Fprintf(w, "Probed:\t%s\n", info.ProbedAt.Local().Format(time.RFC1123))
+	return w.Flush()
+}
+
+// probeNAS returns what the NAS runs, from the cache unless refresh is set
+// or conn can't cache it
+func probeNAS(ctx context.Context, conn connection, refresh bool) (*synology.NASInfo, error) {
+	sshConn, ok := conn.(*synology.Connection)
+	switch {
+	case !ok:
+		return synology.Probe(ctx, conn)
+	case refresh:
+		return sshConn.RefreshNASInfo(ctx)
+	}
+	return sshConn.NASInfo(ctx)
+}
+
+func init() {
+	nasCmd.AddCommand(nasInfoCmd)
+
+	nasInfoCmd.Flags().StringVar(&nasInfoFormat, "format", "", "Format the output using the given Go template")
+	nasInfoCmd.Flags().BoolVar(&nasInfoRefresh, "refresh", false, "Probe the NAS again instead of using the cached result")
+}
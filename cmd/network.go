@@ -1,15 +1,15 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
-	"text/tabwriter"
 
 	"github.com/spf13/cobra"
 
 	"github.com/scttfrdmn/syno-docker/pkg/config"
 	"github.com/scttfrdmn/syno-docker/pkg/deploy"
-	"github.com/scttfrdmn/syno-docker/pkg/synology"
 )
 
 var networkCmd = &cobra.Command{
@@ -111,19 +111,11 @@ var (
 )
 
 func listNetworks(cmd *cobra.Command, args []string) error {
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
-	}
-
-	// Connect to Synology NAS
-	conn := synology.NewConnection(cfg)
-	if err := conn.Connect(); err != nil {
-		return fmt.Errorf("connection failed: %w", err)
-	}
-	defer conn.Close()
+	return forEachHost(cmd, printNetworks)
+}
 
+// printNetworks lists the networks on one NAS
+func printNetworks(ctx context.Context, cfg *config.Config, conn connection, out io.Writer) error {
 	// List networks
 	opts := &deploy.NetworkListOptions{
 		Format: networkListFormat,
@@ -132,28 +124,28 @@ func listNetworks(cmd *cobra.Command, args []string) error {
 	}
 
 	if networkListQuiet {
-		networkIDs, err := deploy.ListNetworkIDs(conn, opts)
+		networkIDs, err := deploy.ListNetworkIDsContext(ctx, conn, opts)
 		if err != nil {
 			return fmt.Errorf("failed to list networks: %w", err)
 		}
 		for _, id := range networkIDs {
-			fmt.Println(id)
+			fmt.Fprintln(out, id)
 		}
 		return nil
 	}
 
-	networks, err := deploy.ListNetworks(conn, opts)
+	networks, err := deploy.ListNetworksContext(ctx, conn, opts)
 	if err != nil {
 		return fmt.Errorf("failed to list networks: %w", err)
 	}
 
 	if len(networks) == 0 {
-		fmt.Println("No networks found.")
+		fmt.Fprintln(out, "No networks found.")
 		return nil
 	}
 
 	// Display networks in table format
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	w := newTable(out)
 	fmt.Fprintln(w, "NETWORK ID\tNAME\tDRIVER\tSCOPE")
 
 	for _, network := range networks {
@@ -167,17 +159,10 @@ func listNetworks(cmd *cobra.Command, args []string) error {
 func createNetwork(cmd *cobra.Command, args []string) error {
 	networkName := args[0]
 
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
-	}
-
 	// Connect to Synology NAS
-	fmt.Printf("Connecting to %s@%s:%d...\n", cfg.User, cfg.Host, cfg.Port)
-	conn := synology.NewConnection(cfg)
-	if err := conn.Connect(); err != nil {
-		return fmt.Errorf("connection failed: %w", err)
+	_, conn, err := connectNAS(cmd, os.Stdout)
+	if err != nil {
+		return err
 	}
 	defer conn.Close()
 
@@ -197,7 +182,7 @@ func createNetwork(cmd *cobra.Command, args []string) error {
 	}
 
 	fmt.Printf("Creating network %s...\n", networkName)
-	networkID, err := deploy.CreateNetwork(conn, networkName, opts)
+	networkID, err := deploy.CreateNetworkContext(cmd.Context(), conn, networkName, opts)
 	if err != nil {
 		return fmt.Errorf("failed to create network: %w", err)
 	}
@@ -207,24 +192,17 @@ func createNetwork(cmd *cobra.Command, args []string) error {
 }
 
 func removeNetworks(cmd *cobra.Command, args []string) error {
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
-	}
-
 	// Connect to Synology NAS
-	fmt.Printf("Connecting to %s@%s:%d...\n", cfg.User, cfg.Host, cfg.Port)
-	conn := synology.NewConnection(cfg)
-	if err := conn.Connect(); err != nil {
-		return fmt.Errorf("connection failed: %w", err)
+	_, conn, err := connectNAS(cmd, os.Stdout)
+	if err != nil {
+		return err
 	}
 	defer conn.Close()
 
 	// Remove each network
 	for _, networkName := range args {
 		fmt.Printf("Removing network %s...\n", networkName)
-		if err := deploy.RemoveNetwork(conn, networkName); err != nil {
+		if err := deploy.RemoveNetworkContext(cmd.Context(), conn, networkName); err != nil {
 			return fmt.Errorf("failed to remove network %s: %w", networkName, err)
 		}
 		fmt.Printf("✅ Network %s removed successfully!\n", networkName)
@@ -234,16 +212,10 @@ func removeNetworks(cmd *cobra.Command, args []string) error {
 }
 
 func inspectNetworks(cmd *cobra.Command, args []string) error {
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
-	}
-
 	// Connect to Synology NAS
-	conn := synology.NewConnection(cfg)
-	if err := conn.Connect(); err != nil {
-		return fmt.Errorf("connection failed: %w", err)
+	_, conn, err := connectNAS(cmd, nil)
+	if err != nil {
+		return err
 	}
 	defer conn.Close()
 
@@ -253,7 +225,7 @@ func inspectNetworks(cmd *cobra.Command, args []string) error {
 	}
 
 	for _, networkName := range args {
-		info, err := deploy.InspectNetwork(conn, networkName, opts)
+		info, err := deploy.InspectNetworkContext(cmd.Context(), conn, networkName, opts)
 		if err != nil {
 			return fmt.Errorf("failed to inspect network %s: %w", networkName, err)
 		}
@@ -267,17 +239,10 @@ func connectToNetwork(cmd *cobra.Command, args []string) error {
 	networkName := args[0]
 	containerName := args[1]
 
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
-	}
-
 	// Connect to Synology NAS
-	fmt.Printf("Connecting to %s@%s:%d...\n", cfg.User, cfg.Host, cfg.Port)
-	conn := synology.NewConnection(cfg)
-	if err := conn.Connect(); err != nil {
-		return fmt.Errorf("connection failed: %w", err)
+	_, conn, err := connectNAS(cmd, os.Stdout)
+	if err != nil {
+		return err
 	}
 	defer conn.Close()
 
@@ -290,7 +255,7 @@ func connectToNetwork(cmd *cobra.Command, args []string) error {
 	}
 
 	fmt.Printf("Connecting container %s to network %s...\n", containerName, networkName)
-	if err := deploy.ConnectContainerToNetwork(conn, networkName, containerName, opts); err != nil {
+	if err := deploy.ConnectContainerToNetworkContext(cmd.Context(), conn, networkName, containerName, opts); err != nil {
 		return fmt.Errorf("failed to connect container to network: %w", err)
 	}
 
@@ -302,17 +267,10 @@ func disconnectFromNetwork(cmd *cobra.Command, args []string) error {
 	networkName := args[0]
 	containerName := args[1]
 
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
-	}
-
 	// Connect to Synology NAS
-	fmt.Printf("Connecting to %s@%s:%d...\n", cfg.User, cfg.Host, cfg.Port)
-	conn := synology.NewConnection(cfg)
-	if err := conn.Connect(); err != nil {
-		return fmt.Errorf("connection failed: %w", err)
+	_, conn, err := connectNAS(cmd, os.Stdout)
+	if err != nil {
+		return err
 	}
 	defer conn.Close()
 
@@ -322,7 +280,7 @@ func disconnectFromNetwork(cmd *cobra.Command, args []string) error {
 	}
 
 	fmt.Printf("Disconnecting container %s from network %s...\n", containerName, networkName)
-	if err := deploy.DisconnectContainerFromNetwork(conn, networkName, containerName, opts); err != nil {
+	if err := deploy.DisconnectContainerFromNetworkContext(cmd.Context(), conn, networkName, containerName, opts); err != nil {
 		return fmt.Errorf("failed to disconnect container from network: %w", err)
 	}
 
@@ -331,17 +289,10 @@ func disconnectFromNetwork(cmd *cobra.Command, args []string) error {
 }
 
 func pruneNetworks(cmd *cobra.Command, args []string) error {
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
-	}
-
 	// Connect to Synology NAS
-	fmt.Printf("Connecting to %s@%s:%d...\n", cfg.User, cfg.Host, cfg.Port)
-	conn := synology.NewConnection(cfg)
-	if err := conn.Connect(); err != nil {
-		return fmt.Errorf("connection failed: %w", err)
+	_, conn, err := connectNAS(cmd, os.Stdout)
+	if err != nil {
+		return err
 	}
 	defer conn.Close()
 
@@ -364,7 +315,7 @@ func pruneNetworks(cmd *cobra.Command, args []string) error {
 		Filter: networkPruneFilter,
 	}
 
-	result, err := deploy.PruneNetworks(conn, opts)
+	result, err := deploy.PruneNetworksContext(cmd.Context(), conn, opts)
 	if err != nil {
 		return fmt.Errorf("failed to prune networks: %w", err)
 	}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/syno-docker/pkg/config"
+)
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage connection profiles for several NAS",
+	Long: `Manage named connection profiles, one per Synology NAS. Each profile keeps
+its own host, user, SSH key and defaults in ~/.syno-docker/config.yaml.
+
+Commands use the profile named by --profile, then $` + config.ProfileEnv + `, then
+the profile set in the nearest ` + config.ProjectConfigFile + `, then the current profile
+set with 'syno-docker profile use'.
+
+A pattern such as '*' or 'edge-*' runs ps, images, pull, start, stop,
+restart, volume ls, network ls, system df, system info and nas info against
+every matching profile concurrently. Tables gain a HOST column, other output
+is prefixed with the host, and the command fails if any host failed.`,
+	Example: `  syno-docker profile add home nas.local --user admin
+  syno-docker profile add office 10.0.0.5 --key ~/.ssh/office_ed25519
+  syno-docker profile use office
+  syno-docker --profile home ps
+  syno-docker --profile '*' ps
+  syno-docker --profile 'edge-*' pull nginx`,
+}
+
+var profileAddCmd = &cobra.Command{
+	Use:   "add NAME HOST",
+	Short: "Add a profile, testing the connection to HOST",
+	Long: `Add a profile or replace an existing one. It takes the same flags as
+'syno-docker init' and tests the connection before saving.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.ValidateProfileName(args[0]); err != nil {
+			return err
+		}
+		return initProfile(cmd, args[0], args[1])
+	},
+}
+
+var profileListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List profiles",
+	Args:    cobra.NoArgs,
+	RunE:    listProfiles,
+}
+
+var profileRmCmd = &cobra.Command{
+	Use:     "rm NAME",
+	Aliases: []string{"remove"},
+	Short:   "Remove a profile",
+	Args:    cobra.ExactArgs(1),
+	RunE:    removeProfile,
+}
+
+var profileUseCmd = &cobra.Command{
+	Use:   "use NAME",
+	Short: "Make a profile the current one",
+	Args:  cobra.ExactArgs(1),
+	RunE:  useProfile,
+}
+
+func listProfiles(cmd *cobra.Command, args []string) error {
+	f, err := config.LoadFile()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if len(f.Profiles) == 0 {
+		fmt.Println("No profiles. Run 'syno-docker init <host>' or 'syno-docker profile add <name> <host>' first.")
+		return nil
+	}
+
+	active := f.ActiveProfile()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CURRENT\tNAME\tHOST\tUSER\tPORT\tTRANSPORT")
+	for _, name := range f.Names() {
+		cfg := f.Profiles[name]
+		current := ""
+		if name == active {
+			current = "*"
+		}
+		transport := cfg.Transport
+		if transport == "" {
+			transport = config.TransportAuto
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n", current, name, cfg.Host, cfg.User, cfg.Port, transport)
+	}
+	return w.Flush()
+}
+
+func removeProfile(cmd *cobra.Command, args []string) error {
+	f, err := config.LoadFile()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := f.RemoveProfile(args[0]); err != nil {
+		return err
+	}
+	if err := f.Save(); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+
+	fmt.Printf("✅ Profile %s removed\n", args[0])
+	if f.CurrentProfile == "" && len(f.Profiles) > 0 {
+		fmt.Println("No current profile; choose one with 'syno-docker profile use <name>'")
+	}
+	return nil
+}
+
+func useProfile(cmd *cobra.Command, args []string) error {
+	f, err := config.LoadFile()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := f.UseProfile(args[0]); err != nil {
+		return err
+	}
+	if err := f.Save(); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+
+	fmt.Printf("✅ Now using profile %s (%s)\n", args[0], f.Profiles[args[0]].Host)
+	if env := os.Getenv(config.ProfileEnv); env != "" && env != args[0] {
+		fmt.Printf("Note: $%s=%s takes precedence in this shell\n", config.ProfileEnv, env)
+	}
+	return nil
+}
+
+func init() {
+	profileCmd.AddCommand(profileAddCmd)
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileRmCmd)
+	profileCmd.AddCommand(profileUseCmd)
+
+	addInitFlags(profileAddCmd)
+}
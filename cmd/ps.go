@@ -1,15 +1,14 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
-	"os"
-	"text/tabwriter"
+	"io"
 
 	"github.com/spf13/cobra"
 
 	"github.com/scttfrdmn/syno-docker/pkg/config"
 	"github.com/scttfrdmn/syno-docker/pkg/deploy"
-	"github.com/scttfrdmn/syno-docker/pkg/synology"
 )
 
 var psAll bool
@@ -22,32 +21,24 @@ var psCmd = &cobra.Command{
 }
 
 func listContainers(cmd *cobra.Command, args []string) error {
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
-	}
-
-	// Connect to Synology NAS
-	conn := synology.NewConnection(cfg)
-	if err := conn.Connect(); err != nil {
-		return fmt.Errorf("connection failed: %w", err)
-	}
-	defer conn.Close()
+	return forEachHost(cmd, printContainers)
+}
 
+// printContainers lists the containers on one NAS
+func printContainers(ctx context.Context, cfg *config.Config, conn connection, out io.Writer) error {
 	// List containers
-	containers, err := deploy.ListContainers(conn, psAll)
+	containers, err := deploy.ListContainersContext(ctx, conn, psAll)
 	if err != nil {
 		return fmt.Errorf("failed to list containers: %w", err)
 	}
 
 	if len(containers) == 0 {
-		fmt.Println("No containers found.")
+		fmt.Fprintln(out, "No containers found.")
 		return nil
 	}
 
 	// Display containers in a table format
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	w := newTable(out)
 	fmt.Fprintln(w, "CONTAINER ID\tNAME\tIMAGE\tSTATUS\tPORTS")
 
 	for _, container := range containers {
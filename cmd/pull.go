@@ -1,13 +1,14 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
 
 	"github.com/spf13/cobra"
 
 	"github.com/scttfrdmn/syno-docker/pkg/config"
 	"github.com/scttfrdmn/syno-docker/pkg/deploy"
-	"github.com/scttfrdmn/syno-docker/pkg/synology"
 )
 
 var (
@@ -28,35 +29,24 @@ var pullCmd = &cobra.Command{
 func pullImage(cmd *cobra.Command, args []string) error {
 	imageName := args[0]
 
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
-	}
-
-	// Connect to Synology NAS
-	fmt.Printf("Connecting to %s@%s:%d...\n", cfg.User, cfg.Host, cfg.Port)
-	conn := synology.NewConnection(cfg)
-	if err := conn.Connect(); err != nil {
-		return fmt.Errorf("connection failed: %w", err)
-	}
-	defer conn.Close()
-
-	// Pull image
-	opts := &deploy.PullOptions{
-		AllTags:             pullAllTags,
-		Platform:            pullPlatform,
-		Quiet:               pullQuiet,
-		DisableContentTrust: pullDisableContentTrust,
-	}
-
-	fmt.Printf("Pulling image %s...\n", imageName)
-	if err := deploy.PullImage(conn, imageName, opts); err != nil {
-		return fmt.Errorf("failed to pull image: %w", err)
-	}
-
-	fmt.Printf("✅ Image %s pulled successfully!\n", imageName)
-	return nil
+	return forEachHost(cmd, func(ctx context.Context, cfg *config.Config, conn connection, out io.Writer) error {
+		// Pull image
+		opts := &deploy.PullOptions{
+			AllTags:             pullAllTags,
+			Platform:            pullPlatform,
+			Quiet:               pullQuiet,
+			DisableContentTrust: pullDisableContentTrust,
+			Stdout:              out,
+		}
+
+		fmt.Fprintf(out, "Pulling image %s...\n", imageName)
+		if err := deploy.PullImageContext(ctx, conn, imageName, opts); err != nil {
+			return fmt.Errorf("failed to pull image: %w", err)
+		}
+
+		fmt.Fprintf(out, "✅ Image %s pulled successfully!\n", imageName)
+		return nil
+	})
 }
 
 func init() {
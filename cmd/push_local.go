@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/docker/go-units"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/scttfrdmn/syno-docker/pkg/deploy"
+)
+
+var pushLocalCmd = &cobra.Command{
+	Use:   "push-local [OPTIONS] IMAGE",
+	Short: "Push an image from the local Docker daemon to the NAS",
+	Long: `Push an image built on your local machine to your Synology NAS without a
+registry. The image is read from the local Docker daemon, configured from the
+environment like the docker CLI (DOCKER_HOST and friends), and streamed into
+the Docker daemon on the NAS.
+
+Layers the NAS already has, such as a shared base image, are compared by the
+layer digests from "docker image inspect" and not sent again.`,
+	Example: `  docker build -t my-app:1.0 .
+  syno-docker push-local my-app:1.0`,
+	Args: cobra.ExactArgs(1),
+	RunE: pushLocal,
+}
+
+var pushLocalQuiet bool
+
+func pushLocal(cmd *cobra.Command, args []string) error {
+	image := args[0]
+
+	// Connect to Synology NAS
+	_, conn, err := connectNAS(cmd, os.Stdout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Push the image
+	opts := &deploy.PushLocalOptions{}
+	if !pushLocalQuiet && term.IsTerminal(int(os.Stderr.Fd())) {
+		opts.Progress = os.Stderr
+	}
+
+	fmt.Printf("Pushing %s from the local Docker daemon...\n", image)
+	result, err := deploy.PushLocalContext(cmd.Context(), conn, image, opts)
+	if err != nil {
+		return fmt.Errorf("failed to push image: %w", err)
+	}
+
+	fmt.Print(result.Output)
+	fmt.Printf("Layers: %d transferred, %d already on the NAS (%s sent, %s skipped)\n",
+		result.Transferred(), result.Skipped, units.HumanSize(float64(result.Bytes)), units.HumanSize(float64(result.SkippedBytes)))
+	fmt.Printf("✅ Image %s pushed successfully!\n", image)
+	return nil
+}
+
+func init() {
+	pushLocalCmd.Flags().BoolVarP(&pushLocalQuiet, "quiet", "q", false, "Suppress progress output")
+}
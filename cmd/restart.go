@@ -1,13 +1,14 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
 
 	"github.com/spf13/cobra"
 
 	"github.com/scttfrdmn/syno-docker/pkg/config"
 	"github.com/scttfrdmn/syno-docker/pkg/deploy"
-	"github.com/scttfrdmn/syno-docker/pkg/synology"
 )
 
 var restartTimeout int
@@ -21,30 +22,18 @@ var restartCmd = &cobra.Command{
 }
 
 func restartContainers(cmd *cobra.Command, args []string) error {
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
-	}
-
-	// Connect to Synology NAS
-	fmt.Printf("Connecting to %s@%s:%d...\n", cfg.User, cfg.Host, cfg.Port)
-	conn := synology.NewConnection(cfg)
-	if err := conn.Connect(); err != nil {
-		return fmt.Errorf("connection failed: %w", err)
-	}
-	defer conn.Close()
-
-	// Restart each container
-	for _, containerNameOrID := range args {
-		fmt.Printf("Restarting container %s...\n", containerNameOrID)
-		if err := deploy.RestartContainer(conn, containerNameOrID, restartTimeout); err != nil {
-			return fmt.Errorf("failed to restart container %s: %w", containerNameOrID, err)
+	return forEachHost(cmd, func(ctx context.Context, cfg *config.Config, conn connection, out io.Writer) error {
+		// Restart each container
+		for _, containerNameOrID := range args {
+			fmt.Fprintf(out, "Restarting container %s...\n", containerNameOrID)
+			if err := deploy.RestartContainerContext(ctx, conn, containerNameOrID, restartTimeout); err != nil {
+				return fmt.Errorf("failed to restart container %s: %w", containerNameOrID, err)
+			}
+			fmt.Fprintf(out, "✅ Container %s restarted successfully!\n", containerNameOrID)
 		}
-		fmt.Printf("✅ Container %s restarted successfully!\n", containerNameOrID)
-	}
 
-	return nil
+		return nil
+	})
 }
 
 func init() {
@@ -2,12 +2,11 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/spf13/cobra"
 
-	"github.com/scttfrdmn/syno-docker/pkg/config"
 	"github.com/scttfrdmn/syno-docker/pkg/deploy"
-	"github.com/scttfrdmn/syno-docker/pkg/synology"
 )
 
 var rmForce bool
@@ -23,23 +22,16 @@ var rmCmd = &cobra.Command{
 func removeContainer(cmd *cobra.Command, args []string) error {
 	containerNameOrID := args[0]
 
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
-	}
-
 	// Connect to Synology NAS
-	fmt.Printf("Connecting to %s@%s:%d...\n", cfg.User, cfg.Host, cfg.Port)
-	conn := synology.NewConnection(cfg)
-	if err := conn.Connect(); err != nil {
-		return fmt.Errorf("connection failed: %w", err)
+	_, conn, err := connectNAS(cmd, os.Stdout)
+	if err != nil {
+		return err
 	}
 	defer conn.Close()
 
 	// Remove container
 	fmt.Printf("Removing container %s...\n", containerNameOrID)
-	if err := deploy.RemoveContainer(conn, containerNameOrID, rmForce); err != nil {
+	if err := deploy.RemoveContainerContext(cmd.Context(), conn, containerNameOrID, rmForce); err != nil {
 		return fmt.Errorf("failed to remove container: %w", err)
 	}
 
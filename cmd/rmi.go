@@ -2,12 +2,11 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/spf13/cobra"
 
-	"github.com/scttfrdmn/syno-docker/pkg/config"
 	"github.com/scttfrdmn/syno-docker/pkg/deploy"
-	"github.com/scttfrdmn/syno-docker/pkg/synology"
 )
 
 var (
@@ -24,17 +23,10 @@ var rmiCmd = &cobra.Command{
 }
 
 func removeImages(cmd *cobra.Command, args []string) error {
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
-	}
-
 	// Connect to Synology NAS
-	fmt.Printf("Connecting to %s@%s:%d...\n", cfg.User, cfg.Host, cfg.Port)
-	conn := synology.NewConnection(cfg)
-	if err := conn.Connect(); err != nil {
-		return fmt.Errorf("connection failed: %w", err)
+	_, conn, err := connectNAS(cmd, os.Stdout)
+	if err != nil {
+		return err
 	}
 	defer conn.Close()
 
@@ -46,7 +38,7 @@ func removeImages(cmd *cobra.Command, args []string) error {
 
 	for _, imageName := range args {
 		fmt.Printf("Removing image %s...\n", imageName)
-		if err := deploy.RemoveImage(conn, imageName, opts); err != nil {
+		if err := deploy.RemoveImageContext(cmd.Context(), conn, imageName, opts); err != nil {
 			return fmt.Errorf("failed to remove image %s: %w", imageName, err)
 		}
 		fmt.Printf("✅ Image %s removed successfully!\n", imageName)
@@ -1,9 +1,16 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/syno-docker/pkg/config"
 )
 
 var (
@@ -15,6 +22,18 @@ var (
 	Date string
 )
 
+var (
+	// commandTimeout bounds the whole command, including connecting
+	commandTimeout time.Duration
+	// cancelTimeout releases the timeout context created for the command
+	cancelTimeout context.CancelFunc = func() {}
+	// profileName selects the configuration profile for the command
+	profileName string
+	// connOverrides are the connection settings given with --host,
+	// --ssh-user, --ssh-port and --ssh-key
+	connOverrides config.Overrides
+)
+
 var rootCmd = &cobra.Command{
 	Use:   "syno-docker",
 	Short: "Deploy containers to Synology DSM 7.2+",
@@ -22,6 +41,21 @@ var rootCmd = &cobra.Command{
 to Synology NAS devices running DSM 7.2+. It handles SSH connection management,
 Docker client setup, and path resolution issues specific to Synology Container Manager.`,
 	Version: getVersion(),
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if profileName != "" {
+			if err := config.ValidateProfilePattern(profileName); err != nil {
+				return err
+			}
+			config.SelectProfile(profileName)
+		}
+		config.SetOverrides(connOverrides)
+		if commandTimeout > 0 {
+			ctx, cancel := context.WithTimeout(cmd.Context(), commandTimeout)
+			cancelTimeout = cancel
+			cmd.SetContext(ctx)
+		}
+		return nil
+	},
 }
 
 func getVersion() string {
@@ -31,25 +65,42 @@ func getVersion() string {
 	return fmt.Sprintf("%s (commit %s, built %s)", Version, Commit, Date)
 }
 
-// Execute runs the root command
+// Execute runs the root command. Interrupts cancel the command's context so
+// that remote processes are stopped rather than left running on the NAS.
 func Execute() error {
-	return rootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	defer func() { cancelTimeout() }()
+
+	return rootCmd.ExecuteContext(ctx)
 }
 
 func init() {
+	rootCmd.PersistentFlags().DurationVar(&commandTimeout, "timeout", 0, "Maximum time to allow the command to run (e.g. 30s, 5m); 0 means no limit")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "Configuration profile to use (default $"+config.ProfileEnv+", then "+config.ProjectConfigFile+", then the current profile); a pattern such as 'edge-*' selects several, see 'syno-docker profile --help'")
+
+	rootCmd.PersistentFlags().StringVar(&connOverrides.Host, "host", "", "NAS host, overriding the profile; with no configuration file this is enough to connect (env "+config.HostEnv+")")
+	rootCmd.PersistentFlags().StringVar(&connOverrides.User, "ssh-user", "", "SSH username, overriding the profile (env "+config.UserEnv+")")
+	rootCmd.PersistentFlags().IntVar(&connOverrides.Port, "ssh-port", 0, "SSH port, overriding the profile (env "+config.PortEnv+")")
+	rootCmd.PersistentFlags().StringVar(&connOverrides.SSHKeyPath, "ssh-key", "", "SSH private key path, overriding the profile (env "+config.KeyEnv+")")
+
 	rootCmd.AddCommand(initCmd)
+	rootCmd.AddCommand(profileCmd)
+	rootCmd.AddCommand(configCmd)
 	rootCmd.AddCommand(runCmd)
 	rootCmd.AddCommand(deployCmd)
 	rootCmd.AddCommand(psCmd)
 	rootCmd.AddCommand(rmCmd)
 	rootCmd.AddCommand(logsCmd)
 	rootCmd.AddCommand(execCmd)
+	rootCmd.AddCommand(attachCmd)
 	rootCmd.AddCommand(restartCmd)
 	rootCmd.AddCommand(startCmd)
 	rootCmd.AddCommand(stopCmd)
 	rootCmd.AddCommand(statsCmd)
 	rootCmd.AddCommand(imagesCmd)
 	rootCmd.AddCommand(pullCmd)
+	rootCmd.AddCommand(buildCmd)
 	rootCmd.AddCommand(rmiCmd)
 	rootCmd.AddCommand(systemCmd)
 	rootCmd.AddCommand(volumeCmd)
@@ -57,4 +108,12 @@ func init() {
 	rootCmd.AddCommand(inspectCmd)
 	rootCmd.AddCommand(exportCmd)
 	rootCmd.AddCommand(importCmd)
+	rootCmd.AddCommand(saveCmd)
+	rootCmd.AddCommand(loadCmd)
+	rootCmd.AddCommand(pushLocalCmd)
+	rootCmd.AddCommand(cpCmd)
+	rootCmd.AddCommand(forwardCmd)
+	rootCmd.AddCommand(socketProxyCmd)
+	rootCmd.AddCommand(nasCmd)
+	rootCmd.AddCommand(daemonCmd)
 }
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/syno-docker/pkg/config"
+)
+
+// overrideFlags are the global flags that override the connection settings
+var overrideFlags = []string{"host", "ssh-user", "ssh-port", "ssh-key"}
+
+func TestSubcommandsAcceptOverrideFlags(t *testing.T) {
+	var visit func(c *cobra.Command)
+	visit = func(c *cobra.Command) {
+		for _, sub := range c.Commands() {
+			for _, name := range overrideFlags {
+				want := rootCmd.PersistentFlags().Lookup(name)
+				if got := sub.InheritedFlags().Lookup(name); got != want {
+					t.Errorf("%s: --%s is hidden by a flag of its own", sub.CommandPath(), name)
+				}
+			}
+			visit(sub)
+		}
+	}
+	visit(rootCmd)
+}
+
+func TestOverrideFlagsBesideContainerFlags(t *testing.T) {
+	t.Cleanup(func() {
+		connOverrides = config.Overrides{}
+		runUser, runPorts = "", nil
+	})
+
+	args := []string{"--ssh-user", "ci", "--ssh-port", "2222", "--user", "1000:1000", "-p", "8080:80"}
+	if err := runCmd.ParseFlags(args); err != nil {
+		t.Fatalf("ParseFlags failed: %v", err)
+	}
+	if connOverrides.User != "ci" || connOverrides.Port != 2222 {
+		t.Errorf("Expected the SSH overrides to be set, got %+v", connOverrides)
+	}
+	if runUser != "1000:1000" || len(runPorts) != 1 || runPorts[0] != "8080:80" {
+		t.Errorf("Expected the container flags to be set, got user %q and ports %v", runUser, runPorts)
+	}
+}
@@ -2,11 +2,12 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
 
-	"github.com/scttfrdmn/syno-docker/pkg/config"
 	"github.com/scttfrdmn/syno-docker/pkg/deploy"
 	"github.com/scttfrdmn/syno-docker/pkg/synology"
 )
@@ -35,17 +36,10 @@ This command pulls the specified image and creates a new container with the give
 func runContainer(cmd *cobra.Command, args []string) error {
 	image := args[0]
 
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
-	}
-
 	// Connect to Synology NAS
-	fmt.Printf("Connecting to %s@%s:%d...\n", cfg.User, cfg.Host, cfg.Port)
-	conn := synology.NewConnection(cfg)
-	if err := conn.Connect(); err != nil {
-		return fmt.Errorf("connection failed: %w", err)
+	cfg, conn, err := connectNAS(cmd, os.Stdout)
+	if err != nil {
+		return err
 	}
 	defer conn.Close()
 
@@ -57,6 +51,10 @@ func runContainer(cmd *cobra.Command, args []string) error {
 	opts.Env = runEnv
 	opts.Restart = runRestart
 	opts.NetworkMode = runNetwork
+	if !cmd.Flags().Changed("network") && cfg.Defaults.Network != "" {
+		opts.NetworkMode = cfg.Defaults.Network
+	}
+	opts.Labels = formatLabels(cfg.Labels)
 	opts.WorkingDir = runWorkingDir
 	opts.User = runUser
 	opts.Command = runCommand
@@ -67,9 +65,9 @@ func runContainer(cmd *cobra.Command, args []string) error {
 	}
 
 	// Deploy container
-	containerID, err := deploy.Container(conn, opts)
+	containerID, err := deploy.ContainerContext(cmd.Context(), conn, opts)
 	if err != nil {
-		return fmt.Errorf("deployment failed: %w", err)
+		return remoteExitError(cmd, fmt.Errorf("deployment failed: %w", err), false)
 	}
 
 	fmt.Printf("✅ Container deployed successfully!\n")
@@ -100,6 +98,16 @@ func processVolumes(volumes []string, defaultVolumePath string) []string {
 	return processed
 }
 
+// formatLabels returns labels as sorted KEY=VALUE strings
+func formatLabels(labels map[string]string) []string {
+	formatted := make([]string, 0, len(labels))
+	for key, value := range labels {
+		formatted = append(formatted, key+"="+value)
+	}
+	sort.Strings(formatted)
+	return formatted
+}
+
 func generateContainerName(image string) string {
 	// Extract image name without registry and tag
 	parts := strings.Split(image, "/")
@@ -119,7 +127,7 @@ func init() {
 	runCmd.Flags().StringSliceVarP(&runVolumes, "volume", "v", []string{}, "Volume mappings (format: host:container)")
 	runCmd.Flags().StringSliceVarP(&runEnv, "env", "e", []string{}, "Environment variables (format: KEY=value)")
 	runCmd.Flags().StringVar(&runRestart, "restart", synology.DefaultRestartPolicy, "Restart policy (no, always, unless-stopped, on-failure)")
-	runCmd.Flags().StringVar(&runNetwork, "network", synology.DefaultNetwork, "Network mode")
+	runCmd.Flags().StringVar(&runNetwork, "network", synology.DefaultNetwork, "Network mode (default: the configured network)")
 	runCmd.Flags().StringVarP(&runWorkingDir, "workdir", "w", "", "Working directory inside container")
 	runCmd.Flags().StringVarP(&runUser, "user", "u", "", "User to run container as (format: uid:gid)")
 	runCmd.Flags().StringSliceVar(&runCommand, "command", []string{}, "Command to run in container")
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/scttfrdmn/syno-docker/pkg/deploy"
+)
+
+var saveCmd = &cobra.Command{
+	Use:   "save [OPTIONS] IMAGE [IMAGE...]",
+	Short: "Save one or more images to a tar archive",
+	Long: `Save one or more images from your Synology NAS, with their layers and tags, to a
+tar archive on your local machine. Load the archive onto another NAS with
+"syno-docker load".
+
+The archive is streamed to the output file, or to STDOUT, and can be
+compressed on the NAS with --compress. An output file ending in .gz, .tgz or
+.zst selects the matching compression automatically.`,
+	Example: `  syno-docker save nginx:alpine -o nginx.tar
+  syno-docker save postgres:16 redis:7 -o services.tar.gz`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: saveImages,
+}
+
+var (
+	saveOutput   string
+	saveCompress string
+	saveQuiet    bool
+)
+
+func saveImages(cmd *cobra.Command, args []string) error {
+	// Status messages must not mix with an archive written to STDOUT
+	status := io.Writer(os.Stdout)
+	if saveOutput == "" || saveOutput == "-" {
+		saveOutput = ""
+		if term.IsTerminal(int(os.Stdout.Fd())) {
+			return fmt.Errorf("refusing to write the archive to a terminal; use --output or redirect STDOUT")
+		}
+		status = os.Stderr
+	}
+
+	compression := saveCompress
+	if !cmd.Flags().Changed("compress") {
+		compression = compressionForFile(saveOutput)
+	}
+
+	// Connect to Synology NAS
+	_, conn, err := connectNAS(cmd, status)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Save images
+	opts := &deploy.SaveOptions{
+		Output:      saveOutput,
+		Compression: compression,
+	}
+	if !saveQuiet && term.IsTerminal(int(os.Stderr.Fd())) {
+		opts.Progress = os.Stderr
+	}
+
+	fmt.Fprintf(status, "Saving %d image(s)...\n", len(args))
+	if err := deploy.SaveImagesContext(cmd.Context(), conn, args, opts); err != nil {
+		return fmt.Errorf("failed to save images: %w", err)
+	}
+
+	if saveOutput != "" {
+		fmt.Fprintf(status, "✅ Images saved to %s successfully!\n", saveOutput)
+	} else {
+		fmt.Fprintf(status, "✅ Images saved successfully!\n")
+	}
+	return nil
+}
+
+func init() {
+	saveCmd.Flags().StringVarP(&saveOutput, "output", "o", "", "Write to a local file, instead of STDOUT")
+	saveCmd.Flags().StringVar(&saveCompress, "compress", "", "Compress the archive on the NAS: gzip or zstd")
+	saveCmd.Flags().BoolVarP(&saveQuiet, "quiet", "q", false, "Suppress progress output")
+}
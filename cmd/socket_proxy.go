@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/syno-docker/pkg/config"
+	"github.com/scttfrdmn/syno-docker/pkg/deploy"
+	"github.com/scttfrdmn/syno-docker/pkg/synology"
+)
+
+var socketProxyCmd = &cobra.Command{
+	Use:   "socket-proxy [OPTIONS]",
+	Short: "Expose the NAS Docker socket locally",
+	Long: `Expose the Docker socket of your Synology NAS as a local unix socket or TCP
+port, tunnelled over the SSH connection, so the docker CLI, docker compose and
+other Docker API clients on your machine can talk to the NAS directly.
+
+By default the socket is created at ~/.syno-docker/run/docker.sock (a TCP port
+on 127.0.0.1 on Windows) and is only accessible to you. A socket given with
+--listen must be in a directory only you can access. Anyone who can connect to
+the proxy controls Docker on the NAS, so binding TCP to an address other than
+loopback is strongly discouraged.
+
+The SSH user needs access to /var/run/docker.sock on the NAS and sshd must
+allow stream local forwarding. The proxy runs until interrupted with Ctrl+C.`,
+	Example: `  syno-docker socket-proxy
+  export DOCKER_HOST=unix://$HOME/.syno-docker/run/docker.sock
+  docker ps
+
+  syno-docker socket-proxy --listen tcp://127.0.0.1:2375`,
+	Args: cobra.NoArgs,
+	RunE: socketProxy,
+}
+
+var socketProxyListen string
+
+func socketProxy(cmd *cobra.Command, args []string) error {
+	listen := socketProxyListen
+	if listen == "" {
+		if runtime.GOOS == "windows" {
+			listen = "tcp://127.0.0.1:2375"
+		} else {
+			path, err := config.GetDockerSocketPath()
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+				return fmt.Errorf("failed to create socket directory: %w", err)
+			}
+			listen = "unix://" + path
+		}
+	}
+
+	network, address, ok := strings.Cut(listen, "://")
+	if !ok || (network != "unix" && network != "tcp") || address == "" {
+		return fmt.Errorf("invalid listen address %q, expected unix://PATH or tcp://HOST:PORT", listen)
+	}
+
+	// Connect to Synology NAS
+	cfg, conn, err := connectNAS(cmd, os.Stdout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	listener, err := listenSocketProxy(network, address)
+	if err != nil {
+		return err
+	}
+	if network == "tcp" {
+		warnIfExposed(listener.Addr())
+	}
+
+	fmt.Printf("Docker socket of %s available at %s://%s\n", cfg.Host, network, listener.Addr())
+	fmt.Printf("  export DOCKER_HOST=%s://%s\n", network, listener.Addr())
+	fmt.Println("Press Ctrl+C to stop")
+	if err := deploy.Forward(cmd.Context(), conn, listener, "unix", synology.SocketPath, &deploy.ForwardOptions{Log: os.Stderr}); err != nil {
+		return fmt.Errorf("proxy failed: %w", err)
+	}
+
+	fmt.Println("✅ Socket proxy stopped")
+	return nil
+}
+
+// listenSocketProxy listens on address. A unix socket left behind by an
+// earlier proxy is replaced, and the new one is only accessible to the
+// current user. As the socket is created with the umask's permissions
+// before they can be restricted, its directory must keep other users out.
+func listenSocketProxy(network, address string) (net.Listener, error) {
+	if network == "tcp" {
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on %s: %w", address, err)
+		}
+		return listener, nil
+	}
+
+	if err := checkPrivateDir(filepath.Dir(address)); err != nil {
+		return nil, err
+	}
+
+	if info, err := os.Lstat(address); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", address)
+		}
+		if c, err := net.Dial("unix", address); err == nil {
+			c.Close()
+			return nil, fmt.Errorf("%s is in use by another socket-proxy", address)
+		}
+		if err := os.Remove(address); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket %s: %w", address, err)
+		}
+	}
+
+	listener, err := net.Listen("unix", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", address, err)
+	}
+	if err := os.Chmod(address, 0600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to restrict access to %s: %w", address, err)
+	}
+	return listener, nil
+}
+
+// checkPrivateDir fails unless dir is only accessible to its owner. Windows
+// doesn't report unix permissions, so it isn't checked there.
+func checkPrivateDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("failed to check socket directory: %w", err)
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("%s is accessible to other users, who could connect to the socket before access to it is restricted; use a directory only you can access (chmod 700 %s)", dir, dir)
+	}
+	return nil
+}
+
+func init() {
+	socketProxyCmd.Flags().StringVar(&socketProxyListen, "listen", "", "Local address to serve the socket on, unix://PATH or tcp://HOST:PORT (default ~/.syno-docker/run/docker.sock)")
+}
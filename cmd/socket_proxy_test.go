@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestListenSocketProxy(t *testing.T) {
+	tests := []struct {
+		name    string
+		dirMode os.FileMode
+		setup   func(t *testing.T, path string)
+		wantErr string
+	}{
+		{
+			name:    "private directory",
+			dirMode: 0700,
+		},
+		{
+			name:    "stale socket replaced",
+			dirMode: 0700,
+			setup: func(t *testing.T, path string) {
+				l, err := net.Listen("unix", path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				l.(*net.UnixListener).SetUnlinkOnClose(false)
+				l.Close()
+			},
+		},
+		{
+			name:    "socket in use",
+			dirMode: 0700,
+			setup: func(t *testing.T, path string) {
+				l, err := net.Listen("unix", path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() { l.Close() })
+			},
+			wantErr: "in use by another socket-proxy",
+		},
+		{
+			name:    "not a socket",
+			dirMode: 0700,
+			setup: func(t *testing.T, path string) {
+				if err := os.WriteFile(path, nil, 0600); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: "is not a socket",
+		},
+		{
+			name:    "directory readable by others",
+			dirMode: 0755,
+			wantErr: "accessible to other users",
+		},
+		{
+			name:    "directory writable by group",
+			dirMode: 0770,
+			wantErr: "accessible to other users",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Unix socket paths are limited to about 100 bytes, too few for
+			// t.TempDir on some systems
+			base, err := os.MkdirTemp("", "proxy")
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { os.RemoveAll(base) })
+			dir := filepath.Join(base, "run")
+			if err := os.Mkdir(dir, 0700); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(dir, "docker.sock")
+			if tt.setup != nil {
+				tt.setup(t, path)
+			}
+			if err := os.Chmod(dir, tt.dirMode); err != nil {
+				t.Fatal(err)
+			}
+
+			listener, err := listenSocketProxy("unix", path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("listenSocketProxy failed: %v", err)
+			}
+			defer listener.Close()
+
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != 0600 {
+				t.Errorf("Expected the socket to be owner-only, got %v", info.Mode().Perm())
+			}
+		})
+	}
+}
@@ -1,13 +1,14 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
 
 	"github.com/spf13/cobra"
 
 	"github.com/scttfrdmn/syno-docker/pkg/config"
 	"github.com/scttfrdmn/syno-docker/pkg/deploy"
-	"github.com/scttfrdmn/syno-docker/pkg/synology"
 )
 
 var startCmd = &cobra.Command{
@@ -19,28 +20,16 @@ var startCmd = &cobra.Command{
 }
 
 func startContainers(cmd *cobra.Command, args []string) error {
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
-	}
-
-	// Connect to Synology NAS
-	fmt.Printf("Connecting to %s@%s:%d...\n", cfg.User, cfg.Host, cfg.Port)
-	conn := synology.NewConnection(cfg)
-	if err := conn.Connect(); err != nil {
-		return fmt.Errorf("connection failed: %w", err)
-	}
-	defer conn.Close()
-
-	// Start each container
-	for _, containerNameOrID := range args {
-		fmt.Printf("Starting container %s...\n", containerNameOrID)
-		if err := deploy.StartContainer(conn, containerNameOrID); err != nil {
-			return fmt.Errorf("failed to start container %s: %w", containerNameOrID, err)
+	return forEachHost(cmd, func(ctx context.Context, cfg *config.Config, conn connection, out io.Writer) error {
+		// Start each container
+		for _, containerNameOrID := range args {
+			fmt.Fprintf(out, "Starting container %s...\n", containerNameOrID)
+			if err := deploy.StartContainerContext(ctx, conn, containerNameOrID); err != nil {
+				return fmt.Errorf("failed to start container %s: %w", containerNameOrID, err)
+			}
+			fmt.Fprintf(out, "✅ Container %s started successfully!\n", containerNameOrID)
 		}
-		fmt.Printf("✅ Container %s started successfully!\n", containerNameOrID)
-	}
 
-	return nil
+		return nil
+	})
 }
@@ -1,13 +1,13 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 
 	"github.com/spf13/cobra"
 
-	"github.com/scttfrdmn/syno-docker/pkg/config"
 	"github.com/scttfrdmn/syno-docker/pkg/deploy"
-	"github.com/scttfrdmn/syno-docker/pkg/synology"
 )
 
 var (
@@ -24,16 +24,10 @@ var statsCmd = &cobra.Command{
 }
 
 func showContainerStats(cmd *cobra.Command, args []string) error {
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
-	}
-
 	// Connect to Synology NAS
-	conn := synology.NewConnection(cfg)
-	if err := conn.Connect(); err != nil {
-		return fmt.Errorf("connection failed: %w", err)
+	_, conn, err := connectNAS(cmd, nil)
+	if err != nil {
+		return err
 	}
 	defer conn.Close()
 
@@ -47,12 +41,17 @@ func showContainerStats(cmd *cobra.Command, args []string) error {
 	if len(args) == 0 {
 		// Show stats for all containers
 		fmt.Println("Showing statistics for all containers...")
-		return deploy.ShowContainerStats(conn, nil, opts)
 	} else {
 		// Show stats for specific containers
 		fmt.Printf("Showing statistics for containers: %v...\n", args)
-		return deploy.ShowContainerStats(conn, args, opts)
 	}
+
+	err = deploy.ShowContainerStatsContext(cmd.Context(), conn, args, opts)
+	if errors.Is(err, context.Canceled) {
+		// Ctrl+C is the normal way to stop streaming
+		return nil
+	}
+	return err
 }
 
 func init() {
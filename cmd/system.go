@@ -1,15 +1,15 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
-	"text/tabwriter"
 
 	"github.com/spf13/cobra"
 
 	"github.com/scttfrdmn/syno-docker/pkg/config"
 	"github.com/scttfrdmn/syno-docker/pkg/deploy"
-	"github.com/scttfrdmn/syno-docker/pkg/synology"
 )
 
 var systemCmd = &cobra.Command{
@@ -50,32 +50,24 @@ var (
 )
 
 func showSystemDf(cmd *cobra.Command, args []string) error {
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
-	}
-
-	// Connect to Synology NAS
-	conn := synology.NewConnection(cfg)
-	if err := conn.Connect(); err != nil {
-		return fmt.Errorf("connection failed: %w", err)
-	}
-	defer conn.Close()
+	return forEachHost(cmd, printSystemDf)
+}
 
+// printSystemDf shows the Docker disk usage of one NAS
+func printSystemDf(ctx context.Context, cfg *config.Config, conn connection, out io.Writer) error {
 	// Show disk usage
 	opts := &deploy.SystemDfOptions{
 		Format:  systemDfFormat,
 		Verbose: systemDfVerbose,
 	}
 
-	usage, err := deploy.GetSystemDf(conn, opts)
+	usage, err := deploy.GetSystemDfContext(ctx, conn, opts)
 	if err != nil {
 		return fmt.Errorf("failed to get system disk usage: %w", err)
 	}
 
 	// Display disk usage in table format
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	w := newTable(out)
 	fmt.Fprintln(w, "TYPE\tTOTAL\tACTIVE\tSIZE\tRECLAIMABLE")
 
 	for _, item := range usage {
@@ -87,45 +79,27 @@ func showSystemDf(cmd *cobra.Command, args []string) error {
 }
 
 func showSystemInfo(cmd *cobra.Command, args []string) error {
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
-	}
-
-	// Connect to Synology NAS
-	conn := synology.NewConnection(cfg)
-	if err := conn.Connect(); err != nil {
-		return fmt.Errorf("connection failed: %w", err)
-	}
-	defer conn.Close()
-
-	// Show system info
-	opts := &deploy.SystemInfoOptions{
-		Format: systemInfoFormat,
-	}
+	return forEachHost(cmd, func(ctx context.Context, cfg *config.Config, conn connection, out io.Writer) error {
+		// Show system info
+		opts := &deploy.SystemInfoOptions{
+			Format: systemInfoFormat,
+		}
 
-	info, err := deploy.GetSystemInfo(conn, opts)
-	if err != nil {
-		return fmt.Errorf("failed to get system info: %w", err)
-	}
+		info, err := deploy.GetSystemInfoContext(ctx, conn, opts)
+		if err != nil {
+			return fmt.Errorf("failed to get system info: %w", err)
+		}
 
-	fmt.Print(info)
-	return nil
+		fmt.Fprint(out, info)
+		return nil
+	})
 }
 
 func systemPrune(cmd *cobra.Command, args []string) error {
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
-	}
-
 	// Connect to Synology NAS
-	fmt.Printf("Connecting to %s@%s:%d...\n", cfg.User, cfg.Host, cfg.Port)
-	conn := synology.NewConnection(cfg)
-	if err := conn.Connect(); err != nil {
-		return fmt.Errorf("connection failed: %w", err)
+	_, conn, err := connectNAS(cmd, os.Stdout)
+	if err != nil {
+		return err
 	}
 	defer conn.Close()
 
@@ -159,7 +133,7 @@ func systemPrune(cmd *cobra.Command, args []string) error {
 		Filter:  systemPruneFilter,
 	}
 
-	result, err := deploy.SystemPrune(conn, opts)
+	result, err := deploy.SystemPruneContext(cmd.Context(), conn, opts)
 	if err != nil {
 		return fmt.Errorf("failed to prune system: %w", err)
 	}
@@ -1,15 +1,15 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
-	"text/tabwriter"
 
 	"github.com/spf13/cobra"
 
 	"github.com/scttfrdmn/syno-docker/pkg/config"
 	"github.com/scttfrdmn/syno-docker/pkg/deploy"
-	"github.com/scttfrdmn/syno-docker/pkg/synology"
 )
 
 var volumeCmd = &cobra.Command{
@@ -70,19 +70,11 @@ var (
 )
 
 func listVolumes(cmd *cobra.Command, args []string) error {
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
-	}
-
-	// Connect to Synology NAS
-	conn := synology.NewConnection(cfg)
-	if err := conn.Connect(); err != nil {
-		return fmt.Errorf("connection failed: %w", err)
-	}
-	defer conn.Close()
+	return forEachHost(cmd, printVolumes)
+}
 
+// printVolumes lists the volumes on one NAS
+func printVolumes(ctx context.Context, cfg *config.Config, conn connection, out io.Writer) error {
 	// List volumes
 	opts := &deploy.VolumeListOptions{
 		Format: volumeListFormat,
@@ -90,28 +82,28 @@ func listVolumes(cmd *cobra.Command, args []string) error {
 	}
 
 	if volumeListQuiet {
-		volumeNames, err := deploy.ListVolumeNames(conn, opts)
+		volumeNames, err := deploy.ListVolumeNamesContext(ctx, conn, opts)
 		if err != nil {
 			return fmt.Errorf("failed to list volumes: %w", err)
 		}
 		for _, name := range volumeNames {
-			fmt.Println(name)
+			fmt.Fprintln(out, name)
 		}
 		return nil
 	}
 
-	volumes, err := deploy.ListVolumes(conn, opts)
+	volumes, err := deploy.ListVolumesContext(ctx, conn, opts)
 	if err != nil {
 		return fmt.Errorf("failed to list volumes: %w", err)
 	}
 
 	if len(volumes) == 0 {
-		fmt.Println("No volumes found.")
+		fmt.Fprintln(out, "No volumes found.")
 		return nil
 	}
 
 	// Display volumes in table format
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	w := newTable(out)
 	fmt.Fprintln(w, "DRIVER\tVOLUME NAME")
 
 	for _, volume := range volumes {
@@ -122,17 +114,10 @@ func listVolumes(cmd *cobra.Command, args []string) error {
 }
 
 func createVolume(cmd *cobra.Command, args []string) error {
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
-	}
-
 	// Connect to Synology NAS
-	fmt.Printf("Connecting to %s@%s:%d...\n", cfg.User, cfg.Host, cfg.Port)
-	conn := synology.NewConnection(cfg)
-	if err := conn.Connect(); err != nil {
-		return fmt.Errorf("connection failed: %w", err)
+	_, conn, err := connectNAS(cmd, os.Stdout)
+	if err != nil {
+		return err
 	}
 	defer conn.Close()
 
@@ -148,7 +133,7 @@ func createVolume(cmd *cobra.Command, args []string) error {
 		Options: volumeCreateOptions,
 	}
 
-	name, err := deploy.CreateVolume(conn, volumeName, opts)
+	name, err := deploy.CreateVolumeContext(cmd.Context(), conn, volumeName, opts)
 	if err != nil {
 		return fmt.Errorf("failed to create volume: %w", err)
 	}
@@ -158,17 +143,10 @@ func createVolume(cmd *cobra.Command, args []string) error {
 }
 
 func removeVolumes(cmd *cobra.Command, args []string) error {
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
-	}
-
 	// Connect to Synology NAS
-	fmt.Printf("Connecting to %s@%s:%d...\n", cfg.User, cfg.Host, cfg.Port)
-	conn := synology.NewConnection(cfg)
-	if err := conn.Connect(); err != nil {
-		return fmt.Errorf("connection failed: %w", err)
+	_, conn, err := connectNAS(cmd, os.Stdout)
+	if err != nil {
+		return err
 	}
 	defer conn.Close()
 
@@ -179,7 +157,7 @@ func removeVolumes(cmd *cobra.Command, args []string) error {
 
 	for _, volumeName := range args {
 		fmt.Printf("Removing volume %s...\n", volumeName)
-		if err := deploy.RemoveVolume(conn, volumeName, opts); err != nil {
+		if err := deploy.RemoveVolumeContext(cmd.Context(), conn, volumeName, opts); err != nil {
 			return fmt.Errorf("failed to remove volume %s: %w", volumeName, err)
 		}
 		fmt.Printf("✅ Volume %s removed successfully!\n", volumeName)
@@ -189,16 +167,10 @@ func removeVolumes(cmd *cobra.Command, args []string) error {
 }
 
 func inspectVolumes(cmd *cobra.Command, args []string) error {
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
-	}
-
 	// Connect to Synology NAS
-	conn := synology.NewConnection(cfg)
-	if err := conn.Connect(); err != nil {
-		return fmt.Errorf("connection failed: %w", err)
+	_, conn, err := connectNAS(cmd, nil)
+	if err != nil {
+		return err
 	}
 	defer conn.Close()
 
@@ -208,7 +180,7 @@ func inspectVolumes(cmd *cobra.Command, args []string) error {
 	}
 
 	for _, volumeName := range args {
-		info, err := deploy.InspectVolume(conn, volumeName, opts)
+		info, err := deploy.InspectVolumeContext(cmd.Context(), conn, volumeName, opts)
 		if err != nil {
 			return fmt.Errorf("failed to inspect volume %s: %w", volumeName, err)
 		}
@@ -219,17 +191,10 @@ func inspectVolumes(cmd *cobra.Command, args []string) error {
 }
 
 func pruneVolumes(cmd *cobra.Command, args []string) error {
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
-	}
-
 	// Connect to Synology NAS
-	fmt.Printf("Connecting to %s@%s:%d...\n", cfg.User, cfg.Host, cfg.Port)
-	conn := synology.NewConnection(cfg)
-	if err := conn.Connect(); err != nil {
-		return fmt.Errorf("connection failed: %w", err)
+	_, conn, err := connectNAS(cmd, os.Stdout)
+	if err != nil {
+		return err
 	}
 	defer conn.Close()
 
@@ -252,7 +217,7 @@ func pruneVolumes(cmd *cobra.Command, args []string) error {
 		Filter: volumePruneFilter,
 	}
 
-	result, err := deploy.PruneVolumes(conn, opts)
+	result, err := deploy.PruneVolumesContext(cmd.Context(), conn, opts)
 	if err != nil {
 		return fmt.Errorf("failed to prune volumes: %w", err)
 	}
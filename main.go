@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"os"
 
 	"github.com/scttfrdmn/syno-docker/cmd"
@@ -18,6 +19,11 @@ func main() {
 	cmd.Date = date
 
 	if err := cmd.Execute(); err != nil {
+		// Forward the exit status of failed remote commands
+		var exitErr *cmd.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
 		os.Exit(1)
 	}
 }
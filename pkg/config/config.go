@@ -4,8 +4,6 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-
-	"gopkg.in/yaml.v3"
 )
 
 const (
@@ -21,21 +19,131 @@ const (
 	ConfigDir = ".syno-docker"
 	// ConfigFile is the configuration file name
 	ConfigFile = "config.yaml"
+	// KnownHostsFile is the syno-docker specific known_hosts file name
+	KnownHostsFile = "known_hosts"
+	// DockerSocketDir is the owner-only directory holding the socket-proxy socket
+	DockerSocketDir = "run"
+	// DockerSocketFile is the default socket name for socket-proxy
+	DockerSocketFile = "docker.sock"
+	// NASInfoFile caches what each NAS runs, as probed by syno-docker
+	NASInfoFile = "nas-info.json"
+)
+
+// SSH authentication methods that can be listed in Config.AuthMethods
+const (
+	// AuthAgent authenticates with keys held by ssh-agent
+	AuthAgent = "agent"
+	// AuthPublicKey authenticates with the private key at SSHKeyPath
+	AuthPublicKey = "publickey"
+	// AuthPassword authenticates with the account password
+	AuthPassword = "password"
+	// AuthKeyboardInteractive answers server prompts, such as 2-step verification codes
+	AuthKeyboardInteractive = "keyboard-interactive"
+)
+
+// Transports that can be set in Config.Transport
+const (
+	// TransportAuto uses SSH, falling back to the DSM Web API when SSH is
+	// unavailable and DSM.URL is set
+	TransportAuto = "auto"
+	// TransportSSH only uses SSH
+	TransportSSH = "ssh"
+	// TransportDSM only uses the DSM Web API, for NAS units without SSH
+	TransportDSM = "dsm"
 )
 
+// DSMConfig configures the DSM Web API transport
+type DSMConfig struct {
+	// URL is the DSM address, such as https://nas.local:5001. It defaults
+	// to https://<host>:5001 when Transport is TransportDSM.
+	URL string `yaml:"url,omitempty"`
+	// InsecureSkipVerify accepts the self-signed certificate DSM uses until
+	// one is installed
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify,omitempty"`
+}
+
+// DefaultAuthMethods is the authentication order used when none is configured
+var DefaultAuthMethods = []string{AuthAgent, AuthPublicKey}
+
 // Config represents the syno-docker configuration
 type Config struct {
 	Host       string `yaml:"host"`
 	Port       int    `yaml:"port,omitempty"`
-	User       string `yaml:"user"`
+	User       string `yaml:"user,omitempty"`
 	SSHKeyPath string `yaml:"ssh_key_path"`
 
+	// AuthMethods lists the SSH authentication methods to try, in order.
+	// DefaultAuthMethods is used when empty.
+	AuthMethods []string `yaml:"auth_methods,omitempty"`
+
+	// HostKeyFingerprint is the SHA256 fingerprint of the NAS host key
+	// pinned during init
+	HostKeyFingerprint string `yaml:"host_key_fingerprint,omitempty"`
+	// InsecureSkipHostKeyCheck disables host key verification entirely
+	InsecureSkipHostKeyCheck bool `yaml:"insecure_skip_host_key_check,omitempty"`
+
+	// Transport selects how to reach the NAS; TransportAuto when empty
+	Transport string    `yaml:"transport,omitempty"`
+	DSM       DSMConfig `yaml:"dsm,omitempty"`
+
 	Defaults struct {
 		VolumePath string `yaml:"volume_path"`
 		Network    string `yaml:"network,omitempty"`
 	} `yaml:"defaults"`
+
+	// Labels are added to the containers run and deployed
+	Labels map[string]string `yaml:"labels,omitempty"`
+	// ComposeFiles are the compose files deploy looks for when none is
+	// given; DefaultComposeFiles when empty
+	ComposeFiles []string `yaml:"compose_files,omitempty"`
+
+	// Profile is the name of the profile the configuration was loaded from
+	// or is saved to
+	Profile string `yaml:"-"`
+	// Sources records where Load took each setting from, keyed by the
+	// Setting constants. A port or user whose source is SourceDefault is
+	// left out when saving and may be provided by ~/.ssh/config.
+	Sources map[string]string `yaml:"-"`
+}
+
+// MarshalYAML leaves out the port and user left at their defaults
+func (c Config) MarshalYAML() (any, error) {
+	type plain Config
+	p := plain(c)
+	if c.IsDefault(SettingPort) {
+		p.Port = 0
+	}
+	if c.IsDefault(SettingUser) {
+		p.User = ""
+	}
+	return p, nil
 }
 
+// IsDefault reports whether setting, one of the Setting constants, was left
+// at its default rather than configured
+func (c *Config) IsDefault(setting string) bool {
+	return c.Sources[setting] == SourceDefault
+}
+
+// Settings whose source Load records in Config.Sources
+const (
+	SettingProfile      = "profile"
+	SettingHost         = "host"
+	SettingPort         = "port"
+	SettingUser         = "user"
+	SettingSSHKeyPath   = "ssh_key_path"
+	SettingVolumePath   = "defaults.volume_path"
+	SettingNetwork      = "defaults.network"
+	SettingLabels       = "labels"
+	SettingComposeFiles = "compose_files"
+)
+
+// SettingKeys lists the settings a profile provides, in display order
+var SettingKeys = []string{SettingHost, SettingPort, SettingUser, SettingSSHKeyPath, SettingVolumePath, SettingNetwork, SettingLabels, SettingComposeFiles}
+
+// SourceDefault is the source of a setting left at its default
+const SourceDefault = "default"
+
 // New creates a new Config with default values
 func New() *Config {
 	return &Config{
@@ -59,13 +167,41 @@ func (c *Config) Validate() error {
 	if c.User == "" {
 		return fmt.Errorf("user is required")
 	}
-	if c.SSHKeyPath == "" {
-		return fmt.Errorf("ssh_key_path is required")
-	}
 	if c.Port <= 0 || c.Port > 65535 {
 		return fmt.Errorf("port must be between 1 and 65535")
 	}
 
+	switch c.Transport {
+	case "", TransportAuto, TransportSSH:
+	case TransportDSM:
+		// Neither SSH auth methods nor a key are used
+		return nil
+	default:
+		return fmt.Errorf("unknown transport %q (valid: %s, %s, %s)", c.Transport, TransportAuto, TransportSSH, TransportDSM)
+	}
+
+	seen := make(map[string]bool)
+	for _, method := range c.AuthMethods {
+		switch method {
+		case AuthAgent, AuthPublicKey, AuthPassword, AuthKeyboardInteractive:
+		default:
+			return fmt.Errorf("unknown auth method %q (valid: %s, %s, %s, %s)", method, AuthAgent, AuthPublicKey, AuthPassword, AuthKeyboardInteractive)
+		}
+		if seen[method] {
+			return fmt.Errorf("auth method %q listed more than once", method)
+		}
+		seen[method] = true
+	}
+
+	// A key is only needed when public key authentication is enabled
+	if !c.HasAuthMethod(AuthPublicKey) {
+		return nil
+	}
+
+	if c.SSHKeyPath == "" {
+		return fmt.Errorf("ssh_key_path is required")
+	}
+
 	// Check if SSH key exists
 	if _, err := os.Stat(c.SSHKeyPath); os.IsNotExist(err) {
 		return fmt.Errorf("SSH key not found at %s", c.SSHKeyPath)
@@ -74,6 +210,30 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// GetAuthMethods returns the configured authentication order, or DefaultAuthMethods
+func (c *Config) GetAuthMethods() []string {
+	if len(c.AuthMethods) == 0 {
+		return append([]string(nil), DefaultAuthMethods...)
+	}
+	return append([]string(nil), c.AuthMethods...)
+}
+
+// HasAuthMethod reports whether method is among the authentication methods to try
+func (c *Config) HasAuthMethod(method string) bool {
+	for _, m := range c.GetAuthMethods() {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultSSHKeyPath returns the SSH private key used when none is configured
+func DefaultSSHKeyPath() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".ssh", "id_rsa")
+}
+
 // GetConfigPath returns the path to the configuration file
 func GetConfigPath() (string, error) {
 	homeDir, err := os.UserHomeDir()
@@ -89,45 +249,79 @@ func GetConfigPath() (string, error) {
 	return filepath.Join(configDir, ConfigFile), nil
 }
 
-// Load loads the configuration from the config file
-func Load() (*Config, error) {
+// configFileName returns the path of the configuration file for messages
+func configFileName() string {
 	configPath, err := GetConfigPath()
 	if err != nil {
-		return nil, err
+		return filepath.Join("~", ConfigDir, ConfigFile)
 	}
+	return configPath
+}
 
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("configuration not found. Run 'syno-docker init <host>' first")
+// GetKnownHostsPath returns the path to the syno-docker known_hosts file
+func GetKnownHostsPath() (string, error) {
+	configPath, err := GetConfigPath()
+	if err != nil {
+		return "", err
 	}
 
-	data, err := os.ReadFile(configPath)
+	return filepath.Join(filepath.Dir(configPath), KnownHostsFile), nil
+}
+
+// GetDockerSocketPath returns the default path of the local socket served
+// by socket-proxy
+func GetDockerSocketPath() (string, error) {
+	configPath, err := GetConfigPath()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		return "", err
 	}
 
-	config := New()
-	if err := yaml.Unmarshal(data, config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	return filepath.Join(filepath.Dir(configPath), DockerSocketDir, DockerSocketFile), nil
+}
+
+// GetNASInfoPath returns the path to the cache of probed NAS information
+func GetNASInfoPath() (string, error) {
+	configPath, err := GetConfigPath()
+	if err != nil {
+		return "", err
 	}
 
-	return config, nil
+	return filepath.Join(filepath.Dir(configPath), NASInfoFile), nil
 }
 
-// Save saves the configuration to the config file
-func (c *Config) Save() error {
-	configPath, err := GetConfigPath()
+// Load loads the active profile from the config file, see File.ActiveProfile,
+// with the project configuration and ConnectionOverrides applied, see
+// File.Resolve
+func Load() (*Config, error) {
+	f, err := LoadFile()
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	name, source := f.activeProfile()
+	cfg, err := f.Resolve(name)
+	if err != nil {
+		return nil, err
 	}
+	cfg.Sources[SettingProfile] = source
+	return cfg, nil
+}
 
-	data, err := yaml.Marshal(c)
+// Save saves the configuration to its profile in the config file, or to the
+// active profile when it has none
+func (c *Config) Save() error {
+	f, err := LoadFile()
 	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+		return err
 	}
 
-	if err := os.WriteFile(configPath, data, 0600); err != nil {
-		return fmt.Errorf("failed to write config file: %w", err)
+	name := c.Profile
+	if name == "" {
+		name = f.ActiveProfile()
+	}
+	if err := f.SetProfile(name, c); err != nil {
+		return err
 	}
 
-	return nil
+	return f.Save()
 }
@@ -80,6 +80,67 @@ func TestValidate(t *testing.T) {
 			},
 			shouldErr: true,
 		},
+		{
+			name: "password auth without SSH key",
+			config: &Config{
+				Host:        "192.168.1.100",
+				User:        "admin",
+				Port:        22,
+				AuthMethods: []string{AuthPassword, AuthKeyboardInteractive},
+			},
+			shouldErr: false,
+		},
+		{
+			name: "publickey auth without SSH key",
+			config: &Config{
+				Host:        "192.168.1.100",
+				User:        "admin",
+				Port:        22,
+				AuthMethods: []string{AuthPassword, AuthPublicKey},
+			},
+			shouldErr: true,
+		},
+		{
+			name: "unknown auth method",
+			config: &Config{
+				Host:        "192.168.1.100",
+				User:        "admin",
+				Port:        22,
+				AuthMethods: []string{"kerberos"},
+			},
+			shouldErr: true,
+		},
+		{
+			name: "duplicate auth method",
+			config: &Config{
+				Host:        "192.168.1.100",
+				User:        "admin",
+				Port:        22,
+				AuthMethods: []string{AuthPassword, AuthPassword},
+			},
+			shouldErr: true,
+		},
+		{
+			name: "dsm transport without SSH key",
+			config: &Config{
+				Host:      "192.168.1.100",
+				User:      "admin",
+				Port:      22,
+				Transport: TransportDSM,
+			},
+			shouldErr: false,
+		},
+		{
+			name: "unknown transport",
+			config: &Config{
+				Host:       "192.168.1.100",
+				User:       "admin",
+				Port:       22,
+				SSHKeyPath: createTempSSHKey(t),
+				Transport:  "telnet",
+			},
+			shouldErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -103,6 +164,7 @@ func TestSaveAndLoad(t *testing.T) {
 	oldHome := os.Getenv("HOME")
 	defer os.Setenv("HOME", oldHome)
 	os.Setenv("HOME", tempDir)
+	chdirProject(t, t.TempDir())
 
 	// Create test config
 	config := &Config{
@@ -191,3 +253,25 @@ func createTempSSHKey(t *testing.T) string {
 
 	return tempFile.Name()
 }
+
+func TestGetAuthMethods(t *testing.T) {
+	config := &Config{}
+	methods := config.GetAuthMethods()
+	if len(methods) != len(DefaultAuthMethods) || methods[0] != AuthAgent || methods[1] != AuthPublicKey {
+		t.Errorf("Expected default auth methods %v, got %v", DefaultAuthMethods, methods)
+	}
+
+	// Modifying the result must not change the defaults
+	methods[0] = AuthPassword
+	if DefaultAuthMethods[0] != AuthAgent {
+		t.Error("GetAuthMethods returned the DefaultAuthMethods slice itself")
+	}
+
+	config.AuthMethods = []string{AuthKeyboardInteractive}
+	if !config.HasAuthMethod(AuthKeyboardInteractive) {
+		t.Error("Expected keyboard-interactive to be enabled")
+	}
+	if config.HasAuthMethod(AuthPublicKey) {
+		t.Error("Expected publickey not to be enabled")
+	}
+}
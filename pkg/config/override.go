@@ -0,0 +1,108 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Environment variables that override the connection settings of the
+// active profile, or stand in for a configuration file
+const (
+	// HostEnv sets the NAS host
+	HostEnv = "SYNO_DOCKER_HOST"
+	// UserEnv sets the SSH username
+	UserEnv = "SYNO_DOCKER_USER"
+	// PortEnv sets the SSH port
+	PortEnv = "SYNO_DOCKER_PORT"
+	// KeyEnv sets the SSH private key path
+	KeyEnv = "SYNO_DOCKER_KEY"
+)
+
+// Overrides are connection settings given for one command, such as with
+// --host, that take precedence over the active profile. Empty fields leave
+// the profile's settings alone.
+type Overrides struct {
+	Host       string
+	User       string
+	Port       int
+	SSHKeyPath string
+}
+
+// flagOverrides are the overrides set with SetOverrides
+var flagOverrides Overrides
+
+// SetOverrides makes Load apply o over the environment and the active
+// profile
+func SetOverrides(o Overrides) {
+	flagOverrides = o
+}
+
+// ConnectionOverrides returns the overrides Load applies: those from the
+// environment with the ones set with SetOverrides over them
+func ConnectionOverrides() (Overrides, error) {
+	o, err := envOverrides()
+	if err != nil {
+		return Overrides{}, err
+	}
+
+	o.merge(flagOverrides)
+	if o.Port < 0 || o.Port > 65535 {
+		return Overrides{}, fmt.Errorf("port must be between 1 and 65535")
+	}
+	return o, nil
+}
+
+// envOverrides returns the overrides set in the environment
+func envOverrides() (Overrides, error) {
+	o := Overrides{
+		Host:       os.Getenv(HostEnv),
+		User:       os.Getenv(UserEnv),
+		SSHKeyPath: os.Getenv(KeyEnv),
+	}
+	if port := os.Getenv(PortEnv); port != "" {
+		n, err := strconv.Atoi(port)
+		if err != nil {
+			return Overrides{}, fmt.Errorf("invalid %s %q: %w", PortEnv, port, err)
+		}
+		o.Port = n
+	}
+	return o, nil
+}
+
+// merge sets the non-empty fields of other on o
+func (o *Overrides) merge(other Overrides) {
+	if other.Host != "" {
+		o.Host = other.Host
+	}
+	if other.User != "" {
+		o.User = other.User
+	}
+	if other.Port != 0 {
+		o.Port = other.Port
+	}
+	if other.SSHKeyPath != "" {
+		o.SSHKeyPath = other.SSHKeyPath
+	}
+}
+
+// apply sets the non-empty overrides on c, recording in c.Sources the source
+// returned for the setting's flag and environment variable
+func (o Overrides) apply(c *Config, source func(flag, env string) string) {
+	if o.Host != "" {
+		c.Host = o.Host
+		c.Sources[SettingHost] = source("host", HostEnv)
+	}
+	if o.User != "" {
+		c.User = o.User
+		c.Sources[SettingUser] = source("ssh-user", UserEnv)
+	}
+	if o.Port != 0 {
+		c.Port = o.Port
+		c.Sources[SettingPort] = source("ssh-port", PortEnv)
+	}
+	if o.SSHKeyPath != "" {
+		c.SSHKeyPath = o.SSHKeyPath
+		c.Sources[SettingSSHKeyPath] = source("ssh-key", KeyEnv)
+	}
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// setupOverrides clears the connection overrides from the environment and
+// SetOverrides
+func setupOverrides(t *testing.T) {
+	for _, env := range []string{HostEnv, UserEnv, PortEnv, KeyEnv} {
+		t.Setenv(env, "")
+	}
+	SetOverrides(Overrides{})
+	t.Cleanup(func() { SetOverrides(Overrides{}) })
+}
+
+func TestConnectionOverrides(t *testing.T) {
+	setupOverrides(t)
+
+	t.Setenv(HostEnv, "env.local")
+	t.Setenv(UserEnv, "ci")
+	t.Setenv(PortEnv, "2222")
+	SetOverrides(Overrides{User: "deploy", SSHKeyPath: "/keys/id"})
+
+	o, err := ConnectionOverrides()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Overrides{Host: "env.local", User: "deploy", Port: 2222, SSHKeyPath: "/keys/id"}
+	if o != want {
+		t.Errorf("ConnectionOverrides() = %+v, want %+v", o, want)
+	}
+
+	t.Setenv(PortEnv, "ssh")
+	if _, err := ConnectionOverrides(); err == nil || !strings.Contains(err.Error(), PortEnv) {
+		t.Errorf("Expected an invalid %s error, got %v", PortEnv, err)
+	}
+	t.Setenv(PortEnv, "")
+	SetOverrides(Overrides{Port: 70000})
+	if _, err := ConnectionOverrides(); err == nil {
+		t.Error("Expected an out of range port error")
+	}
+}
+
+func TestLoadWithOverrides(t *testing.T) {
+	setupProfileHome(t)
+	setupOverrides(t)
+
+	// Without a configuration file a host is enough
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), HostEnv) {
+		t.Errorf("Expected a configuration not found error mentioning %s, got %v", HostEnv, err)
+	}
+	t.Setenv(HostEnv, "ci.local")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Host != "ci.local" || cfg.User != DefaultUser || cfg.Port != DefaultPort || cfg.SSHKeyPath != DefaultSSHKeyPath() {
+		t.Errorf("Expected the defaults with the host set, got %+v", cfg)
+	}
+
+	// A profile asked for by name must exist
+	SelectProfile("office")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "configuration not found") {
+		t.Errorf("Expected the missing profile to be an error, got %v", err)
+	}
+	SelectProfile("")
+
+	// Overrides apply over the profile without changing it
+	saved := New()
+	saved.Host = "nas.local"
+	saved.User = "admin"
+	saved.SSHKeyPath = "/keys/nas"
+	if err := saved.Save(); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(HostEnv, "")
+	SetOverrides(Overrides{User: "deploy", Port: 2200})
+	cfg, err = Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Host != "nas.local" || cfg.User != "deploy" || cfg.Port != 2200 || cfg.SSHKeyPath != "/keys/nas" {
+		t.Errorf("Expected the overrides over the profile, got %+v", cfg)
+	}
+
+	f, err := LoadFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored := f.Profiles[DefaultProfile]; stored.User != "admin" || stored.Port != DefaultPort {
+		t.Errorf("Expected the profile to be unchanged, got %+v", stored)
+	}
+}
@@ -0,0 +1,389 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DefaultProfile is the profile a single-host configuration is kept in
+	DefaultProfile = "default"
+	// ProfileEnv selects the profile to use, unless --profile is passed
+	ProfileEnv = "SYNO_DOCKER_PROFILE"
+)
+
+// profileNamePattern restricts profile names to ones that are easy to type
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// selectedProfile is the profile chosen with SelectProfile
+var selectedProfile string
+
+// File is the configuration file: one named profile per NAS and the profile
+// used when none is selected
+type File struct {
+	CurrentProfile string             `yaml:"current_profile,omitempty"`
+	Profiles       map[string]*Config `yaml:"profiles"`
+
+	// Project is the project configuration found from the working
+	// directory, or nil
+	Project *ProjectConfig `yaml:"-"`
+}
+
+// SelectProfile makes Load and Save use the named profile, ahead of
+// ProfileEnv and the current profile. An empty name clears the selection.
+func SelectProfile(name string) {
+	selectedProfile = name
+}
+
+// ValidateProfileName checks that name can be used as a profile name
+func ValidateProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use letters, digits, '.', '_' and '-'", name)
+	}
+	return nil
+}
+
+// IsProfilePattern reports whether name is a pattern, such as "edge-*",
+// that can select several profiles at once
+func IsProfilePattern(name string) bool {
+	return strings.ContainsAny(name, "*?[")
+}
+
+// ValidateProfilePattern checks that pattern is a profile name or a valid
+// pattern
+func ValidateProfilePattern(pattern string) error {
+	if !IsProfilePattern(pattern) {
+		return ValidateProfileName(pattern)
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid profile pattern %q: %w", pattern, err)
+	}
+	return nil
+}
+
+// LoadFile reads the configuration file and the project configuration
+// found from the working directory. A missing file is an empty one, and a
+// single-host configuration from before profiles is migrated into
+// DefaultProfile and written back.
+func LoadFile() (*File, error) {
+	configPath, err := GetConfigPath()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := readFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// A working directory that no longer exists has no project
+	if wd, err := os.Getwd(); err == nil {
+		if f.Project, err = FindProjectConfig(wd); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// readFile reads the configuration file at configPath
+func readFile(configPath string) (*File, error) {
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return &File{Profiles: map[string]*Config{}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var keys map[string]any
+	if err := yaml.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if _, ok := keys["profiles"]; !ok && keys["host"] != nil {
+		return migrateFile(configPath, data)
+	}
+
+	var raw struct {
+		CurrentProfile string               `yaml:"current_profile"`
+		Profiles       map[string]yaml.Node `yaml:"profiles"`
+	}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	// Decode each profile over the defaults so older profiles pick up new settings
+	f := &File{CurrentProfile: raw.CurrentProfile, Profiles: map[string]*Config{}}
+	for name, node := range raw.Profiles {
+		cfg := New()
+		if err := node.Decode(cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse profile %s: %w", name, err)
+		}
+		cfg.Profile = name
+		cfg.Sources = defaultedSettings(&node)
+		f.Profiles[name] = cfg
+	}
+	return f, nil
+}
+
+// defaultedSettings marks the port and user as defaults when the profile
+// node doesn't set them
+func defaultedSettings(node *yaml.Node) map[string]string {
+	sources := map[string]string{SettingPort: SourceDefault, SettingUser: SourceDefault}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		switch node.Content[i].Value {
+		case "port":
+			delete(sources, SettingPort)
+		case "user":
+			delete(sources, SettingUser)
+		}
+	}
+	return sources
+}
+
+// migrateFile moves a single-host configuration into DefaultProfile. The
+// original file is kept beside the new one with a .bak suffix.
+func migrateFile(configPath string, data []byte) (*File, error) {
+	cfg := New()
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	cfg.Profile = DefaultProfile
+
+	f := &File{
+		CurrentProfile: DefaultProfile,
+		Profiles:       map[string]*Config{DefaultProfile: cfg},
+	}
+	if err := os.WriteFile(configPath+".bak", data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to back up config file: %w", err)
+	}
+	if err := f.Save(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Save writes the configuration file
+func (f *File) Save() error {
+	configPath, err := GetConfigPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(f)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	if err := os.WriteFile(configPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	return nil
+}
+
+// Names returns the profile names in order
+func (f *File) Names() []string {
+	names := make([]string, 0, len(f.Profiles))
+	for name := range f.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ActiveProfile returns the name of the profile in use: the one passed to
+// SelectProfile, then ProfileEnv, then the profile of the project
+// configuration, then the current profile. With none of those set, a file
+// with a single profile uses it, and otherwise DefaultProfile is used.
+func (f *File) ActiveProfile() string {
+	name, _ := f.activeProfile()
+	return name
+}
+
+// activeProfile returns the name of the profile in use and what selected it
+func (f *File) activeProfile() (name, source string) {
+	if selectedProfile != "" {
+		return selectedProfile, "--profile"
+	}
+	if name := os.Getenv(ProfileEnv); name != "" {
+		return name, "$" + ProfileEnv
+	}
+	if f.Project != nil && f.Project.Profile != "" {
+		return f.Project.Profile, f.Project.Path
+	}
+	if f.CurrentProfile != "" {
+		return f.CurrentProfile, "current_profile in " + configFileName()
+	}
+	if len(f.Profiles) == 1 {
+		return f.Names()[0], "only profile in " + configFileName()
+	}
+	return DefaultProfile, SourceDefault
+}
+
+// Match returns the names of the profiles matching pattern, in order. It
+// is an error if none match.
+func (f *File) Match(pattern string) ([]string, error) {
+	var names []string
+	for _, name := range f.Names() {
+		ok, err := path.Match(pattern, name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid profile pattern %q: %w", pattern, err)
+		}
+		if ok {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		if len(f.Profiles) == 0 {
+			return nil, fmt.Errorf("configuration not found. Run 'syno-docker init <host>' first, or set --host or %s", HostEnv)
+		}
+		return nil, fmt.Errorf("no profile matches %q (available: %s)", pattern, strings.Join(f.Names(), ", "))
+	}
+	return names, nil
+}
+
+// Profile returns the named profile. A pattern is accepted when it matches
+// exactly one profile.
+func (f *File) Profile(name string) (*Config, error) {
+	if IsProfilePattern(name) {
+		names, err := f.Match(name)
+		if err != nil {
+			return nil, err
+		}
+		if len(names) > 1 {
+			return nil, fmt.Errorf("profile pattern %q matches %d profiles (%s) but this command runs against one NAS at a time", name, len(names), strings.Join(names, ", "))
+		}
+		name = names[0]
+	}
+
+	cfg, ok := f.Profiles[name]
+	if !ok {
+		if len(f.Profiles) == 0 {
+			return nil, fmt.Errorf("configuration not found. Run 'syno-docker init <host>' first, or set --host or %s", HostEnv)
+		}
+		return nil, fmt.Errorf("profile %q not found (available: %s)", name, strings.Join(f.Names(), ", "))
+	}
+	return cfg, nil
+}
+
+// Resolve returns the named profile with the project configuration and
+// then ConnectionOverrides applied, recording where each setting came from
+// in Config.Sources. When the profile is missing and none was asked for, an
+// overridden host is enough: the defaults are used for the rest, so a
+// command can run without a configuration file, as on a CI runner.
+func (f *File) Resolve(name string) (*Config, error) {
+	env, err := envOverrides()
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := ConnectionOverrides()
+	if err != nil {
+		return nil, err
+	}
+
+	var c Config
+	var source string
+	if cfg, err := f.Profile(name); err == nil {
+		c = *cfg
+		source = fmt.Sprintf("%s (profile %s)", configFileName(), c.Profile)
+	} else {
+		asked := selectedProfile != "" || os.Getenv(ProfileEnv) != "" || f.Project != nil && f.Project.Profile != ""
+		if overrides.Host == "" || asked || IsProfilePattern(name) {
+			return nil, err
+		}
+		c = *New()
+		c.SSHKeyPath = DefaultSSHKeyPath()
+		source = SourceDefault
+	}
+
+	// Keep the defaults the profile itself records
+	profileSources := c.Sources
+	c.Sources = map[string]string{}
+	for _, key := range SettingKeys {
+		c.Sources[key] = source
+		if profileSources[key] == SourceDefault {
+			c.Sources[key] = SourceDefault
+		}
+	}
+	if len(c.Labels) == 0 {
+		c.Sources[SettingLabels] = SourceDefault
+	}
+	if len(c.ComposeFiles) == 0 {
+		c.Sources[SettingComposeFiles] = SourceDefault
+	}
+
+	if p := f.Project; p != nil {
+		if p.Defaults.VolumePath != "" {
+			c.Defaults.VolumePath = p.Defaults.VolumePath
+			c.Sources[SettingVolumePath] = p.Path
+		}
+		if p.Defaults.Network != "" {
+			c.Defaults.Network = p.Defaults.Network
+			c.Sources[SettingNetwork] = p.Path
+		}
+		if len(p.Labels) > 0 {
+			labels := make(map[string]string, len(c.Labels)+len(p.Labels))
+			for key, value := range c.Labels {
+				labels[key] = value
+			}
+			for key, value := range p.Labels {
+				labels[key] = value
+			}
+			c.Labels = labels
+			c.Sources[SettingLabels] = p.Path
+		}
+		if len(p.ComposeFiles) > 0 {
+			c.ComposeFiles = p.ComposeFiles
+			c.Sources[SettingComposeFiles] = p.Path
+		}
+	}
+
+	env.apply(&c, func(flag, env string) string { return "$" + env })
+	flagOverrides.apply(&c, func(flag, env string) string { return "--" + flag })
+	return &c, nil
+}
+
+// SetProfile adds or replaces the named profile. The first profile added
+// becomes the current one.
+func (f *File) SetProfile(name string, cfg *Config) error {
+	if err := ValidateProfileName(name); err != nil {
+		return err
+	}
+	if f.Profiles == nil {
+		f.Profiles = map[string]*Config{}
+	}
+	cfg.Profile = name
+	f.Profiles[name] = cfg
+	if f.CurrentProfile == "" {
+		f.CurrentProfile = name
+	}
+	return nil
+}
+
+// RemoveProfile removes the named profile, clearing the current profile if
+// it was the one removed
+func (f *File) RemoveProfile(name string) error {
+	if _, err := f.Profile(name); err != nil {
+		return err
+	}
+	delete(f.Profiles, name)
+	if f.CurrentProfile == name {
+		f.CurrentProfile = ""
+	}
+	return nil
+}
+
+// UseProfile makes the named profile the current one
+func (f *File) UseProfile(name string) error {
+	if _, err := f.Profile(name); err != nil {
+		return err
+	}
+	f.CurrentProfile = name
+	return nil
+}
@@ -0,0 +1,263 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupProfileHome points HOME and the working directory at temporary
+// directories and clears the profile selection
+func setupProfileHome(t *testing.T) string {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	chdirProject(t, t.TempDir())
+	t.Setenv(ProfileEnv, "")
+	SelectProfile("")
+	t.Cleanup(func() { SelectProfile("") })
+	return home
+}
+
+func TestLoadMigratesSingleHostConfig(t *testing.T) {
+	home := setupProfileHome(t)
+	legacy := "host: nas.local\nport: 2222\nuser: deploy\nssh_key_path: /keys/id\ndefaults:\n  volume_path: /volume2/docker\n"
+	configPath := filepath.Join(home, ConfigDir, ConfigFile)
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(configPath, []byte(legacy), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Host != "nas.local" || cfg.Port != 2222 || cfg.User != "deploy" || cfg.Defaults.VolumePath != "/volume2/docker" {
+		t.Errorf("Unexpected migrated config: %+v", cfg)
+	}
+	if cfg.Defaults.Network != DefaultNetwork {
+		t.Errorf("Expected the default network, got %q", cfg.Defaults.Network)
+	}
+	if cfg.Profile != DefaultProfile {
+		t.Errorf("Expected profile %s, got %q", DefaultProfile, cfg.Profile)
+	}
+
+	data, _ := os.ReadFile(configPath)
+	if !strings.Contains(string(data), "current_profile: default") || !strings.Contains(string(data), "profiles:") {
+		t.Errorf("Expected the file to be rewritten with profiles, got:\n%s", data)
+	}
+	if backup, err := os.ReadFile(configPath + ".bak"); err != nil || string(backup) != legacy {
+		t.Errorf("Expected the original file kept as a backup, got %q, %v", backup, err)
+	}
+}
+
+func TestActiveProfile(t *testing.T) {
+	setupProfileHome(t)
+	f := &File{CurrentProfile: "home", Profiles: map[string]*Config{"home": New(), "office": New(), "lab": New()}}
+
+	if got := f.ActiveProfile(); got != "home" {
+		t.Errorf("Expected the current profile, got %q", got)
+	}
+	t.Setenv(ProfileEnv, "office")
+	if got := f.ActiveProfile(); got != "office" {
+		t.Errorf("Expected %s to take precedence, got %q", ProfileEnv, got)
+	}
+	SelectProfile("lab")
+	if got := f.ActiveProfile(); got != "lab" {
+		t.Errorf("Expected the selected profile to take precedence, got %q", got)
+	}
+
+	single := &File{Profiles: map[string]*Config{"only": New()}}
+	SelectProfile("")
+	t.Setenv(ProfileEnv, "")
+	if got := single.ActiveProfile(); got != "only" {
+		t.Errorf("Expected the only profile, got %q", got)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	setupProfileHome(t)
+
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "syno-docker init") {
+		t.Errorf("Expected a configuration not found error, got %v", err)
+	}
+
+	for _, host := range []string{"home.local", "office.local"} {
+		cfg := New()
+		cfg.Host = host
+		cfg.Profile = strings.TrimSuffix(host, ".local")
+		if err := cfg.Save(); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	}
+
+	// The first profile saved becomes the current one
+	cfg, err := Load()
+	if err != nil || cfg.Host != "home.local" {
+		t.Fatalf("Expected the home profile, got %+v, %v", cfg, err)
+	}
+
+	SelectProfile("office")
+	if cfg, err := Load(); err != nil || cfg.Host != "office.local" {
+		t.Errorf("Expected the office profile, got %+v, %v", cfg, err)
+	}
+	SelectProfile("missing")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "available: home, office") {
+		t.Errorf("Expected a profile not found error, got %v", err)
+	}
+	SelectProfile("")
+
+	f, err := LoadFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.UseProfile("office"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.RemoveProfile("office"); err != nil {
+		t.Fatal(err)
+	}
+	if f.CurrentProfile != "" {
+		t.Errorf("Expected removing the current profile to clear it, got %q", f.CurrentProfile)
+	}
+	if err := f.RemoveProfile("office"); err == nil {
+		t.Error("Expected an error removing a missing profile")
+	}
+	if err := f.SetProfile("bad name", New()); err == nil {
+		t.Error("Expected an invalid profile name error")
+	}
+	if err := f.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg, err := Load(); err != nil || cfg.Host != "home.local" {
+		t.Errorf("Expected the remaining profile, got %+v, %v", cfg, err)
+	}
+}
+
+func TestProfileDefaultedSettings(t *testing.T) {
+	home := setupProfileHome(t)
+	setupOverrides(t)
+
+	// A port and user left at their defaults aren't saved
+	cfg := New()
+	cfg.Host = "nas"
+	cfg.Profile = "alias"
+	cfg.Sources = map[string]string{SettingPort: SourceDefault, SettingUser: SourceDefault}
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+	cfg = New()
+	cfg.Host = "nas.local"
+	cfg.Profile = "explicit"
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(home, ConfigDir, ConfigFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(data), "port: 22") != 1 || strings.Count(string(data), "user: admin") != 1 {
+		t.Errorf("Expected only the explicit profile to save its port and user, got:\n%s", data)
+	}
+
+	tests := []struct {
+		profile     string
+		wantDefault bool
+	}{
+		{"alias", true},
+		{"explicit", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			SelectProfile(tt.profile)
+			cfg, err := Load()
+			if err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			if cfg.Port != DefaultPort || cfg.User != DefaultUser {
+				t.Errorf("Expected the default port and user, got %d and %q", cfg.Port, cfg.User)
+			}
+			for _, key := range []string{SettingPort, SettingUser} {
+				if cfg.IsDefault(key) != tt.wantDefault {
+					t.Errorf("Expected %s default to be %v, got source %q", key, tt.wantDefault, cfg.Sources[key])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateProfileName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"default", true},
+		{"nas-2.office_1", true},
+		{"", false},
+		{"-nas", false},
+		{"home nas", false},
+		{"a/b", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateProfileName(tt.name); (err == nil) != tt.valid {
+				t.Errorf("ValidateProfileName(%q) = %v, want valid %v", tt.name, err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestMatchProfiles(t *testing.T) {
+	setupProfileHome(t)
+	f := &File{Profiles: map[string]*Config{"edge-1": New(), "edge-2": New(), "home": New()}}
+
+	tests := []struct {
+		pattern string
+		want    string
+		wantErr string
+	}{
+		{"*", "edge-1 edge-2 home", ""},
+		{"edge-*", "edge-1 edge-2", ""},
+		{"edge-?", "edge-1 edge-2", ""},
+		{"h*", "home", ""},
+		{"office-*", "", "no profile matches"},
+		{"[", "", "invalid profile pattern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			names, err := f.Match(tt.pattern)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Match(%q) error = %v, want %q", tt.pattern, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || strings.Join(names, " ") != tt.want {
+				t.Errorf("Match(%q) = %v, %v, want %s", tt.pattern, names, err, tt.want)
+			}
+		})
+	}
+
+	// Commands that run against one NAS accept a pattern matching one profile
+	if cfg, err := f.Profile("h*"); err != nil || cfg != f.Profiles["home"] {
+		t.Errorf("Expected the home profile, got %v, %v", cfg, err)
+	}
+	if _, err := f.Profile("edge-*"); err == nil || !strings.Contains(err.Error(), "one NAS at a time") {
+		t.Errorf("Expected a pattern matching several profiles to be refused, got %v", err)
+	}
+
+	if !IsProfilePattern("edge-*") || IsProfilePattern("edge-1") {
+		t.Error("IsProfilePattern is wrong")
+	}
+	if err := ValidateProfilePattern("edge-["); err == nil {
+		t.Error("Expected an invalid pattern error")
+	}
+	if err := ValidateProfilePattern("edge-*"); err != nil {
+		t.Errorf("ValidateProfilePattern() = %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectConfigFile is the name of the project configuration file, looked
+// for in the working directory and its parents
+const ProjectConfigFile = ".syno-docker.yaml"
+
+// DefaultComposeFiles are the compose file names deploy looks for when no
+// compose file is given, in order
+var DefaultComposeFiles = []string{"compose.yaml", "compose.yml", "docker-compose.yaml", "docker-compose.yml"}
+
+// ProjectConfig is a project configuration file, which sets what a
+// repository deploys to over the user configuration
+type ProjectConfig struct {
+	// Profile selects the profile, unless --profile or ProfileEnv is set
+	Profile  string `yaml:"profile,omitempty"`
+	Defaults struct {
+		VolumePath string `yaml:"volume_path,omitempty"`
+		Network    string `yaml:"network,omitempty"`
+	} `yaml:"defaults,omitempty"`
+	// Labels are added to the containers run and deployed from the project
+	Labels map[string]string `yaml:"labels,omitempty"`
+	// ComposeFiles are the compose file names deploy looks for, relative to
+	// the directory of the project configuration file
+	ComposeFiles []string `yaml:"compose_files,omitempty"`
+
+	// Path is the file the project configuration was read from
+	Path string `yaml:"-"`
+}
+
+// projectConfigCeiling, when set, is the directory FindProjectConfig stops
+// at, so tests aren't affected by project files above their directories
+var projectConfigCeiling string
+
+// FindProjectConfig reads the ProjectConfigFile in dir or the nearest of its
+// parents that has one, like git looks for .git. It returns nil when there
+// is none.
+func FindProjectConfig(dir string) (*ProjectConfig, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		path := filepath.Join(dir, ProjectConfigFile)
+		data, err := os.ReadFile(path)
+		if err == nil {
+			return parseProjectConfig(path, data)
+		}
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir || dir == projectConfigCeiling {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// parseProjectConfig parses the project configuration read from path
+func parseProjectConfig(path string, data []byte) (*ProjectConfig, error) {
+	p := &ProjectConfig{Path: path}
+	if err := yaml.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if p.Profile != "" {
+		if err := ValidateProfilePattern(p.Profile); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", path, err)
+		}
+	}
+
+	// Compose files are relative to the project configuration file
+	for i, name := range p.ComposeFiles {
+		if !filepath.IsAbs(name) {
+			p.ComposeFiles[i] = filepath.Join(filepath.Dir(path), name)
+		}
+	}
+	return p, nil
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// chdirProject makes dir the working directory and the directory the search
+// for project configuration files stops at
+func chdirProject(t *testing.T, dir string) {
+	t.Chdir(dir)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	projectConfigCeiling = wd
+	t.Cleanup(func() { projectConfigCeiling = "" })
+}
+
+func TestFindProjectConfig(t *testing.T) {
+	root := t.TempDir()
+	projectConfigCeiling = root
+	t.Cleanup(func() { projectConfigCeiling = "" })
+	sub := filepath.Join(root, "services", "web")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if p, err := FindProjectConfig(sub); err != nil || p != nil {
+		t.Fatalf("Expected no project configuration, got %+v, %v", p, err)
+	}
+
+	path := filepath.Join(root, ProjectConfigFile)
+	data := "profile: office\ndefaults:\n  volume_path: /volume2/apps\nlabels:\n  team: web\ncompose_files: [deploy/compose.yaml, /abs/compose.yml]\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := FindProjectConfig(sub)
+	if err != nil {
+		t.Fatalf("FindProjectConfig failed: %v", err)
+	}
+	if p == nil || p.Path != path {
+		t.Fatalf("Expected %s, got %+v", path, p)
+	}
+	if p.Profile != "office" || p.Defaults.VolumePath != "/volume2/apps" || p.Labels["team"] != "web" {
+		t.Errorf("Unexpected project configuration: %+v", p)
+	}
+	want := []string{filepath.Join(root, "deploy", "compose.yaml"), "/abs/compose.yml"}
+	if strings.Join(p.ComposeFiles, " ") != strings.Join(want, " ") {
+		t.Errorf("Expected compose files relative to the file, got %v", p.ComposeFiles)
+	}
+
+	if err := os.WriteFile(path, []byte("profile: bad name\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := FindProjectConfig(sub); err == nil || !strings.Contains(err.Error(), "invalid profile name") {
+		t.Errorf("Expected an invalid profile error, got %v", err)
+	}
+}
+
+func TestLoadWithProjectConfig(t *testing.T) {
+	setupProfileHome(t)
+	setupOverrides(t)
+
+	for _, name := range []string{"home", "office"} {
+		cfg := New()
+		cfg.Host = name + ".local"
+		cfg.Profile = name
+		cfg.Labels = map[string]string{"owner": name, "tier": "nas"}
+		if err := cfg.Save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	repo := t.TempDir()
+	project := filepath.Join(repo, ProjectConfigFile)
+	data := "profile: office\ndefaults:\n  network: apps\nlabels:\n  tier: web\n"
+	if err := os.WriteFile(project, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	chdirProject(t, repo)
+
+	t.Setenv(PortEnv, "2200")
+	SetOverrides(Overrides{Port: 2300})
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Host != "office.local" || cfg.Defaults.Network != "apps" || cfg.Defaults.VolumePath != DefaultVolumePath {
+		t.Errorf("Expected the office profile with the project network, got %+v", cfg)
+	}
+	if cfg.Labels["owner"] != "office" || cfg.Labels["tier"] != "web" {
+		t.Errorf("Expected the project labels over the profile's, got %v", cfg.Labels)
+	}
+
+	configPath, _ := GetConfigPath()
+	profileSource := configPath + " (profile office)"
+	sources := map[string]string{
+		SettingProfile:      project,
+		SettingHost:         profileSource,
+		SettingPort:         "--ssh-port",
+		SettingVolumePath:   profileSource,
+		SettingNetwork:      project,
+		SettingLabels:       project,
+		SettingComposeFiles: SourceDefault,
+	}
+	for key, want := range sources {
+		if got := cfg.Sources[key]; got != want {
+			t.Errorf("Expected %s from %s, got %s", key, want, got)
+		}
+	}
+
+	// --profile and the environment take precedence over the project
+	SelectProfile("home")
+	if cfg, err := Load(); err != nil || cfg.Host != "home.local" || cfg.Sources[SettingProfile] != "--profile" {
+		t.Errorf("Expected the selected profile, got %+v, %v", cfg, err)
+	}
+	SelectProfile("")
+	t.Setenv(ProfileEnv, "home")
+	if cfg, err := Load(); err != nil || cfg.Host != "home.local" {
+		t.Errorf("Expected the profile from %s, got %+v, %v", ProfileEnv, cfg, err)
+	}
+}
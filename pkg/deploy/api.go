@@ -0,0 +1,279 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
+	"github.com/docker/go-units"
+)
+
+// shortIDLength is the length Docker uses for truncated IDs
+const shortIDLength = 12
+
+// listContainersAPI lists containers through the Docker Engine API
+func listContainersAPI(ctx context.Context, cli *client.Client, all bool) ([]ContainerInfo, error) {
+	summaries, err := cli.ContainerList(ctx, container.ListOptions{All: all})
+	if err != nil {
+		return nil, err
+	}
+
+	containers := []ContainerInfo{}
+	for _, summary := range summaries {
+		info := ContainerInfo{
+			ID:     shortID(summary.ID),
+			Image:  summary.Image,
+			Status: summary.Status,
+		}
+		if len(summary.Names) > 0 {
+			info.Name = strings.TrimPrefix(summary.Names[0], "/")
+		}
+		for _, port := range summary.Ports {
+			info.Ports = append(info.Ports, formatPort(port))
+		}
+		containers = append(containers, info)
+	}
+
+	return containers, nil
+}
+
+// runContainerAPI creates and starts a container through the Docker Engine
+// API, as docker run -d does, and returns its ID
+func runContainerAPI(ctx context.Context, cli *client.Client, opts *ContainerOptions) (string, error) {
+	exposedPorts, portBindings, err := nat.ParsePortSpecs(opts.Ports)
+	if err != nil {
+		return "", err
+	}
+	restartPolicy, err := parseRestartPolicy(opts.Restart)
+	if err != nil {
+		return "", err
+	}
+
+	config := &container.Config{
+		Image:        opts.Image,
+		Cmd:          opts.Command,
+		Env:          opts.Env,
+		User:         opts.User,
+		WorkingDir:   opts.WorkingDir,
+		Labels:       parseKeyValues(opts.Labels),
+		ExposedPorts: exposedPorts,
+	}
+	hostConfig := &container.HostConfig{
+		PortBindings:  portBindings,
+		RestartPolicy: restartPolicy,
+		NetworkMode:   container.NetworkMode(opts.NetworkMode),
+	}
+	// A volume without a source, like -v /data, is an anonymous volume
+	for _, spec := range opts.Volumes {
+		if strings.Contains(spec, ":") {
+			hostConfig.Binds = append(hostConfig.Binds, spec)
+			continue
+		}
+		if config.Volumes == nil {
+			config.Volumes = map[string]struct{}{}
+		}
+		config.Volumes[spec] = struct{}{}
+	}
+
+	created, err := cli.ContainerCreate(ctx, config, hostConfig, nil, nil, opts.Name)
+	if err != nil {
+		return "", err
+	}
+	// Like docker run, a container that fails to start is left created
+	if err := cli.ContainerStart(ctx, created.ID, container.StartOptions{}); err != nil {
+		return "", err
+	}
+
+	return created.ID, nil
+}
+
+// parseRestartPolicy parses a --restart value such as "unless-stopped" or
+// "on-failure:3"
+func parseRestartPolicy(value string) (container.RestartPolicy, error) {
+	name, count, hasCount := strings.Cut(value, ":")
+	policy := container.RestartPolicy{Name: container.RestartPolicyMode(name)}
+	if hasCount {
+		retries, err := strconv.Atoi(count)
+		if err != nil {
+			return container.RestartPolicy{}, fmt.Errorf("invalid restart policy %q", value)
+		}
+		policy.MaximumRetryCount = retries
+	}
+	if err := container.ValidateRestartPolicy(policy); err != nil {
+		return container.RestartPolicy{}, err
+	}
+	return policy, nil
+}
+
+// listImagesAPI lists images through the Docker Engine API
+func listImagesAPI(ctx context.Context, cli *client.Client, repository string, opts *ImagesOptions) ([]ImageInfo, error) {
+	summaries, err := cli.ImageList(ctx, image.ListOptions{All: opts.All, Filters: imageFilters(repository, opts)})
+	if err != nil {
+		return nil, err
+	}
+
+	images := []ImageInfo{}
+	for _, summary := range summaries {
+		id := strings.TrimPrefix(summary.ID, "sha256:")
+		if !opts.NoTrunc {
+			id = shortID(id)
+		}
+
+		repoTags := summary.RepoTags
+		if len(repoTags) == 0 {
+			repoTags = []string{"<none>:<none>"}
+		}
+
+		for _, repoTag := range repoTags {
+			info := ImageInfo{
+				Repository: repoTag,
+				Tag:        "<none>",
+				Digest:     "<none>",
+				ID:         id,
+				Created:    units.HumanDuration(time.Since(time.Unix(summary.Created, 0))) + " ago",
+				Size:       units.HumanSizeWithPrecision(float64(summary.Size), 3),
+			}
+			if idx := strings.LastIndex(repoTag, ":"); idx != -1 {
+				info.Repository = repoTag[:idx]
+				info.Tag = repoTag[idx+1:]
+			}
+			for _, repoDigest := range summary.RepoDigests {
+				if strings.HasPrefix(repoDigest, info.Repository+"@") {
+					info.Digest = strings.TrimPrefix(repoDigest, info.Repository+"@")
+				}
+			}
+			images = append(images, info)
+		}
+	}
+
+	return images, nil
+}
+
+// listImageIDsAPI lists image IDs through the Docker Engine API
+func listImageIDsAPI(ctx context.Context, cli *client.Client, repository string, opts *ImagesOptions) ([]string, error) {
+	summaries, err := cli.ImageList(ctx, image.ListOptions{All: opts.All, Filters: imageFilters(repository, opts)})
+	if err != nil {
+		return nil, err
+	}
+
+	imageIDs := []string{}
+	for _, summary := range summaries {
+		id := strings.TrimPrefix(summary.ID, "sha256:")
+		if !opts.NoTrunc {
+			id = shortID(id)
+		}
+		imageIDs = append(imageIDs, id)
+	}
+
+	return imageIDs, nil
+}
+
+// listVolumesAPI lists volumes through the Docker Engine API
+func listVolumesAPI(ctx context.Context, cli *client.Client) ([]VolumeInfo, error) {
+	resp, err := cli.VolumeList(ctx, volume.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	volumes := []VolumeInfo{}
+	for _, v := range resp.Volumes {
+		volumes = append(volumes, VolumeInfo{Name: v.Name, Driver: v.Driver})
+	}
+
+	return volumes, nil
+}
+
+// createVolumeAPI creates a volume through the Docker Engine API
+func createVolumeAPI(ctx context.Context, cli *client.Client, volumeName string, opts *VolumeCreateOptions) (string, error) {
+	v, err := cli.VolumeCreate(ctx, volume.CreateOptions{
+		Name:       volumeName,
+		Driver:     opts.Driver,
+		Labels:     parseKeyValues(opts.Labels),
+		DriverOpts: parseKeyValues(opts.Options),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return v.Name, nil
+}
+
+// listNetworksAPI lists networks through the Docker Engine API
+func listNetworksAPI(ctx context.Context, cli *client.Client, opts *NetworkListOptions) ([]NetworkInfo, error) {
+	summaries, err := cli.NetworkList(ctx, network.ListOptions{Filters: parseFilters(opts.Filter)})
+	if err != nil {
+		return nil, err
+	}
+
+	networks := []NetworkInfo{}
+	for _, summary := range summaries {
+		networks = append(networks, NetworkInfo{
+			ID:     shortID(summary.ID),
+			Name:   summary.Name,
+			Driver: summary.Driver,
+			Scope:  summary.Scope,
+		})
+	}
+
+	return networks, nil
+}
+
+func imageFilters(repository string, opts *ImagesOptions) filters.Args {
+	args := filters.NewArgs()
+	if opts.Dangling {
+		args.Add("dangling", "true")
+	}
+	if repository != "" {
+		args.Add("reference", repository)
+	}
+	return args
+}
+
+// parseFilters converts CLI style "key=value" filters to API filter arguments
+func parseFilters(values []string) filters.Args {
+	args := filters.NewArgs()
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) == 2 {
+			args.Add(parts[0], parts[1])
+		}
+	}
+	return args
+}
+
+// parseKeyValues converts "key=value" strings to a map
+func parseKeyValues(values []string) map[string]string {
+	result := make(map[string]string)
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) == 2 {
+			result[parts[0]] = parts[1]
+		} else {
+			result[parts[0]] = ""
+		}
+	}
+	return result
+}
+
+func formatPort(port container.Port) string {
+	if port.PublicPort == 0 {
+		return fmt.Sprintf("%d/%s", port.PrivatePort, port.Type)
+	}
+	return fmt.Sprintf("%s:%d->%d/%s", port.IP, port.PublicPort, port.PrivatePort, port.Type)
+}
+
+func shortID(id string) string {
+	if len(id) > shortIDLength {
+		return id[:shortIDLength]
+	}
+	return id
+}
@@ -0,0 +1,231 @@
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+func newTestDockerClient(t *testing.T, handler http.HandlerFunc) *client.Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	cli, err := client.NewClientWithOpts(client.WithHost("tcp://"+strings.TrimPrefix(server.URL, "http://")), client.WithVersion("1.43"))
+	if err != nil {
+		t.Fatalf("Failed to create Docker client: %v", err)
+	}
+	t.Cleanup(func() { cli.Close() })
+	return cli
+}
+
+func TestListContainersAPI(t *testing.T) {
+	cli := newTestDockerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/containers/json") {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("all") != "1" {
+			t.Errorf("Expected all=1, got %q", r.URL.Query().Get("all"))
+		}
+		json.NewEncoder(w).Encode([]container.Summary{
+			{
+				ID:     "0123456789abcdef0123456789abcdef",
+				Names:  []string{"/web"},
+				Image:  "nginx:latest",
+				Status: "Up 2 hours",
+				Ports: []container.Port{
+					{IP: "0.0.0.0", PrivatePort: 80, PublicPort: 8080, Type: "tcp"},
+					{PrivatePort: 443, Type: "tcp"},
+				},
+			},
+		})
+	})
+
+	containers, err := listContainersAPI(context.Background(), cli, true)
+	if err != nil {
+		t.Fatalf("listContainersAPI failed: %v", err)
+	}
+
+	if len(containers) != 1 {
+		t.Fatalf("Expected 1 container, got %d", len(containers))
+	}
+
+	c := containers[0]
+	if c.ID != "0123456789ab" {
+		t.Errorf("Expected short ID 0123456789ab, got %s", c.ID)
+	}
+	if c.Name != "web" {
+		t.Errorf("Expected name web, got %s", c.Name)
+	}
+	if c.Status != "Up 2 hours" {
+		t.Errorf("Expected status 'Up 2 hours', got %s", c.Status)
+	}
+	expectedPorts := []string{"0.0.0.0:8080->80/tcp", "443/tcp"}
+	if len(c.Ports) != len(expectedPorts) {
+		t.Fatalf("Expected ports %v, got %v", expectedPorts, c.Ports)
+	}
+	for i, port := range expectedPorts {
+		if c.Ports[i] != port {
+			t.Errorf("Port %d: expected %s, got %s", i, port, c.Ports[i])
+		}
+	}
+}
+
+func TestRunContainerAPI(t *testing.T) {
+	var requests []string
+	var created container.CreateRequest
+	cli := newTestDockerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path[strings.Index(r.URL.Path, "/containers"):])
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/containers/create"):
+			if r.URL.Query().Get("name") != "web" {
+				t.Errorf("Expected name web, got %q", r.URL.Query().Get("name"))
+			}
+			if err := json.NewDecoder(r.Body).Decode(&created); err != nil {
+				t.Errorf("Failed to decode create request: %v", err)
+			}
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(container.CreateResponse{ID: "0123456789abcdef"})
+		case strings.HasSuffix(r.URL.Path, "/containers/0123456789abcdef/start"):
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	opts := NewContainerOptions("nginx:alpine")
+	opts.Name = "web"
+	opts.Ports = []string{"127.0.0.1:8080:80", "443:443/tcp"}
+	opts.Volumes = []string{"/volume1/docker/web:/usr/share/nginx/html:ro", "/cache"}
+	opts.Env = []string{"MODE=prod"}
+	opts.Restart = "on-failure:3"
+	opts.Labels = []string{"app=web"}
+	opts.Command = []string{"nginx", "-g", "daemon off;"}
+
+	id, err := runContainerAPI(context.Background(), cli, opts)
+	if err != nil {
+		t.Fatalf("runContainerAPI failed: %v", err)
+	}
+	if id != "0123456789abcdef" {
+		t.Errorf("Expected the created container's ID, got %s", id)
+	}
+	if strings.Join(requests, ", ") != "POST /containers/create, POST /containers/0123456789abcdef/start" {
+		t.Errorf("Expected create then start, got %v", requests)
+	}
+
+	if created.Config == nil || created.HostConfig == nil {
+		t.Fatalf("Expected a config and host config, got %+v", created)
+	}
+	if created.Image != "nginx:alpine" || strings.Join(created.Cmd, " ") != "nginx -g daemon off;" || created.Labels["app"] != "web" {
+		t.Errorf("Unexpected config %+v", created.Config)
+	}
+	if len(created.Env) != 1 || created.Env[0] != "MODE=prod" {
+		t.Errorf("Expected MODE=prod, got %v", created.Env)
+	}
+	if _, ok := created.Volumes["/cache"]; !ok || len(created.HostConfig.Binds) != 1 {
+		t.Errorf("Expected one bind and one anonymous volume, got %v and %v", created.HostConfig.Binds, created.Volumes)
+	}
+	binding := created.HostConfig.PortBindings["80/tcp"]
+	if len(binding) != 1 || binding[0].HostIP != "127.0.0.1" || binding[0].HostPort != "8080" {
+		t.Errorf("Expected 127.0.0.1:8080 bound to 80/tcp, got %v", created.HostConfig.PortBindings)
+	}
+	if len(created.HostConfig.PortBindings["443/tcp"]) != 1 {
+		t.Errorf("Expected 443/tcp bound, got %v", created.HostConfig.PortBindings)
+	}
+	if policy := created.HostConfig.RestartPolicy; policy.Name != container.RestartPolicyOnFailure || policy.MaximumRetryCount != 3 {
+		t.Errorf("Expected on-failure:3, got %+v", policy)
+	}
+	if created.HostConfig.NetworkMode != "bridge" {
+		t.Errorf("Expected the bridge network, got %s", created.HostConfig.NetworkMode)
+	}
+}
+
+func TestParseRestartPolicy(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    container.RestartPolicy
+		wantErr bool
+	}{
+		{"", container.RestartPolicy{}, false},
+		{"no", container.RestartPolicy{Name: container.RestartPolicyDisabled}, false},
+		{"unless-stopped", container.RestartPolicy{Name: container.RestartPolicyUnlessStopped}, false},
+		{"on-failure:5", container.RestartPolicy{Name: container.RestartPolicyOnFailure, MaximumRetryCount: 5}, false},
+		{"on-failure:many", container.RestartPolicy{}, true},
+		{"always:2", container.RestartPolicy{}, true},
+		{"sometimes", container.RestartPolicy{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseRestartPolicy(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRestartPolicy(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseKeyValues(t *testing.T) {
+	result := parseKeyValues([]string{"env=prod", "team=web=ops", "flag"})
+
+	expected := map[string]string{
+		"env":  "prod",
+		"team": "web=ops",
+		"flag": "",
+	}
+
+	if len(result) != len(expected) {
+		t.Errorf("Expected %d entries, got %d", len(expected), len(result))
+	}
+	for key, value := range expected {
+		if result[key] != value {
+			t.Errorf("Key %s: expected %q, got %q", key, value, result[key])
+		}
+	}
+}
+
+func TestParseFilters(t *testing.T) {
+	args := parseFilters([]string{"label=a", "label=b", "driver=bridge", "invalid"})
+
+	if labels := args.Get("label"); len(labels) != 2 {
+		t.Errorf("Expected 2 label filters, got %v", labels)
+	}
+	if !args.ExactMatch("driver", "bridge") {
+		t.Error("Expected driver=bridge filter")
+	}
+	if args.Len() != 2 {
+		t.Errorf("Expected 2 filter keys, got %d", args.Len())
+	}
+}
+
+func TestStopOptions(t *testing.T) {
+	if opts := stopOptions(0); opts.Timeout != nil {
+		t.Error("Expected no timeout for 0")
+	}
+	if opts := stopOptions(10); opts.Timeout == nil || *opts.Timeout != 10 {
+		t.Error("Expected timeout of 10 seconds")
+	}
+}
+
+func TestListContainersAPICancelled(t *testing.T) {
+	cli := newTestDockerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Request should not be sent with a cancelled context")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := listContainersAPI(ctx, cli, true); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
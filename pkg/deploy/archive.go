@@ -0,0 +1,360 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/client"
+	"github.com/pkg/errors"
+
+	"github.com/scttfrdmn/syno-docker/pkg/synology"
+)
+
+// exportCompressors maps the compression formats ExportContainer and
+// SaveImages support to the command that compresses the tar stream on the NAS
+var exportCompressors = map[string]string{
+	"":     "",
+	"gzip": "gzip -c",
+	"zstd": "zstd -c -q",
+}
+
+// ExportCompressions lists the compression formats ExportContainer and
+// SaveImages support
+func ExportCompressions() []string {
+	var formats []string
+	for format := range exportCompressors {
+		if format != "" {
+			formats = append(formats, format)
+		}
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// ExportOptions defines options for exporting containers
+type ExportOptions struct {
+	// Output is the local file to write; Stdout is used when empty
+	Output string
+	// Compression compresses the archive on the NAS: "", "gzip" or "zstd"
+	Compression string
+	// Stdout receives the archive when Output is empty; os.Stdout when nil
+	Stdout io.Writer
+	// Progress, when set, receives a progress line while the archive is written
+	Progress io.Writer
+}
+
+// SaveOptions defines options for saving images
+type SaveOptions struct {
+	// Output is the local file to write; Stdout is used when empty
+	Output string
+	// Compression compresses the archive on the NAS: "", "gzip" or "zstd"
+	Compression string
+	// Stdout receives the archive when Output is empty; os.Stdout when nil
+	Stdout io.Writer
+	// Progress, when set, receives a progress line while the archive is written
+	Progress io.Writer
+}
+
+// ImportOptions defines options for importing images
+type ImportOptions struct {
+	Change   []string
+	Message  string
+	Platform string
+	// Stdin is read when the source is "-"; os.Stdin when nil
+	Stdin io.Reader
+	// Progress, when set, receives a progress line while a local file is sent
+	Progress io.Writer
+}
+
+// LoadOptions defines options for loading images
+type LoadOptions struct {
+	// Input is the local archive to send; Stdin is used when empty
+	Input string
+	// Stdin is read when Input is empty; os.Stdin when nil
+	Stdin io.Reader
+	Quiet bool
+	// Progress, when set, receives a progress line while the archive is sent
+	Progress io.Writer
+}
+
+// ExportContainer streams a container's filesystem as a tar archive to a
+// local file or stdout. The archive is never held in memory, so containers
+// of any size can be exported. A partially written file is removed when
+// the export fails.
+func ExportContainer(conn synology.Executor, containerName string, opts *ExportOptions) error {
+	return ExportContainerContext(context.Background(), conn, containerName, opts)
+}
+
+// ExportContainerContext is like ExportContainer but honors ctx
+func ExportContainerContext(ctx context.Context, conn synology.Executor, containerName string, opts *ExportOptions) error {
+	download := &archiveDownload{
+		args:        []string{"export", containerName},
+		label:       "Exporting " + containerName,
+		output:      opts.Output,
+		compression: opts.Compression,
+		stdout:      opts.Stdout,
+		progress:    opts.Progress,
+		api: func(cli *client.Client) (io.ReadCloser, error) {
+			return cli.ContainerExport(ctx, containerName)
+		},
+	}
+	if err := download.run(ctx, conn); err != nil {
+		return errors.Wrapf(err, "failed to export container %s", containerName)
+	}
+	return nil
+}
+
+// SaveImages streams one or more images, with their layers and tags, as a
+// docker save archive to a local file or stdout. As with ExportContainer
+// the archive is never held in memory.
+func SaveImages(conn synology.Executor, images []string, opts *SaveOptions) error {
+	return SaveImagesContext(context.Background(), conn, images, opts)
+}
+
+// SaveImagesContext is like SaveImages but honors ctx
+func SaveImagesContext(ctx context.Context, conn synology.Executor, images []string, opts *SaveOptions) error {
+	download := &archiveDownload{
+		args:        append([]string{"save"}, images...),
+		label:       "Saving " + strings.Join(images, ", "),
+		output:      opts.Output,
+		compression: opts.Compression,
+		stdout:      opts.Stdout,
+		progress:    opts.Progress,
+		api: func(cli *client.Client) (io.ReadCloser, error) {
+			return cli.ImageSave(ctx, images)
+		},
+	}
+	if err := download.run(ctx, conn); err != nil {
+		return errors.Wrapf(err, "failed to save %s", strings.Join(images, ", "))
+	}
+	return nil
+}
+
+// archiveDownload streams the output of a docker command that writes an
+// archive, such as export or save, to a local file or stdout
+type archiveDownload struct {
+	args        []string
+	label       string
+	output      string
+	compression string
+	stdout      io.Writer
+	progress    io.Writer
+	// api produces the archive through the Docker Engine API, which is
+	// used for uncompressed downloads when available
+	api func(*client.Client) (io.ReadCloser, error)
+}
+
+// run writes the archive. A partially written output file is removed when
+// the download fails.
+func (d *archiveDownload) run(ctx context.Context, conn synology.Executor) error {
+	compressor, ok := exportCompressors[d.compression]
+	if !ok {
+		return fmt.Errorf("unsupported compression %q (supported: %v)", d.compression, ExportCompressions())
+	}
+
+	out := d.stdout
+	if out == nil {
+		out = os.Stdout
+	}
+	var file *os.File
+	if d.output != "" {
+		var err error
+		if file, err = os.Create(d.output); err != nil {
+			return fmt.Errorf("failed to create %s: %w", d.output, err)
+		}
+		out = file
+	}
+
+	var progress *progressWriter
+	if d.progress != nil {
+		progress = newProgressWriter(d.progress, d.label, 0)
+		out = io.MultiWriter(out, progress)
+	}
+
+	err := d.stream(ctx, conn, compressor, out)
+	if progress != nil {
+		progress.Finish()
+	}
+
+	if file != nil {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(d.output)
+		}
+	}
+	return err
+}
+
+// stream copies the archive to w, through the Docker Engine API when
+// possible and otherwise from the docker CLI over an SSH session, piped
+// through compressor
+func (d *archiveDownload) stream(ctx context.Context, conn synology.Executor, compressor string, w io.Writer) error {
+	if cli := dockerClient(conn); cli != nil && compressor == "" {
+		archive, err := d.api(cli)
+		if err != nil {
+			return synology.ClassifyError(err)
+		}
+		defer archive.Close()
+
+		_, err = io.Copy(w, archive)
+		return err
+	}
+
+	cmd := synology.DockerCommand(d.args)
+	if compressor != "" {
+		// pipefail keeps a docker failure from being hidden behind the
+		// compressor's successful exit
+		cmd = "set -o pipefail && " + cmd + " | " + compressor
+	}
+
+	if err := conn.StreamCommandContext(ctx, cmd, w, nil); err != nil {
+		var remoteErr *synology.RemoteCommandError
+		if compressor != "" && errors.As(err, &remoteErr) && remoteErr.ExitStatus == 127 {
+			return fmt.Errorf("compression command not found on the NAS: %w", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// ImportImage imports the contents of a tarball to create a filesystem
+// image. source is a local file, which is streamed to the NAS, "-" for
+// Stdin, a URL the NAS downloads, or "nas:PATH" for a file already on the
+// NAS. It returns the new image ID.
+func ImportImage(conn synology.Executor, source, repository string, opts *ImportOptions) (string, error) {
+	return ImportImageContext(context.Background(), conn, source, repository, opts)
+}
+
+// ImportImageContext is like ImportImage but honors ctx
+func ImportImageContext(ctx context.Context, conn synology.Executor, source, repository string, opts *ImportOptions) (string, error) {
+	args := []string{"import"}
+
+	for _, change := range opts.Change {
+		args = append(args, "--change", change)
+	}
+	if opts.Message != "" {
+		args = append(args, "--message", opts.Message)
+	}
+	if opts.Platform != "" {
+		args = append(args, "--platform", opts.Platform)
+	}
+
+	var input string
+	switch {
+	case strings.HasPrefix(source, "http://"), strings.HasPrefix(source, "https://"):
+		args = append(args, source)
+	case strings.HasPrefix(source, "nas:"):
+		args = append(args, strings.TrimPrefix(source, "nas:"))
+	default:
+		// A local file, or stdin for "-", is sent over the session
+		args = append(args, "-")
+		input = source
+	}
+	if repository != "" {
+		args = append(args, repository)
+	}
+
+	if input != "" && input != "-" {
+		if _, err := os.Stat(input); os.IsNotExist(err) {
+			return "", fmt.Errorf("local file %s does not exist; use nas:%s for a file on the NAS", source, source)
+		}
+	}
+
+	var output string
+	var err error
+	if input == "" {
+		output, err = conn.ExecuteDockerCommandContext(ctx, args)
+	} else {
+		upload := &archiveUpload{args: args, input: input, stdin: opts.Stdin, label: "Importing " + source, progress: opts.Progress}
+		output, err = upload.run(ctx, conn)
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to import image from %s", source)
+	}
+
+	return strings.TrimSpace(output), nil
+}
+
+// LoadImages loads the images of a docker save archive from a local file or
+// stdin onto the NAS, so that images can be sideloaded onto a NAS without
+// internet access. It returns the docker load output listing the images.
+func LoadImages(conn synology.Executor, opts *LoadOptions) (string, error) {
+	return LoadImagesContext(context.Background(), conn, opts)
+}
+
+// LoadImagesContext is like LoadImages but honors ctx
+func LoadImagesContext(ctx context.Context, conn synology.Executor, opts *LoadOptions) (string, error) {
+	args := []string{"load"}
+	if opts.Quiet {
+		args = append(args, "--quiet")
+	}
+
+	input := opts.Input
+	if input == "" {
+		input = "-"
+	}
+
+	upload := &archiveUpload{args: args, input: input, stdin: opts.Stdin, label: "Loading " + input, progress: opts.Progress}
+	output, err := upload.run(ctx, conn)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to load images from %s", input)
+	}
+	return output, nil
+}
+
+// archiveUpload streams a local archive into a docker command that reads
+// it from stdin, such as load or import -
+type archiveUpload struct {
+	args []string
+	// input is a local file, or "-" for stdin
+	input string
+	stdin io.Reader
+	// size is the size of stdin, when known, for progress
+	size     int64
+	label    string
+	progress io.Writer
+}
+
+// run sends the archive and returns the command output
+func (u *archiveUpload) run(ctx context.Context, conn synology.Executor) (string, error) {
+	in := u.stdin
+	if in == nil {
+		in = os.Stdin
+	}
+	size := u.size
+	if u.input != "-" {
+		file, err := os.Open(u.input)
+		if err != nil {
+			return "", err
+		}
+		defer file.Close()
+
+		if info, err := file.Stat(); err == nil {
+			size = info.Size()
+		}
+		in = file
+	}
+
+	var progress *progressWriter
+	if u.progress != nil {
+		progress = newProgressWriter(u.progress, u.label, size)
+		in = io.TeeReader(in, progress)
+	}
+
+	var stdout bytes.Buffer
+	err := conn.RunSessionContext(ctx, synology.DockerCommand(u.args), &synology.SessionOptions{
+		Stdin:  &contextReader{ctx: ctx, r: in},
+		Stdout: &stdout,
+	})
+	if progress != nil {
+		progress.Finish()
+	}
+	return stdout.String(), err
+}
@@ -0,0 +1,189 @@
+package deploy
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/scttfrdmn/syno-docker/pkg/synology"
+	"github.com/scttfrdmn/syno-docker/pkg/synology/synologytest"
+)
+
+func TestExportContainerToFile(t *testing.T) {
+	fake := synologytest.New()
+	fake.OnDocker("export", "web").Return("tar data")
+
+	output := filepath.Join(t.TempDir(), "web.tar")
+	var progress bytes.Buffer
+	if err := ExportContainer(fake, "web", &ExportOptions{Output: output, Progress: &progress}); err != nil {
+		t.Fatalf("ExportContainer failed: %v", err)
+	}
+
+	if data, _ := os.ReadFile(output); string(data) != "tar data" {
+		t.Errorf("Expected the archive in the output file, got %q", data)
+	}
+	if !strings.Contains(progress.String(), "Exporting web  8B") {
+		t.Errorf("Expected progress output, got %q", progress.String())
+	}
+}
+
+func TestExportContainerToStdout(t *testing.T) {
+	fake := synologytest.New()
+	fake.OnDocker("export", "web").Return("tar data")
+
+	var stdout bytes.Buffer
+	if err := ExportContainer(fake, "web", &ExportOptions{Stdout: &stdout}); err != nil {
+		t.Fatalf("ExportContainer failed: %v", err)
+	}
+	if stdout.String() != "tar data" {
+		t.Errorf("Expected the archive on stdout, got %q", stdout.String())
+	}
+}
+
+func TestExportContainerCompressed(t *testing.T) {
+	docker := synologytest.NewFakeDocker()
+	server := synologytest.NewSSHServer(t, docker)
+	conn := connectTestServer(t, server)
+
+	opts := NewContainerOptions("nginx:alpine")
+	opts.Name = "web"
+	if _, err := Container(conn, opts); err != nil {
+		t.Fatalf("Container failed: %v", err)
+	}
+
+	output := filepath.Join(t.TempDir(), "web.tar.gz")
+	if err := ExportContainer(conn, "web", &ExportOptions{Output: output, Compression: "gzip"}); err != nil {
+		t.Fatalf("ExportContainer failed: %v", err)
+	}
+
+	file, err := os.Open(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("Expected a gzip archive: %v", err)
+	}
+	header, err := tar.NewReader(gz).Next()
+	if err != nil || header.Name != "etc/hostname" {
+		t.Errorf("Expected a tar archive inside, got %v, %v", header, err)
+	}
+
+	commands := server.Commands()
+	if last := commands[len(commands)-1]; !strings.HasPrefix(last, "set -o pipefail && ") || !strings.HasSuffix(last, "| gzip -c") {
+		t.Errorf("Expected a pipefail pipeline, got %q", last)
+	}
+}
+
+func TestExportContainerFailure(t *testing.T) {
+	server := synologytest.NewSSHServer(t, nil)
+	conn := connectTestServer(t, server)
+
+	// The failure of docker export is not hidden by gzip succeeding
+	output := filepath.Join(t.TempDir(), "missing.tar.gz")
+	err := ExportContainer(conn, "missing", &ExportOptions{Output: output, Compression: "gzip"})
+	if !errors.Is(err, synology.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if _, statErr := os.Stat(output); !os.IsNotExist(statErr) {
+		t.Errorf("Expected the partial file to be removed, got %v", statErr)
+	}
+
+	err = ExportContainer(conn, "missing", &ExportOptions{Stdout: io.Discard, Compression: "zstd"})
+	if err == nil || !strings.Contains(err.Error(), "compression command not found") {
+		t.Errorf("Expected a missing compressor error, got %v", err)
+	}
+
+	err = ExportContainer(conn, "missing", &ExportOptions{Stdout: io.Discard, Compression: "bzip2"})
+	if err == nil || !strings.Contains(err.Error(), `unsupported compression "bzip2"`) {
+		t.Errorf("Expected an unsupported compression error, got %v", err)
+	}
+}
+
+func TestImportImageSources(t *testing.T) {
+	local := filepath.Join(t.TempDir(), "rootfs.tar")
+	if err := os.WriteFile(local, []byte("rootfs"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+		stdin    string
+	}{
+		{"local file", local, synology.DockerBinary + " import - app:1", "rootfs"},
+		{"stdin", "-", synology.DockerBinary + " import - app:1", "piped"},
+		{"url", "https://example.com/rootfs.tar", synology.DockerBinary + " import https://example.com/rootfs.tar app:1", ""},
+		{"nas path", "nas:/volume1/rootfs.tar", synology.DockerBinary + " import /volume1/rootfs.tar app:1", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := synologytest.New()
+			fake.OnDocker("import").Return("sha256:abc\n")
+
+			id, err := ImportImage(fake, tt.source, "app:1", &ImportOptions{Stdin: strings.NewReader("piped")})
+			if err != nil {
+				t.Fatalf("ImportImage failed: %v", err)
+			}
+			if id != "sha256:abc" {
+				t.Errorf("Expected image ID, got %q", id)
+			}
+
+			calls := fake.Calls()
+			if len(calls) != 1 || calls[0].Command != tt.expected || calls[0].Stdin != tt.stdin {
+				t.Errorf("Expected %q with stdin %q, got %+v", tt.expected, tt.stdin, calls)
+			}
+		})
+	}
+}
+
+func TestImportImageMissingLocalFile(t *testing.T) {
+	_, err := ImportImage(synologytest.New(), "/no/such/rootfs.tar", "", &ImportOptions{})
+	if err == nil || !strings.Contains(err.Error(), "use nas:/no/such/rootfs.tar") {
+		t.Errorf("Expected a hint about NAS paths, got %v", err)
+	}
+}
+
+func TestSaveAndLoadImages(t *testing.T) {
+	source := synologytest.NewFakeDocker()
+	source.AddImage("nginx:alpine")
+	source.AddImage("redis:7")
+	conn := connectTestServer(t, synologytest.NewSSHServer(t, source))
+
+	archive := filepath.Join(t.TempDir(), "images.tar.gz")
+	if err := SaveImages(conn, []string{"nginx:alpine", "redis:7"}, &SaveOptions{Output: archive, Compression: "gzip"}); err != nil {
+		t.Fatalf("SaveImages failed: %v", err)
+	}
+
+	// Sideload onto a second NAS that has no images
+	target := synologytest.NewFakeDocker()
+	offline := connectTestServer(t, synologytest.NewSSHServer(t, target))
+
+	var progress bytes.Buffer
+	output, err := LoadImages(offline, &LoadOptions{Input: archive, Progress: &progress})
+	if err != nil {
+		t.Fatalf("LoadImages failed: %v", err)
+	}
+	if !strings.Contains(output, "Loaded image: nginx:alpine") || !strings.Contains(output, "Loaded image: redis:7") {
+		t.Errorf("Expected both images to be loaded, got %q", output)
+	}
+	if len(target.Images()) != 2 {
+		t.Errorf("Expected 2 images on the target, got %+v", target.Images())
+	}
+	if !strings.Contains(progress.String(), "100%") {
+		t.Errorf("Expected upload progress, got %q", progress.String())
+	}
+
+	if err := SaveImages(conn, []string{"missing:1"}, &SaveOptions{Stdout: io.Discard}); !errors.Is(err, synology.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound saving a missing image, got %v", err)
+	}
+}
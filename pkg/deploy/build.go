@@ -0,0 +1,297 @@
+package deploy
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/moby/patternmatcher"
+	"github.com/moby/patternmatcher/ignorefile"
+	"github.com/pkg/errors"
+
+	"github.com/scttfrdmn/syno-docker/pkg/synology"
+)
+
+// BuildOptions defines options for building images
+type BuildOptions struct {
+	// Context is the local directory sent to the NAS as the build context
+	Context string
+	// Dockerfile is the path of the Dockerfile, relative to the current
+	// directory as with docker build -f; Context/Dockerfile when empty. A
+	// Dockerfile outside Context is added to the context sent.
+	Dockerfile string
+	Tags       []string
+	// BuildArgs are KEY=VALUE pairs; a bare KEY takes its value from the
+	// local environment and is left out when it is not set
+	BuildArgs []string
+	Target    string
+	NoCache   bool
+	Pull      bool
+	// Output receives the build output as it is produced; os.Stdout when nil
+	Output io.Writer
+}
+
+// BuildImage builds an image on the NAS from a local build context. The
+// context is packed while honoring .dockerignore, streamed to the NAS and
+// built there with docker build, without being stored on the NAS.
+func BuildImage(conn synology.Executor, opts *BuildOptions) error {
+	return BuildImageContext(context.Background(), conn, opts)
+}
+
+// BuildImageContext is like BuildImage but honors ctx
+func BuildImageContext(ctx context.Context, conn synology.Executor, opts *BuildOptions) error {
+	contextDir := opts.Context
+	if info, err := os.Stat(contextDir); err != nil || !info.IsDir() {
+		return fmt.Errorf("build context %s is not a directory", contextDir)
+	}
+
+	dockerfile := opts.Dockerfile
+	if dockerfile == "" {
+		dockerfile = filepath.Join(contextDir, "Dockerfile")
+	}
+	if _, err := os.Stat(dockerfile); err != nil {
+		return fmt.Errorf("cannot find Dockerfile %s: %w", dockerfile, err)
+	}
+
+	buildContext, err := newBuildContext(contextDir, dockerfile)
+	if err != nil {
+		return errors.Wrap(err, "failed to prepare build context")
+	}
+
+	args := []string{"build", "-f", buildContext.dockerfile}
+	for _, tag := range opts.Tags {
+		args = append(args, "-t", tag)
+	}
+	for _, arg := range opts.BuildArgs {
+		if !strings.Contains(arg, "=") {
+			value, ok := os.LookupEnv(arg)
+			if !ok {
+				continue
+			}
+			arg += "=" + value
+		}
+		args = append(args, "--build-arg", arg)
+	}
+	if opts.Target != "" {
+		args = append(args, "--target", opts.Target)
+	}
+	if opts.NoCache {
+		args = append(args, "--no-cache")
+	}
+	if opts.Pull {
+		args = append(args, "--pull")
+	}
+	args = append(args, "-")
+
+	output := opts.Output
+	if output == nil {
+		output = os.Stdout
+	}
+
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		err := buildContext.write(pw)
+		pw.CloseWithError(err)
+		done <- err
+	}()
+
+	err = conn.RunSessionContext(ctx, synology.DockerCommand(args), &synology.SessionOptions{
+		Stdin:  &contextReader{ctx: ctx, r: pr},
+		Stdout: output,
+		Stderr: output,
+	})
+	// Unblock the writer if docker build stopped reading early
+	pr.Close()
+	if contextErr := <-done; contextErr != nil && contextErr != io.ErrClosedPipe {
+		return errors.Wrap(contextErr, "failed to send build context")
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to build image")
+	}
+	return nil
+}
+
+// buildContext is a local directory packed as a docker build context
+type buildContext struct {
+	dir      string
+	excludes *patternmatcher.PatternMatcher
+	// dockerfile is the Dockerfile's path in the context
+	dockerfile string
+	// outside is the Dockerfile to add to the context when it isn't in dir
+	outside string
+}
+
+// newBuildContext reads the ignore rules for dir. As with docker build, a
+// <Dockerfile>.dockerignore next to the Dockerfile takes precedence over
+// dir/.dockerignore, and neither the Dockerfile nor .dockerignore is ever
+// excluded.
+func newBuildContext(dir, dockerfile string) (*buildContext, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	absDockerfile, err := filepath.Abs(dockerfile)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &buildContext{dir: absDir}
+	if rel, err := filepath.Rel(absDir, absDockerfile); err == nil && filepath.IsLocal(rel) {
+		b.dockerfile = filepath.ToSlash(rel)
+	} else {
+		suffix := make([]byte, 10)
+		if _, err := rand.Read(suffix); err != nil {
+			return nil, err
+		}
+		b.dockerfile = ".dockerfile." + hex.EncodeToString(suffix)
+		b.outside = absDockerfile
+	}
+
+	patterns, err := readDockerignore(absDockerfile+".dockerignore", filepath.Join(absDir, ".dockerignore"))
+	if err != nil {
+		return nil, err
+	}
+	if ignored, _ := patternmatcher.MatchesOrParentMatches(".dockerignore", patterns); ignored {
+		patterns = append(patterns, "!.dockerignore")
+	}
+	if ignored, _ := patternmatcher.MatchesOrParentMatches(b.dockerfile, patterns); ignored {
+		patterns = append(patterns, "!"+b.dockerfile)
+	}
+
+	if b.excludes, err = patternmatcher.New(patterns); err != nil {
+		return nil, fmt.Errorf("invalid .dockerignore: %w", err)
+	}
+	return b, nil
+}
+
+// readDockerignore returns the patterns of the first ignore file that exists
+func readDockerignore(paths ...string) ([]string, error) {
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		patterns, err := ignorefile.ReadAll(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		return patterns, nil
+	}
+	return nil, nil
+}
+
+// write packs the context as a gzip compressed tar archive
+func (b *buildContext) write(w io.Writer) error {
+	gz, err := gzip.NewWriterLevel(w, gzip.BestSpeed)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(gz)
+
+	err = filepath.WalkDir(b.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(b.dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+
+		excluded, err := b.excludes.MatchesOrParentMatches(name)
+		if err != nil {
+			return err
+		}
+		if excluded {
+			if entry.IsDir() && !b.mayInclude(name) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		return addToTar(tw, path, name)
+	})
+	if err != nil {
+		return err
+	}
+
+	if b.outside != "" {
+		if err := addToTar(tw, b.outside, b.dockerfile); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// mayInclude reports whether an exception pattern could include something
+// under the excluded directory name, which must then still be walked
+func (b *buildContext) mayInclude(name string) bool {
+	if !b.excludes.Exclusions() {
+		return false
+	}
+	for _, pattern := range b.excludes.Patterns() {
+		if pattern.Exclusion() && strings.HasPrefix(filepath.ToSlash(pattern.String())+"/", name+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// addToTar adds the file at path to tw as name, owned by root like the
+// contexts docker build sends
+func addToTar(tw *tar.Writer, path, name string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+
+	var link string
+	if info.Mode()&os.ModeSymlink != 0 {
+		if link, err = os.Readlink(path); err != nil {
+			return err
+		}
+	}
+
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if info.IsDir() {
+		header.Name += "/"
+	}
+	header.Uid, header.Gid = 0, 0
+	header.Uname, header.Gname = "", ""
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(tw, f)
+	return err
+}
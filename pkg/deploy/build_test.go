@@ -0,0 +1,202 @@
+package deploy
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/scttfrdmn/syno-docker/pkg/synology/synologytest"
+)
+
+// contextNames packs b and returns the sorted names in the archive
+func contextNames(t *testing.T, b *buildContext) []string {
+	t.Helper()
+	var archive bytes.Buffer
+	if err := b.write(&archive); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	gz, err := gzip.NewReader(&archive)
+	if err != nil {
+		t.Fatalf("context is not gzip compressed: %v", err)
+	}
+	var names []string
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid archive: %v", err)
+		}
+		if header.Uid != 0 || header.Gid != 0 {
+			t.Errorf("Expected %s to be owned by root, got %d:%d", header.Name, header.Uid, header.Gid)
+		}
+		names = append(names, header.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestBuildContext(t *testing.T) {
+	tests := []struct {
+		name       string
+		files      map[string]string
+		dockerfile string
+		want       []string
+	}{
+		{
+			name: "dockerignore",
+			files: map[string]string{
+				"Dockerfile":              "FROM alpine",
+				".dockerignore":           "node_modules\n*.log\n!keep.log\nsecrets/\n",
+				"app.js":                  "",
+				"debug.log":               "",
+				"keep.log":                "",
+				"node_modules/x/index.js": "",
+				"secrets/key":             "",
+				"src/main.js":             "",
+			},
+			want: []string{".dockerignore", "Dockerfile", "app.js", "keep.log", "src/", "src/main.js"},
+		},
+		{
+			name: "dockerfile and dockerignore are never excluded",
+			files: map[string]string{
+				"Dockerfile":    "FROM alpine",
+				".dockerignore": "*\n",
+				"app.js":        "",
+			},
+			want: []string{".dockerignore", "Dockerfile"},
+		},
+		{
+			name: "exception inside excluded directory",
+			files: map[string]string{
+				"Dockerfile":       "FROM alpine",
+				".dockerignore":    "docs\n!docs/README.md\n",
+				"docs/README.md":   "",
+				"docs/internal.md": "",
+			},
+			want: []string{".dockerignore", "Dockerfile", "docs/README.md"},
+		},
+		{
+			name: "dockerfile specific ignore file",
+			files: map[string]string{
+				"build/app.Dockerfile":              "FROM alpine",
+				"build/app.Dockerfile.dockerignore": "tests\n",
+				".dockerignore":                     "app.js\n",
+				"app.js":                            "",
+				"tests/app_test.js":                 "",
+			},
+			dockerfile: "build/app.Dockerfile",
+			want:       []string{".dockerignore", "app.js", "build/", "build/app.Dockerfile", "build/app.Dockerfile.dockerignore"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTree(t, dir, tt.files)
+
+			dockerfile := tt.dockerfile
+			if dockerfile == "" {
+				dockerfile = "Dockerfile"
+			}
+			b, err := newBuildContext(dir, filepath.Join(dir, dockerfile))
+			if err != nil {
+				t.Fatalf("newBuildContext failed: %v", err)
+			}
+			if b.dockerfile != dockerfile {
+				t.Errorf("Expected Dockerfile %s in the context, got %s", dockerfile, b.dockerfile)
+			}
+
+			if got := contextNames(t, b); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Context contains %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildContextDockerfileOutside(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"app/app.js": "", "docker/Dockerfile": "FROM alpine"})
+
+	b, err := newBuildContext(filepath.Join(dir, "app"), filepath.Join(dir, "docker", "Dockerfile"))
+	if err != nil {
+		t.Fatalf("newBuildContext failed: %v", err)
+	}
+	if !strings.HasPrefix(b.dockerfile, ".dockerfile.") {
+		t.Fatalf("Expected a generated Dockerfile name, got %s", b.dockerfile)
+	}
+	if got := contextNames(t, b); strings.Join(got, ",") != b.dockerfile+",app.js" {
+		t.Errorf("Expected the Dockerfile added to the context, got %v", got)
+	}
+}
+
+func TestBuildImage(t *testing.T) {
+	docker := synologytest.NewFakeDocker()
+	server := synologytest.NewSSHServer(t, docker)
+	conn := connectTestServer(t, server)
+
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"Dockerfile":    "FROM alpine\nARG VERSION\nARG SYNO_DOCKER_TEST_COMMIT\nCOPY app.js /app/\n",
+		".dockerignore": "*.log\n",
+		"app.js":        "",
+	})
+
+	os.Setenv("SYNO_DOCKER_TEST_COMMIT", "abc123")
+	defer os.Unsetenv("SYNO_DOCKER_TEST_COMMIT")
+
+	var output bytes.Buffer
+	err := BuildImage(conn, &BuildOptions{
+		Context:   dir,
+		Tags:      []string{"my-app:1.0"},
+		BuildArgs: []string{"VERSION=2", "SYNO_DOCKER_TEST_COMMIT", "SYNO_DOCKER_TEST_UNSET"},
+		Output:    &output,
+	})
+	if err != nil {
+		t.Fatalf("BuildImage failed: %v\n%s", err, output.String())
+	}
+
+	for _, want := range []string{"Step 4/4 : COPY app.js /app/", "Successfully tagged my-app:1.0"} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("Expected %q in the build output, got:\n%s", want, output.String())
+		}
+	}
+	if strings.Contains(output.String(), "[Warning]") {
+		t.Errorf("Expected unset build args to be left out, got:\n%s", output.String())
+	}
+	commands := server.Commands()
+	if build := commands[len(commands)-1]; !strings.Contains(build, "--build-arg 'SYNO_DOCKER_TEST_COMMIT=abc123'") {
+		t.Errorf("Expected the build arg value from the local environment, got %q", build)
+	}
+	if len(docker.Images()) != 1 || docker.Images()[0].Ref() != "my-app:1.0" {
+		t.Errorf("Expected my-app:1.0 on the NAS, got %+v", docker.Images())
+	}
+
+	// A source excluded by .dockerignore is missing on the NAS
+	writeTree(t, dir, map[string]string{".dockerignore": "app.js\n"})
+	output.Reset()
+	err = BuildImage(conn, &BuildOptions{Context: dir, Output: &output})
+	if err == nil || !strings.Contains(output.String(), "COPY failed") {
+		t.Errorf("Expected the build to fail without app.js, got %v:\n%s", err, output.String())
+	}
+}
+
+func TestBuildImageMissingDockerfile(t *testing.T) {
+	fake := synologytest.New()
+	err := BuildImage(fake, &BuildOptions{Context: t.TempDir()})
+	if err == nil || !strings.Contains(err.Error(), "cannot find Dockerfile") {
+		t.Errorf("Expected missing Dockerfile error, got %v", err)
+	}
+	if len(fake.Calls()) != 0 {
+		t.Errorf("Expected nothing run on the NAS, got:\n%s", fake)
+	}
+}
@@ -1,9 +1,12 @@
 package deploy
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -42,10 +45,32 @@ type ComposeOptions struct {
 	ComposeFile string
 	ProjectName string
 	EnvFile     string
+	// ProjectDir is the folder on the NAS the compose file is uploaded to,
+	// as Container Manager keeps each project in its own folder under the
+	// shared docker folder. It defaults to <DefaultVolume>/<ProjectName>;
+	// an existing project keeps its folder.
+	ProjectDir string
+	// Adopt replaces the containers of an existing project that
+	// syno-docker did not deploy, such as one created in Container Manager
+	Adopt bool
+	// Network is the network of the containers; synology.DefaultNetwork
+	// when empty
+	Network string
+	// Labels are added to every container; a service's own labels take
+	// precedence
+	Labels map[string]string
 }
 
 // Compose deploys a docker-compose file to the Synology NAS
-func Compose(conn *synology.Connection, opts *ComposeOptions) error {
+func Compose(conn synology.Executor, opts *ComposeOptions) error {
+	return ComposeContext(context.Background(), conn, opts)
+}
+
+// ComposeContext is like Compose but honors ctx. The compose file is
+// uploaded to the project folder and the containers get the compose labels,
+// so Container Manager shows them as a project. The containers of an
+// earlier deployment of the project are replaced.
+func ComposeContext(ctx context.Context, conn synology.Executor, opts *ComposeOptions) error {
 	// Read and parse compose file
 	composeData, err := parseComposeFile(opts.ComposeFile)
 	if err != nil {
@@ -61,24 +86,107 @@ func Compose(conn *synology.Connection, opts *ComposeOptions) error {
 		}
 	}
 
-	// Deploy each service as a container
+	// Find an earlier deployment of the project
+	existing, err := FindProjectContext(ctx, conn, opts.ProjectName)
+	if err != nil {
+		return err
+	}
+	projectDir := opts.ProjectDir
+	if projectDir == "" {
+		projectDir = path.Join(synology.DefaultVolume, opts.ProjectName)
+	}
+	configFile := path.Join(projectDir, ProjectFile)
+	if existing != nil {
+		if !existing.Managed && !opts.Adopt {
+			return fmt.Errorf("project %s already exists on the NAS and was not deployed by syno-docker (containers: %s); use --adopt to replace its containers",
+				opts.ProjectName, strings.Join(existing.Containers, ", "))
+		}
+		if existing.Dir != "" {
+			projectDir, configFile = existing.Dir, path.Join(existing.Dir, ProjectFile)
+			if existing.ConfigFile != "" && path.Dir(existing.ConfigFile) == existing.Dir {
+				configFile = existing.ConfigFile
+			}
+		}
+	}
+
 	fmt.Printf("Deploying compose project: %s\n", opts.ProjectName)
+	composeDir := filepath.Dir(opts.ComposeFile)
 
-	for serviceName, service := range composeData.Services {
-		containerName := fmt.Sprintf("%s_%s_1", opts.ProjectName, serviceName)
+	// Convert every service, building images first, before touching the
+	// running project
+	serviceNames := make([]string, 0, len(composeData.Services))
+	for serviceName := range composeData.Services {
+		serviceNames = append(serviceNames, serviceName)
+	}
+	sort.Strings(serviceNames)
 
-		fmt.Printf("Deploying service: %s (container: %s)\n", serviceName, containerName)
+	containers := make([]*ContainerOptions, 0, len(serviceNames))
+	for _, serviceName := range serviceNames {
+		service := composeData.Services[serviceName]
+		containerName := fmt.Sprintf("%s_%s_1", opts.ProjectName, serviceName)
 
 		// Convert compose service to container options
 		containerOpts, err := convertServiceToContainer(service, containerName, envVars)
 		if err != nil {
 			return errors.Wrapf(err, "failed to convert service %s to container options", serviceName)
 		}
+		containerOpts.Volumes = resolveProjectVolumes(containerOpts.Volumes, projectDir)
+		if opts.Network != "" {
+			containerOpts.NetworkMode = opts.Network
+		}
+		containerOpts.Labels = projectLabels(opts.ProjectName, serviceName, projectDir, configFile, mergeLabels(opts.Labels, service.Labels))
 
-		// Deploy container
-		_, err = Container(conn, containerOpts)
+		// Build the image on the NAS for services with a build section
+		if service.Build != nil {
+			buildOpts, err := convertServiceToBuild(service, serviceName, opts.ProjectName, composeDir, envVars)
+			if err != nil {
+				return errors.Wrapf(err, "failed to convert build section of service %s", serviceName)
+			}
+
+			fmt.Printf("Building image %s for service %s...\n", buildOpts.Tags[0], serviceName)
+			if err := BuildImageContext(ctx, conn, buildOpts); err != nil {
+				return errors.Wrapf(err, "failed to build service %s", serviceName)
+			}
+			containerOpts.Image = buildOpts.Tags[0]
+			containerOpts.SkipPull = true
+		}
+		containers = append(containers, containerOpts)
+	}
+
+	// Place the compose file, and the env file as .env, in the project folder
+	fmt.Printf("Uploading compose file to %s...\n", configFile)
+	data, err := os.ReadFile(opts.ComposeFile)
+	if err != nil {
+		return fmt.Errorf("failed to read compose file: %w", err)
+	}
+	if err := uploadProjectFile(ctx, conn, configFile, data); err != nil {
+		return err
+	}
+	if opts.EnvFile != "" {
+		data, err = os.ReadFile(opts.EnvFile)
 		if err != nil {
-			return errors.Wrapf(err, "failed to deploy service %s", serviceName)
+			return fmt.Errorf("failed to read env file: %w", err)
+		}
+		if err := uploadProjectFile(ctx, conn, path.Join(projectDir, ".env"), data); err != nil {
+			return err
+		}
+	}
+
+	// Replace the containers of the earlier deployment
+	if existing != nil {
+		for _, name := range existing.Containers {
+			fmt.Printf("Removing container %s...\n", name)
+			if _, err := conn.ExecuteDockerCommandContext(ctx, []string{"rm", "-f", name}); err != nil {
+				return errors.Wrapf(err, "failed to remove container %s", name)
+			}
+		}
+	}
+
+	// Deploy each service as a container
+	for i, containerOpts := range containers {
+		fmt.Printf("Deploying service: %s (container: %s)\n", serviceNames[i], containerOpts.Name)
+		if _, err := ContainerContext(ctx, conn, containerOpts); err != nil {
+			return errors.Wrapf(err, "failed to deploy service %s", serviceNames[i])
 		}
 	}
 
@@ -176,6 +284,100 @@ func convertServiceToContainer(service ComposeService, containerName string, env
 	return opts, nil
 }
 
+// convertServiceToBuild converts a service's build section, either a context
+// path or a mapping with context, dockerfile, args and target, to build
+// options. Paths are relative to the compose file's directory. The image is
+// tagged with the service's image, or <project>_<service> when it has none.
+func convertServiceToBuild(service ComposeService, serviceName, projectName, composeDir string, envVars map[string]string) (*BuildOptions, error) {
+	opts := &BuildOptions{}
+	var dockerfile string
+
+	switch b := service.Build.(type) {
+	case string:
+		opts.Context = b
+	case map[string]interface{}:
+		opts.Context, _ = b["context"].(string)
+		dockerfile, _ = b["dockerfile"].(string)
+		opts.Target, _ = b["target"].(string)
+
+		args, err := processBuildArgs(b["args"], envVars)
+		if err != nil {
+			return nil, err
+		}
+		opts.BuildArgs = args
+	default:
+		return nil, fmt.Errorf("unsupported build format: %T", service.Build)
+	}
+
+	if opts.Context == "" {
+		opts.Context = "."
+	}
+	if strings.Contains(opts.Context, "://") || strings.HasPrefix(opts.Context, "git@") {
+		return nil, fmt.Errorf("remote build context %s is not supported", opts.Context)
+	}
+	if !filepath.IsAbs(opts.Context) {
+		opts.Context = filepath.Join(composeDir, opts.Context)
+	}
+	if dockerfile != "" {
+		if !filepath.IsAbs(dockerfile) {
+			dockerfile = filepath.Join(opts.Context, dockerfile)
+		}
+		opts.Dockerfile = dockerfile
+	}
+
+	tag := service.Image
+	if tag == "" {
+		tag = strings.ToLower(projectName + "_" + serviceName)
+	}
+	opts.Tags = []string{tag}
+
+	return opts, nil
+}
+
+// processBuildArgs converts build args, in list or mapping form, to KEY=VALUE
+// pairs. Args without a value take it from envVars, or are left bare for
+// BuildImage to look up in the local environment.
+func processBuildArgs(args interface{}, envVars map[string]string) ([]string, error) {
+	withValue := func(key string) string {
+		if value, ok := envVars[key]; ok {
+			return key + "=" + value
+		}
+		return key
+	}
+
+	var result []string
+	switch a := args.(type) {
+	case []interface{}:
+		for _, item := range a {
+			str, ok := item.(string)
+			if !ok {
+				continue
+			}
+			if strings.Contains(str, "=") {
+				result = append(result, expandEnvVar(str, envVars))
+			} else {
+				result = append(result, withValue(str))
+			}
+		}
+	case map[string]interface{}:
+		for key, value := range a {
+			if value == nil {
+				result = append(result, withValue(key))
+			} else {
+				result = append(result, fmt.Sprintf("%s=%s", key, expandEnvVar(fmt.Sprint(value), envVars)))
+			}
+		}
+		// Mappings have no order; keep the command line stable
+		sort.Strings(result)
+	case nil:
+		return result, nil
+	default:
+		return nil, fmt.Errorf("unsupported build args format: %T", args)
+	}
+
+	return result, nil
+}
+
 func processEnvironment(env interface{}, envVars map[string]string) ([]string, error) {
 	var result []string
 
@@ -207,8 +409,8 @@ func processEnvironment(env interface{}, envVars map[string]string) ([]string, e
 func processCommand(cmd interface{}) ([]string, error) {
 	switch c := cmd.(type) {
 	case string:
-		// Single command string
-		return []string{c}, nil
+		// Single command string, split into words like docker compose does
+		return synology.ShellSplit(c)
 	case []interface{}:
 		// Array of command parts
 		var result []string
@@ -3,7 +3,10 @@ package deploy
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/scttfrdmn/syno-docker/pkg/synology/synologytest"
 )
 
 func TestParseComposeFile(t *testing.T) {
@@ -212,7 +215,13 @@ func TestProcessCommand(t *testing.T) {
 		{
 			name:     "string command",
 			cmd:      "npm start",
-			expected: []string{"npm start"},
+			expected: []string{"npm", "start"},
+			hasError: false,
+		},
+		{
+			name:     "quoted string command",
+			cmd:      `sh -c "echo hello world"`,
+			expected: []string{"sh", "-c", "echo hello world"},
 			hasError: false,
 		},
 		{
@@ -312,3 +321,117 @@ func TestGenerateProjectName(t *testing.T) {
 		})
 	}
 }
+
+func TestConvertServiceToBuild(t *testing.T) {
+	envVars := map[string]string{"VERSION": "2.0"}
+
+	tests := []struct {
+		name           string
+		service        ComposeService
+		wantContext    string
+		wantDockerfile string
+		wantArgs       []string
+		wantTag        string
+		wantErr        bool
+	}{
+		{
+			name:        "context path",
+			service:     ComposeService{Build: "./web"},
+			wantContext: "/project/web",
+			wantTag:     "myproject_web",
+		},
+		{
+			name: "mapping with args",
+			service: ComposeService{
+				Image: "registry.local/web:${VERSION}",
+				Build: map[string]interface{}{
+					"context":    "web",
+					"dockerfile": "docker/Dockerfile.prod",
+					"target":     "production",
+					"args":       map[string]interface{}{"VERSION": nil, "DEBUG": false, "NAME": "web-${VERSION}"},
+				},
+			},
+			wantContext:    "/project/web",
+			wantDockerfile: "/project/web/docker/Dockerfile.prod",
+			wantArgs:       []string{"DEBUG=false", "NAME=web-2.0", "VERSION=2.0"},
+			wantTag:        "registry.local/web:${VERSION}",
+		},
+		{
+			name: "list args",
+			service: ComposeService{
+				Build: map[string]interface{}{"args": []interface{}{"VERSION", "GIT_COMMIT", "MODE=$VERSION"}},
+			},
+			wantContext: "/project",
+			wantArgs:    []string{"VERSION=2.0", "GIT_COMMIT", "MODE=2.0"},
+			wantTag:     "myproject_web",
+		},
+		{
+			name:    "remote context",
+			service: ComposeService{Build: "https://github.com/example/web.git"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid format",
+			service: ComposeService{Build: 42},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := convertServiceToBuild(tt.service, "web", "myproject", "/project", envVars)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %+v", opts)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if opts.Context != filepath.FromSlash(tt.wantContext) {
+				t.Errorf("Expected context %s, got %s", tt.wantContext, opts.Context)
+			}
+			if opts.Dockerfile != filepath.FromSlash(tt.wantDockerfile) {
+				t.Errorf("Expected Dockerfile %s, got %s", tt.wantDockerfile, opts.Dockerfile)
+			}
+			if strings.Join(opts.BuildArgs, ",") != strings.Join(tt.wantArgs, ",") {
+				t.Errorf("Expected build args %v, got %v", tt.wantArgs, opts.BuildArgs)
+			}
+			if len(opts.Tags) != 1 || opts.Tags[0] != tt.wantTag {
+				t.Errorf("Expected tag %s, got %v", tt.wantTag, opts.Tags)
+			}
+		})
+	}
+}
+
+func TestComposeBuild(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"docker-compose.yml": "services:\n  web:\n    build: ./web\n    ports:\n      - \"8080:80\"\n",
+		"web/Dockerfile":     "FROM nginx:alpine\n",
+	})
+
+	fake := synologytest.New()
+	fake.OnDocker("ps").Return("")
+	fake.On("mkdir").Return("")
+	fake.OnDocker("build").Return("Successfully tagged myproject_web:latest\n")
+	fake.OnDocker("run").Return("abc123\n")
+
+	err := Compose(fake, &ComposeOptions{ComposeFile: filepath.Join(dir, "docker-compose.yml"), ProjectName: "myproject"})
+	if err != nil {
+		t.Fatalf("Compose failed: %v\n%s", err, fake)
+	}
+
+	calls := fake.DockerCalls()
+	if len(calls) != 3 {
+		t.Fatalf("Expected a project lookup, a build and a run without a pull, got:\n%s", fake)
+	}
+	if strings.Join(calls[1], " ") != "build -f Dockerfile -t myproject_web -" {
+		t.Errorf("Unexpected build command %q", calls[1])
+	}
+	if run := calls[2]; run[0] != "run" || run[len(run)-1] != "myproject_web" {
+		t.Errorf("Expected the built image to be run, got %q", run)
+	}
+}
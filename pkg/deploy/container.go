@@ -1,10 +1,15 @@
 package deploy
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"os"
 	"strings"
 
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/client"
 	"github.com/pkg/errors"
 
@@ -23,6 +28,10 @@ type ContainerOptions struct {
 	WorkingDir  string
 	Command     []string
 	User        string
+	Labels      []string // ["KEY=value"]
+	// SkipPull uses the image already on the NAS, such as one just built,
+	// instead of pulling it first
+	SkipPull bool
 }
 
 // ContainerInfo represents container information
@@ -43,8 +52,15 @@ func NewContainerOptions(image string) *ContainerOptions {
 	}
 }
 
-// Container deploys a container using direct Docker commands over SSH
-func Container(conn *synology.Connection, opts *ContainerOptions) (string, error) {
+// Container deploys a container, through the Docker Engine API when the
+// socket is accessible and the docker CLI otherwise. The image is pulled
+// with the CLI, which has the NAS's registry credentials.
+func Container(conn synology.Executor, opts *ContainerOptions) (string, error) {
+	return ContainerContext(context.Background(), conn, opts)
+}
+
+// ContainerContext is like Container but honors ctx
+func ContainerContext(ctx context.Context, conn synology.Executor, opts *ContainerOptions) (string, error) {
 	if opts.Name == "" {
 		opts.Name = generateContainerName(opts.Image)
 	}
@@ -90,6 +106,11 @@ func Container(conn *synology.Connection, opts *ContainerOptions) (string, error
 		dockerArgs = append(dockerArgs, "-w", opts.WorkingDir)
 	}
 
+	// Add labels
+	for _, label := range opts.Labels {
+		dockerArgs = append(dockerArgs, "--label", label)
+	}
+
 	// Add image
 	dockerArgs = append(dockerArgs, opts.Image)
 
@@ -97,16 +118,26 @@ func Container(conn *synology.Connection, opts *ContainerOptions) (string, error
 	dockerArgs = append(dockerArgs, opts.Command...)
 
 	// Pull image first
-	fmt.Printf("Pulling image %s...\n", opts.Image)
-	if _, err := conn.ExecuteDockerCommand([]string{"pull", opts.Image}); err != nil {
-		return "", errors.Wrap(err, "failed to pull image")
+	if !opts.SkipPull {
+		fmt.Printf("Pulling image %s...\n", opts.Image)
+		if _, err := conn.ExecuteDockerCommandContext(ctx, []string{"pull", opts.Image}); err != nil {
+			return "", errors.Wrap(err, "failed to pull image")
+		}
 	}
 
 	// Run container
 	fmt.Printf("Creating and starting container %s...\n", opts.Name)
-	output, err := conn.ExecuteDockerCommand(dockerArgs)
+	if cli := dockerClient(conn); cli != nil {
+		containerID, err := runContainerAPI(ctx, cli, opts)
+		if err != nil {
+			return "", errors.Wrap(synology.ClassifyError(err), "failed to run container")
+		}
+		return containerID, nil
+	}
+
+	output, err := conn.ExecuteDockerCommandContext(ctx, dockerArgs)
 	if err != nil {
-		return "", errors.Wrapf(err, "failed to run container: %s", output)
+		return "", errors.Wrap(err, "failed to run container")
 	}
 
 	containerID := strings.TrimSpace(output)
@@ -114,13 +145,39 @@ func Container(conn *synology.Connection, opts *ContainerOptions) (string, error
 }
 
 // ListContainers lists containers using direct Docker commands
-func ListContainers(conn *synology.Connection, all bool) ([]ContainerInfo, error) {
-	args := []string{"ps", "--format", "'table {{.ID}}\t{{.Names}}\t{{.Image}}\t{{.Status}}\t{{.Ports}}'"}
+func ListContainers(conn synology.Executor, all bool) ([]ContainerInfo, error) {
+	return ListContainersContext(context.Background(), conn, all)
+}
+
+// ListContainersContext is like ListContainers but honors ctx
+func ListContainersContext(ctx context.Context, conn synology.Executor, all bool) ([]ContainerInfo, error) {
+	return retry(ctx, conn, func() ([]ContainerInfo, error) {
+		return listContainers(ctx, conn, all)
+	})
+}
+
+func listContainers(ctx context.Context, conn synology.Executor, all bool) ([]ContainerInfo, error) {
+	if cli := dockerClient(conn); cli != nil {
+		containers, err := listContainersAPI(ctx, cli, all)
+		if err != nil {
+			return nil, errors.Wrap(synology.ClassifyError(err), "failed to list containers")
+		}
+		return containers, nil
+	}
+	if client := dsmClient(conn); client != nil {
+		containers, err := listContainersDSM(ctx, client, all)
+		if err != nil {
+			return nil, errors.Wrap(synology.ClassifyError(err), "failed to list containers")
+		}
+		return containers, nil
+	}
+
+	args := []string{"ps", "--format", "table {{.ID}}\t{{.Names}}\t{{.Image}}\t{{.Status}}\t{{.Ports}}"}
 	if all {
 		args = append(args, "-a")
 	}
 
-	output, err := conn.ExecuteDockerCommand(args)
+	output, err := conn.ExecuteDockerCommandContext(ctx, args)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to list containers")
 	}
@@ -151,7 +208,12 @@ func ListContainers(conn *synology.Connection, all bool) ([]ContainerInfo, error
 }
 
 // RemoveContainer removes a container using direct Docker commands
-func RemoveContainer(conn *synology.Connection, nameOrID string, force bool) error {
+func RemoveContainer(conn synology.Executor, nameOrID string, force bool) error {
+	return RemoveContainerContext(context.Background(), conn, nameOrID, force)
+}
+
+// RemoveContainerContext is like RemoveContainer but honors ctx
+func RemoveContainerContext(ctx context.Context, conn synology.Executor, nameOrID string, force bool) error {
 	args := []string{"rm"}
 	if force {
 		args = append(args, "-f")
@@ -159,9 +221,16 @@ func RemoveContainer(conn *synology.Connection, nameOrID string, force bool) err
 	args = append(args, nameOrID)
 
 	fmt.Printf("Removing container %s...\n", nameOrID)
-	output, err := conn.ExecuteDockerCommand(args)
+	if cli := dockerClient(conn); cli != nil {
+		if err := cli.ContainerRemove(ctx, nameOrID, container.RemoveOptions{Force: force}); err != nil {
+			return errors.Wrap(synology.ClassifyError(err), "failed to remove container")
+		}
+		return nil
+	}
+
+	_, err := conn.ExecuteDockerCommandContext(ctx, args)
 	if err != nil {
-		return errors.Wrapf(err, "failed to remove container: %s", output)
+		return errors.Wrap(err, "failed to remove container")
 	}
 
 	return nil
@@ -180,17 +249,37 @@ func generateContainerName(image string) string {
 	return name
 }
 
-// GetDockerClient returns a placeholder - not used in simple implementation
-func GetDockerClient() (*client.Client, error) {
-	// This is a placeholder for compatibility
-	// The simple implementation uses SSH commands directly
-	return nil, fmt.Errorf("docker client not needed in simple implementation")
+// GetDockerClient returns the Docker Engine API client tunneled over the connection
+func GetDockerClient(conn synology.Executor) (*client.Client, error) {
+	cli := dockerClient(conn)
+	if cli == nil {
+		return nil, fmt.Errorf("docker socket %s is not accessible, using docker CLI instead", synology.SocketPath)
+	}
+	return cli, nil
 }
 
 // TestDockerConnection tests Docker availability over SSH
-func TestDockerConnection(conn *synology.Connection) error {
+func TestDockerConnection(conn synology.Executor) error {
+	return TestDockerConnectionContext(context.Background(), conn)
+}
+
+// TestDockerConnectionContext is like TestDockerConnection but honors ctx
+func TestDockerConnectionContext(ctx context.Context, conn synology.Executor) error {
+	if cli := dockerClient(conn); cli != nil {
+		if _, err := cli.ServerVersion(ctx); err != nil {
+			return fmt.Errorf("docker connection test failed: %w", synology.ClassifyError(err))
+		}
+		return nil
+	}
+	if client := dsmClient(conn); client != nil {
+		if _, err := client.ListContainers(ctx); err != nil {
+			return fmt.Errorf("docker connection test failed: %w", synology.ClassifyError(err))
+		}
+		return nil
+	}
+
 	// Test Docker command
-	if _, err := conn.ExecuteDockerCommand([]string{"version", "--format", "'{{.Server.Version}}'"}); err != nil {
+	if _, err := conn.ExecuteDockerCommandContext(ctx, []string{"version", "--format", "{{.Server.Version}}"}); err != nil {
 		return fmt.Errorf("docker connection test failed: %w", err)
 	}
 
@@ -207,7 +296,26 @@ type ExecOptions struct {
 }
 
 // GetContainerLogs retrieves container logs
-func GetContainerLogs(conn *synology.Connection, nameOrID, tail, since string, timestamps bool) (string, error) {
+func GetContainerLogs(conn synology.Executor, nameOrID, tail, since string, timestamps bool) (string, error) {
+	return GetContainerLogsContext(context.Background(), conn, nameOrID, tail, since, timestamps)
+}
+
+// GetContainerLogsContext is like GetContainerLogs but honors ctx
+func GetContainerLogsContext(ctx context.Context, conn synology.Executor, nameOrID, tail, since string, timestamps bool) (string, error) {
+	return retry(ctx, conn, func() (string, error) {
+		return getContainerLogs(ctx, conn, nameOrID, tail, since, timestamps)
+	})
+}
+
+func getContainerLogs(ctx context.Context, conn synology.Executor, nameOrID, tail, since string, timestamps bool) (string, error) {
+	if client := dsmClient(conn); client != nil {
+		output, err := containerLogsDSM(ctx, client, nameOrID, tail, since, timestamps)
+		if err != nil {
+			return "", errors.Wrapf(synology.ClassifyError(err), "failed to get logs for container %s", nameOrID)
+		}
+		return output, nil
+	}
+
 	args := []string{"logs"}
 
 	if tail != "all" && tail != "" {
@@ -222,7 +330,8 @@ func GetContainerLogs(conn *synology.Connection, nameOrID, tail, since string, t
 
 	args = append(args, nameOrID)
 
-	output, err := conn.ExecuteDockerCommand(args)
+	// docker logs replays the container's stderr on stderr, so keep both
+	output, err := combinedDockerOutput(ctx, conn, args)
 	if err != nil {
 		return "", errors.Wrapf(err, "failed to get logs for container %s", nameOrID)
 	}
@@ -231,27 +340,35 @@ func GetContainerLogs(conn *synology.Connection, nameOrID, tail, since string, t
 }
 
 // FollowContainerLogs follows container logs in real-time
-func FollowContainerLogs(conn *synology.Connection, nameOrID, tail, since string, timestamps bool, stdout, stderr io.Writer) error {
-	args := []string{"logs", "--follow"}
+func FollowContainerLogs(conn synology.Executor, nameOrID, tail, since string, timestamps bool, stdout, stderr io.Writer) error {
+	return FollowContainerLogsContext(context.Background(), conn, nameOrID, tail, since, timestamps, stdout, stderr)
+}
 
-	if tail != "all" && tail != "" {
-		args = append(args, "--tail", tail)
-	}
-	if since != "" {
-		args = append(args, "--since", since)
-	}
-	if timestamps {
-		args = append(args, "--timestamps")
-	}
+// FollowContainerLogsContext is like FollowContainerLogs but honors ctx. If
+// the connection drops, it reconnects and resumes after the last line seen.
+func FollowContainerLogsContext(ctx context.Context, conn synology.Executor, nameOrID, tail, since string, timestamps bool, stdout, stderr io.Writer) error {
+	cursor := &logCursor{}
+	out := newTimestampWriter(stdout, cursor, timestamps)
+	errOut := newTimestampWriter(stderr, cursor, timestamps)
+	defer out.Flush()
+	defer errOut.Flush()
 
-	args = append(args, nameOrID)
+	return retryOnDisconnect(ctx, conn, func() error {
+		// A line cut off by the disconnect is sent again in full after resuming
+		out.Reset()
+		errOut.Reset()
+		return conn.StreamCommandContext(ctx, synology.DockerCommand(followLogsArgs(nameOrID, tail, since, cursor)), out, errOut)
+	})
+}
 
-	cmd := strings.Join(append([]string{"/usr/local/bin/docker"}, args...), " ")
-	return conn.StreamCommand(cmd, stdout, stderr)
+// ExecCommand executes a command in a container and returns its output. If the
+// command fails, the error wraps a *synology.RemoteCommandError with its exit status.
+func ExecCommand(conn synology.Executor, nameOrID string, command []string, opts *ExecOptions) (string, error) {
+	return ExecCommandContext(context.Background(), conn, nameOrID, command, opts)
 }
 
-// ExecCommand executes a command in a container and returns output
-func ExecCommand(conn *synology.Connection, nameOrID string, command []string, opts *ExecOptions) (string, error) {
+// ExecCommandContext is like ExecCommand but honors ctx
+func ExecCommandContext(ctx context.Context, conn synology.Executor, nameOrID string, command []string, opts *ExecOptions) (string, error) {
 	args := []string{"exec"}
 
 	if opts.User != "" {
@@ -267,16 +384,31 @@ func ExecCommand(conn *synology.Connection, nameOrID string, command []string, o
 	args = append(args, nameOrID)
 	args = append(args, command...)
 
-	output, err := conn.ExecuteDockerCommand(args)
+	output, err := combinedDockerOutput(ctx, conn, args)
 	if err != nil {
-		return "", errors.Wrapf(err, "failed to execute command in container %s", nameOrID)
+		// Keep what the command printed before it failed
+		return output, errors.Wrapf(err, "failed to execute command in container %s", nameOrID)
 	}
 
 	return output, nil
 }
 
-// ExecInteractive executes an interactive command in a container
-func ExecInteractive(conn *synology.Connection, nameOrID string, command []string, opts *ExecOptions) error {
+// combinedDockerOutput runs a docker command and returns stdout and stderr
+// interleaved, as a terminal would show them
+func combinedDockerOutput(ctx context.Context, conn synology.Executor, args []string) (string, error) {
+	var output bytes.Buffer
+	err := conn.StreamCommandContext(ctx, synology.DockerCommand(args), &output, &output)
+	return output.String(), err
+}
+
+// ExecInteractive executes a command in a container with the local terminal
+// attached, allocating a pseudo-terminal when opts.TTY is set
+func ExecInteractive(conn synology.Executor, nameOrID string, command []string, opts *ExecOptions) error {
+	return ExecInteractiveContext(context.Background(), conn, nameOrID, command, opts)
+}
+
+// ExecInteractiveContext is like ExecInteractive but honors ctx
+func ExecInteractiveContext(ctx context.Context, conn synology.Executor, nameOrID string, command []string, opts *ExecOptions) error {
 	args := []string{"exec"}
 
 	if opts.Interactive {
@@ -298,54 +430,181 @@ func ExecInteractive(conn *synology.Connection, nameOrID string, command []strin
 	args = append(args, nameOrID)
 	args = append(args, command...)
 
-	cmd := strings.Join(append([]string{"/usr/local/bin/docker"}, args...), " ")
-	return conn.StreamCommand(cmd, nil, nil)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sessionOpts, restore, err := interactiveSession(ctx, opts.TTY, opts.Interactive)
+	if err != nil {
+		return errors.Wrap(err, "failed to prepare terminal")
+	}
+	defer restore()
+
+	return conn.RunSessionContext(ctx, synology.DockerCommand(args), sessionOpts)
+}
+
+// AttachOptions defines options for attaching to a running container
+type AttachOptions struct {
+	NoStdin    bool
+	DetachKeys string
+}
+
+// AttachContainer attaches the local terminal to a running container's main process
+func AttachContainer(conn synology.Executor, nameOrID string, opts *AttachOptions) error {
+	return AttachContainerContext(context.Background(), conn, nameOrID, opts)
+}
+
+// AttachContainerContext is like AttachContainer but honors ctx
+func AttachContainerContext(ctx context.Context, conn synology.Executor, nameOrID string, opts *AttachOptions) error {
+	// A pseudo-terminal is only wanted if the container was started with one
+	tty, err := containerHasTTY(ctx, conn, nameOrID)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"attach"}
+	if opts.NoStdin {
+		args = append(args, "--no-stdin")
+	}
+	if opts.DetachKeys != "" {
+		args = append(args, "--detach-keys", opts.DetachKeys)
+	}
+	args = append(args, nameOrID)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sessionOpts, restore, err := interactiveSession(ctx, tty, !opts.NoStdin)
+	if err != nil {
+		return errors.Wrap(err, "failed to prepare terminal")
+	}
+	defer restore()
+
+	return conn.RunSessionContext(ctx, synology.DockerCommand(args), sessionOpts)
+}
+
+// containerHasTTY reports whether a container was created with a TTY
+func containerHasTTY(ctx context.Context, conn synology.Executor, nameOrID string) (bool, error) {
+	if cli := dockerClient(conn); cli != nil {
+		info, err := cli.ContainerInspect(ctx, nameOrID)
+		if err != nil {
+			return false, errors.Wrapf(synology.ClassifyError(err), "failed to inspect container %s", nameOrID)
+		}
+		return info.Config != nil && info.Config.Tty, nil
+	}
+
+	output, err := conn.ExecuteDockerCommandContext(ctx, []string{"container", "inspect", "--format", "{{.Config.Tty}}", nameOrID})
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to inspect container %s", nameOrID)
+	}
+	return strings.TrimSpace(output) == "true", nil
 }
 
 // RestartContainer restarts a container
-func RestartContainer(conn *synology.Connection, nameOrID string, timeout int) error {
+func RestartContainer(conn synology.Executor, nameOrID string, timeout int) error {
+	return RestartContainerContext(context.Background(), conn, nameOrID, timeout)
+}
+
+// RestartContainerContext is like RestartContainer but honors ctx
+func RestartContainerContext(ctx context.Context, conn synology.Executor, nameOrID string, timeout int) error {
+	if cli := dockerClient(conn); cli != nil {
+		if err := cli.ContainerRestart(ctx, nameOrID, stopOptions(timeout)); err != nil {
+			return errors.Wrapf(synology.ClassifyError(err), "failed to restart container %s", nameOrID)
+		}
+		return nil
+	}
+	if client := dsmClient(conn); client != nil {
+		if err := manageContainerDSM(ctx, client, nameOrID, client.RestartContainer); err != nil {
+			return errors.Wrapf(synology.ClassifyError(err), "failed to restart container %s", nameOrID)
+		}
+		return nil
+	}
+
 	args := []string{"restart"}
 	if timeout > 0 {
 		args = append(args, "--time", fmt.Sprintf("%d", timeout))
 	}
 	args = append(args, nameOrID)
 
-	output, err := conn.ExecuteDockerCommand(args)
+	_, err := conn.ExecuteDockerCommandContext(ctx, args)
 	if err != nil {
-		return errors.Wrapf(err, "failed to restart container %s: %s", nameOrID, output)
+		return errors.Wrapf(err, "failed to restart container %s", nameOrID)
 	}
 
 	return nil
 }
 
 // StartContainer starts a stopped container
-func StartContainer(conn *synology.Connection, nameOrID string) error {
+func StartContainer(conn synology.Executor, nameOrID string) error {
+	return StartContainerContext(context.Background(), conn, nameOrID)
+}
+
+// StartContainerContext is like StartContainer but honors ctx
+func StartContainerContext(ctx context.Context, conn synology.Executor, nameOrID string) error {
+	if cli := dockerClient(conn); cli != nil {
+		if err := cli.ContainerStart(ctx, nameOrID, container.StartOptions{}); err != nil {
+			return errors.Wrapf(synology.ClassifyError(err), "failed to start container %s", nameOrID)
+		}
+		return nil
+	}
+	if client := dsmClient(conn); client != nil {
+		if err := manageContainerDSM(ctx, client, nameOrID, client.StartContainer); err != nil {
+			return errors.Wrapf(synology.ClassifyError(err), "failed to start container %s", nameOrID)
+		}
+		return nil
+	}
+
 	args := []string{"start", nameOrID}
 
-	output, err := conn.ExecuteDockerCommand(args)
+	_, err := conn.ExecuteDockerCommandContext(ctx, args)
 	if err != nil {
-		return errors.Wrapf(err, "failed to start container %s: %s", nameOrID, output)
+		return errors.Wrapf(err, "failed to start container %s", nameOrID)
 	}
 
 	return nil
 }
 
 // StopContainer stops a running container
-func StopContainer(conn *synology.Connection, nameOrID string, timeout int) error {
+func StopContainer(conn synology.Executor, nameOrID string, timeout int) error {
+	return StopContainerContext(context.Background(), conn, nameOrID, timeout)
+}
+
+// StopContainerContext is like StopContainer but honors ctx
+func StopContainerContext(ctx context.Context, conn synology.Executor, nameOrID string, timeout int) error {
+	if cli := dockerClient(conn); cli != nil {
+		if err := cli.ContainerStop(ctx, nameOrID, stopOptions(timeout)); err != nil {
+			return errors.Wrapf(synology.ClassifyError(err), "failed to stop container %s", nameOrID)
+		}
+		return nil
+	}
+	if client := dsmClient(conn); client != nil {
+		if err := manageContainerDSM(ctx, client, nameOrID, client.StopContainer); err != nil {
+			return errors.Wrapf(synology.ClassifyError(err), "failed to stop container %s", nameOrID)
+		}
+		return nil
+	}
+
 	args := []string{"stop"}
 	if timeout > 0 {
 		args = append(args, "--time", fmt.Sprintf("%d", timeout))
 	}
 	args = append(args, nameOrID)
 
-	output, err := conn.ExecuteDockerCommand(args)
+	_, err := conn.ExecuteDockerCommandContext(ctx, args)
 	if err != nil {
-		return errors.Wrapf(err, "failed to stop container %s: %s", nameOrID, output)
+		return errors.Wrapf(err, "failed to stop container %s", nameOrID)
 	}
 
 	return nil
 }
 
+// stopOptions converts a stop timeout in seconds to API stop options
+func stopOptions(timeout int) container.StopOptions {
+	if timeout <= 0 {
+		return container.StopOptions{}
+	}
+	return container.StopOptions{Timeout: &timeout}
+}
+
 // StatsOptions defines options for container stats
 type StatsOptions struct {
 	All      bool
@@ -379,6 +638,8 @@ type PullOptions struct {
 	Platform            string
 	Quiet               bool
 	DisableContentTrust bool
+	// Stdout receives the pull progress unless Quiet is set; os.Stdout when nil
+	Stdout io.Writer
 }
 
 // RmiOptions defines options for removing images
@@ -425,7 +686,12 @@ type SystemPruneResult struct {
 }
 
 // ShowContainerStats displays container resource usage statistics
-func ShowContainerStats(conn *synology.Connection, containers []string, opts *StatsOptions) error {
+func ShowContainerStats(conn synology.Executor, containers []string, opts *StatsOptions) error {
+	return ShowContainerStatsContext(context.Background(), conn, containers, opts)
+}
+
+// ShowContainerStatsContext is like ShowContainerStats but honors ctx
+func ShowContainerStatsContext(ctx context.Context, conn synology.Executor, containers []string, opts *StatsOptions) error {
 	args := []string{"stats"}
 
 	if opts.All {
@@ -440,12 +706,32 @@ func ShowContainerStats(conn *synology.Connection, containers []string, opts *St
 
 	args = append(args, containers...)
 
-	cmd := strings.Join(append([]string{"/usr/local/bin/docker"}, args...), " ")
-	return conn.StreamCommand(cmd, nil, nil)
+	return retryOnDisconnect(ctx, conn, func() error {
+		return conn.StreamCommandContext(ctx, synology.DockerCommand(args), os.Stdout, os.Stderr)
+	})
 }
 
 // ListImages lists Docker images
-func ListImages(conn *synology.Connection, repository string, opts *ImagesOptions) ([]ImageInfo, error) {
+func ListImages(conn synology.Executor, repository string, opts *ImagesOptions) ([]ImageInfo, error) {
+	return ListImagesContext(context.Background(), conn, repository, opts)
+}
+
+// ListImagesContext is like ListImages but honors ctx
+func ListImagesContext(ctx context.Context, conn synology.Executor, repository string, opts *ImagesOptions) ([]ImageInfo, error) {
+	return retry(ctx, conn, func() ([]ImageInfo, error) {
+		return listImages(ctx, conn, repository, opts)
+	})
+}
+
+func listImages(ctx context.Context, conn synology.Executor, repository string, opts *ImagesOptions) ([]ImageInfo, error) {
+	if cli := dockerClient(conn); cli != nil {
+		images, err := listImagesAPI(ctx, cli, repository, opts)
+		if err != nil {
+			return nil, errors.Wrap(synology.ClassifyError(err), "failed to list images")
+		}
+		return images, nil
+	}
+
 	args := []string{"images"}
 
 	if opts.All {
@@ -462,16 +748,16 @@ func ListImages(conn *synology.Connection, repository string, opts *ImagesOption
 	}
 
 	if opts.Digests {
-		args = append(args, "--format", "'table {{.Repository}}\t{{.Tag}}\t{{.Digest}}\t{{.ID}}\t{{.CreatedSince}}\t{{.Size}}'")
+		args = append(args, "--format", "table {{.Repository}}\t{{.Tag}}\t{{.Digest}}\t{{.ID}}\t{{.CreatedSince}}\t{{.Size}}")
 	} else {
-		args = append(args, "--format", "'table {{.Repository}}\t{{.Tag}}\t{{.ID}}\t{{.CreatedSince}}\t{{.Size}}'")
+		args = append(args, "--format", "table {{.Repository}}\t{{.Tag}}\t{{.ID}}\t{{.CreatedSince}}\t{{.Size}}")
 	}
 
 	if repository != "" {
 		args = append(args, repository)
 	}
 
-	output, err := conn.ExecuteDockerCommand(args)
+	output, err := conn.ExecuteDockerCommandContext(ctx, args)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to list images")
 	}
@@ -510,7 +796,20 @@ func ListImages(conn *synology.Connection, repository string, opts *ImagesOption
 }
 
 // ListImageIDs lists Docker image IDs only
-func ListImageIDs(conn *synology.Connection, repository string, opts *ImagesOptions) ([]string, error) {
+func ListImageIDs(conn synology.Executor, repository string, opts *ImagesOptions) ([]string, error) {
+	return ListImageIDsContext(context.Background(), conn, repository, opts)
+}
+
+// ListImageIDsContext is like ListImageIDs but honors ctx
+func ListImageIDsContext(ctx context.Context, conn synology.Executor, repository string, opts *ImagesOptions) ([]string, error) {
+	if cli := dockerClient(conn); cli != nil {
+		imageIDs, err := listImageIDsAPI(ctx, cli, repository, opts)
+		if err != nil {
+			return nil, errors.Wrap(synology.ClassifyError(err), "failed to list image IDs")
+		}
+		return imageIDs, nil
+	}
+
 	args := []string{"images", "--quiet"}
 
 	if opts.All {
@@ -527,7 +826,7 @@ func ListImageIDs(conn *synology.Connection, repository string, opts *ImagesOpti
 		args = append(args, repository)
 	}
 
-	output, err := conn.ExecuteDockerCommand(args)
+	output, err := conn.ExecuteDockerCommandContext(ctx, args)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to list image IDs")
 	}
@@ -544,7 +843,19 @@ func ListImageIDs(conn *synology.Connection, repository string, opts *ImagesOpti
 }
 
 // PullImage pulls a Docker image
-func PullImage(conn *synology.Connection, imageName string, opts *PullOptions) error {
+func PullImage(conn synology.Executor, imageName string, opts *PullOptions) error {
+	return PullImageContext(context.Background(), conn, imageName, opts)
+}
+
+// PullImageContext is like PullImage but honors ctx
+func PullImageContext(ctx context.Context, conn synology.Executor, imageName string, opts *PullOptions) error {
+	// Layers already downloaded are kept, so a retried pull picks up where it stopped
+	return retryOnDisconnect(ctx, conn, func() error {
+		return pullImage(ctx, conn, imageName, opts)
+	})
+}
+
+func pullImage(ctx context.Context, conn synology.Executor, imageName string, opts *PullOptions) error {
 	args := []string{"pull"}
 
 	if opts.AllTags {
@@ -562,20 +873,37 @@ func PullImage(conn *synology.Connection, imageName string, opts *PullOptions) e
 
 	args = append(args, imageName)
 
-	output, err := conn.ExecuteDockerCommand(args)
+	output, err := conn.ExecuteDockerCommandContext(ctx, args)
 	if err != nil {
-		return errors.Wrapf(err, "failed to pull image %s: %s", imageName, output)
+		return errors.Wrapf(err, "failed to pull image %s", imageName)
 	}
 
 	if !opts.Quiet {
-		fmt.Print(output)
+		stdout := opts.Stdout
+		if stdout == nil {
+			stdout = os.Stdout
+		}
+		fmt.Fprint(stdout, output)
 	}
 
 	return nil
 }
 
 // RemoveImage removes a Docker image
-func RemoveImage(conn *synology.Connection, imageName string, opts *RmiOptions) error {
+func RemoveImage(conn synology.Executor, imageName string, opts *RmiOptions) error {
+	return RemoveImageContext(context.Background(), conn, imageName, opts)
+}
+
+// RemoveImageContext is like RemoveImage but honors ctx
+func RemoveImageContext(ctx context.Context, conn synology.Executor, imageName string, opts *RmiOptions) error {
+	if cli := dockerClient(conn); cli != nil {
+		_, err := cli.ImageRemove(ctx, imageName, image.RemoveOptions{Force: opts.Force, PruneChildren: !opts.NoPrune})
+		if err != nil {
+			return errors.Wrapf(synology.ClassifyError(err), "failed to remove image %s", imageName)
+		}
+		return nil
+	}
+
 	args := []string{"rmi"}
 
 	if opts.Force {
@@ -587,16 +915,21 @@ func RemoveImage(conn *synology.Connection, imageName string, opts *RmiOptions)
 
 	args = append(args, imageName)
 
-	output, err := conn.ExecuteDockerCommand(args)
+	_, err := conn.ExecuteDockerCommandContext(ctx, args)
 	if err != nil {
-		return errors.Wrapf(err, "failed to remove image %s: %s", imageName, output)
+		return errors.Wrapf(err, "failed to remove image %s", imageName)
 	}
 
 	return nil
 }
 
 // GetSystemDf gets Docker system disk usage
-func GetSystemDf(conn *synology.Connection, opts *SystemDfOptions) ([]SystemDfItem, error) {
+func GetSystemDf(conn synology.Executor, opts *SystemDfOptions) ([]SystemDfItem, error) {
+	return GetSystemDfContext(context.Background(), conn, opts)
+}
+
+// GetSystemDfContext is like GetSystemDf but honors ctx
+func GetSystemDfContext(ctx context.Context, conn synology.Executor, opts *SystemDfOptions) ([]SystemDfItem, error) {
 	args := []string{"system", "df"}
 
 	if opts.Verbose {
@@ -607,7 +940,7 @@ func GetSystemDf(conn *synology.Connection, opts *SystemDfOptions) ([]SystemDfIt
 	}
 	// Don't add default format, let Docker use its default table format
 
-	output, err := conn.ExecuteDockerCommand(args)
+	output, err := conn.ExecuteDockerCommandContext(ctx, args)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get system disk usage")
 	}
@@ -640,14 +973,19 @@ func GetSystemDf(conn *synology.Connection, opts *SystemDfOptions) ([]SystemDfIt
 }
 
 // GetSystemInfo gets Docker system information
-func GetSystemInfo(conn *synology.Connection, opts *SystemInfoOptions) (string, error) {
+func GetSystemInfo(conn synology.Executor, opts *SystemInfoOptions) (string, error) {
+	return GetSystemInfoContext(context.Background(), conn, opts)
+}
+
+// GetSystemInfoContext is like GetSystemInfo but honors ctx
+func GetSystemInfoContext(ctx context.Context, conn synology.Executor, opts *SystemInfoOptions) (string, error) {
 	args := []string{"system", "info"}
 
 	if opts.Format != "" {
 		args = append(args, "--format", opts.Format)
 	}
 
-	output, err := conn.ExecuteDockerCommand(args)
+	output, err := conn.ExecuteDockerCommandContext(ctx, args)
 	if err != nil {
 		return "", errors.Wrap(err, "failed to get system info")
 	}
@@ -656,7 +994,12 @@ func GetSystemInfo(conn *synology.Connection, opts *SystemInfoOptions) (string,
 }
 
 // SystemPrune removes unused Docker data
-func SystemPrune(conn *synology.Connection, opts *SystemPruneOptions) (*SystemPruneResult, error) {
+func SystemPrune(conn synology.Executor, opts *SystemPruneOptions) (*SystemPruneResult, error) {
+	return SystemPruneContext(context.Background(), conn, opts)
+}
+
+// SystemPruneContext is like SystemPrune but honors ctx
+func SystemPruneContext(ctx context.Context, conn synology.Executor, opts *SystemPruneOptions) (*SystemPruneResult, error) {
 	args := []string{"system", "prune"}
 
 	if opts.All {
@@ -672,7 +1015,7 @@ func SystemPrune(conn *synology.Connection, opts *SystemPruneOptions) (*SystemPr
 		args = append(args, "--filter", filter)
 	}
 
-	output, err := conn.ExecuteDockerCommand(args)
+	output, err := conn.ExecuteDockerCommandContext(ctx, args)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to prune system")
 	}
@@ -737,29 +1080,30 @@ type InspectOptions struct {
 	Type   string
 }
 
-// ExportOptions defines options for exporting containers
-type ExportOptions struct {
-	Output string
+// ListVolumes lists Docker volumes
+func ListVolumes(conn synology.Executor, opts *VolumeListOptions) ([]VolumeInfo, error) {
+	return ListVolumesContext(context.Background(), conn, opts)
 }
 
-// ImportOptions defines options for importing images
-type ImportOptions struct {
-	Change   []string
-	Message  string
-	Platform string
-}
+// ListVolumesContext is like ListVolumes but honors ctx
+func ListVolumesContext(ctx context.Context, conn synology.Executor, opts *VolumeListOptions) ([]VolumeInfo, error) {
+	if cli := dockerClient(conn); cli != nil && opts.Format == "" {
+		volumes, err := listVolumesAPI(ctx, cli)
+		if err != nil {
+			return nil, errors.Wrap(synology.ClassifyError(err), "failed to list volumes")
+		}
+		return volumes, nil
+	}
 
-// ListVolumes lists Docker volumes
-func ListVolumes(conn *synology.Connection, opts *VolumeListOptions) ([]VolumeInfo, error) {
 	args := []string{"volume", "ls"}
 
 	if opts.Format != "" {
 		args = append(args, "--format", opts.Format)
 	} else {
-		args = append(args, "--format", "'table {{.Driver}}\t{{.Name}}'")
+		args = append(args, "--format", "table {{.Driver}}\t{{.Name}}")
 	}
 
-	output, err := conn.ExecuteDockerCommand(args)
+	output, err := conn.ExecuteDockerCommandContext(ctx, args)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to list volumes")
 	}
@@ -785,10 +1129,27 @@ func ListVolumes(conn *synology.Connection, opts *VolumeListOptions) ([]VolumeIn
 }
 
 // ListVolumeNames lists Docker volume names only
-func ListVolumeNames(conn *synology.Connection, opts *VolumeListOptions) ([]string, error) {
+func ListVolumeNames(conn synology.Executor, opts *VolumeListOptions) ([]string, error) {
+	return ListVolumeNamesContext(context.Background(), conn, opts)
+}
+
+// ListVolumeNamesContext is like ListVolumeNames but honors ctx
+func ListVolumeNamesContext(ctx context.Context, conn synology.Executor, opts *VolumeListOptions) ([]string, error) {
+	if cli := dockerClient(conn); cli != nil {
+		volumes, err := listVolumesAPI(ctx, cli)
+		if err != nil {
+			return nil, errors.Wrap(synology.ClassifyError(err), "failed to list volume names")
+		}
+		volumeNames := []string{}
+		for _, volume := range volumes {
+			volumeNames = append(volumeNames, volume.Name)
+		}
+		return volumeNames, nil
+	}
+
 	args := []string{"volume", "ls", "--quiet"}
 
-	output, err := conn.ExecuteDockerCommand(args)
+	output, err := conn.ExecuteDockerCommandContext(ctx, args)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to list volume names")
 	}
@@ -805,7 +1166,20 @@ func ListVolumeNames(conn *synology.Connection, opts *VolumeListOptions) ([]stri
 }
 
 // CreateVolume creates a Docker volume
-func CreateVolume(conn *synology.Connection, volumeName string, opts *VolumeCreateOptions) (string, error) {
+func CreateVolume(conn synology.Executor, volumeName string, opts *VolumeCreateOptions) (string, error) {
+	return CreateVolumeContext(context.Background(), conn, volumeName, opts)
+}
+
+// CreateVolumeContext is like CreateVolume but honors ctx
+func CreateVolumeContext(ctx context.Context, conn synology.Executor, volumeName string, opts *VolumeCreateOptions) (string, error) {
+	if cli := dockerClient(conn); cli != nil {
+		name, err := createVolumeAPI(ctx, cli, volumeName, opts)
+		if err != nil {
+			return "", errors.Wrap(synology.ClassifyError(err), "failed to create volume")
+		}
+		return name, nil
+	}
+
 	args := []string{"volume", "create"}
 
 	if opts.Driver != "" {
@@ -822,16 +1196,28 @@ func CreateVolume(conn *synology.Connection, volumeName string, opts *VolumeCrea
 		args = append(args, volumeName)
 	}
 
-	output, err := conn.ExecuteDockerCommand(args)
+	output, err := conn.ExecuteDockerCommandContext(ctx, args)
 	if err != nil {
-		return "", errors.Wrapf(err, "failed to create volume: %s", output)
+		return "", errors.Wrap(err, "failed to create volume")
 	}
 
 	return strings.TrimSpace(output), nil
 }
 
 // RemoveVolume removes a Docker volume
-func RemoveVolume(conn *synology.Connection, volumeName string, opts *VolumeRemoveOptions) error {
+func RemoveVolume(conn synology.Executor, volumeName string, opts *VolumeRemoveOptions) error {
+	return RemoveVolumeContext(context.Background(), conn, volumeName, opts)
+}
+
+// RemoveVolumeContext is like RemoveVolume but honors ctx
+func RemoveVolumeContext(ctx context.Context, conn synology.Executor, volumeName string, opts *VolumeRemoveOptions) error {
+	if cli := dockerClient(conn); cli != nil {
+		if err := cli.VolumeRemove(ctx, volumeName, opts.Force); err != nil {
+			return errors.Wrapf(synology.ClassifyError(err), "failed to remove volume %s", volumeName)
+		}
+		return nil
+	}
+
 	args := []string{"volume", "rm"}
 
 	if opts.Force {
@@ -840,16 +1226,21 @@ func RemoveVolume(conn *synology.Connection, volumeName string, opts *VolumeRemo
 
 	args = append(args, volumeName)
 
-	output, err := conn.ExecuteDockerCommand(args)
+	_, err := conn.ExecuteDockerCommandContext(ctx, args)
 	if err != nil {
-		return errors.Wrapf(err, "failed to remove volume %s: %s", volumeName, output)
+		return errors.Wrapf(err, "failed to remove volume %s", volumeName)
 	}
 
 	return nil
 }
 
 // InspectVolume inspects a Docker volume
-func InspectVolume(conn *synology.Connection, volumeName string, opts *VolumeInspectOptions) (string, error) {
+func InspectVolume(conn synology.Executor, volumeName string, opts *VolumeInspectOptions) (string, error) {
+	return InspectVolumeContext(context.Background(), conn, volumeName, opts)
+}
+
+// InspectVolumeContext is like InspectVolume but honors ctx
+func InspectVolumeContext(ctx context.Context, conn synology.Executor, volumeName string, opts *VolumeInspectOptions) (string, error) {
 	args := []string{"volume", "inspect"}
 
 	if opts.Format != "" {
@@ -858,7 +1249,7 @@ func InspectVolume(conn *synology.Connection, volumeName string, opts *VolumeIns
 
 	args = append(args, volumeName)
 
-	output, err := conn.ExecuteDockerCommand(args)
+	output, err := conn.ExecuteDockerCommandContext(ctx, args)
 	if err != nil {
 		return "", errors.Wrapf(err, "failed to inspect volume %s", volumeName)
 	}
@@ -867,7 +1258,12 @@ func InspectVolume(conn *synology.Connection, volumeName string, opts *VolumeIns
 }
 
 // PruneVolumes removes unused Docker volumes
-func PruneVolumes(conn *synology.Connection, opts *VolumePruneOptions) (*VolumePruneResult, error) {
+func PruneVolumes(conn synology.Executor, opts *VolumePruneOptions) (*VolumePruneResult, error) {
+	return PruneVolumesContext(context.Background(), conn, opts)
+}
+
+// PruneVolumesContext is like PruneVolumes but honors ctx
+func PruneVolumesContext(ctx context.Context, conn synology.Executor, opts *VolumePruneOptions) (*VolumePruneResult, error) {
 	args := []string{"volume", "prune"}
 
 	if opts.Force {
@@ -877,7 +1273,7 @@ func PruneVolumes(conn *synology.Connection, opts *VolumePruneOptions) (*VolumeP
 		args = append(args, "--filter", filter)
 	}
 
-	output, err := conn.ExecuteDockerCommand(args)
+	output, err := conn.ExecuteDockerCommandContext(ctx, args)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to prune volumes")
 	}
@@ -894,7 +1290,18 @@ func PruneVolumes(conn *synology.Connection, opts *VolumePruneOptions) (*VolumeP
 }
 
 // InspectObject inspects a Docker object (container, image, volume, network)
-func InspectObject(conn *synology.Connection, objectName string, opts *InspectOptions) (string, error) {
+func InspectObject(conn synology.Executor, objectName string, opts *InspectOptions) (string, error) {
+	return InspectObjectContext(context.Background(), conn, objectName, opts)
+}
+
+// InspectObjectContext is like InspectObject but honors ctx
+func InspectObjectContext(ctx context.Context, conn synology.Executor, objectName string, opts *InspectOptions) (string, error) {
+	return retry(ctx, conn, func() (string, error) {
+		return inspectObject(ctx, conn, objectName, opts)
+	})
+}
+
+func inspectObject(ctx context.Context, conn synology.Executor, objectName string, opts *InspectOptions) (string, error) {
 	args := []string{"inspect"}
 
 	if opts.Format != "" {
@@ -909,7 +1316,7 @@ func InspectObject(conn *synology.Connection, objectName string, opts *InspectOp
 
 	args = append(args, objectName)
 
-	output, err := conn.ExecuteDockerCommand(args)
+	output, err := conn.ExecuteDockerCommandContext(ctx, args)
 	if err != nil {
 		return "", errors.Wrapf(err, "failed to inspect object %s", objectName)
 	}
@@ -917,55 +1324,6 @@ func InspectObject(conn *synology.Connection, objectName string, opts *InspectOp
 	return output, nil
 }
 
-// ExportContainer exports a container's filesystem as a tar archive
-func ExportContainer(conn *synology.Connection, containerName string, opts *ExportOptions) error {
-	args := []string{"export"}
-
-	if opts.Output != "" {
-		args = append(args, "--output", opts.Output)
-	}
-
-	args = append(args, containerName)
-
-	output, err := conn.ExecuteDockerCommand(args)
-	if err != nil {
-		return errors.Wrapf(err, "failed to export container %s: %s", containerName, output)
-	}
-
-	if opts.Output == "" {
-		fmt.Print(output)
-	}
-
-	return nil
-}
-
-// ImportImage imports the contents from a tarball to create a filesystem image
-func ImportImage(conn *synology.Connection, source, repository string, opts *ImportOptions) (string, error) {
-	args := []string{"import"}
-
-	for _, change := range opts.Change {
-		args = append(args, "--change", change)
-	}
-	if opts.Message != "" {
-		args = append(args, "--message", opts.Message)
-	}
-	if opts.Platform != "" {
-		args = append(args, "--platform", opts.Platform)
-	}
-
-	args = append(args, source)
-	if repository != "" {
-		args = append(args, repository)
-	}
-
-	output, err := conn.ExecuteDockerCommand(args)
-	if err != nil {
-		return "", errors.Wrapf(err, "failed to import image from %s: %s", source, output)
-	}
-
-	return strings.TrimSpace(output), nil
-}
-
 // NetworkInfo represents Docker network information
 type NetworkInfo struct {
 	ID     string
@@ -1027,20 +1385,33 @@ type NetworkPruneResult struct {
 }
 
 // ListNetworks lists Docker networks
-func ListNetworks(conn *synology.Connection, opts *NetworkListOptions) ([]NetworkInfo, error) {
+func ListNetworks(conn synology.Executor, opts *NetworkListOptions) ([]NetworkInfo, error) {
+	return ListNetworksContext(context.Background(), conn, opts)
+}
+
+// ListNetworksContext is like ListNetworks but honors ctx
+func ListNetworksContext(ctx context.Context, conn synology.Executor, opts *NetworkListOptions) ([]NetworkInfo, error) {
+	if cli := dockerClient(conn); cli != nil && opts.Format == "" {
+		networks, err := listNetworksAPI(ctx, cli, opts)
+		if err != nil {
+			return nil, errors.Wrap(synology.ClassifyError(err), "failed to list networks")
+		}
+		return networks, nil
+	}
+
 	args := []string{"network", "ls"}
 
 	if opts.Format != "" {
 		args = append(args, "--format", opts.Format)
 	} else {
-		args = append(args, "--format", "'table {{.ID}}\t{{.Name}}\t{{.Driver}}\t{{.Scope}}'")
+		args = append(args, "--format", "table {{.ID}}\t{{.Name}}\t{{.Driver}}\t{{.Scope}}")
 	}
 
 	for _, filter := range opts.Filter {
 		args = append(args, "--filter", filter)
 	}
 
-	output, err := conn.ExecuteDockerCommand(args)
+	output, err := conn.ExecuteDockerCommandContext(ctx, args)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to list networks")
 	}
@@ -1068,14 +1439,31 @@ func ListNetworks(conn *synology.Connection, opts *NetworkListOptions) ([]Networ
 }
 
 // ListNetworkIDs lists Docker network IDs only
-func ListNetworkIDs(conn *synology.Connection, opts *NetworkListOptions) ([]string, error) {
+func ListNetworkIDs(conn synology.Executor, opts *NetworkListOptions) ([]string, error) {
+	return ListNetworkIDsContext(context.Background(), conn, opts)
+}
+
+// ListNetworkIDsContext is like ListNetworkIDs but honors ctx
+func ListNetworkIDsContext(ctx context.Context, conn synology.Executor, opts *NetworkListOptions) ([]string, error) {
+	if cli := dockerClient(conn); cli != nil {
+		networks, err := listNetworksAPI(ctx, cli, opts)
+		if err != nil {
+			return nil, errors.Wrap(synology.ClassifyError(err), "failed to list network IDs")
+		}
+		networkIDs := []string{}
+		for _, network := range networks {
+			networkIDs = append(networkIDs, network.ID)
+		}
+		return networkIDs, nil
+	}
+
 	args := []string{"network", "ls", "--quiet"}
 
 	for _, filter := range opts.Filter {
 		args = append(args, "--filter", filter)
 	}
 
-	output, err := conn.ExecuteDockerCommand(args)
+	output, err := conn.ExecuteDockerCommandContext(ctx, args)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to list network IDs")
 	}
@@ -1092,7 +1480,12 @@ func ListNetworkIDs(conn *synology.Connection, opts *NetworkListOptions) ([]stri
 }
 
 // CreateNetwork creates a Docker network
-func CreateNetwork(conn *synology.Connection, networkName string, opts *NetworkCreateOptions) (string, error) {
+func CreateNetwork(conn synology.Executor, networkName string, opts *NetworkCreateOptions) (string, error) {
+	return CreateNetworkContext(context.Background(), conn, networkName, opts)
+}
+
+// CreateNetworkContext is like CreateNetwork but honors ctx
+func CreateNetworkContext(ctx context.Context, conn synology.Executor, networkName string, opts *NetworkCreateOptions) (string, error) {
 	args := []string{"network", "create"}
 
 	if opts.Driver != "" {
@@ -1131,28 +1524,45 @@ func CreateNetwork(conn *synology.Connection, networkName string, opts *NetworkC
 
 	args = append(args, networkName)
 
-	output, err := conn.ExecuteDockerCommand(args)
+	output, err := conn.ExecuteDockerCommandContext(ctx, args)
 	if err != nil {
-		return "", errors.Wrapf(err, "failed to create network %s: %s", networkName, output)
+		return "", errors.Wrapf(err, "failed to create network %s", networkName)
 	}
 
 	return strings.TrimSpace(output), nil
 }
 
 // RemoveNetwork removes a Docker network
-func RemoveNetwork(conn *synology.Connection, networkName string) error {
+func RemoveNetwork(conn synology.Executor, networkName string) error {
+	return RemoveNetworkContext(context.Background(), conn, networkName)
+}
+
+// RemoveNetworkContext is like RemoveNetwork but honors ctx
+func RemoveNetworkContext(ctx context.Context, conn synology.Executor, networkName string) error {
+	if cli := dockerClient(conn); cli != nil {
+		if err := cli.NetworkRemove(ctx, networkName); err != nil {
+			return errors.Wrapf(synology.ClassifyError(err), "failed to remove network %s", networkName)
+		}
+		return nil
+	}
+
 	args := []string{"network", "rm", networkName}
 
-	output, err := conn.ExecuteDockerCommand(args)
+	_, err := conn.ExecuteDockerCommandContext(ctx, args)
 	if err != nil {
-		return errors.Wrapf(err, "failed to remove network %s: %s", networkName, output)
+		return errors.Wrapf(err, "failed to remove network %s", networkName)
 	}
 
 	return nil
 }
 
 // InspectNetwork inspects a Docker network
-func InspectNetwork(conn *synology.Connection, networkName string, opts *NetworkInspectOptions) (string, error) {
+func InspectNetwork(conn synology.Executor, networkName string, opts *NetworkInspectOptions) (string, error) {
+	return InspectNetworkContext(context.Background(), conn, networkName, opts)
+}
+
+// InspectNetworkContext is like InspectNetwork but honors ctx
+func InspectNetworkContext(ctx context.Context, conn synology.Executor, networkName string, opts *NetworkInspectOptions) (string, error) {
 	args := []string{"network", "inspect"}
 
 	if opts.Format != "" {
@@ -1161,7 +1571,7 @@ func InspectNetwork(conn *synology.Connection, networkName string, opts *Network
 
 	args = append(args, networkName)
 
-	output, err := conn.ExecuteDockerCommand(args)
+	output, err := conn.ExecuteDockerCommandContext(ctx, args)
 	if err != nil {
 		return "", errors.Wrapf(err, "failed to inspect network %s", networkName)
 	}
@@ -1170,7 +1580,12 @@ func InspectNetwork(conn *synology.Connection, networkName string, opts *Network
 }
 
 // ConnectContainerToNetwork connects a container to a network
-func ConnectContainerToNetwork(conn *synology.Connection, networkName, containerName string, opts *NetworkConnectOptions) error {
+func ConnectContainerToNetwork(conn synology.Executor, networkName, containerName string, opts *NetworkConnectOptions) error {
+	return ConnectContainerToNetworkContext(context.Background(), conn, networkName, containerName, opts)
+}
+
+// ConnectContainerToNetworkContext is like ConnectContainerToNetwork but honors ctx
+func ConnectContainerToNetworkContext(ctx context.Context, conn synology.Executor, networkName, containerName string, opts *NetworkConnectOptions) error {
 	args := []string{"network", "connect"}
 
 	for _, alias := range opts.Alias {
@@ -1188,16 +1603,21 @@ func ConnectContainerToNetwork(conn *synology.Connection, networkName, container
 
 	args = append(args, networkName, containerName)
 
-	output, err := conn.ExecuteDockerCommand(args)
+	_, err := conn.ExecuteDockerCommandContext(ctx, args)
 	if err != nil {
-		return errors.Wrapf(err, "failed to connect container %s to network %s: %s", containerName, networkName, output)
+		return errors.Wrapf(err, "failed to connect container %s to network %s", containerName, networkName)
 	}
 
 	return nil
 }
 
 // DisconnectContainerFromNetwork disconnects a container from a network
-func DisconnectContainerFromNetwork(conn *synology.Connection, networkName, containerName string, opts *NetworkDisconnectOptions) error {
+func DisconnectContainerFromNetwork(conn synology.Executor, networkName, containerName string, opts *NetworkDisconnectOptions) error {
+	return DisconnectContainerFromNetworkContext(context.Background(), conn, networkName, containerName, opts)
+}
+
+// DisconnectContainerFromNetworkContext is like DisconnectContainerFromNetwork but honors ctx
+func DisconnectContainerFromNetworkContext(ctx context.Context, conn synology.Executor, networkName, containerName string, opts *NetworkDisconnectOptions) error {
 	args := []string{"network", "disconnect"}
 
 	if opts.Force {
@@ -1206,16 +1626,21 @@ func DisconnectContainerFromNetwork(conn *synology.Connection, networkName, cont
 
 	args = append(args, networkName, containerName)
 
-	output, err := conn.ExecuteDockerCommand(args)
+	_, err := conn.ExecuteDockerCommandContext(ctx, args)
 	if err != nil {
-		return errors.Wrapf(err, "failed to disconnect container %s from network %s: %s", containerName, networkName, output)
+		return errors.Wrapf(err, "failed to disconnect container %s from network %s", containerName, networkName)
 	}
 
 	return nil
 }
 
 // PruneNetworks removes unused Docker networks
-func PruneNetworks(conn *synology.Connection, opts *NetworkPruneOptions) (*NetworkPruneResult, error) {
+func PruneNetworks(conn synology.Executor, opts *NetworkPruneOptions) (*NetworkPruneResult, error) {
+	return PruneNetworksContext(context.Background(), conn, opts)
+}
+
+// PruneNetworksContext is like PruneNetworks but honors ctx
+func PruneNetworksContext(ctx context.Context, conn synology.Executor, opts *NetworkPruneOptions) (*NetworkPruneResult, error) {
 	args := []string{"network", "prune"}
 
 	if opts.Force {
@@ -1225,7 +1650,7 @@ func PruneNetworks(conn *synology.Connection, opts *NetworkPruneOptions) (*Netwo
 		args = append(args, "--filter", filter)
 	}
 
-	output, err := conn.ExecuteDockerCommand(args)
+	output, err := conn.ExecuteDockerCommandContext(ctx, args)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to prune networks")
 	}
@@ -0,0 +1,91 @@
+package deploy
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/scttfrdmn/syno-docker/pkg/synology"
+	"github.com/scttfrdmn/syno-docker/pkg/synology/synologytest"
+)
+
+func TestContainerFake(t *testing.T) {
+	fake := synologytest.New()
+	fake.OnDocker("pull").Return("")
+	fake.OnDocker("run").Return("abc123\n")
+
+	id, err := Container(fake, &ContainerOptions{
+		Image:   "nginx:latest",
+		Name:    "web",
+		Ports:   []string{"8080:80"},
+		Env:     []string{"TITLE=my site"},
+		Restart: "unless-stopped",
+	})
+	if err != nil {
+		t.Fatalf("Container failed: %v", err)
+	}
+	if id != "abc123" {
+		t.Errorf("Expected container ID abc123, got %q", id)
+	}
+
+	calls := fake.DockerCalls()
+	expected := []string{
+		"pull nginx:latest",
+		"run -d --name web -p 8080:80 -e TITLE=my site --restart unless-stopped nginx:latest",
+	}
+	if len(calls) != len(expected) {
+		t.Fatalf("Expected %d docker calls, got:\n%s", len(expected), fake)
+	}
+	for i := range expected {
+		if got := strings.Join(calls[i], " "); got != expected[i] {
+			t.Errorf("Call %d: expected %q, got %q", i, expected[i], got)
+		}
+	}
+}
+
+func TestListContainersFake(t *testing.T) {
+	fake := synologytest.New()
+	fake.OnDocker("ps").Return("CONTAINER ID\tNAMES\tIMAGE\tSTATUS\tPORTS\n" +
+		"0123456789ab\tweb\tnginx:latest\tUp\t0.0.0.0:8080->80/tcp\n")
+
+	containers, err := ListContainers(fake, true)
+	if err != nil {
+		t.Fatalf("ListContainers failed: %v", err)
+	}
+	if len(containers) != 1 || containers[0].Name != "web" || containers[0].Image != "nginx:latest" {
+		t.Errorf("Unexpected containers: %+v", containers)
+	}
+	if args := fake.DockerCalls()[0]; args[len(args)-1] != "-a" {
+		t.Errorf("Expected -a for all containers, got %q", args)
+	}
+}
+
+func TestRemoveContainerFakeNotFound(t *testing.T) {
+	fake := synologytest.New()
+	fake.OnDocker("rm").Fail(1, "Error response from daemon: No such container: web")
+
+	err := RemoveContainer(fake, "web", true)
+	if !errors.Is(err, synology.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestFollowContainerLogsFake(t *testing.T) {
+	fake := synologytest.New()
+	fake.OnDocker("logs").Respond(synologytest.Response{
+		Stdout: "2024-05-01T10:00:00Z started\n",
+		Stderr: "2024-05-01T10:00:01Z warning\n",
+	})
+
+	var stdout, stderr bytes.Buffer
+	if err := FollowContainerLogs(fake, "web", "10", "", false, &stdout, &stderr); err != nil {
+		t.Fatalf("FollowContainerLogs failed: %v", err)
+	}
+	if stdout.String() != "started\n" || stderr.String() != "warning\n" {
+		t.Errorf("Expected timestamps stripped, got %q, %q", stdout.String(), stderr.String())
+	}
+	if got := strings.Join(fake.DockerCalls()[0], " "); got != "logs --follow --timestamps --tail 10 web" {
+		t.Errorf("Unexpected args: %s", got)
+	}
+}
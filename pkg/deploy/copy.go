@@ -0,0 +1,572 @@
+package deploy
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/pkg/sftp"
+
+	"github.com/scttfrdmn/syno-docker/pkg/synology"
+)
+
+const (
+	// DefaultCopyStagingDir is the NAS directory where files copied to or
+	// from containers are staged. It is on a data volume because the DSM
+	// system partition is small.
+	DefaultCopyStagingDir = synology.DefaultVolume + "/.syno-docker-cp"
+	// checksumBatchSize bounds how many files one sha256sum invocation checks
+	checksumBatchSize = 64
+)
+
+// CopyLocationKind says where a CopyLocation is
+type CopyLocationKind int
+
+const (
+	// CopyLocal is a path on this machine
+	CopyLocal CopyLocationKind = iota
+	// CopyNAS is a path on the NAS filesystem
+	CopyNAS
+	// CopyContainer is a path inside a container
+	CopyContainer
+)
+
+// String returns the name used in messages
+func (k CopyLocationKind) String() string {
+	switch k {
+	case CopyNAS:
+		return "NAS"
+	case CopyContainer:
+		return "container"
+	}
+	return "local"
+}
+
+// CopyLocation is the source or destination of a copy
+type CopyLocation struct {
+	Kind      CopyLocationKind
+	Container string
+	Path      string
+}
+
+// String returns the location in the form ParseCopyLocation accepts
+func (l CopyLocation) String() string {
+	switch l.Kind {
+	case CopyNAS:
+		return "nas:" + l.Path
+	case CopyContainer:
+		return l.Container + ":" + l.Path
+	}
+	return l.Path
+}
+
+// ParseCopyLocation parses a cp argument. "nas:PATH" is a path on the NAS,
+// "CONTAINER:PATH" is a path inside a container, and anything else,
+// including "local:PATH", is a local path. NAS and container paths must be
+// absolute.
+func ParseCopyLocation(arg string) (CopyLocation, error) {
+	prefix, rest, found := strings.Cut(arg, ":")
+
+	// Paths such as ./a:b or C:\data are local even though they contain a colon
+	localPath := !found || strings.ContainsAny(prefix, `/\`) || strings.HasPrefix(arg, ".") ||
+		(len(prefix) == 1 && strings.HasPrefix(rest, `\`))
+	if localPath || prefix == "local" {
+		if localPath {
+			rest = arg
+		}
+		if rest == "" {
+			return CopyLocation{}, fmt.Errorf("empty local path in %q", arg)
+		}
+		return CopyLocation{Kind: CopyLocal, Path: rest}, nil
+	}
+
+	if !path.IsAbs(rest) {
+		return CopyLocation{}, fmt.Errorf("path in %q must be absolute", arg)
+	}
+	if prefix == "nas" {
+		return CopyLocation{Kind: CopyNAS, Path: path.Clean(rest)}, nil
+	}
+	if prefix == "" {
+		return CopyLocation{}, fmt.Errorf("missing container name in %q", arg)
+	}
+	return CopyLocation{Kind: CopyContainer, Container: prefix, Path: rest}, nil
+}
+
+// CopyOptions defines options for copying files
+type CopyOptions struct {
+	// StagingDir is the NAS directory for staging container copies;
+	// DefaultCopyStagingDir when empty
+	StagingDir string
+	// Progress, when set, receives progress lines for large files
+	Progress io.Writer
+}
+
+// CopyResult summarizes the files sent over SFTP by a copy
+type CopyResult struct {
+	Files int
+	Bytes int64
+}
+
+// Copy copies files between this machine, the NAS and containers. Files
+// travel over SFTP and keep their permission bits and modification times;
+// each is verified with a SHA-256 checksum computed on both ends. Copies to
+// and from containers are staged on the NAS and moved with docker cp.
+func Copy(conn synology.Executor, src, dst CopyLocation, opts *CopyOptions) (*CopyResult, error) {
+	return CopyContext(context.Background(), conn, src, dst, opts)
+}
+
+// CopyContext is like Copy but honors ctx
+func CopyContext(ctx context.Context, conn synology.Executor, src, dst CopyLocation, opts *CopyOptions) (*CopyResult, error) {
+	if opts == nil {
+		opts = &CopyOptions{}
+	}
+
+	switch {
+	case src.Kind == CopyLocal && dst.Kind == CopyNAS:
+		return withSFTP(conn, func(client *sftp.Client) (*CopyResult, error) {
+			return upload(ctx, conn, client, src.Path, dst.Path, opts)
+		})
+	case src.Kind == CopyNAS && dst.Kind == CopyLocal:
+		return withSFTP(conn, func(client *sftp.Client) (*CopyResult, error) {
+			return download(ctx, conn, client, src.Path, dst.Path, opts)
+		})
+	case src.Kind == CopyLocal && dst.Kind == CopyContainer:
+		return withSFTP(conn, func(client *sftp.Client) (*CopyResult, error) {
+			return copyToContainer(ctx, conn, client, src, dst, opts)
+		})
+	case src.Kind == CopyContainer && dst.Kind == CopyLocal:
+		return withSFTP(conn, func(client *sftp.Client) (*CopyResult, error) {
+			return copyFromContainer(ctx, conn, client, src, dst, opts)
+		})
+	case src.Kind == CopyNAS && dst.Kind == CopyContainer, src.Kind == CopyContainer && dst.Kind == CopyNAS:
+		// Both ends are on the NAS, so docker cp does the whole job
+		if err := dockerCopy(ctx, conn, src.String(), dst.String()); err != nil {
+			return nil, err
+		}
+		return &CopyResult{}, nil
+	}
+
+	return nil, fmt.Errorf("copying from %s to %s is not supported", src.Kind, dst.Kind)
+}
+
+// withSFTP runs fn with an SFTP client opened on conn
+func withSFTP(conn synology.Executor, fn func(*sftp.Client) (*CopyResult, error)) (*CopyResult, error) {
+	transferer, ok := conn.(synology.FileTransferer)
+	if !ok {
+		return nil, fmt.Errorf("connection does not support file transfers")
+	}
+
+	client, err := transferer.SFTPClient()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	return fn(client)
+}
+
+// dockerCopy runs docker cp between a container and the NAS filesystem
+func dockerCopy(ctx context.Context, conn synology.Executor, src, dst string) error {
+	if _, err := conn.ExecuteDockerCommandContext(ctx, []string{"cp", src, dst}); err != nil {
+		return errors.Wrapf(err, "failed to copy %s to %s", src, dst)
+	}
+	return nil
+}
+
+// copyToContainer uploads src to a staging directory on the NAS and moves
+// it into the container with docker cp
+func copyToContainer(ctx context.Context, conn synology.Executor, client *sftp.Client, src, dst CopyLocation, opts *CopyOptions) (*CopyResult, error) {
+	staging, err := createStagingDir(client, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer removeStagingDir(ctx, conn, staging)
+
+	localPath, err := filepath.Abs(src.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", src.Path, err)
+	}
+
+	result, err := upload(ctx, conn, client, localPath, staging, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	staged := path.Join(staging, filepath.Base(localPath))
+	if err := dockerCopy(ctx, conn, staged, dst.String()); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// copyFromContainer moves src out of the container into a staging
+// directory with docker cp and downloads it from there
+func copyFromContainer(ctx context.Context, conn synology.Executor, client *sftp.Client, src, dst CopyLocation, opts *CopyOptions) (*CopyResult, error) {
+	staging, err := createStagingDir(client, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer removeStagingDir(ctx, conn, staging)
+
+	if err := dockerCopy(ctx, conn, src.String(), staging); err != nil {
+		return nil, err
+	}
+
+	return download(ctx, conn, client, path.Join(staging, path.Base(path.Clean(src.Path))), dst.Path, opts)
+}
+
+// createStagingDir creates a uniquely named directory under the staging
+// directory
+func createStagingDir(client *sftp.Client, opts *CopyOptions) (string, error) {
+	base := opts.StagingDir
+	if base == "" {
+		base = DefaultCopyStagingDir
+	}
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("failed to name staging directory: %w", err)
+	}
+
+	dir := path.Join(base, hex.EncodeToString(suffix))
+	if err := client.MkdirAll(dir); err != nil {
+		return "", fmt.Errorf("failed to create staging directory %s: %w", dir, err)
+	}
+	return dir, nil
+}
+
+// removeStagingDir deletes a staging directory. docker cp may have created
+// files the SFTP user cannot remove one by one, so this uses rm -rf.
+func removeStagingDir(ctx context.Context, conn synology.Executor, dir string) {
+	cmd := synology.ShellJoin("rm", "-rf", "--", dir)
+	if _, err := conn.ExecuteCommandContext(context.WithoutCancel(ctx), cmd); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to remove staging directory %s: %v\n", dir, err)
+	}
+}
+
+// transfer tracks the files of one copy and their checksums
+type transfer struct {
+	ctx       context.Context
+	client    *sftp.Client
+	opts      *CopyOptions
+	checksums map[string]string // remote path to SHA-256
+	result    CopyResult
+}
+
+func newTransfer(ctx context.Context, client *sftp.Client, opts *CopyOptions) *transfer {
+	return &transfer{ctx: ctx, client: client, opts: opts, checksums: map[string]string{}}
+}
+
+// copyFile copies r to w, hashing the data and reporting progress for large
+// files, and records the checksum for remotePath
+func (t *transfer) copyFile(w io.Writer, r io.Reader, name, remotePath string, size int64) error {
+	hash := sha256.New()
+	writers := []io.Writer{w, hash}
+
+	var progress *progressWriter
+	if t.opts.Progress != nil && size >= progressThreshold {
+		progress = newProgressWriter(t.opts.Progress, name, size)
+		writers = append(writers, progress)
+	}
+
+	n, err := io.Copy(io.MultiWriter(writers...), &contextReader{ctx: t.ctx, r: r})
+	if progress != nil {
+		progress.Finish()
+	}
+	if err != nil {
+		return err
+	}
+
+	t.checksums[remotePath] = hex.EncodeToString(hash.Sum(nil))
+	t.result.Files++
+	t.result.Bytes += n
+	return nil
+}
+
+// upload copies a local file or directory tree to remotePath. When
+// remotePath is an existing directory, localPath is copied into it.
+func upload(ctx context.Context, conn synology.Executor, client *sftp.Client, localPath, remotePath string, opts *CopyOptions) (*CopyResult, error) {
+	localPath = filepath.Clean(localPath)
+	if _, err := os.Lstat(localPath); err != nil {
+		return nil, err
+	}
+
+	target := remotePath
+	if info, err := client.Stat(remotePath); err == nil && info.IsDir() {
+		target = path.Join(remotePath, filepath.Base(localPath))
+	}
+
+	t := newTransfer(ctx, client, opts)
+	var dirs []string
+	var modes []fs.FileInfo
+
+	err := filepath.WalkDir(localPath, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(localPath, p)
+		if err != nil {
+			return err
+		}
+		remote := path.Join(target, filepath.ToSlash(rel))
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case info.IsDir():
+			if err := client.MkdirAll(remote); err != nil {
+				return fmt.Errorf("failed to create %s: %w", remote, err)
+			}
+			// Directory permissions are applied last so read-only
+			// directories can still be filled
+			dirs = append(dirs, remote)
+			modes = append(modes, info)
+		case info.Mode()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			client.Remove(remote)
+			if err := client.Symlink(link, remote); err != nil {
+				return fmt.Errorf("failed to create symlink %s: %w", remote, err)
+			}
+		case info.Mode().IsRegular():
+			if err := t.uploadFile(p, remote, info); err != nil {
+				return fmt.Errorf("failed to upload %s: %w", p, err)
+			}
+		default:
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s: not a regular file or directory\n", p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := applyRemoteMode(client, dirs[i], modes[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := verifyChecksums(ctx, conn, t.checksums); err != nil {
+		return nil, err
+	}
+	return &t.result, nil
+}
+
+func (t *transfer) uploadFile(localPath, remotePath string, info fs.FileInfo) error {
+	in, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := t.client.OpenFile(remotePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return fmt.Errorf("failed to create %s on the NAS: %w", remotePath, err)
+	}
+
+	if err := t.copyFile(out, in, filepath.Base(localPath), remotePath, info.Size()); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	return applyRemoteMode(t.client, remotePath, info)
+}
+
+// applyRemoteMode gives a remote file the permission bits and modification
+// time of info
+func applyRemoteMode(client *sftp.Client, remotePath string, info fs.FileInfo) error {
+	if err := client.Chmod(remotePath, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to set permissions of %s: %w", remotePath, err)
+	}
+	if err := client.Chtimes(remotePath, info.ModTime(), info.ModTime()); err != nil {
+		return fmt.Errorf("failed to set modification time of %s: %w", remotePath, err)
+	}
+	return nil
+}
+
+// download copies a remote file or directory tree to localPath. When
+// localPath is an existing directory, remotePath is copied into it.
+func download(ctx context.Context, conn synology.Executor, client *sftp.Client, remotePath, localPath string, opts *CopyOptions) (*CopyResult, error) {
+	remotePath = path.Clean(remotePath)
+	if _, err := client.Lstat(remotePath); err != nil {
+		return nil, fmt.Errorf("failed to read %s on the NAS: %w", remotePath, err)
+	}
+
+	target := localPath
+	if info, err := os.Stat(localPath); err == nil && info.IsDir() {
+		target = filepath.Join(localPath, path.Base(remotePath))
+	}
+
+	t := newTransfer(ctx, client, opts)
+	var dirs []string
+	var modes []fs.FileInfo
+
+	walker := client.Walk(remotePath)
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read %s on the NAS: %w", walker.Path(), err)
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		remote := walker.Path()
+		local := filepath.Join(target, filepath.FromSlash(strings.TrimPrefix(remote, remotePath)))
+		info := walker.Stat()
+
+		switch {
+		case info.IsDir():
+			if err := os.MkdirAll(local, 0755); err != nil {
+				return nil, err
+			}
+			dirs = append(dirs, local)
+			modes = append(modes, info)
+		case info.Mode()&fs.ModeSymlink != 0:
+			link, err := client.ReadLink(remote)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read symlink %s: %w", remote, err)
+			}
+			os.Remove(local)
+			if err := os.Symlink(link, local); err != nil {
+				return nil, err
+			}
+		case info.Mode().IsRegular():
+			if err := t.downloadFile(remote, local, info); err != nil {
+				return nil, fmt.Errorf("failed to download %s: %w", remote, err)
+			}
+		default:
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s: not a regular file or directory\n", remote)
+		}
+	}
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := applyLocalMode(dirs[i], modes[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := verifyChecksums(ctx, conn, t.checksums); err != nil {
+		return nil, err
+	}
+	return &t.result, nil
+}
+
+func (t *transfer) downloadFile(remotePath, localPath string, info fs.FileInfo) error {
+	in, err := t.client.Open(remotePath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(localPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	if err := t.copyFile(out, in, path.Base(remotePath), remotePath, info.Size()); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	return applyLocalMode(localPath, info)
+}
+
+// applyLocalMode gives a local file the permission bits and modification
+// time of info, which the umask may have changed on creation
+func applyLocalMode(localPath string, info fs.FileInfo) error {
+	if err := os.Chmod(localPath, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(localPath, info.ModTime(), info.ModTime())
+}
+
+// verifyChecksums compares checksums, keyed by remote path, with those
+// sha256sum computes on the NAS
+func verifyChecksums(ctx context.Context, conn synology.Executor, checksums map[string]string) error {
+	paths := make([]string, 0, len(checksums))
+	for p := range checksums {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	for start := 0; start < len(paths); start += checksumBatchSize {
+		batch := paths[start:min(start+checksumBatchSize, len(paths))]
+
+		output, err := conn.ExecuteCommandContext(ctx, synology.ShellJoin(append([]string{"sha256sum", "--"}, batch...)...))
+		if err != nil {
+			return errors.Wrap(err, "failed to verify checksums")
+		}
+
+		remote := parseChecksums(output)
+		for _, p := range batch {
+			if remote[p] != checksums[p] {
+				return fmt.Errorf("checksum mismatch for %s: sent %s, NAS has %s", p, checksums[p], remote[p])
+			}
+		}
+	}
+	return nil
+}
+
+// checksumNameUnescaper undoes the escaping of names in sha256sum output
+var checksumNameUnescaper = strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\r`, "\r")
+
+// parseChecksums parses sha256sum output into a map from path to checksum
+func parseChecksums(output string) map[string]string {
+	checksums := map[string]string{}
+	for _, line := range strings.Split(output, "\n") {
+		// Lines for names containing a backslash, newline or carriage return
+		// start with a backslash, and those characters are escaped
+		escaped := strings.HasPrefix(line, `\`)
+		line = strings.TrimPrefix(line, `\`)
+		sum, name, found := strings.Cut(line, " ")
+		if !found || len(sum) != sha256.Size*2 {
+			continue
+		}
+		// The separator is two spaces, or a space and an asterisk in binary mode
+		name = strings.TrimPrefix(strings.TrimPrefix(name, " "), "*")
+		if escaped {
+			name = checksumNameUnescaper.Replace(name)
+		}
+		checksums[name] = sum
+	}
+	return checksums
+}
+
+// contextReader fails reads once ctx is done
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
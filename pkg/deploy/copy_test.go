@@ -0,0 +1,281 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/scttfrdmn/syno-docker/pkg/synology"
+	"github.com/scttfrdmn/syno-docker/pkg/synology/synologytest"
+)
+
+func TestParseCopyLocation(t *testing.T) {
+	tests := []struct {
+		arg      string
+		expected CopyLocation
+		wantErr  bool
+	}{
+		{"./site", CopyLocation{Kind: CopyLocal, Path: "./site"}, false},
+		{"backup.tar", CopyLocation{Kind: CopyLocal, Path: "backup.tar"}, false},
+		{"/tmp/a:b", CopyLocation{Kind: CopyLocal, Path: "/tmp/a:b"}, false},
+		{"./a:b", CopyLocation{Kind: CopyLocal, Path: "./a:b"}, false},
+		{`C:\data`, CopyLocation{Kind: CopyLocal, Path: `C:\data`}, false},
+		{"local:notes.txt", CopyLocation{Kind: CopyLocal, Path: "notes.txt"}, false},
+		{"nas:/volume1/docker/", CopyLocation{Kind: CopyNAS, Path: "/volume1/docker"}, false},
+		{"web:/usr/share/nginx/html/.", CopyLocation{Kind: CopyContainer, Container: "web", Path: "/usr/share/nginx/html/."}, false},
+		{"nas:docker", CopyLocation{}, true},
+		{"web:etc", CopyLocation{}, true},
+		{":/etc", CopyLocation{}, true},
+		{"local:", CopyLocation{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			location, err := ParseCopyLocation(tt.arg)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q, got %+v", tt.arg, location)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if location != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, location)
+			}
+		})
+	}
+}
+
+func TestParseChecksums(t *testing.T) {
+	const (
+		emptySum = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+		helloSum = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	)
+
+	tests := []struct {
+		name   string
+		output string
+		want   map[string]string
+	}{
+		{
+			name:   "name with a space",
+			output: emptySum + "  /volume1/docker/empty file\n",
+			want:   map[string]string{"/volume1/docker/empty file": emptySum},
+		},
+		{
+			name:   "binary mode",
+			output: helloSum + " */volume1/docker/hello\n",
+			want:   map[string]string{"/volume1/docker/hello": helloSum},
+		},
+		{
+			name:   "error line skipped",
+			output: "sha256sum: /volume1/docker/missing: No such file or directory\n",
+			want:   map[string]string{},
+		},
+		{
+			name:   "escaped backslash and newline",
+			output: `\` + emptySum + `  /volume1/docker/a\\b\nc` + "\n",
+			want:   map[string]string{"/volume1/docker/a\\b\nc": emptySum},
+		},
+		{
+			name:   "escaped backslash before n",
+			output: `\` + helloSum + `  /volume1/docker/dir\\name` + "\n",
+			want:   map[string]string{`/volume1/docker/dir\name`: helloSum},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseChecksums(tt.output)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+// writeTree creates files, keyed by slash-separated relative path, under dir
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func connectTestServer(t *testing.T, server *synologytest.SSHServer) *synology.Connection {
+	conn := synology.NewConnection(server.Config())
+	if err := conn.Connect(); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestCopyUploadDownload(t *testing.T) {
+	server := synologytest.NewSSHServer(t, nil)
+	conn := connectTestServer(t, server)
+
+	local := t.TempDir()
+	writeTree(t, local, map[string]string{"site/index.html": "<h1>hi</h1>", "site/css/app.css": "body {}"})
+	if err := os.Chmod(filepath.Join(local, "site", "index.html"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(server.Path("/volume1/docker/web"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	src := CopyLocation{Kind: CopyLocal, Path: filepath.Join(local, "site")}
+	dst := CopyLocation{Kind: CopyNAS, Path: "/volume1/docker/web"}
+	result, err := Copy(conn, src, dst, nil)
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if result.Files != 2 || result.Bytes != int64(len("<h1>hi</h1>")+len("body {}")) {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	// The existing directory receives the source by name
+	info, err := os.Stat(server.Path("/volume1/docker/web/site/index.html"))
+	if err != nil {
+		t.Fatalf("Expected uploaded file: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected permissions 0600 to be kept, got %v", info.Mode().Perm())
+	}
+
+	back := filepath.Join(t.TempDir(), "restored")
+	src = CopyLocation{Kind: CopyNAS, Path: "/volume1/docker/web/site"}
+	dst = CopyLocation{Kind: CopyLocal, Path: back}
+	if _, err := Copy(conn, src, dst, nil); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(back, "css", "app.css"))
+	if err != nil || string(data) != "body {}" {
+		t.Errorf("Expected downloaded file, got %q, %v", data, err)
+	}
+	info, err = os.Stat(filepath.Join(back, "index.html"))
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected permissions 0600 after download, got %v, %v", info, err)
+	}
+}
+
+func TestCopyUploadEscapedName(t *testing.T) {
+	server := synologytest.NewSSHServer(t, nil)
+	conn := connectTestServer(t, server)
+
+	// sha256sum escapes the backslash, which the verification must undo
+	local := filepath.Join(t.TempDir(), `back\slash`)
+	if err := os.WriteFile(local, []byte("hi"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	src := CopyLocation{Kind: CopyLocal, Path: local}
+	dst := CopyLocation{Kind: CopyNAS, Path: "/volume1/docker/"}
+	if _, err := Copy(conn, src, dst, nil); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+}
+
+func TestCopyProgress(t *testing.T) {
+	server := synologytest.NewSSHServer(t, nil)
+	conn := connectTestServer(t, server)
+
+	local := filepath.Join(t.TempDir(), "large.bin")
+	if err := os.WriteFile(local, bytes.Repeat([]byte{1}, progressThreshold), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var progress bytes.Buffer
+	src := CopyLocation{Kind: CopyLocal, Path: local}
+	dst := CopyLocation{Kind: CopyNAS, Path: "/large.bin"}
+	if _, err := Copy(conn, src, dst, &CopyOptions{Progress: &progress}); err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	if !strings.Contains(progress.String(), "large.bin  100%") {
+		t.Errorf("Expected progress for a large file, got %q", progress.String())
+	}
+}
+
+func TestCopyContainer(t *testing.T) {
+	docker := synologytest.NewFakeDocker()
+	docker.AddImage("nginx:alpine")
+	server := synologytest.NewSSHServer(t, docker)
+	conn := connectTestServer(t, server)
+
+	opts := NewContainerOptions("nginx:alpine")
+	opts.Name = "web"
+	if _, err := Container(conn, opts); err != nil {
+		t.Fatalf("Container failed: %v", err)
+	}
+
+	local := t.TempDir()
+	writeTree(t, local, map[string]string{"nginx.conf": "worker_processes 1;"})
+
+	copyOpts := &CopyOptions{StagingDir: "/volume1/docker/.staging"}
+	src := CopyLocation{Kind: CopyLocal, Path: filepath.Join(local, "nginx.conf")}
+	dst := CopyLocation{Kind: CopyContainer, Container: "web", Path: "/etc/nginx/nginx.conf"}
+	if _, err := Copy(conn, src, dst, copyOpts); err != nil {
+		t.Fatalf("Copy into container failed: %v", err)
+	}
+
+	container, _ := docker.Container("web")
+	if string(container.Files["/etc/nginx/nginx.conf"]) != "worker_processes 1;" {
+		t.Errorf("Expected file in container, got %v", container.Files)
+	}
+
+	back := filepath.Join(local, "restored.conf")
+	src = CopyLocation{Kind: CopyContainer, Container: "web", Path: "/etc/nginx/nginx.conf"}
+	dst = CopyLocation{Kind: CopyLocal, Path: back}
+	if _, err := Copy(conn, src, dst, copyOpts); err != nil {
+		t.Fatalf("Copy from container failed: %v", err)
+	}
+	if data, _ := os.ReadFile(back); string(data) != "worker_processes 1;" {
+		t.Errorf("Expected file from container, got %q", data)
+	}
+
+	// Staging directories are removed afterwards
+	entries, _ := os.ReadDir(server.Path("/volume1/docker/.staging"))
+	if len(entries) != 0 {
+		t.Errorf("Expected staging directory to be cleaned up, got %d entries", len(entries))
+	}
+}
+
+func TestCopyUnsupported(t *testing.T) {
+	local := CopyLocation{Kind: CopyLocal, Path: "a"}
+	if _, err := Copy(synologytest.New(), local, local, nil); err == nil || !strings.Contains(err.Error(), "from local to local") {
+		t.Errorf("Expected local to local copy to fail, got %v", err)
+	}
+
+	nas := CopyLocation{Kind: CopyNAS, Path: "/a"}
+	if _, err := Copy(synologytest.New(), local, nas, nil); err == nil || !strings.Contains(err.Error(), "does not support file transfers") {
+		t.Errorf("Expected an executor without SFTP to fail, got %v", err)
+	}
+}
+
+func TestVerifyChecksums(t *testing.T) {
+	sum := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+
+	fake := synologytest.New()
+	fake.On("sha256sum").Return(sum + "  /volume1/docker/hello\n")
+
+	if err := verifyChecksums(context.Background(), fake, map[string]string{"/volume1/docker/hello": sum}); err != nil {
+		t.Errorf("Expected matching checksums to verify, got %v", err)
+	}
+
+	err := verifyChecksums(context.Background(), fake, map[string]string{"/volume1/docker/hello": strings.Repeat("0", 64)})
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch for /volume1/docker/hello") {
+		t.Errorf("Expected a checksum mismatch, got %v", err)
+	}
+}
@@ -0,0 +1,334 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/scttfrdmn/syno-docker/pkg/synology"
+)
+
+// DefaultDaemonHealthTimeout is how long ApplyDaemonConfig waits for the
+// Docker daemon to answer after a restart
+const DefaultDaemonHealthTimeout = 90 * time.Second
+
+// DaemonConfig is the Container Manager daemon configuration in
+// synology.ConfigPath, the dockerd daemon.json settings
+type DaemonConfig map[string]any
+
+// DaemonConfigOptions defines options for applying a daemon configuration
+type DaemonConfigOptions struct {
+	// HealthTimeout is how long to wait for the daemon after a restart;
+	// DefaultDaemonHealthTimeout when zero
+	HealthTimeout time.Duration
+	// HealthInterval is the time between health checks; 2s when zero
+	HealthInterval time.Duration
+}
+
+// DaemonConfigResult reports what ApplyDaemonConfig changed
+type DaemonConfigResult struct {
+	// Backup is the copy of the previous configuration, or empty when there
+	// was none
+	Backup string
+	// DockerVersion is the version reported by the restarted daemon
+	DockerVersion string
+}
+
+// daemonConfigKinds lists the value kinds of the daemon settings that are
+// checked before a change is applied. Other settings are passed through.
+var daemonConfigKinds = map[string]string{
+	"registry-mirrors":         "urls",
+	"insecure-registries":      "strings",
+	"dns":                      "strings",
+	"dns-search":               "strings",
+	"labels":                   "strings",
+	"storage-opts":             "strings",
+	"log-driver":               "string",
+	"log-level":                "log level",
+	"storage-driver":           "string",
+	"data-root":                "path",
+	"log-opts":                 "string map",
+	"debug":                    "bool",
+	"experimental":             "bool",
+	"ipv6":                     "bool",
+	"live-restore":             "bool",
+	"userland-proxy":           "bool",
+	"max-concurrent-downloads": "count",
+	"max-concurrent-uploads":   "count",
+	"max-download-attempts":    "count",
+	"shutdown-timeout":         "count",
+	"mtu":                      "count",
+}
+
+// ReadDaemonConfig reads the Container Manager daemon configuration. A
+// missing file is an empty configuration.
+func ReadDaemonConfig(conn synology.Executor) (DaemonConfig, error) {
+	return ReadDaemonConfigContext(context.Background(), conn)
+}
+
+// ReadDaemonConfigContext is like ReadDaemonConfig but honors ctx
+func ReadDaemonConfigContext(ctx context.Context, conn synology.Executor) (DaemonConfig, error) {
+	cfg, _, err := readDaemonConfig(ctx, conn)
+	return cfg, err
+}
+
+// readDaemonConfig reads the configuration and reports whether the file exists
+func readDaemonConfig(ctx context.Context, conn synology.Executor) (DaemonConfig, bool, error) {
+	output, err := retry(ctx, conn, func() (string, error) {
+		return conn.ExecuteCommandContext(ctx, synology.ShellJoin("cat", synology.ConfigPath))
+	})
+	if errors.Is(err, synology.ErrNotFound) {
+		return DaemonConfig{}, false, nil
+	}
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "failed to read %s", synology.ConfigPath)
+	}
+
+	cfg, err := ParseDaemonConfig([]byte(output))
+	if err != nil {
+		return nil, true, err
+	}
+	return cfg, true, nil
+}
+
+// ParseDaemonConfig parses and validates daemon configuration JSON. Empty
+// input is an empty configuration.
+func ParseDaemonConfig(data []byte) (DaemonConfig, error) {
+	cfg := DaemonConfig{}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return cfg, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("invalid daemon configuration: %w", err)
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("invalid daemon configuration: unexpected data after the JSON object")
+	}
+	return cfg, cfg.Validate()
+}
+
+// Validate checks the kinds of the settings dockerd would reject at startup,
+// such as a registry mirror that is not a URL
+func (c DaemonConfig) Validate() error {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		kind, ok := daemonConfigKinds[key]
+		if !ok {
+			continue
+		}
+		if err := validateDaemonValue(kind, c[key]); err != nil {
+			return fmt.Errorf("invalid daemon configuration: %s %w", key, err)
+		}
+	}
+	return nil
+}
+
+func validateDaemonValue(kind string, value any) error {
+	switch kind {
+	case "string", "path", "log level":
+		s, ok := value.(string)
+		switch {
+		case !ok:
+			return fmt.Errorf("must be a string")
+		case kind == "path" && !path.IsAbs(s):
+			return fmt.Errorf("must be an absolute path")
+		case kind == "log level" && !strings.Contains(" debug info warn error fatal ", " "+s+" "):
+			return fmt.Errorf("must be one of debug, info, warn, error or fatal")
+		}
+	case "bool":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("must be true or false")
+		}
+	case "count":
+		n, ok := value.(json.Number)
+		if i, err := n.Int64(); !ok || err != nil || i < 0 {
+			return fmt.Errorf("must be a whole number of at least 0")
+		}
+	case "strings", "urls":
+		items, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("must be a list")
+		}
+		for _, item := range items {
+			s, ok := item.(string)
+			if !ok {
+				return fmt.Errorf("must be a list of strings")
+			}
+			if kind == "urls" {
+				u, err := url.Parse(s)
+				if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+					return fmt.Errorf("entry %q must be an http or https URL", s)
+				}
+			}
+		}
+	case "string map":
+		entries, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("must be an object")
+		}
+		for name, entry := range entries {
+			if _, ok := entry.(string); !ok {
+				return fmt.Errorf("%s must be a string, such as \"10m\"", name)
+			}
+		}
+	}
+	return nil
+}
+
+// Set sets key to value, which is parsed as JSON when it is valid JSON and
+// taken as a string otherwise. A comma-separated string sets a list setting,
+// such as registry-mirrors, and null removes the key.
+func (c DaemonConfig) Set(key, value string) error {
+	if key == "" {
+		return fmt.Errorf("setting name is empty")
+	}
+
+	var parsed any
+	decoder := json.NewDecoder(strings.NewReader(value))
+	decoder.UseNumber()
+	if err := decoder.Decode(&parsed); err != nil || decoder.More() {
+		parsed = value
+		if kind := daemonConfigKinds[key]; kind == "strings" || kind == "urls" {
+			var items []any
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+			parsed = items
+		}
+	}
+
+	if parsed == nil {
+		delete(c, key)
+		return nil
+	}
+	if kind, ok := daemonConfigKinds[key]; ok {
+		if err := validateDaemonValue(kind, parsed); err != nil {
+			return fmt.Errorf("%s %w", key, err)
+		}
+	}
+	c[key] = parsed
+	return nil
+}
+
+// Marshal returns the configuration as indented JSON
+func (c DaemonConfig) Marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// ApplyDaemonConfig replaces the Container Manager daemon configuration with
+// cfg and restarts the daemon. The previous configuration is kept in a
+// timestamped backup beside it, and restored, with another restart, if the
+// daemon does not come back healthy. The SSH user needs sudo without a
+// password prompt.
+func ApplyDaemonConfig(conn synology.Executor, cfg DaemonConfig, opts *DaemonConfigOptions) (*DaemonConfigResult, error) {
+	return ApplyDaemonConfigContext(context.Background(), conn, cfg, opts)
+}
+
+// ApplyDaemonConfigContext is like ApplyDaemonConfig but honors ctx
+func ApplyDaemonConfigContext(ctx context.Context, conn synology.Executor, cfg DaemonConfig, opts *DaemonConfigOptions) (*DaemonConfigResult, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	data, err := cfg.Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode daemon configuration: %w", err)
+	}
+
+	// Back up the current configuration
+	_, exists, err := readDaemonConfig(ctx, conn)
+	if err != nil && !exists {
+		return nil, err
+	}
+	result := &DaemonConfigResult{}
+	if exists {
+		result.Backup = fmt.Sprintf("%s.%s.bak", synology.ConfigPath, time.Now().Format("20060102-150405"))
+		fmt.Printf("Backing up %s to %s...\n", synology.ConfigPath, result.Backup)
+		if _, err := conn.ExecuteCommandContext(ctx, synology.ShellJoin("sudo", "cp", "-p", synology.ConfigPath, result.Backup)); err != nil {
+			return nil, errors.Wrap(err, "failed to back up the daemon configuration")
+		}
+	}
+
+	fmt.Printf("Writing %s...\n", synology.ConfigPath)
+	err = conn.RunSessionContext(ctx, synology.ShellJoin("sudo", "tee", synology.ConfigPath), &synology.SessionOptions{Stdin: bytes.NewReader(data)})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to write the daemon configuration")
+	}
+
+	version, restartErr := restartDaemon(ctx, conn, opts)
+	if restartErr == nil {
+		result.DockerVersion = version
+		return result, nil
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	// Roll back to the previous configuration
+	fmt.Printf("Container Manager did not come back healthy (%v), restoring the previous configuration...\n", restartErr)
+	restore := synology.ShellJoin("sudo", "rm", "-f", synology.ConfigPath)
+	if exists {
+		restore = synology.ShellJoin("sudo", "cp", "-p", result.Backup, synology.ConfigPath)
+	}
+	if _, err := conn.ExecuteCommandContext(ctx, restore); err != nil {
+		return nil, errors.Wrapf(err, "daemon unhealthy after the change (%v) and failed to restore the previous configuration", restartErr)
+	}
+	if _, err := restartDaemon(ctx, conn, opts); err != nil {
+		return nil, errors.Wrapf(err, "daemon unhealthy after the change (%v) and after restoring the previous configuration", restartErr)
+	}
+	return nil, fmt.Errorf("daemon unhealthy after the change, previous configuration restored: %w", restartErr)
+}
+
+// restartDaemon restarts Container Manager and waits for the daemon to
+// answer, returning its version
+func restartDaemon(ctx context.Context, conn synology.Executor, opts *DaemonConfigOptions) (string, error) {
+	timeout, interval := DefaultDaemonHealthTimeout, 2*time.Second
+	if opts != nil && opts.HealthTimeout > 0 {
+		timeout = opts.HealthTimeout
+	}
+	if opts != nil && opts.HealthInterval > 0 {
+		interval = opts.HealthInterval
+	}
+
+	fmt.Printf("Restarting %s...\n", synology.ServiceName)
+	if _, err := conn.ExecuteCommandContext(ctx, synology.RestartCommand); err != nil {
+		return "", errors.Wrapf(err, "failed to restart %s", synology.ServiceName)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		output, err := conn.ExecuteDockerCommandContext(ctx, []string{"version", "--format", "{{.Server.Version}}"})
+		if err == nil {
+			return strings.TrimSpace(output), nil
+		}
+		if time.Now().After(deadline) {
+			return "", errors.Wrapf(err, "daemon not answering after %s", timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
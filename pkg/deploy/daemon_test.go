@@ -0,0 +1,176 @@
+package deploy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scttfrdmn/syno-docker/pkg/synology"
+	"github.com/scttfrdmn/syno-docker/pkg/synology/synologytest"
+)
+
+func TestParseDaemonConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"empty", "", ""},
+		{"valid", `{"registry-mirrors": ["https://mirror.gcr.io"], "log-opts": {"max-size": "10m"}, "max-concurrent-downloads": 3, "custom": [1]}`, ""},
+		{"not an object", `["a"]`, "invalid daemon configuration"},
+		{"trailing data", `{} {}`, "unexpected data"},
+		{"mirror not a URL", `{"registry-mirrors": ["mirror.gcr.io"]}`, "registry-mirrors entry \"mirror.gcr.io\" must be an http or https URL"},
+		{"mirrors not a list", `{"registry-mirrors": "https://mirror.gcr.io"}`, "registry-mirrors must be a list"},
+		{"log opt number", `{"log-opts": {"max-file": 3}}`, "log-opts max-file must be a string"},
+		{"relative data root", `{"data-root": "docker"}`, "data-root must be an absolute path"},
+		{"negative count", `{"max-concurrent-uploads": -1}`, "must be a whole number"},
+		{"bad log level", `{"log-level": "verbose"}`, "log-level must be one of"},
+		{"bool as string", `{"live-restore": "true"}`, "live-restore must be true or false"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseDaemonConfig([]byte(tt.data))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ParseDaemonConfig() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseDaemonConfig() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDaemonConfigSet(t *testing.T) {
+	tests := []struct {
+		key     string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"log-driver", "local", `"log-driver": "local"`, false},
+		{"debug", "true", `"debug": true`, false},
+		{"max-concurrent-downloads", "5", `"max-concurrent-downloads": 5`, false},
+		{"registry-mirrors", "https://a.example, https://b.example", `"https://b.example"`, false},
+		{"insecure-registries", `["nas.local:5000"]`, `"nas.local:5000"`, false},
+		{"log-opts", `{"max-size": "10m"}`, `"max-size": "10m"`, false},
+		{"debug", "yes", "", true},
+		{"registry-mirrors", "mirror.gcr.io", "", true},
+		{"", "x", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.value, func(t *testing.T) {
+			cfg := DaemonConfig{}
+			err := cfg.Set(tt.key, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Set() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			data, _ := cfg.Marshal()
+			if !strings.Contains(string(data), tt.want) {
+				t.Errorf("Expected %s in:\n%s", tt.want, data)
+			}
+		})
+	}
+
+	cfg := DaemonConfig{"debug": true}
+	if err := cfg.Set("debug", "null"); err != nil || len(cfg) != 0 {
+		t.Errorf("Expected null to remove the key, got %v, %v", cfg, err)
+	}
+}
+
+// daemonTestServer returns a server whose daemon only starts when the
+// configuration file does not set log-driver to "broken"
+func daemonTestServer(t *testing.T) *synologytest.SSHServer {
+	server := synologytest.NewSSHServer(t, nil)
+	server.Systemctl = func(args []string) int {
+		data, _ := os.ReadFile(server.Path(synology.ConfigPath))
+		broken := strings.Contains(string(data), `"broken"`)
+		server.Docker.SetRunning(!broken)
+		if broken {
+			return 1
+		}
+		return 0
+	}
+	return server
+}
+
+func TestApplyDaemonConfig(t *testing.T) {
+	server := daemonTestServer(t)
+	configFile := server.Path(synology.ConfigPath)
+	if err := os.MkdirAll(filepath.Dir(configFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	original := `{"log-driver": "db", "registry-mirrors": []}`
+	if err := os.WriteFile(configFile, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+	conn := connectTestServer(t, server)
+	opts := &DaemonConfigOptions{HealthTimeout: 50 * time.Millisecond, HealthInterval: 10 * time.Millisecond}
+
+	cfg, err := ReadDaemonConfig(conn)
+	if err != nil || cfg["log-driver"] != "db" {
+		t.Fatalf("ReadDaemonConfig() = %v, %v", cfg, err)
+	}
+	if err := cfg.Set("registry-mirrors", "https://mirror.gcr.io"); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ApplyDaemonConfig(conn, cfg, opts)
+	if err != nil {
+		t.Fatalf("ApplyDaemonConfig failed: %v", err)
+	}
+	if result.DockerVersion != synologytest.FakeDockerVersion {
+		t.Errorf("Expected the restarted daemon's version, got %q", result.DockerVersion)
+	}
+	if backup, err := os.ReadFile(server.Path(result.Backup)); err != nil || string(backup) != original {
+		t.Errorf("Expected the original configuration in %s, got %q, %v", result.Backup, backup, err)
+	}
+	if data, _ := os.ReadFile(configFile); !strings.Contains(string(data), "https://mirror.gcr.io") {
+		t.Errorf("Expected the new configuration, got %s", data)
+	}
+
+	// A change the daemon does not start with is rolled back
+	applied, _ := os.ReadFile(configFile)
+	cfg["log-driver"] = "broken"
+	_, err = ApplyDaemonConfig(conn, cfg, opts)
+	if err == nil || !strings.Contains(err.Error(), "previous configuration restored") {
+		t.Fatalf("Expected a rollback, got %v", err)
+	}
+	if data, _ := os.ReadFile(configFile); string(data) != string(applied) {
+		t.Errorf("Expected the previous configuration back, got %s", data)
+	}
+	if _, err := conn.ExecuteDockerCommand([]string{"version"}); err != nil {
+		t.Errorf("Expected the daemon to be running again, got %v", err)
+	}
+}
+
+func TestApplyDaemonConfigWithoutFile(t *testing.T) {
+	server := daemonTestServer(t)
+	if err := os.MkdirAll(filepath.Dir(server.Path(synology.ConfigPath)), 0755); err != nil {
+		t.Fatal(err)
+	}
+	conn := connectTestServer(t, server)
+	opts := &DaemonConfigOptions{HealthTimeout: 50 * time.Millisecond, HealthInterval: 10 * time.Millisecond}
+
+	cfg, err := ReadDaemonConfig(conn)
+	if err != nil || len(cfg) != 0 {
+		t.Fatalf("Expected an empty configuration, got %v, %v", cfg, err)
+	}
+
+	cfg["log-driver"] = "broken"
+	if _, err := ApplyDaemonConfig(conn, cfg, opts); err == nil {
+		t.Fatal("Expected a rollback error")
+	}
+	if _, err := os.Stat(server.Path(synology.ConfigPath)); !os.IsNotExist(err) {
+		t.Errorf("Expected the new file to be removed, got %v", err)
+	}
+}
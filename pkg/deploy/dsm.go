@@ -0,0 +1,148 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/scttfrdmn/syno-docker/pkg/dsm"
+)
+
+// listContainersDSM lists containers through the DSM Web API
+func listContainersDSM(ctx context.Context, client *dsm.Client, all bool) ([]ContainerInfo, error) {
+	dsmContainers, err := client.ListContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	containers := []ContainerInfo{}
+	for _, c := range dsmContainers {
+		if !all && !c.Running() {
+			continue
+		}
+		info := ContainerInfo{
+			ID:     shortID(c.ID),
+			Name:   c.Name,
+			Image:  c.Image,
+			Status: c.UpStatus,
+		}
+		if info.Status == "" {
+			info.Status = c.Status
+		}
+		for _, port := range c.PortBindings {
+			info.Ports = append(info.Ports, fmt.Sprintf("0.0.0.0:%d->%d/%s", port.HostPort, port.ContainerPort, port.Type))
+		}
+		containers = append(containers, info)
+	}
+
+	return containers, nil
+}
+
+// resolveContainerDSM returns the name of the container nameOrID, which
+// may also be an ID or ID prefix, as the DSM Web API addresses containers
+// by name
+func resolveContainerDSM(ctx context.Context, client *dsm.Client, nameOrID string) (string, error) {
+	containers, err := client.ListContainers(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var matches []string
+	for _, c := range containers {
+		if c.Name == strings.TrimPrefix(nameOrID, "/") || c.ID == nameOrID {
+			return c.Name, nil
+		}
+		if strings.HasPrefix(c.ID, nameOrID) {
+			matches = append(matches, c.Name)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no such container: %s", nameOrID)
+	case 1:
+		return matches[0], nil
+	}
+	return "", fmt.Errorf("multiple containers match ID prefix %s: %s", nameOrID, strings.Join(matches, ", "))
+}
+
+// containerLogsDSM reads container logs through the DSM Web API, applying
+// tail, since and timestamps as docker logs does
+func containerLogsDSM(ctx context.Context, client *dsm.Client, nameOrID, tail, since string, timestamps bool) (string, error) {
+	name, err := resolveContainerDSM(ctx, client, nameOrID)
+	if err != nil {
+		return "", err
+	}
+
+	opts := &dsm.LogsOptions{}
+	if tail != "all" && tail != "" {
+		limit, err := strconv.Atoi(tail)
+		if err != nil || limit < 0 {
+			return "", fmt.Errorf("invalid tail value %q", tail)
+		}
+		if limit == 0 {
+			return "", nil
+		}
+		opts.Limit, opts.Newest = limit, true
+	}
+
+	var cutoff time.Time
+	if since != "" {
+		if cutoff, err = parseSince(since, time.Now()); err != nil {
+			return "", err
+		}
+	}
+
+	entries, err := client.ContainerLogs(ctx, name, opts)
+	if err != nil {
+		return "", err
+	}
+	if opts.Newest {
+		for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+			entries[i], entries[j] = entries[j], entries[i]
+		}
+	}
+
+	var output strings.Builder
+	for _, entry := range entries {
+		if !cutoff.IsZero() {
+			created, err := time.Parse(time.RFC3339Nano, entry.Created)
+			if err == nil && created.Before(cutoff) {
+				continue
+			}
+		}
+		if timestamps {
+			output.WriteString(entry.Created + " ")
+		}
+		output.WriteString(strings.TrimSuffix(entry.Text, "\n") + "\n")
+	}
+	return output.String(), nil
+}
+
+// parseSince parses a docker logs --since value: a duration before now, an
+// RFC 3339 time or date, or Unix seconds
+func parseSince(since string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(since); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, since); err == nil {
+			return t, nil
+		}
+	}
+	if seconds, err := strconv.ParseFloat(since, 64); err == nil {
+		return time.Unix(0, int64(seconds*float64(time.Second))), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid since value %q", since)
+}
+
+// manageContainerDSM resolves nameOrID and applies action, such as
+// client.StartContainer, to the container
+func manageContainerDSM(ctx context.Context, client *dsm.Client, nameOrID string, action func(context.Context, string) error) error {
+	name, err := resolveContainerDSM(ctx, client, nameOrID)
+	if err != nil {
+		return err
+	}
+	return action(ctx, name)
+}
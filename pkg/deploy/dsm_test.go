@@ -0,0 +1,158 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scttfrdmn/syno-docker/pkg/config"
+	"github.com/scttfrdmn/syno-docker/pkg/dsm/dsmtest"
+	"github.com/scttfrdmn/syno-docker/pkg/synology"
+)
+
+// connectDSMTestServer returns a connection to server over the DSM Web API
+func connectDSMTestServer(t *testing.T, server *dsmtest.Server) *synology.Connection {
+	t.Setenv(synology.PasswordEnv, server.Password)
+	cfg := config.New()
+	cfg.Host = "127.0.0.1"
+	cfg.User = server.Account
+	cfg.Transport = config.TransportDSM
+	cfg.DSM.URL = server.URL
+	cfg.DSM.InsecureSkipVerify = true
+
+	conn := synology.NewConnection(cfg)
+	if err := conn.Connect(); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestDSMListContainers(t *testing.T) {
+	server := dsmtest.NewServer(t)
+	server.AddContainer(dsmtest.FakeContainer{ID: "0123456789abcdef", Name: "web", Image: "nginx:latest", Running: true, Ports: []string{"8080:80/tcp"}})
+	server.AddContainer(dsmtest.FakeContainer{Name: "db", Image: "postgres:16"})
+	conn := connectDSMTestServer(t, server)
+
+	running, err := ListContainers(conn, false)
+	if err != nil {
+		t.Fatalf("ListContainers failed: %v", err)
+	}
+	want := ContainerInfo{ID: "0123456789ab", Name: "web", Image: "nginx:latest", Status: "Up", Ports: []string{"0.0.0.0:8080->80/tcp"}}
+	if len(running) != 1 || running[0].ID != want.ID || running[0].Status != want.Status || strings.Join(running[0].Ports, ",") != want.Ports[0] {
+		t.Errorf("ListContainers() = %+v, want [%+v]", running, want)
+	}
+
+	all, err := ListContainers(conn, true)
+	if err != nil || len(all) != 2 {
+		t.Errorf("Expected both containers with all, got %+v, %v", all, err)
+	}
+
+	// An expired session is renewed by logging in again
+	server.ExpireSessions()
+	if _, err := ListContainersContext(context.Background(), conn, true); err != nil {
+		t.Errorf("Expected a new session after expiry, got %v", err)
+	}
+}
+
+func TestDSMContainerLifecycle(t *testing.T) {
+	server := dsmtest.NewServer(t)
+	server.AddContainer(dsmtest.FakeContainer{ID: "0123456789abcdef", Name: "web", Image: "nginx:latest"})
+	server.AddContainer(dsmtest.FakeContainer{ID: "0123ffffffffffff", Name: "db", Image: "postgres:16"})
+	conn := connectDSMTestServer(t, server)
+
+	if err := StartContainer(conn, "web"); err != nil {
+		t.Fatalf("StartContainer failed: %v", err)
+	}
+	if err := StopContainer(conn, "01234567", 10); err != nil {
+		t.Fatalf("StopContainer by ID prefix failed: %v", err)
+	}
+	if c, _ := server.Container("web"); c.Running {
+		t.Error("Expected web to be stopped")
+	}
+	if err := RestartContainer(conn, "db", 0); err != nil {
+		t.Fatalf("RestartContainer failed: %v", err)
+	}
+	if c, _ := server.Container("db"); !c.Running {
+		t.Error("Expected db to be running")
+	}
+
+	if err := StartContainer(conn, "0123"); err == nil || !strings.Contains(err.Error(), "multiple containers") {
+		t.Errorf("Expected an ambiguous prefix error, got %v", err)
+	}
+	if err := StartContainer(conn, "missing"); !errors.Is(err, synology.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if err := RemoveContainer(conn, "web", false); !errors.Is(err, synology.ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported for rm, got %v", err)
+	}
+}
+
+func TestDSMContainerLogs(t *testing.T) {
+	server := dsmtest.NewServer(t)
+	now := time.Now().UTC()
+	server.AddContainer(dsmtest.FakeContainer{Name: "web", Image: "nginx:latest", Logs: []dsmtest.FakeLog{
+		{Time: now.Add(-2 * time.Hour), Stream: "stdout", Text: "starting\n"},
+		{Time: now.Add(-30 * time.Minute), Stream: "stderr", Text: "warning\n"},
+		{Time: now.Add(-time.Minute), Stream: "stdout", Text: "ready\n"},
+	}})
+	conn := connectDSMTestServer(t, server)
+
+	tests := []struct {
+		name  string
+		tail  string
+		since string
+		want  string
+	}{
+		{"all", "all", "", "starting\nwarning\nready\n"},
+		{"tail", "2", "", "warning\nready\n"},
+		{"none", "0", "", ""},
+		{"since", "all", "1h", "warning\nready\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetContainerLogs(conn, "web", tt.tail, tt.since, false)
+			if err != nil {
+				t.Fatalf("GetContainerLogs failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("GetContainerLogs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	got, err := GetContainerLogs(conn, "web", "1", "", true)
+	if want := now.Add(-time.Minute).Format(time.RFC3339Nano) + " ready\n"; err != nil || got != want {
+		t.Errorf("Expected a timestamped line %q, got %q, %v", want, got, err)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		since   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"10m", now.Add(-10 * time.Minute), false},
+		{"2024-05-01T10:30:00Z", time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC), false},
+		{"2024-04-30", time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC), false},
+		{"1714564800", time.Unix(1714564800, 0), false},
+		{"yesterday", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.since, func(t *testing.T) {
+			got, err := parseSince(tt.since, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSince() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parseSince() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
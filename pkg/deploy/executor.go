@@ -0,0 +1,59 @@
+package deploy
+
+import (
+	"context"
+
+	"github.com/docker/docker/client"
+
+	"github.com/scttfrdmn/syno-docker/pkg/dsm"
+	"github.com/scttfrdmn/syno-docker/pkg/synology"
+)
+
+// dockerClient returns the Docker Engine API client of conn, or nil when conn
+// has none and the docker CLI must be used
+func dockerClient(conn synology.Executor) *client.Client {
+	if provider, ok := conn.(synology.DockerClientProvider); ok {
+		return provider.GetDockerClient()
+	}
+	return nil
+}
+
+// dsmClient returns the DSM Web API client of conn, or nil when conn has
+// none and the Docker API or CLI must be used
+func dsmClient(conn synology.Executor) *dsm.Client {
+	if provider, ok := conn.(synology.DSMClientProvider); ok {
+		return provider.DSMClient()
+	}
+	return nil
+}
+
+// nasInfo returns what the NAS runs, or nil when conn can't tell, so
+// features are only gated on what is known
+func nasInfo(ctx context.Context, conn synology.Executor) *synology.NASInfo {
+	if provider, ok := conn.(synology.NASInfoProvider); ok {
+		if info, err := provider.NASInfo(ctx); err == nil {
+			return info
+		}
+	}
+	return nil
+}
+
+// retryOnDisconnect runs fn, repeating it after a reconnect if conn supports
+// reconnecting and the connection drops. fn must be safe to repeat.
+func retryOnDisconnect(ctx context.Context, conn synology.Executor, fn func() error) error {
+	if reconnector, ok := conn.(synology.Reconnector); ok {
+		return reconnector.Retry(ctx, fn)
+	}
+	return fn()
+}
+
+// retry is retryOnDisconnect for operations that return a result
+func retry[T any](ctx context.Context, conn synology.Executor, fn func() (T, error)) (T, error) {
+	var result T
+	err := retryOnDisconnect(ctx, conn, func() error {
+		var err error
+		result, err = fn()
+		return err
+	})
+	return result, err
+}
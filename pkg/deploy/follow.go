@@ -0,0 +1,115 @@
+package deploy
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"time"
+)
+
+// logCursor records the timestamp of the newest log line seen
+type logCursor struct {
+	mu     sync.Mutex
+	newest time.Time
+}
+
+func (c *logCursor) observe(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t.After(c.newest) {
+		c.newest = t
+	}
+}
+
+func (c *logCursor) latest() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.newest
+}
+
+// followLogsArgs builds the docker logs arguments, resuming just after the
+// cursor once a line has been seen. Timestamps are always requested so the
+// cursor can advance.
+func followLogsArgs(nameOrID, tail, since string, cursor *logCursor) []string {
+	args := []string{"logs", "--follow", "--timestamps"}
+
+	if last := cursor.latest(); !last.IsZero() {
+		// --since is inclusive, so skip past the last line already printed
+		args = append(args, "--since", last.Add(time.Nanosecond).Format(time.RFC3339Nano))
+	} else {
+		if tail != "all" && tail != "" {
+			args = append(args, "--tail", tail)
+		}
+		if since != "" {
+			args = append(args, "--since", since)
+		}
+	}
+
+	return append(args, nameOrID)
+}
+
+// timestampWriter passes docker logs --timestamps output to w line by line,
+// recording each line's timestamp in cursor. Unless keep is set, the
+// timestamp prefix is removed again before writing.
+type timestampWriter struct {
+	w       io.Writer
+	cursor  *logCursor
+	keep    bool
+	partial []byte
+}
+
+func newTimestampWriter(w io.Writer, cursor *logCursor, keep bool) *timestampWriter {
+	return &timestampWriter{w: w, cursor: cursor, keep: keep}
+}
+
+func (tw *timestampWriter) Write(p []byte) (int, error) {
+	tw.partial = append(tw.partial, p...)
+
+	for {
+		end := bytes.IndexByte(tw.partial, '\n')
+		if end == -1 {
+			break
+		}
+		line := tw.partial[:end+1]
+		if _, err := tw.w.Write(tw.strip(line)); err != nil {
+			return len(p), err
+		}
+		tw.partial = tw.partial[end+1:]
+	}
+
+	return len(p), nil
+}
+
+// Flush writes any incomplete final line
+func (tw *timestampWriter) Flush() error {
+	if len(tw.partial) == 0 {
+		return nil
+	}
+	_, err := tw.w.Write(tw.strip(tw.partial))
+	tw.partial = nil
+	return err
+}
+
+// Reset discards any incomplete line
+func (tw *timestampWriter) Reset() {
+	tw.partial = nil
+}
+
+// strip records the line's timestamp and removes it unless keep is set.
+// Lines without a timestamp, such as docker's own errors, pass through.
+func (tw *timestampWriter) strip(line []byte) []byte {
+	space := bytes.IndexByte(line, ' ')
+	if space == -1 {
+		return line
+	}
+	t, err := time.Parse(time.RFC3339Nano, string(line[:space]))
+	if err != nil {
+		return line
+	}
+
+	tw.cursor.observe(t)
+	if tw.keep {
+		return line
+	}
+	return line[space+1:]
+}
@@ -0,0 +1,99 @@
+package deploy
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimestampWriter(t *testing.T) {
+	tests := []struct {
+		name     string
+		keep     bool
+		writes   []string
+		expected string
+		last     string
+	}{
+		{
+			name:     "strips timestamps",
+			writes:   []string{"2024-05-01T10:00:00.000000001Z first\n2024-05-01T10:00:01.5Z second\n"},
+			expected: "first\nsecond\n",
+			last:     "2024-05-01T10:00:01.5Z",
+		},
+		{
+			name:     "keeps timestamps",
+			keep:     true,
+			writes:   []string{"2024-05-01T10:00:00Z first\n"},
+			expected: "2024-05-01T10:00:00Z first\n",
+			last:     "2024-05-01T10:00:00Z",
+		},
+		{
+			name:     "joins split lines",
+			writes:   []string{"2024-05-01T10:00:00Z fir", "st\n2024-05-01T10:00:02Z sec", "ond\n"},
+			expected: "first\nsecond\n",
+			last:     "2024-05-01T10:00:02Z",
+		},
+		{
+			name:     "passes lines without timestamp",
+			writes:   []string{"Error response from daemon: No such container: web\n"},
+			expected: "Error response from daemon: No such container: web\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			cursor := &logCursor{}
+			tw := newTimestampWriter(&out, cursor, tt.keep)
+			for _, w := range tt.writes {
+				if _, err := tw.Write([]byte(w)); err != nil {
+					t.Fatalf("Write failed: %v", err)
+				}
+			}
+
+			if out.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, out.String())
+			}
+
+			var expectedLast time.Time
+			if tt.last != "" {
+				expectedLast, _ = time.Parse(time.RFC3339Nano, tt.last)
+			}
+			if !cursor.latest().Equal(expectedLast) {
+				t.Errorf("Expected cursor %v, got %v", expectedLast, cursor.latest())
+			}
+		})
+	}
+}
+
+func TestTimestampWriterResetAndFlush(t *testing.T) {
+	var out bytes.Buffer
+	tw := newTimestampWriter(&out, &logCursor{}, false)
+
+	tw.Write([]byte("2024-05-01T10:00:00Z cut off"))
+	tw.Reset()
+	tw.Write([]byte("2024-05-01T10:00:00Z no newline"))
+	tw.Flush()
+
+	if out.String() != "no newline" {
+		t.Errorf("Expected only the flushed line, got %q", out.String())
+	}
+}
+
+func TestFollowLogsArgs(t *testing.T) {
+	cursor := &logCursor{}
+
+	args := strings.Join(followLogsArgs("web", "100", "1h", cursor), " ")
+	if args != "logs --follow --timestamps --tail 100 --since 1h web" {
+		t.Errorf("Unexpected initial args: %s", args)
+	}
+
+	last, _ := time.Parse(time.RFC3339Nano, "2024-05-01T10:00:00.5Z")
+	cursor.observe(last)
+
+	args = strings.Join(followLogsArgs("web", "100", "1h", cursor), " ")
+	if args != "logs --follow --timestamps --since 2024-05-01T10:00:00.500000001Z web" {
+		t.Errorf("Unexpected resume args: %s", args)
+	}
+}
@@ -0,0 +1,171 @@
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/scttfrdmn/syno-docker/pkg/synology"
+)
+
+// ForwardOptions defines options for forwarding local connections
+type ForwardOptions struct {
+	// Log, when set, receives a line for each connection and each failure
+	// to reach the target
+	Log io.Writer
+}
+
+// ParseForwardTarget parses a CONTAINER:PORT argument
+func ParseForwardTarget(arg string) (string, int, error) {
+	i := strings.LastIndex(arg, ":")
+	if i <= 0 {
+		return "", 0, fmt.Errorf("invalid target %q, expected CONTAINER:PORT", arg)
+	}
+	port, err := strconv.Atoi(arg[i+1:])
+	if err != nil || port < 1 || port > 65535 {
+		return "", 0, fmt.Errorf("invalid port in %q", arg)
+	}
+	return arg[:i], port, nil
+}
+
+// ContainerAddress returns the host:port at which the NAS reaches port of
+// a running container: its IP address on the first of its networks that
+// has one, or the NAS loopback for containers on the host network. Ports
+// don't need to be published.
+func ContainerAddress(conn synology.Executor, containerName string, port int) (string, error) {
+	return ContainerAddressContext(context.Background(), conn, containerName, port)
+}
+
+// ContainerAddressContext is like ContainerAddress but honors ctx
+func ContainerAddressContext(ctx context.Context, conn synology.Executor, containerName string, port int) (string, error) {
+	output, err := InspectObjectContext(ctx, conn, containerName, &InspectOptions{Type: "container", Format: "{{json .}}"})
+	if err != nil {
+		return "", err
+	}
+
+	var container struct {
+		State struct {
+			Running bool
+		}
+		NetworkSettings struct {
+			Networks map[string]struct {
+				IPAddress string
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(output), &container); err != nil {
+		return "", fmt.Errorf("failed to parse inspect output for %s: %w", containerName, err)
+	}
+	if !container.State.Running {
+		return "", fmt.Errorf("container %s is not running", containerName)
+	}
+
+	networks := container.NetworkSettings.Networks
+	if _, ok := networks["host"]; ok {
+		return net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), nil
+	}
+
+	var names []string
+	for name := range networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if ip := networks[name].IPAddress; ip != "" {
+			return net.JoinHostPort(ip, strconv.Itoa(port)), nil
+		}
+	}
+	return "", fmt.Errorf("container %s has no IP address the NAS can reach", containerName)
+}
+
+// Forward accepts connections on listener and tunnels each one over the
+// SSH connection to addr on network ("tcp" or "unix") as seen from the
+// NAS, until ctx is done. conn must support tunnelling, as
+// *synology.Connection does; a dropped SSH connection is redialed for the
+// next connection.
+func Forward(ctx context.Context, conn synology.Executor, listener net.Listener, network, addr string, opts *ForwardOptions) error {
+	dialer, ok := conn.(synology.Dialer)
+	if !ok {
+		return fmt.Errorf("the connection does not support tunnelling")
+	}
+
+	logf := func(format string, args ...any) {
+		if opts.Log != nil {
+			fmt.Fprintf(opts.Log, format+"\n", args...)
+		}
+	}
+
+	stop := context.AfterFunc(ctx, func() { listener.Close() })
+	defer stop()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		local, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.Wrap(err, "failed to accept connection")
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer local.Close()
+
+			logf("Handling connection from %s", local.RemoteAddr())
+			var remote net.Conn
+			err := retryOnDisconnect(ctx, conn, func() error {
+				var err error
+				remote, err = dialer.DialContext(ctx, network, addr)
+				return err
+			})
+			if err != nil {
+				logf("Failed to connect to %s: %v", addr, err)
+				return
+			}
+			defer remote.Close()
+
+			// Closing both ends on ctx unblocks the copies when stopping
+			stopConn := context.AfterFunc(ctx, func() {
+				local.Close()
+				remote.Close()
+			})
+			defer stopConn()
+			pipe(local, remote)
+		}()
+	}
+}
+
+// pipe copies between a and b in both directions until both are done,
+// passing on half-closes so request-response protocols finish cleanly
+func pipe(a, b net.Conn) {
+	done := make(chan struct{})
+	go func() {
+		io.Copy(b, a)
+		closeWrite(b)
+		close(done)
+	}()
+	io.Copy(a, b)
+	closeWrite(a)
+	<-done
+}
+
+// closeWrite shuts down the writing side of c, or closes c when it can't
+// be half-closed
+func closeWrite(c net.Conn) {
+	if hc, ok := c.(interface{ CloseWrite() error }); ok {
+		hc.CloseWrite()
+		return
+	}
+	c.Close()
+}
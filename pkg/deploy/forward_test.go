@@ -0,0 +1,263 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/scttfrdmn/syno-docker/pkg/synology"
+	"github.com/scttfrdmn/syno-docker/pkg/synology/synologytest"
+)
+
+func TestParseForwardTarget(t *testing.T) {
+	tests := []struct {
+		arg       string
+		container string
+		port      int
+		wantErr   bool
+	}{
+		{"postgres:5432", "postgres", 5432, false},
+		{"my-app_web_1:80", "my-app_web_1", 80, false},
+		{"postgres", "", 0, true},
+		{":80", "", 0, true},
+		{"web:http", "", 0, true},
+		{"web:0", "", 0, true},
+		{"web:70000", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			container, port, err := ParseForwardTarget(tt.arg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseForwardTarget() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if container != tt.container || port != tt.port {
+				t.Errorf("ParseForwardTarget() = %s, %d, want %s, %d", container, port, tt.container, tt.port)
+			}
+		})
+	}
+}
+
+func TestContainerAddress(t *testing.T) {
+	docker := synologytest.NewFakeDocker()
+	docker.AddImage("nginx:latest")
+	server := synologytest.NewSSHServer(t, docker)
+	conn := connectTestServer(t, server)
+
+	for _, args := range [][]string{
+		{"run", "-d", "--name", "web", "nginx:latest"},
+		{"run", "-d", "--name", "proxy", "--network", "host", "nginx:latest"},
+		{"run", "-d", "--name", "stopped", "nginx:latest"},
+		{"stop", "stopped"},
+	} {
+		if _, err := conn.ExecuteDockerCommand(args); err != nil {
+			t.Fatalf("docker %s failed: %v", strings.Join(args, " "), err)
+		}
+	}
+
+	tests := []struct {
+		container string
+		want      string
+		wantErr   string
+	}{
+		{"web", "172.17.0.2:80", ""},
+		{"proxy", "127.0.0.1:80", ""},
+		{"stopped", "", "is not running"},
+		{"missing", "", "No such object"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.container, func(t *testing.T) {
+			got, err := ContainerAddress(conn, tt.container, 80)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ContainerAddress() = %s, %v, want %s", got, err, tt.want)
+			}
+		})
+	}
+}
+
+// startEcho starts a TCP server that echoes what it receives, standing in
+// for a container, and returns its address
+func startEcho(t *testing.T) string {
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { echo.Close() })
+	go func() {
+		for {
+			c, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				io.Copy(c, c)
+				c.Close()
+			}()
+		}
+	}()
+	return echo.Addr().String()
+}
+
+// exchange sends msg to addr and returns the reply
+func exchange(addr, msg string) (string, error) {
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		return "", err
+	}
+	defer c.Close()
+	fmt.Fprint(c, msg)
+	c.(*net.TCPConn).CloseWrite()
+	reply, err := io.ReadAll(c)
+	return string(reply), err
+}
+
+func TestForward(t *testing.T) {
+	echo := startEcho(t)
+
+	var mu sync.Mutex
+	var dialed []string
+	server := synologytest.NewSSHServer(t, nil)
+	server.Forward = func(network, addr string) (net.Conn, error) {
+		if network == "unix" {
+			return nil, fmt.Errorf("connection refused")
+		}
+		mu.Lock()
+		dialed = append(dialed, addr)
+		mu.Unlock()
+		return net.Dial("tcp", echo)
+	}
+	conn := connectTestServer(t, server)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Forward(ctx, conn, listener, "tcp", "172.17.0.2:80", &ForwardOptions{})
+	}()
+
+	for i := 0; i < 2; i++ {
+		msg := fmt.Sprintf("hello %d", i)
+		if reply, err := exchange(listener.Addr().String(), msg); err != nil || reply != msg {
+			t.Errorf("Expected the request echoed back, got %q, %v", reply, err)
+		}
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Expected Forward to stop cleanly, got %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Join(dialed, ",") != "172.17.0.2:80,172.17.0.2:80" {
+		t.Errorf("Expected a tunnel to the container per connection, got %v", dialed)
+	}
+}
+
+func TestForwardReconnect(t *testing.T) {
+	echo := startEcho(t)
+	server := synologytest.NewSSHServer(t, nil)
+	server.Forward = func(network, addr string) (net.Conn, error) {
+		return net.Dial("tcp", echo)
+	}
+	conn := connectTestServer(t, server)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go Forward(ctx, conn, listener, "tcp", "172.17.0.2:80", &ForwardOptions{})
+
+	// Both connections find the SSH connection dropped and reconnect it
+	// concurrently; run with -race to catch unsynchronized redials
+	server.DropConnections()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			msg := fmt.Sprintf("hello %d", i)
+			if reply, err := exchange(listener.Addr().String(), msg); err != nil || reply != msg {
+				t.Errorf("Expected the request echoed back after reconnecting, got %q, %v", reply, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestForwardDockerSocket(t *testing.T) {
+	// An HTTP server on a unix socket stands in for the Docker daemon
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	daemon, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go http.Serve(daemon, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "daemon %s", r.URL.Path)
+	}))
+	defer daemon.Close()
+
+	server := synologytest.NewSSHServer(t, nil)
+	server.Forward = func(network, addr string) (net.Conn, error) {
+		if network != "unix" || addr != synology.SocketPath {
+			return nil, fmt.Errorf("unexpected tunnel to %s %s", network, addr)
+		}
+		return net.Dial("unix", socket)
+	}
+	conn := connectTestServer(t, server)
+
+	proxy := filepath.Join(t.TempDir(), "proxy.sock")
+	listener, err := net.Listen("unix", proxy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go Forward(ctx, conn, listener, "unix", synology.SocketPath, &ForwardOptions{})
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return net.Dial("unix", proxy)
+		},
+	}}
+	resp, err := client.Get("http://docker/containers/json")
+	if err != nil {
+		t.Fatalf("Request through the proxy failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "daemon /containers/json" {
+		t.Errorf("Expected the daemon's response, got %q", body)
+	}
+}
+
+func TestForwardRefused(t *testing.T) {
+	server := synologytest.NewSSHServer(t, nil)
+	conn := connectTestServer(t, server)
+
+	if _, err := conn.DialContext(context.Background(), "tcp", "172.17.0.2:80"); err == nil || !strings.Contains(err.Error(), "AllowTcpForwarding") {
+		t.Errorf("Expected a hint about sshd forwarding settings, got %v", err)
+	}
+
+	if err := Forward(context.Background(), synologytest.New(), nil, "tcp", "172.17.0.2:80", &ForwardOptions{}); err == nil {
+		t.Error("Expected an error for an executor that can't tunnel")
+	}
+}
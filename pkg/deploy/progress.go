@@ -0,0 +1,60 @@
+package deploy
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/docker/go-units"
+)
+
+const (
+	// progressThreshold is the size from which file transfers report progress
+	progressThreshold = 8 << 20
+	// progressInterval limits how often the progress line is redrawn
+	progressInterval = 200 * time.Millisecond
+)
+
+// progressWriter counts the bytes written through it and redraws a single
+// progress line on out. total is zero when the size is not known.
+type progressWriter struct {
+	out     io.Writer
+	label   string
+	total   int64
+	written int64
+	started time.Time
+	drawn   time.Time
+	now     func() time.Time
+}
+
+func newProgressWriter(out io.Writer, label string, total int64) *progressWriter {
+	return &progressWriter{out: out, label: label, total: total, started: time.Now(), now: time.Now}
+}
+
+// Write counts p and redraws the progress line at most every progressInterval
+func (p *progressWriter) Write(b []byte) (int, error) {
+	p.written += int64(len(b))
+	if now := p.now(); now.Sub(p.drawn) >= progressInterval {
+		p.drawn = now
+		p.draw()
+	}
+	return len(b), nil
+}
+
+// Finish draws the final progress line and ends it
+func (p *progressWriter) Finish() {
+	p.draw()
+	fmt.Fprintln(p.out)
+}
+
+func (p *progressWriter) draw() {
+	line := fmt.Sprintf("%s  %s", p.label, units.HumanSize(float64(p.written)))
+	if p.total > 0 {
+		line = fmt.Sprintf("%s  %3d%%  %s / %s", p.label, p.written*100/p.total, units.HumanSize(float64(p.written)), units.HumanSize(float64(p.total)))
+	}
+	if elapsed := p.now().Sub(p.started).Seconds(); elapsed > 0 {
+		line += fmt.Sprintf("  %s/s", units.HumanSize(float64(p.written)/elapsed))
+	}
+	// Clear the rest of the previous, possibly longer, line
+	fmt.Fprintf(p.out, "\r%s\033[K", line)
+}
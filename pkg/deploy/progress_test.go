@@ -0,0 +1,52 @@
+package deploy
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgressWriter(t *testing.T) {
+	var out bytes.Buffer
+	start := time.Unix(1700000000, 0)
+	now := start
+
+	p := newProgressWriter(&out, "backup.tar", 4<<20)
+	p.started = start
+	p.now = func() time.Time { return now }
+
+	now = now.Add(time.Second)
+	p.Write(make([]byte, 1<<20))
+	if !strings.Contains(out.String(), "backup.tar   25%") {
+		t.Errorf("Expected 25%% progress, got %q", out.String())
+	}
+
+	// Writes within progressInterval do not redraw
+	out.Reset()
+	p.Write(make([]byte, 1<<20))
+	if out.Len() != 0 {
+		t.Errorf("Expected no redraw within the interval, got %q", out.String())
+	}
+
+	now = now.Add(time.Second)
+	p.Write(make([]byte, 2<<20))
+	p.Finish()
+	if !strings.Contains(out.String(), "100%") || !strings.HasSuffix(out.String(), "\n") {
+		t.Errorf("Expected a finished 100%% line, got %q", out.String())
+	}
+}
+
+func TestProgressWriterUnknownTotal(t *testing.T) {
+	var out bytes.Buffer
+	p := newProgressWriter(&out, "stream", 0)
+	p.Write([]byte("hello"))
+	p.Finish()
+
+	if strings.Contains(out.String(), "%") {
+		t.Errorf("Expected no percentage without a total, got %q", out.String())
+	}
+	if !strings.Contains(out.String(), "stream  5B") {
+		t.Errorf("Expected byte count, got %q", out.String())
+	}
+}
@@ -0,0 +1,156 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/scttfrdmn/syno-docker/pkg/synology"
+)
+
+// Labels docker compose puts on a project's containers, which Container
+// Manager uses to group them into a project
+const (
+	ProjectLabel         = "com.docker.compose.project"
+	ServiceLabel         = "com.docker.compose.service"
+	WorkingDirLabel      = "com.docker.compose.project.working_dir"
+	ConfigFilesLabel     = "com.docker.compose.project.config_files"
+	ContainerNumberLabel = "com.docker.compose.container-number"
+	OneoffLabel          = "com.docker.compose.oneoff"
+	// ManagedLabel marks containers deployed by syno-docker
+	ManagedLabel = "com.syno-docker.managed"
+)
+
+// ProjectFile is the compose file name in a Container Manager project folder
+const ProjectFile = "docker-compose.yml"
+
+// Project is a compose project on the NAS
+type Project struct {
+	Name       string
+	Dir        string
+	ConfigFile string
+	Containers []string
+	// Managed is true when syno-docker deployed every container of the
+	// project, and false for projects created in Container Manager
+	Managed bool
+}
+
+// FindProject returns the project name on the NAS, or nil if no container
+// belongs to it
+func FindProject(conn synology.Executor, name string) (*Project, error) {
+	return FindProjectContext(context.Background(), conn, name)
+}
+
+// FindProjectContext is like FindProject but honors ctx
+func FindProjectContext(ctx context.Context, conn synology.Executor, name string) (*Project, error) {
+	return retry(ctx, conn, func() (*Project, error) {
+		return findProject(ctx, conn, name)
+	})
+}
+
+func findProject(ctx context.Context, conn synology.Executor, name string) (*Project, error) {
+	output, err := conn.ExecuteDockerCommandContext(ctx, []string{
+		"ps", "-a", "--filter", "label=" + ProjectLabel + "=" + name, "--format", "{{.Names}}",
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list containers of project %s", name)
+	}
+	containers := strings.Fields(output)
+	if len(containers) == 0 {
+		return nil, nil
+	}
+	sort.Strings(containers)
+
+	args := append([]string{"inspect", "--type", "container", "--format", "{{json .Config.Labels}}"}, containers...)
+	output, err = conn.ExecuteDockerCommandContext(ctx, args)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to inspect containers of project %s", name)
+	}
+
+	project := &Project{Name: name, Containers: containers, Managed: true}
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		var labels map[string]string
+		if err := json.Unmarshal([]byte(line), &labels); err != nil {
+			return nil, fmt.Errorf("failed to parse container labels: %w", err)
+		}
+		if labels[ManagedLabel] != "true" {
+			project.Managed = false
+		}
+		if project.Dir == "" {
+			project.Dir = labels[WorkingDirLabel]
+			project.ConfigFile, _, _ = strings.Cut(labels[ConfigFilesLabel], ",")
+		}
+	}
+	return project, nil
+}
+
+// mergeLabels returns defaults with labels set over them
+func mergeLabels(defaults, labels map[string]string) map[string]string {
+	if len(defaults) == 0 {
+		return labels
+	}
+	merged := make(map[string]string, len(defaults)+len(labels))
+	for key, value := range defaults {
+		merged[key] = value
+	}
+	for key, value := range labels {
+		merged[key] = value
+	}
+	return merged
+}
+
+// projectLabels returns the labels of a service's container: the service's
+// own, then the compose labels that place it in the project
+func projectLabels(project, service, dir, configFile string, serviceLabels map[string]string) []string {
+	keys := make([]string, 0, len(serviceLabels))
+	for key := range serviceLabels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var labels []string
+	for _, key := range keys {
+		labels = append(labels, key+"="+serviceLabels[key])
+	}
+	return append(labels,
+		ProjectLabel+"="+project,
+		ServiceLabel+"="+service,
+		WorkingDirLabel+"="+dir,
+		ConfigFilesLabel+"="+configFile,
+		ContainerNumberLabel+"=1",
+		OneoffLabel+"=False",
+		ManagedLabel+"=true",
+	)
+}
+
+// resolveProjectVolumes resolves bind mounts relative to the compose file,
+// such as ./data:/data, against the project directory on the NAS. Named
+// volumes and absolute paths are kept.
+func resolveProjectVolumes(volumes []string, dir string) []string {
+	resolved := make([]string, len(volumes))
+	for i, volume := range volumes {
+		source, rest, ok := strings.Cut(volume, ":")
+		if ok && (source == "." || source == ".." || strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../")) {
+			volume = path.Join(dir, source) + ":" + rest
+		}
+		resolved[i] = volume
+	}
+	return resolved
+}
+
+// uploadProjectFile writes data to file on the NAS, creating its directory
+// if needed
+func uploadProjectFile(ctx context.Context, conn synology.Executor, file string, data []byte) error {
+	cmd := synology.ShellJoin("mkdir", "-p", path.Dir(file)) + " && " + synology.ShellJoin("tee", file)
+	err := conn.RunSessionContext(ctx, cmd, &synology.SessionOptions{Stdin: bytes.NewReader(data)})
+	if err != nil {
+		return errors.Wrapf(err, "failed to upload %s", file)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package deploy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/scttfrdmn/syno-docker/pkg/synology/synologytest"
+)
+
+const projectCompose = `services:
+  web:
+    image: nginx:alpine
+    ports:
+      - "8080:80"
+    volumes:
+      - ./html:/usr/share/nginx/html
+      - cache:/var/cache/nginx
+    labels:
+      tier: front
+`
+
+func TestComposeProject(t *testing.T) {
+	local := t.TempDir()
+	writeTree(t, local, map[string]string{"compose.yaml": projectCompose, ".env": "TAG=1\n"})
+	server := synologytest.NewSSHServer(t, nil)
+	conn := connectTestServer(t, server)
+
+	opts := &ComposeOptions{ComposeFile: filepath.Join(local, "compose.yaml"), ProjectName: "site", EnvFile: filepath.Join(local, ".env")}
+	if err := Compose(conn, opts); err != nil {
+		t.Fatalf("Compose failed: %v", err)
+	}
+
+	data, err := os.ReadFile(server.Path("/volume1/docker/site/docker-compose.yml"))
+	if err != nil || string(data) != projectCompose {
+		t.Errorf("Expected the compose file in the project folder, got %q, %v", data, err)
+	}
+	if data, err := os.ReadFile(server.Path("/volume1/docker/site/.env")); err != nil || string(data) != "TAG=1\n" {
+		t.Errorf("Expected the env file as .env, got %q, %v", data, err)
+	}
+
+	web, ok := server.Docker.Container("site_web_1")
+	if !ok {
+		t.Fatal("Expected container site_web_1")
+	}
+	labels := strings.Join(web.Labels, "\n")
+	for _, want := range []string{
+		"tier=front",
+		ProjectLabel + "=site",
+		ServiceLabel + "=web",
+		WorkingDirLabel + "=/volume1/docker/site",
+		ConfigFilesLabel + "=/volume1/docker/site/docker-compose.yml",
+		ManagedLabel + "=true",
+	} {
+		if !strings.Contains(labels, want) {
+			t.Errorf("Expected label %s, got:\n%s", want, labels)
+		}
+	}
+	if strings.Join(web.Volumes, ",") != "/volume1/docker/site/html:/usr/share/nginx/html,cache:/var/cache/nginx" {
+		t.Errorf("Expected relative mounts in the project folder, got %v", web.Volumes)
+	}
+
+	project, err := FindProject(conn, "site")
+	if err != nil || project == nil || !project.Managed || project.Dir != "/volume1/docker/site" {
+		t.Fatalf("FindProject() = %+v, %v", project, err)
+	}
+
+	// Deploying again replaces the containers
+	if err := Compose(conn, opts); err != nil {
+		t.Fatalf("Redeploy failed: %v", err)
+	}
+	if n := len(server.Docker.Containers()); n != 1 {
+		t.Errorf("Expected the container to be replaced, got %d containers", n)
+	}
+}
+
+func TestComposeAdopt(t *testing.T) {
+	local := t.TempDir()
+	writeTree(t, local, map[string]string{"compose.yaml": projectCompose})
+	server := synologytest.NewSSHServer(t, nil)
+	conn := connectTestServer(t, server)
+
+	// A project created in Container Manager
+	if _, err := conn.ExecuteDockerCommand([]string{"run", "-d", "--name", "site-web-1",
+		"--label", ProjectLabel + "=site",
+		"--label", WorkingDirLabel + "=/volume2/apps/site",
+		"--label", ConfigFilesLabel + "=/volume2/apps/site/compose.yaml",
+		"nginx:alpine"}); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := &ComposeOptions{ComposeFile: filepath.Join(local, "compose.yaml"), ProjectName: "site"}
+	err := Compose(conn, opts)
+	if err == nil || !strings.Contains(err.Error(), "--adopt") {
+		t.Fatalf("Expected an existing project error, got %v", err)
+	}
+	if _, ok := server.Docker.Container("site-web-1"); !ok {
+		t.Fatal("Expected the project to be left alone without adopt")
+	}
+
+	opts.Adopt = true
+	if err := Compose(conn, opts); err != nil {
+		t.Fatalf("Compose with adopt failed: %v", err)
+	}
+	if _, ok := server.Docker.Container("site-web-1"); ok {
+		t.Error("Expected the adopted container to be removed")
+	}
+	if data, err := os.ReadFile(server.Path("/volume2/apps/site/compose.yaml")); err != nil || string(data) != projectCompose {
+		t.Errorf("Expected the project's compose file to be replaced, got %q, %v", data, err)
+	}
+	web, ok := server.Docker.Container("site_web_1")
+	if !ok || !strings.Contains(strings.Join(web.Labels, "\n"), WorkingDirLabel+"=/volume2/apps/site") {
+		t.Errorf("Expected the project folder to be kept, got %+v", web)
+	}
+}
+
+func TestResolveProjectVolumes(t *testing.T) {
+	tests := []struct {
+		volume string
+		want   string
+	}{
+		{"./data:/data", "/volume1/docker/app/data:/data"},
+		{".:/src:ro", "/volume1/docker/app:/src:ro"},
+		{"../shared:/shared", "/volume1/docker/shared:/shared"},
+		{"/volume1/media:/media", "/volume1/media:/media"},
+		{"db-data:/var/lib/postgresql/data", "db-data:/var/lib/postgresql/data"},
+		{"/tmp", "/tmp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.volume, func(t *testing.T) {
+			got := resolveProjectVolumes([]string{tt.volume}, "/volume1/docker/app")
+			if got[0] != tt.want {
+				t.Errorf("resolveProjectVolumes(%q) = %q, want %q", tt.volume, got[0], tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeLabels(t *testing.T) {
+	defaults := map[string]string{"team": "web", "tier": "nas"}
+	merged := mergeLabels(defaults, map[string]string{"tier": "db"})
+	if len(merged) != 2 || merged["team"] != "web" || merged["tier"] != "db" {
+		t.Errorf("Expected the service's labels over the defaults, got %v", merged)
+	}
+	if defaults["tier"] != "nas" {
+		t.Errorf("Expected the defaults to be unchanged, got %v", defaults)
+	}
+	if merged := mergeLabels(nil, map[string]string{"a": "b"}); len(merged) != 1 {
+		t.Errorf("Expected the service's labels, got %v", merged)
+	}
+}
@@ -0,0 +1,316 @@
+package deploy
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+	"github.com/pkg/errors"
+
+	"github.com/scttfrdmn/syno-docker/pkg/synology"
+)
+
+// LocalDocker is the part of the Docker Engine API that PushLocal reads
+// images from. *client.Client implements it.
+type LocalDocker interface {
+	ImageInspect(ctx context.Context, imageID string, opts ...client.ImageInspectOption) (image.InspectResponse, error)
+	ImageSave(ctx context.Context, imageIDs []string, opts ...client.ImageSaveOption) (io.ReadCloser, error)
+}
+
+// PushLocalOptions defines options for pushing local images
+type PushLocalOptions struct {
+	// Local is the local Docker daemon; when nil it is configured from the
+	// environment, such as DOCKER_HOST, like the docker CLI
+	Local LocalDocker
+	// Progress, when set, receives a progress line while the image is sent
+	Progress io.Writer
+}
+
+// PushLocalResult reports what PushLocal transferred
+type PushLocalResult struct {
+	Image string
+	// Layers is the number of layers in the image
+	Layers int
+	// Skipped is the number of layers left out because the NAS has them
+	Skipped int
+	// Bytes is the size of the archive sent to the NAS
+	Bytes int64
+	// SkippedBytes is the size of the layers left out
+	SkippedBytes int64
+	// Output is the docker load output listing the images
+	Output string
+}
+
+// Transferred returns the number of layers sent to the NAS
+func (r *PushLocalResult) Transferred() int {
+	return r.Layers - r.Skipped
+}
+
+// PushLocal copies an image from the local Docker daemon to the NAS without
+// a registry. Layers the NAS already has, with the same parent layers, are
+// left out of the archive sent to docker load.
+func PushLocal(conn synology.Executor, ref string, opts *PushLocalOptions) (*PushLocalResult, error) {
+	return PushLocalContext(context.Background(), conn, ref, opts)
+}
+
+// PushLocalContext is like PushLocal but honors ctx
+func PushLocalContext(ctx context.Context, conn synology.Executor, ref string, opts *PushLocalOptions) (*PushLocalResult, error) {
+	local := opts.Local
+	if local == nil {
+		cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure the local Docker client: %w", err)
+		}
+		defer cli.Close()
+		local = cli
+	}
+
+	info, err := local.ImageInspect(ctx, ref)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to inspect local image %s", ref)
+	}
+	if nas := nasInfo(ctx, conn); nas != nil && info.Architecture != "" && info.Architecture != nas.Architecture() {
+		return nil, fmt.Errorf("local image %s is for %s/%s but the NAS is %s; build it with --platform %s",
+			ref, info.Os, info.Architecture, nas.Platform(), nas.Platform())
+	}
+
+	chains, err := nasLayerChains(ctx, conn)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list the image layers on the NAS")
+	}
+	shared := sharedLayers(info.RootFS.Layers, chains)
+
+	// The archive is saved to a temporary file first, because manifest.json,
+	// which maps layers to files, comes last in docker save output
+	file, err := os.CreateTemp("", "syno-docker-push-*.tar")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if err := saveLocalImage(ctx, local, ref, file); err != nil {
+		return nil, errors.Wrapf(err, "failed to save local image %s", ref)
+	}
+
+	plan, err := planPush(file, len(info.RootFS.Layers), shared)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the archive of %s", ref)
+	}
+
+	result := &PushLocalResult{Image: ref, Layers: len(info.RootFS.Layers)}
+	output, sent, err := sendPushArchive(ctx, conn, file, plan, "Pushing "+ref, opts.Progress)
+	if err != nil && len(plan.skip) > 0 && ctx.Err() == nil {
+		// Daemons using the containerd image store need every layer in the
+		// archive, so try again with the complete archive
+		plan = &pushPlan{}
+		output, sent, err = sendPushArchive(ctx, conn, file, plan, "Pushing "+ref, opts.Progress)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load %s on the NAS", ref)
+	}
+
+	result.Skipped = plan.skipped
+	result.Bytes = sent
+	result.SkippedBytes = plan.skippedBytes
+	result.Output = output
+	return result, nil
+}
+
+// saveLocalImage writes the docker save archive of ref to file
+func saveLocalImage(ctx context.Context, local LocalDocker, ref string, file *os.File) error {
+	archive, err := local.ImageSave(ctx, []string{ref})
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	_, err = io.Copy(file, &contextReader{ctx: ctx, r: archive})
+	return err
+}
+
+// nasLayerChains returns the layer diff IDs of every image on the NAS,
+// base layer first
+func nasLayerChains(ctx context.Context, conn synology.Executor) ([][]string, error) {
+	output, err := conn.ExecuteDockerCommandContext(ctx, []string{"images", "--quiet", "--no-trunc"})
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"image", "inspect", "--format", "{{json .RootFS.Layers}}"}
+	seen := map[string]bool{}
+	for _, id := range strings.Fields(output) {
+		if !seen[id] {
+			seen[id] = true
+			args = append(args, id)
+		}
+	}
+	if len(seen) == 0 {
+		return nil, nil
+	}
+
+	output, err = conn.ExecuteDockerCommandContext(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	var chains [][]string
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		var layers []string
+		if err := json.Unmarshal([]byte(line), &layers); err != nil {
+			return nil, fmt.Errorf("unexpected image inspect output %q: %w", line, err)
+		}
+		chains = append(chains, layers)
+	}
+	return chains, nil
+}
+
+// sharedLayers returns how many of the first layers match the first
+// layers of one of chains. docker load only reuses a layer when its parents
+// match too, so a common layer after a difference doesn't count.
+func sharedLayers(layers []string, chains [][]string) int {
+	longest := 0
+	for _, chain := range chains {
+		n := 0
+		for n < len(layers) && n < len(chain) && layers[n] == chain[n] {
+			n++
+		}
+		if n > longest {
+			longest = n
+		}
+	}
+	return longest
+}
+
+// pushPlan lists the files left out of the archive sent to the NAS
+type pushPlan struct {
+	skip         map[string]bool
+	skipped      int
+	skippedBytes int64
+}
+
+// planPush reads the manifest of the archive in file and picks the layer
+// files of the first shared layers to leave out. Nothing is left out when
+// the manifest doesn't match the layers of the image.
+func planPush(file *os.File, layers, shared int) (*pushPlan, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var manifest []struct{ Layers []string }
+	sizes := map[string]int64{}
+	tr := tar.NewReader(file)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		sizes[header.Name] = header.Size
+		if header.Name == "manifest.json" {
+			if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+				return nil, fmt.Errorf("invalid manifest.json: %w", err)
+			}
+		}
+	}
+
+	plan := &pushPlan{skip: map[string]bool{}}
+	if len(manifest) != 1 || len(manifest[0].Layers) != layers {
+		return plan, nil
+	}
+
+	// A file may hold several identical layers, and is only left out when
+	// every one of them is shared
+	needed := map[string]bool{}
+	for _, name := range manifest[0].Layers[shared:] {
+		needed[name] = true
+	}
+	for _, name := range manifest[0].Layers[:shared] {
+		if needed[name] {
+			continue
+		}
+		plan.skipped++
+		if !plan.skip[name] {
+			plan.skip[name] = true
+			plan.skippedBytes += sizes[name]
+		}
+	}
+	return plan, nil
+}
+
+// sendPushArchive streams the archive in file, without the files plan
+// leaves out, into docker load on the NAS. It returns the load output and
+// the number of bytes sent.
+func sendPushArchive(ctx context.Context, conn synology.Executor, file *os.File, plan *pushPlan, label string, progress io.Writer) (string, int64, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", 0, err
+	}
+	var size int64
+	if info, err := file.Stat(); err == nil {
+		size = info.Size() - plan.skippedBytes
+	}
+
+	pr, pw := io.Pipe()
+	counter := &countingWriter{w: pw}
+	done := make(chan error, 1)
+	go func() {
+		err := filterArchive(counter, file, plan.skip)
+		pw.CloseWithError(err)
+		done <- err
+	}()
+
+	upload := &archiveUpload{args: []string{"load"}, input: "-", stdin: pr, size: size, label: label, progress: progress}
+	output, err := upload.run(ctx, conn)
+	// Unblock the writer if docker load stopped reading early
+	pr.Close()
+	if filterErr := <-done; err == nil && filterErr != nil {
+		err = filterErr
+	}
+	return output, counter.n, err
+}
+
+// filterArchive copies the tar archive in r to w without the files in skip
+func filterArchive(w io.Writer, r io.Reader, skip map[string]bool) error {
+	tr := tar.NewReader(r)
+	tw := tar.NewWriter(w)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if skip[header.Name] {
+			continue
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// countingWriter counts the bytes written to w
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+// Write writes p to w
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
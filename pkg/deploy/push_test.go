@@ -0,0 +1,200 @@
+package deploy
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+
+	"github.com/scttfrdmn/syno-docker/pkg/synology/synologytest"
+)
+
+// fakeLocalDocker serves LocalDocker from a FakeDocker standing in for the
+// local Docker daemon
+type fakeLocalDocker struct {
+	docker *synologytest.FakeDocker
+	// arch, when set, is the architecture images report
+	arch string
+}
+
+func (f *fakeLocalDocker) run(args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	if status := f.docker.Run(args, nil, &stdout, &stderr); status != 0 {
+		return nil, errors.New(strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+func (f *fakeLocalDocker) ImageInspect(ctx context.Context, ref string, opts ...client.ImageInspectOption) (image.InspectResponse, error) {
+	output, err := f.run("image", "inspect", ref)
+	if err != nil {
+		return image.InspectResponse{}, err
+	}
+	var images []image.InspectResponse
+	if err := json.Unmarshal(output, &images); err != nil {
+		return image.InspectResponse{}, err
+	}
+	if f.arch != "" {
+		images[0].Os, images[0].Architecture = "linux", f.arch
+	}
+	return images[0], nil
+}
+
+func (f *fakeLocalDocker) ImageSave(ctx context.Context, refs []string, opts ...client.ImageSaveOption) (io.ReadCloser, error) {
+	output, err := f.run(append([]string{"save"}, refs...)...)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(output)), nil
+}
+
+func TestSharedLayers(t *testing.T) {
+	tests := []struct {
+		name   string
+		layers []string
+		chains [][]string
+		want   int
+	}{
+		{"no images", []string{"a", "b"}, nil, 0},
+		{"same base", []string{"a", "b"}, [][]string{{"a", "c"}}, 1},
+		{"longest chain wins", []string{"a", "b", "c"}, [][]string{{"a"}, {"a", "b", "d"}, {"x"}}, 2},
+		{"whole image", []string{"a", "b"}, [][]string{{"a", "b"}}, 2},
+		{"different parent", []string{"a", "b"}, [][]string{{"x", "b"}}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sharedLayers(tt.layers, tt.chains); got != tt.want {
+				t.Errorf("sharedLayers() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPushLocal(t *testing.T) {
+	local := synologytest.NewFakeDocker()
+	local.AddImageLayers("my-app:1.0", "sha256:base", "sha256:deps", "sha256:app")
+
+	docker := synologytest.NewFakeDocker()
+	docker.AddImageLayers("debian:12", "sha256:base")
+	docker.AddImageLayers("other:1.0", "sha256:other", "sha256:deps")
+	server := synologytest.NewSSHServer(t, docker)
+	conn := connectTestServer(t, server)
+
+	var progress bytes.Buffer
+	opts := &PushLocalOptions{Local: &fakeLocalDocker{docker: local}, Progress: &progress}
+	result, err := PushLocal(conn, "my-app:1.0", opts)
+	if err != nil {
+		t.Fatalf("PushLocal failed: %v", err)
+	}
+
+	if result.Layers != 3 || result.Skipped != 1 || result.Transferred() != 2 {
+		t.Errorf("Expected 2 of 3 layers transferred, got %+v", result)
+	}
+	if result.SkippedBytes != int64(len("layer sha256:base")) {
+		t.Errorf("Expected the base layer's size skipped, got %d", result.SkippedBytes)
+	}
+	if result.Output != "Loaded image: my-app:1.0\n" {
+		t.Errorf("Unexpected load output %q", result.Output)
+	}
+	if !strings.Contains(progress.String(), "Pushing my-app:1.0") {
+		t.Errorf("Expected progress output, got %q", progress.String())
+	}
+
+	var loaded *synologytest.FakeImage
+	for _, image := range docker.Images() {
+		if image.Ref() == "my-app:1.0" {
+			loaded = &image
+		}
+	}
+	if loaded == nil || strings.Join(loaded.Layers, ",") != "sha256:base,sha256:deps,sha256:app" {
+		t.Fatalf("Expected my-app:1.0 with its layers on the NAS, got %+v", docker.Images())
+	}
+
+	// Pushing again sends only the config and manifest
+	again, err := PushLocal(conn, "my-app:1.0", opts)
+	if err != nil {
+		t.Fatalf("second PushLocal failed: %v", err)
+	}
+	if again.Skipped != 3 || again.Bytes >= result.Bytes {
+		t.Errorf("Expected every layer skipped and less sent, got %+v after %+v", again, result)
+	}
+}
+
+func TestPushLocalFallsBackToFullArchive(t *testing.T) {
+	local := synologytest.NewFakeDocker()
+	local.AddImageLayers("my-app:1.0", "sha256:base", "sha256:app")
+
+	fake := synologytest.New()
+	fake.OnDocker("images").Return("sha256:1111\n")
+	fake.OnDocker("image", "inspect").Return("[\"sha256:base\"]\n")
+	fake.OnDocker("load").
+		Fail(1, "content digest sha256:base: not found").
+		Return("Loaded image: my-app:1.0\n")
+
+	result, err := PushLocal(fake, "my-app:1.0", &PushLocalOptions{Local: &fakeLocalDocker{docker: local}})
+	if err != nil {
+		t.Fatalf("PushLocal failed: %v", err)
+	}
+	if result.Skipped != 0 || result.SkippedBytes != 0 {
+		t.Errorf("Expected the complete archive after the retry, got %+v", result)
+	}
+
+	calls := fake.Calls()
+	trimmed, full := archiveNames(t, calls[2].Stdin), archiveNames(t, calls[3].Stdin)
+	if trimmed["base/layer.tar"] || !full["base/layer.tar"] || !full["app/layer.tar"] {
+		t.Errorf("Expected the base layer only in the second archive, got %v and %v", trimmed, full)
+	}
+}
+
+func TestPushLocalArchitectureMismatch(t *testing.T) {
+	local := synologytest.NewFakeDocker()
+	local.AddImageLayers("my-app:1.0", "sha256:base")
+	server := synologytest.NewSSHServer(t, nil)
+	server.Arch = "aarch64"
+	conn := connectTestServer(t, server)
+
+	_, err := PushLocal(conn, "my-app:1.0", &PushLocalOptions{Local: &fakeLocalDocker{docker: local, arch: "amd64"}})
+	if err == nil || !strings.Contains(err.Error(), "is for linux/amd64 but the NAS is linux/arm64") {
+		t.Fatalf("Expected an architecture error, got %v", err)
+	}
+
+	if _, err := PushLocal(conn, "my-app:1.0", &PushLocalOptions{Local: &fakeLocalDocker{docker: local, arch: "arm64"}}); err != nil {
+		t.Errorf("Expected a matching image to be pushed, got %v", err)
+	}
+}
+
+func TestPushLocalMissingImage(t *testing.T) {
+	fake := synologytest.New()
+	_, err := PushLocal(fake, "missing:1.0", &PushLocalOptions{Local: &fakeLocalDocker{docker: synologytest.NewFakeDocker()}})
+	if err == nil || !strings.Contains(err.Error(), "failed to inspect local image missing:1.0") {
+		t.Errorf("Expected local inspect error, got %v", err)
+	}
+	if len(fake.Calls()) != 0 {
+		t.Errorf("Expected nothing run on the NAS, got:\n%s", fake)
+	}
+}
+
+// archiveNames returns the names of the files in a tar archive
+func archiveNames(t *testing.T, archive string) map[string]bool {
+	t.Helper()
+	names := map[string]bool{}
+	tr := tar.NewReader(strings.NewReader(archive))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return names
+		}
+		if err != nil {
+			t.Fatalf("invalid archive: %v", err)
+		}
+		names[header.Name] = true
+	}
+}
@@ -0,0 +1,63 @@
+package deploy
+
+import (
+	"context"
+	"os"
+
+	"golang.org/x/term"
+
+	"github.com/scttfrdmn/syno-docker/pkg/synology"
+)
+
+// defaultTerminalSize is used when stdout is not a terminal
+var defaultTerminalSize = synology.TerminalSize{Width: 80, Height: 24}
+
+// interactiveSession wires the local terminal to a remote session. When tty is
+// set a pseudo-terminal of the local size is requested, the local terminal is
+// put in raw mode and resizes are forwarded until ctx is done. The returned
+// function restores the local terminal and must always be called.
+func interactiveSession(ctx context.Context, tty, stdin bool) (*synology.SessionOptions, func(), error) {
+	opts := &synology.SessionOptions{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+	if stdin {
+		opts.Stdin = os.Stdin
+	}
+
+	restore := func() {}
+	if !tty {
+		return opts, restore, nil
+	}
+
+	opts.TTY = true
+	opts.Term = os.Getenv("TERM")
+	opts.Size = defaultTerminalSize
+
+	outFd := int(os.Stdout.Fd())
+	if term.IsTerminal(outFd) {
+		opts.Size = terminalSize(outFd)
+		opts.Resize = watchTerminalSize(ctx, outFd)
+	}
+
+	// Raw mode passes keystrokes such as Ctrl+C through to the remote process
+	inFd := int(os.Stdin.Fd())
+	if stdin && term.IsTerminal(inFd) {
+		state, err := term.MakeRaw(inFd)
+		if err != nil {
+			return nil, nil, err
+		}
+		restore = func() { term.Restore(inFd, state) }
+	}
+
+	return opts, restore, nil
+}
+
+// terminalSize returns the size of the terminal fd, or the default size
+func terminalSize(fd int) synology.TerminalSize {
+	width, height, err := term.GetSize(fd)
+	if err != nil || width <= 0 || height <= 0 {
+		return defaultTerminalSize
+	}
+	return synology.TerminalSize{Width: width, Height: height}
+}
@@ -0,0 +1,45 @@
+//go:build !unix
+
+package deploy
+
+import (
+	"context"
+	"time"
+
+	"github.com/scttfrdmn/syno-docker/pkg/synology"
+)
+
+// resizePollInterval is how often the terminal size is checked without SIGWINCH
+const resizePollInterval = 250 * time.Millisecond
+
+// watchTerminalSize polls the size of the terminal fd, since there is no
+// resize signal on this platform, and reports changes until ctx is done
+func watchTerminalSize(ctx context.Context, fd int) <-chan synology.TerminalSize {
+	sizes := make(chan synology.TerminalSize, 1)
+
+	go func() {
+		defer close(sizes)
+
+		ticker := time.NewTicker(resizePollInterval)
+		defer ticker.Stop()
+
+		last := terminalSize(fd)
+		for {
+			select {
+			case <-ticker.C:
+				if size := terminalSize(fd); size != last {
+					last = size
+					select {
+					case sizes <- size:
+					case <-ctx.Done():
+						return
+					}
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return sizes
+}
@@ -0,0 +1,59 @@
+package deploy
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestInteractiveSessionWithoutTTY(t *testing.T) {
+	opts, restore, err := interactiveSession(context.Background(), false, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer restore()
+
+	if opts.TTY {
+		t.Error("Expected no pseudo-terminal")
+	}
+	if opts.Stdin != os.Stdin {
+		t.Error("Expected stdin to be wired through")
+	}
+	if opts.Stdout != os.Stdout || opts.Stderr != os.Stderr {
+		t.Error("Expected stdout and stderr to be wired through")
+	}
+}
+
+func TestInteractiveSessionWithoutStdin(t *testing.T) {
+	opts, restore, err := interactiveSession(context.Background(), false, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer restore()
+
+	if opts.Stdin != nil {
+		t.Error("Expected stdin not to be attached")
+	}
+}
+
+func TestInteractiveSessionTTY(t *testing.T) {
+	originalTerm := os.Getenv("TERM")
+	os.Setenv("TERM", "xterm-256color")
+	defer os.Setenv("TERM", originalTerm)
+
+	opts, restore, err := interactiveSession(context.Background(), true, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer restore()
+
+	if !opts.TTY {
+		t.Error("Expected a pseudo-terminal to be requested")
+	}
+	if opts.Term != "xterm-256color" {
+		t.Errorf("Expected TERM xterm-256color, got %s", opts.Term)
+	}
+	if opts.Size.Width <= 0 || opts.Size.Height <= 0 {
+		t.Errorf("Expected a positive terminal size, got %v", opts.Size)
+	}
+}
@@ -0,0 +1,40 @@
+//go:build unix
+
+package deploy
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/scttfrdmn/syno-docker/pkg/synology"
+)
+
+// watchTerminalSize reports the new size of the terminal fd on every SIGWINCH
+// until ctx is done
+func watchTerminalSize(ctx context.Context, fd int) <-chan synology.TerminalSize {
+	sizes := make(chan synology.TerminalSize, 1)
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, syscall.SIGWINCH)
+
+	go func() {
+		defer close(sizes)
+		defer signal.Stop(winch)
+
+		for {
+			select {
+			case <-winch:
+				select {
+				case sizes <- terminalSize(fd):
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return sizes
+}
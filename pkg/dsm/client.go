@@ -0,0 +1,218 @@
+// Package dsm is a client for the DSM Web API, the HTTPS API behind the DSM
+// web interface. It handles API discovery, logging in and the Container
+// Manager (SYNO.Docker.*) endpoints, for NAS units that don't allow SSH.
+package dsm
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultPort is the default HTTPS port of DSM
+	DefaultPort = 5001
+	// infoAPI describes the other APIs and is always at query.cgi
+	infoAPI = "SYNO.API.Info"
+	// authAPI logs in and out
+	authAPI = "SYNO.API.Auth"
+	// sessionName names the sessions this client opens
+	sessionName = "syno-docker"
+)
+
+// APIInfo describes where an API is served and which versions it supports
+type APIInfo struct {
+	Path       string `json:"path"`
+	MinVersion int    `json:"minVersion"`
+	MaxVersion int    `json:"maxVersion"`
+}
+
+// Options configures a Client
+type Options struct {
+	// InsecureSkipVerify disables TLS certificate verification, for the
+	// self-signed certificate DSM uses until one is installed
+	InsecureSkipVerify bool
+	// HTTPClient replaces the default HTTP client
+	HTTPClient *http.Client
+}
+
+// LoginOptions holds the credentials for Login
+type LoginOptions struct {
+	Account  string
+	Password string
+	// OTPCode is the 2-step verification code, required when Login fails
+	// with CodeOTPRequired
+	OTPCode string
+}
+
+// Client calls the DSM Web API. Discover must be called before anything
+// else, then Login for every API other than SYNO.API.Info. A Client is safe
+// for concurrent use once logged in.
+type Client struct {
+	baseURL *url.URL
+	http    *http.Client
+	apis    map[string]APIInfo
+	sid     string
+}
+
+// New creates a client for the DSM at baseURL, such as https://nas:5001
+func New(baseURL string, opts *Options) (*Client, error) {
+	u, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid DSM URL %q, expected http(s)://host:port", baseURL)
+	}
+
+	if opts == nil {
+		opts = &Options{}
+	}
+	httpClient := opts.HTTPClient
+	if httpClient == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		if opts.InsecureSkipVerify {
+			transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		}
+		httpClient = &http.Client{Transport: transport}
+	}
+
+	return &Client{baseURL: u, http: httpClient}, nil
+}
+
+// URL returns the base URL of the DSM
+func (c *Client) URL() string {
+	return c.baseURL.String()
+}
+
+// Discover queries SYNO.API.Info for the paths and versions of the APIs
+// the NAS provides
+func (c *Client) Discover(ctx context.Context) error {
+	params := url.Values{"query": {"all"}}
+	var apis map[string]APIInfo
+	if err := c.request(ctx, "query.cgi", infoAPI, "query", 1, params, &apis); err != nil {
+		return err
+	}
+	c.apis = apis
+	return nil
+}
+
+// API returns what Discover found out about api
+func (c *Client) API(api string) (APIInfo, bool) {
+	info, ok := c.apis[api]
+	return info, ok
+}
+
+// Login opens a session. On failure the returned error is an *Error whose
+// Code tells why, such as CodeOTPRequired.
+func (c *Client) Login(ctx context.Context, opts *LoginOptions) error {
+	params := url.Values{
+		"account": {opts.Account},
+		"passwd":  {opts.Password},
+		"session": {sessionName},
+		"format":  {"sid"},
+	}
+	if opts.OTPCode != "" {
+		params.Set("otp_code", opts.OTPCode)
+	}
+
+	var result struct {
+		SID string `json:"sid"`
+	}
+	if err := c.Call(ctx, authAPI, "login", 6, params, &result); err != nil {
+		return err
+	}
+	if result.SID == "" {
+		return fmt.Errorf("%s login returned no session ID", authAPI)
+	}
+	c.sid = result.SID
+	return nil
+}
+
+// Logout ends the session opened by Login
+func (c *Client) Logout(ctx context.Context) error {
+	if c.sid == "" {
+		return nil
+	}
+	err := c.Call(ctx, authAPI, "logout", 6, url.Values{"session": {sessionName}}, nil)
+	c.sid = ""
+	return err
+}
+
+// Call calls method of api with params, using the highest version the NAS
+// supports up to version, and decodes the response data into result unless
+// it is nil
+func (c *Client) Call(ctx context.Context, api, method string, version int, params url.Values, result any) error {
+	info, ok := c.apis[api]
+	if !ok {
+		if c.apis == nil {
+			return fmt.Errorf("DSM APIs not discovered yet")
+		}
+		return fmt.Errorf("the NAS does not provide %s; is the package that serves it installed?", api)
+	}
+	if version > info.MaxVersion {
+		version = info.MaxVersion
+	}
+	if version < info.MinVersion {
+		return fmt.Errorf("the NAS requires %s version %d or later", api, info.MinVersion)
+	}
+
+	values := url.Values{}
+	for key, value := range params {
+		values[key] = value
+	}
+	if c.sid != "" {
+		values.Set("_sid", c.sid)
+	}
+	return c.request(ctx, info.Path, api, method, version, values, result)
+}
+
+// request posts a call to webapi/path and decodes the response envelope
+func (c *Client) request(ctx context.Context, path, api, method string, version int, params url.Values, result any) error {
+	params.Set("api", api)
+	params.Set("method", method)
+	params.Set("version", strconv.Itoa(version))
+
+	endpoint := c.baseURL.JoinPath("webapi", path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.String(), strings.NewReader(params.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach DSM at %s: %w", c.baseURL.Host, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s failed: HTTP %s: %s", api, method, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var envelope struct {
+		Success bool            `json:"success"`
+		Data    json.RawMessage `json:"data"`
+		Error   struct {
+			Code int `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("%s %s returned an invalid response: %w", api, method, err)
+	}
+	if !envelope.Success {
+		return &Error{API: api, Method: method, Code: envelope.Error.Code}
+	}
+
+	if result == nil || len(envelope.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(envelope.Data, result); err != nil {
+		return fmt.Errorf("%s %s returned unexpected data: %w", api, method, err)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package dsm
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/scttfrdmn/syno-docker/pkg/dsm/dsmtest"
+)
+
+// loginTestServer returns a client logged in to server
+func loginTestServer(t *testing.T, server *dsmtest.Server) *Client {
+	t.Helper()
+	client, err := New(server.URL, &Options{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	ctx := context.Background()
+	if err := client.Discover(ctx); err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+	if err := client.Login(ctx, &LoginOptions{Account: server.Account, Password: server.Password}); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	return client
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{"https://nas.local:5001", false},
+		{"http://192.168.1.10:5000/", false},
+		{"nas.local:5001", true},
+		{"ftp://nas.local", true},
+		{"https://", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			client, err := New(tt.url, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && strings.HasSuffix(client.URL(), "/") {
+				t.Errorf("Expected the trailing slash trimmed, got %s", client.URL())
+			}
+		})
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	server := dsmtest.NewServer(t)
+	server.DSM6 = true
+
+	client, err := New(server.URL, &Options{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Login(context.Background(), &LoginOptions{}); err == nil || !strings.Contains(err.Error(), "not discovered") {
+		t.Errorf("Expected an error before Discover, got %v", err)
+	}
+
+	if err := client.Discover(context.Background()); err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+	auth, ok := client.API("SYNO.API.Auth")
+	if !ok || auth.Path != "auth.cgi" || auth.MaxVersion != 6 {
+		t.Errorf("Unexpected SYNO.API.Auth info %+v", auth)
+	}
+}
+
+func TestDiscoverRejectsUntrustedCertificate(t *testing.T) {
+	server := dsmtest.NewServer(t)
+
+	client, err := New(server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Discover(context.Background()); err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Errorf("Expected a certificate error, got %v", err)
+	}
+}
+
+func TestLogin(t *testing.T) {
+	server := dsmtest.NewServer(t)
+	server.OTPCode = "123456"
+
+	client, err := New(server.URL, &Options{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := client.Discover(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		otp      string
+		wantCode int
+	}{
+		{"wrong password", "wrong", "", CodeLoginFailed},
+		{"missing code", server.Password, "", CodeOTPRequired},
+		{"wrong code", server.Password, "000000", CodeOTPInvalid},
+		{"valid", server.Password, "123456", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := client.Login(ctx, &LoginOptions{Account: server.Account, Password: tt.password, OTPCode: tt.otp})
+			if tt.wantCode == 0 {
+				if err != nil {
+					t.Fatalf("Login failed: %v", err)
+				}
+				return
+			}
+			if !IsCode(err, tt.wantCode) {
+				t.Errorf("Expected error code %d, got %v", tt.wantCode, err)
+			}
+		})
+	}
+
+	calls := server.Calls()
+	if login := calls[len(calls)-1]; login.Version != 6 || login.Params.Get("format") != "sid" {
+		t.Errorf("Expected a version 6 login for a session ID, got %+v", login)
+	}
+
+	if err := client.Logout(ctx); err != nil {
+		t.Errorf("Logout failed: %v", err)
+	}
+	if server.Sessions() != 0 {
+		t.Errorf("Expected the session ended, %d left", server.Sessions())
+	}
+}
+
+func TestSessionExpired(t *testing.T) {
+	server := dsmtest.NewServer(t)
+	server.AddContainer(dsmtest.FakeContainer{Name: "web", Image: "nginx:latest"})
+	client := loginTestServer(t, server)
+
+	server.ExpireSessions()
+	_, err := client.ListContainers(context.Background())
+	if !IsSessionError(err) {
+		t.Fatalf("Expected a session error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "SYNO.Docker.Container list failed: session not found (code 119)") {
+		t.Errorf("Unexpected error message %q", err)
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	tests := []struct {
+		err  *Error
+		want string
+	}{
+		{&Error{API: "SYNO.API.Auth", Method: "login", Code: CodeLoginFailed}, "SYNO.API.Auth login failed: no such account or incorrect password (code 400)"},
+		{&Error{API: "SYNO.Docker.Container", Method: "start", Code: 1234}, "SYNO.Docker.Container start failed: error code 1234"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("Error() = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package dsm
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+)
+
+// Container Manager APIs. DSM 7 Container Manager and the DSM 6 Docker
+// package both serve them.
+const (
+	ContainerAPI    = "SYNO.Docker.Container"
+	ContainerLogAPI = "SYNO.Docker.Container.Log"
+)
+
+// Container is a container as Container Manager lists it
+type Container struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Image string `json:"image"`
+	// Status is "running" or "stopped"
+	Status string `json:"status"`
+	// UpStatus is the status as docker ps shows it, such as "Up 2 hours"
+	UpStatus     string        `json:"up_status"`
+	Created      int64         `json:"created"`
+	PortBindings []PortBinding `json:"port_bindings"`
+}
+
+// Running reports whether the container is running
+func (c Container) Running() bool {
+	return c.Status == "running"
+}
+
+// PortBinding is a container port published on the NAS
+type PortBinding struct {
+	HostPort      int    `json:"host_port"`
+	ContainerPort int    `json:"container_port"`
+	Type          string `json:"type"`
+}
+
+// LogEntry is a line of container output
+type LogEntry struct {
+	// Created is the RFC 3339 time the line was written
+	Created string `json:"created"`
+	// Stream is "stdout" or "stderr"
+	Stream string `json:"stream"`
+	Text   string `json:"text"`
+}
+
+// LogsOptions selects the log lines ContainerLogs returns
+type LogsOptions struct {
+	// Limit is the maximum number of lines; 0 returns all of them
+	Limit int
+	// Newest returns the newest lines first instead of the oldest
+	Newest bool
+}
+
+// ListContainers lists all containers, running or not
+func (c *Client) ListContainers(ctx context.Context) ([]Container, error) {
+	params := jsonParams(map[string]any{"limit": -1, "offset": 0, "type": "all"})
+	var result struct {
+		Containers []Container `json:"containers"`
+	}
+	if err := c.Call(ctx, ContainerAPI, "list", 1, params, &result); err != nil {
+		return nil, err
+	}
+	return result.Containers, nil
+}
+
+// StartContainer starts the named container
+func (c *Client) StartContainer(ctx context.Context, name string) error {
+	return c.Call(ctx, ContainerAPI, "start", 1, jsonParams(map[string]any{"name": name}), nil)
+}
+
+// StopContainer stops the named container
+func (c *Client) StopContainer(ctx context.Context, name string) error {
+	return c.Call(ctx, ContainerAPI, "stop", 1, jsonParams(map[string]any{"name": name}), nil)
+}
+
+// RestartContainer restarts the named container. DSM 6 has no restart
+// method, so the container is stopped and started there.
+func (c *Client) RestartContainer(ctx context.Context, name string) error {
+	err := c.Call(ctx, ContainerAPI, "restart", 1, jsonParams(map[string]any{"name": name}), nil)
+	if !IsCode(err, CodeMethodNotFound) {
+		return err
+	}
+	if err := c.StopContainer(ctx, name); err != nil {
+		return err
+	}
+	return c.StartContainer(ctx, name)
+}
+
+// ContainerLogs returns log lines of the named container
+func (c *Client) ContainerLogs(ctx context.Context, name string, opts *LogsOptions) ([]LogEntry, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+	sortDir := "ASC"
+	if opts.Newest {
+		sortDir = "DESC"
+	}
+
+	params := jsonParams(map[string]any{
+		"name":     name,
+		"limit":    limit,
+		"offset":   0,
+		"sort_dir": sortDir,
+		"keyword":  "",
+	})
+	var result struct {
+		Logs []LogEntry `json:"logs"`
+	}
+	if err := c.Call(ctx, ContainerLogAPI, "get", 1, params, &result); err != nil {
+		return nil, err
+	}
+	return result.Logs, nil
+}
+
+// jsonParams encodes each value as JSON, as Container Manager expects its
+// parameters
+func jsonParams(values map[string]any) url.Values {
+	params := url.Values{}
+	for key, value := range values {
+		encoded, _ := json.Marshal(value)
+		params.Set(key, string(encoded))
+	}
+	return params
+}
@@ -0,0 +1,106 @@
+package dsm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/scttfrdmn/syno-docker/pkg/dsm/dsmtest"
+)
+
+func TestListContainers(t *testing.T) {
+	server := dsmtest.NewServer(t)
+	server.AddContainer(dsmtest.FakeContainer{Name: "web", Image: "nginx:latest", Running: true, Ports: []string{"8080:80/tcp"}})
+	server.AddContainer(dsmtest.FakeContainer{Name: "db", Image: "postgres:16"})
+	client := loginTestServer(t, server)
+
+	containers, err := client.ListContainers(context.Background())
+	if err != nil {
+		t.Fatalf("ListContainers failed: %v", err)
+	}
+	if len(containers) != 2 {
+		t.Fatalf("Expected 2 containers, got %+v", containers)
+	}
+	web, db := containers[0], containers[1]
+	if web.Name != "web" || !web.Running() || web.UpStatus != "Up" {
+		t.Errorf("Unexpected container %+v", web)
+	}
+	if len(web.PortBindings) != 1 || web.PortBindings[0] != (PortBinding{HostPort: 8080, ContainerPort: 80, Type: "tcp"}) {
+		t.Errorf("Unexpected port bindings %+v", web.PortBindings)
+	}
+	if db.Running() {
+		t.Errorf("Expected db to be stopped, got %+v", db)
+	}
+}
+
+func TestContainerLifecycle(t *testing.T) {
+	for _, dsm6 := range []bool{false, true} {
+		server := dsmtest.NewServer(t)
+		server.DSM6 = dsm6
+		server.AddContainer(dsmtest.FakeContainer{Name: "web", Image: "nginx:latest"})
+		client := loginTestServer(t, server)
+		ctx := context.Background()
+
+		if err := client.StartContainer(ctx, "web"); err != nil {
+			t.Fatalf("StartContainer failed: %v", err)
+		}
+		if c, _ := server.Container("web"); !c.Running {
+			t.Error("Expected web to be running after start")
+		}
+		if err := client.StopContainer(ctx, "web"); err != nil {
+			t.Fatalf("StopContainer failed: %v", err)
+		}
+		if err := client.RestartContainer(ctx, "web"); err != nil {
+			t.Fatalf("RestartContainer failed with DSM6=%v: %v", dsm6, err)
+		}
+		if c, _ := server.Container("web"); !c.Running {
+			t.Errorf("Expected web to be running after restart with DSM6=%v", dsm6)
+		}
+	}
+}
+
+func TestContainerLogs(t *testing.T) {
+	server := dsmtest.NewServer(t)
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	var logs []dsmtest.FakeLog
+	for i, text := range []string{"one", "two", "three"} {
+		logs = append(logs, dsmtest.FakeLog{Time: start.Add(time.Duration(i) * time.Second), Stream: "stdout", Text: text})
+	}
+	server.AddContainer(dsmtest.FakeContainer{Name: "web", Image: "nginx:latest", Logs: logs})
+	client := loginTestServer(t, server)
+
+	tests := []struct {
+		name string
+		opts LogsOptions
+		want []string
+	}{
+		{"all", LogsOptions{}, []string{"one", "two", "three"}},
+		{"oldest", LogsOptions{Limit: 2}, []string{"one", "two"}},
+		{"newest", LogsOptions{Limit: 2, Newest: true}, []string{"three", "two"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := client.ContainerLogs(context.Background(), "web", &tt.opts)
+			if err != nil {
+				t.Fatalf("ContainerLogs failed: %v", err)
+			}
+			var got []string
+			for _, entry := range entries {
+				got = append(got, entry.Text)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ContainerLogs() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("ContainerLogs() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	if entries, _ := client.ContainerLogs(context.Background(), "web", &LogsOptions{Limit: 1}); entries[0].Created != "2024-05-01T10:00:00Z" {
+		t.Errorf("Unexpected log time %q", entries[0].Created)
+	}
+}
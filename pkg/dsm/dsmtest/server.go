@@ -0,0 +1,336 @@
+// Package dsmtest provides a local stand-in for the DSM Web API of a
+// Synology NAS, serving SYNO.API.Info, SYNO.API.Auth and the Container
+// Manager endpoints that package dsm uses from in-memory containers.
+package dsmtest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	// DefaultAccount is the account Server accepts unless changed
+	DefaultAccount = "admin"
+	// DefaultPassword is the password Server accepts unless changed
+	DefaultPassword = "secret"
+)
+
+// FakeContainer is a container known to Server
+type FakeContainer struct {
+	ID      string
+	Name    string
+	Image   string
+	Running bool
+	// Ports are published ports as HOST:CONTAINER/PROTO, e.g. 8080:80/tcp
+	Ports []string
+	Logs  []FakeLog
+}
+
+// FakeLog is a line of container output
+type FakeLog struct {
+	Time   time.Time
+	Stream string
+	Text   string
+}
+
+// Call is a request received by Server
+type Call struct {
+	API     string
+	Method  string
+	Version int
+	Params  url.Values
+}
+
+// Server is an HTTPS server emulating the DSM Web API. Its certificate is
+// self-signed, as on a NAS without one installed.
+type Server struct {
+	// URL is the base URL, as https://127.0.0.1:port
+	URL string
+	// Account and Password are the only credentials accepted
+	Account  string
+	Password string
+	// OTPCode, when set, is the 2-step verification code required to log in
+	OTPCode string
+	// DSM6 serves the APIs as the DSM 6 Docker package does, without the
+	// restart method
+	DSM6 bool
+
+	mu         sync.Mutex
+	containers []*FakeContainer
+	sessions   map[string]bool
+	calls      []Call
+	server     *httptest.Server
+}
+
+// NewServer starts a Server that is closed when the test ends
+func NewServer(t testing.TB) *Server {
+	s := &Server{
+		Account:  DefaultAccount,
+		Password: DefaultPassword,
+		sessions: map[string]bool{},
+	}
+	s.server = httptest.NewTLSServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.server.URL
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+// AddContainer adds a container, generating its ID when it has none
+func (s *Server) AddContainer(container FakeContainer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if container.ID == "" {
+		container.ID = newID()
+	}
+	s.containers = append(s.containers, &container)
+}
+
+// Container returns the named container
+func (s *Server) Container(name string) (FakeContainer, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c := s.findContainer(name); c != nil {
+		return *c, true
+	}
+	return FakeContainer{}, false
+}
+
+// Calls returns the requests received so far, in order
+func (s *Server) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Call(nil), s.calls...)
+}
+
+// Sessions returns the number of sessions logged in
+func (s *Server) Sessions() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sessions)
+}
+
+// ExpireSessions ends every session, as DSM does after a timeout
+func (s *Server) ExpireSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions = map[string]bool{}
+}
+
+func (s *Server) findContainer(name string) *FakeContainer {
+	for _, c := range s.containers {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// apis returns what SYNO.API.Info reports
+func (s *Server) apis() map[string]map[string]any {
+	authPath, authVersion := "entry.cgi", 7
+	if s.DSM6 {
+		authPath, authVersion = "auth.cgi", 6
+	}
+	api := func(path string, max int) map[string]any {
+		return map[string]any{"path": path, "minVersion": 1, "maxVersion": max}
+	}
+	return map[string]map[string]any{
+		"SYNO.API.Info":             api("query.cgi", 1),
+		"SYNO.API.Auth":             api(authPath, authVersion),
+		"SYNO.Docker.Container":     api("entry.cgi", 1),
+		"SYNO.Docker.Container.Log": api("entry.cgi", 1),
+	}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	apiName, method := r.Form.Get("api"), r.Form.Get("method")
+	version, _ := strconv.Atoi(r.Form.Get("version"))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, Call{API: apiName, Method: method, Version: version, Params: r.Form})
+
+	api, ok := s.apis()[apiName]
+	switch {
+	case !ok:
+		reply(w, 102, nil)
+	case r.URL.Path != "/webapi/"+api["path"].(string):
+		http.NotFound(w, r)
+	case version < api["minVersion"].(int) || version > api["maxVersion"].(int):
+		reply(w, 104, nil)
+	case apiName == "SYNO.API.Info":
+		reply(w, 0, s.apis())
+	case apiName == "SYNO.API.Auth":
+		s.auth(w, method, r.Form)
+	case !s.sessions[r.Form.Get("_sid")]:
+		reply(w, 119, nil)
+	case apiName == "SYNO.Docker.Container":
+		s.container(w, method, r.Form)
+	default:
+		s.containerLog(w, method, r.Form)
+	}
+}
+
+func (s *Server) auth(w http.ResponseWriter, method string, form url.Values) {
+	switch method {
+	case "login":
+		if form.Get("account") != s.Account || form.Get("passwd") != s.Password {
+			reply(w, 400, nil)
+			return
+		}
+		if s.OTPCode != "" && form.Get("otp_code") == "" {
+			reply(w, 403, nil)
+			return
+		}
+		if s.OTPCode != "" && form.Get("otp_code") != s.OTPCode {
+			reply(w, 404, nil)
+			return
+		}
+		sid := newID()
+		s.sessions[sid] = true
+		reply(w, 0, map[string]any{"sid": sid})
+	case "logout":
+		delete(s.sessions, form.Get("_sid"))
+		reply(w, 0, nil)
+	default:
+		reply(w, 103, nil)
+	}
+}
+
+func (s *Server) container(w http.ResponseWriter, method string, form url.Values) {
+	if method == "list" {
+		containers := []map[string]any{}
+		for _, c := range s.containers {
+			containers = append(containers, containerObject(c))
+		}
+		reply(w, 0, map[string]any{"containers": containers, "total": len(containers), "offset": 0, "limit": -1})
+		return
+	}
+
+	var name string
+	if !jsonParam(form, "name", &name) {
+		reply(w, 101, nil)
+		return
+	}
+	c := s.findContainer(name)
+	if c == nil {
+		reply(w, 100, nil)
+		return
+	}
+
+	switch {
+	case method == "start":
+		c.Running = true
+	case method == "stop":
+		c.Running = false
+	case method == "restart" && !s.DSM6:
+		c.Running = true
+	default:
+		reply(w, 103, nil)
+		return
+	}
+	reply(w, 0, nil)
+}
+
+func (s *Server) containerLog(w http.ResponseWriter, method string, form url.Values) {
+	var name, sortDir string
+	var limit int
+	if method != "get" {
+		reply(w, 103, nil)
+		return
+	}
+	if !jsonParam(form, "name", &name) || !jsonParam(form, "sort_dir", &sortDir) || !jsonParam(form, "limit", &limit) {
+		reply(w, 101, nil)
+		return
+	}
+	c := s.findContainer(name)
+	if c == nil {
+		reply(w, 100, nil)
+		return
+	}
+
+	logs := []map[string]any{}
+	for _, line := range c.Logs {
+		logs = append(logs, map[string]any{
+			"created": line.Time.UTC().Format(time.RFC3339Nano),
+			"stream":  line.Stream,
+			"text":    line.Text,
+		})
+	}
+	if sortDir == "DESC" {
+		for i, j := 0, len(logs)-1; i < j; i, j = i+1, j-1 {
+			logs[i], logs[j] = logs[j], logs[i]
+		}
+	}
+	total := len(logs)
+	if limit >= 0 && limit < len(logs) {
+		logs = logs[:limit]
+	}
+	reply(w, 0, map[string]any{"logs": logs, "total": total})
+}
+
+// containerObject renders c as Container Manager lists containers
+func containerObject(c *FakeContainer) map[string]any {
+	status, upStatus := "stopped", "Exited (0)"
+	if c.Running {
+		status, upStatus = "running", "Up"
+	}
+	bindings := []map[string]any{}
+	for _, port := range c.Ports {
+		mapping, proto, _ := strings.Cut(port, "/")
+		host, ctr, _ := strings.Cut(mapping, ":")
+		hostPort, _ := strconv.Atoi(host)
+		ctrPort, _ := strconv.Atoi(ctr)
+		bindings = append(bindings, map[string]any{"host_port": hostPort, "container_port": ctrPort, "type": proto})
+	}
+	return map[string]any{
+		"id":            c.ID,
+		"name":          c.Name,
+		"image":         c.Image,
+		"status":        status,
+		"up_status":     upStatus,
+		"created":       time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Unix(),
+		"port_bindings": bindings,
+	}
+}
+
+// jsonParam decodes the JSON encoded parameter key into v
+func jsonParam(form url.Values, key string, v any) bool {
+	return json.Unmarshal([]byte(form.Get(key)), v) == nil
+}
+
+// reply writes the response envelope, failing with code unless it is 0
+func reply(w http.ResponseWriter, code int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	if code != 0 {
+		json.NewEncoder(w).Encode(map[string]any{"success": false, "error": map[string]any{"code": code}})
+		return
+	}
+	response := map[string]any{"success": true}
+	if data != nil {
+		response["data"] = data
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func newID() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("dsmtest: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
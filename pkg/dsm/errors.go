@@ -0,0 +1,84 @@
+package dsm
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DSM Web API error codes. Codes below 400 are common to every API; the
+// others are specific to SYNO.API.Auth.
+const (
+	CodeUnknown             = 100
+	CodeInvalidParameter    = 101
+	CodeAPINotFound         = 102
+	CodeMethodNotFound      = 103
+	CodeVersionNotSupported = 104
+	CodePermissionDenied    = 105
+	CodeSessionTimeout      = 106
+	CodeSessionInterrupted  = 107
+	CodeSessionNotFound     = 119
+	CodeLoginFailed         = 400
+	CodeAccountDisabled     = 401
+	CodeLoginDenied         = 402
+	CodeOTPRequired         = 403
+	CodeOTPInvalid          = 404
+	CodeOTPEnforced         = 406
+	CodeIPBlocked           = 407
+)
+
+var errorMessages = map[int]string{
+	CodeUnknown:             "unknown error",
+	CodeInvalidParameter:    "invalid parameter",
+	CodeAPINotFound:         "the requested API does not exist",
+	CodeMethodNotFound:      "the requested method does not exist",
+	CodeVersionNotSupported: "the requested version does not support the functionality",
+	CodePermissionDenied:    "the logged in session does not have permission",
+	CodeSessionTimeout:      "session timeout",
+	CodeSessionInterrupted:  "session interrupted by duplicate login",
+	CodeSessionNotFound:     "session not found",
+	CodeLoginFailed:         "no such account or incorrect password",
+	CodeAccountDisabled:     "account disabled",
+	CodeLoginDenied:         "permission denied",
+	CodeOTPRequired:         "2-step verification code required",
+	CodeOTPInvalid:          "failed to authenticate 2-step verification code",
+	CodeOTPEnforced:         "2-step verification must be enabled for this account",
+	CodeIPBlocked:           "IP address blocked",
+}
+
+// Error is a failure reported by the DSM Web API
+type Error struct {
+	API    string
+	Method string
+	Code   int
+}
+
+// Error describes the code, or shows it as is when it is not a known one
+func (e *Error) Error() string {
+	msg, ok := errorMessages[e.Code]
+	if !ok {
+		msg = fmt.Sprintf("error code %d", e.Code)
+	} else {
+		msg = fmt.Sprintf("%s (code %d)", msg, e.Code)
+	}
+	return fmt.Sprintf("%s %s failed: %s", e.API, e.Method, msg)
+}
+
+// IsSessionError reports whether err means the session expired or was
+// ended by the NAS, so logging in again may help
+func IsSessionError(err error) bool {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.Code {
+	case CodeSessionTimeout, CodeSessionInterrupted, CodeSessionNotFound:
+		return true
+	}
+	return false
+}
+
+// IsCode reports whether err is an *Error with the given code
+func IsCode(err error, code int) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
@@ -0,0 +1,213 @@
+package synology
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/scttfrdmn/syno-docker/pkg/config"
+)
+
+const (
+	// KeyPassphraseEnv supplies the passphrase for an encrypted private key
+	KeyPassphraseEnv = "SYNO_DOCKER_KEY_PASSPHRASE"
+	// PasswordEnv supplies the account password for password and
+	// keyboard-interactive authentication
+	PasswordEnv = "SYNO_DOCKER_PASSWORD"
+)
+
+// CredentialPrompt asks the user for a secret or an answer to a server
+// challenge. echo reports whether the answer may be shown while typing.
+type CredentialPrompt func(prompt string, echo bool) (string, error)
+
+// SetCredentialPrompt sets the prompt used for key passphrases, passwords and
+// keyboard-interactive challenges that are not supplied by environment variables
+func (c *Connection) SetCredentialPrompt(prompt CredentialPrompt) {
+	c.credentialPrompt = prompt
+}
+
+// authMethods builds the SSH auth methods for hop in the configured order. The
+// returned function releases resources such as the ssh-agent connection.
+func (c *Connection) authMethods(hop sshHop) ([]ssh.AuthMethod, func()) {
+	var methods []ssh.AuthMethod
+	var cleanups []func()
+
+	// The agent's keys and the key file share one publickey method, placed
+	// where the first of them is listed. The client doesn't retry a method
+	// name it has already tried, so a second publickey method would never
+	// be offered once the agent's keys were rejected.
+	var keySources []func() ([]ssh.Signer, error)
+	publicKeyIndex := -1
+
+	for _, name := range c.config.GetAuthMethods() {
+		switch name {
+		case config.AuthAgent, config.AuthPublicKey:
+			if name == config.AuthAgent {
+				// A missing agent just means its keys are skipped
+				signers, cleanup, err := agentSigners()
+				if err != nil {
+					continue
+				}
+				keySources = append(keySources, signers)
+				cleanups = append(cleanups, cleanup)
+			} else {
+				keySources = append(keySources, c.keyFileSigners(hop.keyPath))
+			}
+			if publicKeyIndex < 0 {
+				publicKeyIndex = len(methods)
+				methods = append(methods, nil)
+			}
+		case config.AuthPassword:
+			methods = append(methods, ssh.PasswordCallback(c.passwordCallback(hop)))
+		case config.AuthKeyboardInteractive:
+			methods = append(methods, ssh.KeyboardInteractive(c.keyboardInteractive))
+		}
+	}
+
+	if publicKeyIndex >= 0 {
+		methods[publicKeyIndex] = ssh.PublicKeysCallback(combineSigners(keySources))
+	}
+
+	return methods, func() {
+		for _, cleanup := range cleanups {
+			cleanup()
+		}
+	}
+}
+
+// combineSigners returns the signers of every source in order, without
+// duplicates. A source that fails, such as a missing key file, is only an
+// error when no source has a key to offer.
+func combineSigners(sources []func() ([]ssh.Signer, error)) func() ([]ssh.Signer, error) {
+	return func() ([]ssh.Signer, error) {
+		var signers []ssh.Signer
+		var firstErr error
+		seen := make(map[string]bool)
+		for _, source := range sources {
+			result, err := source()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			for _, signer := range result {
+				key := string(signer.PublicKey().Marshal())
+				if !seen[key] {
+					seen[key] = true
+					signers = append(signers, signer)
+				}
+			}
+		}
+		if len(signers) == 0 && firstErr != nil {
+			return nil, firstErr
+		}
+		return signers, nil
+	}
+}
+
+// keyFileSigners loads the private key at keyPath the first time the server
+// asks for it, decrypting it with a passphrase if needed
+func (c *Connection) keyFileSigners(keyPath string) func() ([]ssh.Signer, error) {
+	var once sync.Once
+	var signers []ssh.Signer
+	var loadErr error
+
+	return func() ([]ssh.Signer, error) {
+		once.Do(func() {
+			signer, err := c.loadPrivateKey(keyPath)
+			if err != nil {
+				loadErr = err
+				return
+			}
+			signers = []ssh.Signer{signer}
+		})
+		return signers, loadErr
+	}
+}
+
+// loadPrivateKey reads and parses a private key, asking for its passphrase
+// when it is encrypted
+func (c *Connection) loadPrivateKey(keyPath string) (ssh.Signer, error) {
+	// Read SSH private key
+	keyBytes, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SSH key: %w", err)
+	}
+
+	// Parse private key
+	signer, err := ssh.ParsePrivateKey(keyBytes)
+	var missingErr *ssh.PassphraseMissingError
+	if !errors.As(err, &missingErr) {
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse SSH key: %w", err)
+		}
+		return signer, nil
+	}
+
+	passphrase, ok := os.LookupEnv(KeyPassphraseEnv)
+	if !ok {
+		passphrase, err = c.prompt(fmt.Sprintf("Enter passphrase for key '%s': ", keyPath), false)
+		if err != nil {
+			return nil, fmt.Errorf("SSH key %s is encrypted: %w (set %s to provide the passphrase)", keyPath, err, KeyPassphraseEnv)
+		}
+	}
+
+	signer, err = ssh.ParsePrivateKeyWithPassphrase(keyBytes, []byte(passphrase))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt SSH key: %w", err)
+	}
+
+	return signer, nil
+}
+
+// passwordCallback returns the account password for hop from PasswordEnv or the prompt
+func (c *Connection) passwordCallback(hop sshHop) func() (string, error) {
+	return func() (string, error) {
+		if password, ok := os.LookupEnv(PasswordEnv); ok {
+			return password, nil
+		}
+		return c.prompt(fmt.Sprintf("%s@%s's password: ", hop.user, hop.host()), false)
+	}
+}
+
+// keyboardInteractive answers server challenges. Password questions use
+// PasswordEnv when set; everything else, such as 2-step verification codes,
+// is asked through the prompt.
+func (c *Connection) keyboardInteractive(name, instruction string, questions []string, echos []bool) ([]string, error) {
+	if len(questions) == 0 {
+		return nil, nil
+	}
+
+	if instruction != "" && c.credentialPrompt != nil {
+		fmt.Fprintln(os.Stderr, instruction)
+	}
+
+	answers := make([]string, len(questions))
+	for i, question := range questions {
+		if password, ok := os.LookupEnv(PasswordEnv); ok && strings.Contains(strings.ToLower(question), "password") {
+			answers[i] = password
+			continue
+		}
+
+		answer, err := c.prompt(question, echos[i])
+		if err != nil {
+			return nil, err
+		}
+		answers[i] = answer
+	}
+
+	return answers, nil
+}
+
+// prompt asks through the credential prompt, failing when none is set
+func (c *Connection) prompt(prompt string, echo bool) (string, error) {
+	if c.credentialPrompt == nil {
+		return "", fmt.Errorf("no terminal available to prompt for credentials")
+	}
+	return c.credentialPrompt(prompt, echo)
+}
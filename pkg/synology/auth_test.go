@@ -0,0 +1,254 @@
+package synology
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/scttfrdmn/syno-docker/pkg/config"
+)
+
+func writeTestKey(t *testing.T, passphrase string) string {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	var block *pem.Block
+	if passphrase == "" {
+		block, err = ssh.MarshalPrivateKey(priv, "")
+	} else {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(priv, "", []byte(passphrase))
+	}
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	keyPath := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	return keyPath
+}
+
+func TestLoadPrivateKey(t *testing.T) {
+	os.Unsetenv(KeyPassphraseEnv)
+	conn := NewConnection(&config.Config{})
+
+	if _, err := conn.loadPrivateKey(writeTestKey(t, "")); err != nil {
+		t.Errorf("Unexpected error for unencrypted key: %v", err)
+	}
+
+	if _, err := conn.loadPrivateKey("/nonexistent/key"); err == nil {
+		t.Error("Expected error for missing key")
+	}
+}
+
+func TestLoadPrivateKeyPassphrase(t *testing.T) {
+	keyPath := writeTestKey(t, "correct horse")
+
+	t.Run("no prompt", func(t *testing.T) {
+		os.Unsetenv(KeyPassphraseEnv)
+		conn := NewConnection(&config.Config{})
+
+		_, err := conn.loadPrivateKey(keyPath)
+		if err == nil || !strings.Contains(err.Error(), KeyPassphraseEnv) {
+			t.Errorf("Expected error mentioning %s, got %v", KeyPassphraseEnv, err)
+		}
+	})
+
+	t.Run("environment", func(t *testing.T) {
+		os.Setenv(KeyPassphraseEnv, "correct horse")
+		defer os.Unsetenv(KeyPassphraseEnv)
+		conn := NewConnection(&config.Config{})
+
+		if _, err := conn.loadPrivateKey(keyPath); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("prompt", func(t *testing.T) {
+		os.Unsetenv(KeyPassphraseEnv)
+		conn := NewConnection(&config.Config{})
+		prompts := 0
+		conn.SetCredentialPrompt(func(prompt string, echo bool) (string, error) {
+			prompts++
+			if echo {
+				t.Error("Passphrase should not be echoed")
+			}
+			return "correct horse", nil
+		})
+
+		if _, err := conn.loadPrivateKey(keyPath); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if prompts != 1 {
+			t.Errorf("Expected 1 prompt, got %d", prompts)
+		}
+	})
+
+	t.Run("wrong passphrase", func(t *testing.T) {
+		os.Setenv(KeyPassphraseEnv, "battery staple")
+		defer os.Unsetenv(KeyPassphraseEnv)
+		conn := NewConnection(&config.Config{})
+
+		if _, err := conn.loadPrivateKey(keyPath); err == nil {
+			t.Error("Expected error for wrong passphrase")
+		}
+	})
+}
+
+func TestKeyboardInteractive(t *testing.T) {
+	os.Setenv(PasswordEnv, "secret")
+	defer os.Unsetenv(PasswordEnv)
+
+	conn := NewConnection(&config.Config{})
+	var asked []string
+	conn.SetCredentialPrompt(func(prompt string, echo bool) (string, error) {
+		asked = append(asked, prompt)
+		return "123456", nil
+	})
+
+	answers, err := conn.keyboardInteractive("", "", []string{"Password: ", "Verification code: "}, []bool{false, true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(answers) != 2 || answers[0] != "secret" || answers[1] != "123456" {
+		t.Errorf("Unexpected answers %q", answers)
+	}
+	if len(asked) != 1 || asked[0] != "Verification code: " {
+		t.Errorf("Expected only the verification code to be prompted, got %q", asked)
+	}
+}
+
+func TestKeyboardInteractiveNoPrompt(t *testing.T) {
+	os.Unsetenv(PasswordEnv)
+	conn := NewConnection(&config.Config{})
+
+	if _, err := conn.keyboardInteractive("", "", []string{"Verification code: "}, []bool{true}); err == nil {
+		t.Error("Expected error without a prompt")
+	}
+}
+
+func TestPasswordFromEnvironment(t *testing.T) {
+	os.Setenv(PasswordEnv, "secret")
+	defer os.Unsetenv(PasswordEnv)
+
+	password, err := NewConnection(&config.Config{}).passwordCallback(sshHop{address: "nas:22", user: "admin"})()
+	if err != nil || password != "secret" {
+		t.Errorf("Expected password from environment, got %q, %v", password, err)
+	}
+}
+
+func TestAuthMethods(t *testing.T) {
+	originalSock := os.Getenv("SSH_AUTH_SOCK")
+	os.Unsetenv("SSH_AUTH_SOCK")
+	defer os.Setenv("SSH_AUTH_SOCK", originalSock)
+
+	tests := []struct {
+		name     string
+		methods  []string
+		expected int
+	}{
+		{"default without agent", nil, 1},
+		{"password and keyboard-interactive", []string{config.AuthPassword, config.AuthKeyboardInteractive}, 2},
+		{"agent only without agent", []string{config.AuthAgent}, 0},
+		{"all", []string{config.AuthAgent, config.AuthPublicKey, config.AuthPassword, config.AuthKeyboardInteractive}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := NewConnection(&config.Config{AuthMethods: tt.methods})
+			methods, cleanup := conn.authMethods(sshHop{})
+			defer cleanup()
+
+			if len(methods) != tt.expected {
+				t.Errorf("Expected %d methods, got %d", tt.expected, len(methods))
+			}
+		})
+	}
+}
+
+func TestKeyFileSignersLoadsOnce(t *testing.T) {
+	os.Unsetenv(KeyPassphraseEnv)
+	keyPath := writeTestKey(t, "pass")
+
+	conn := NewConnection(&config.Config{})
+	prompts := 0
+	conn.SetCredentialPrompt(func(prompt string, echo bool) (string, error) {
+		prompts++
+		return "pass", nil
+	})
+
+	signers := conn.keyFileSigners(keyPath)
+	for i := 0; i < 2; i++ {
+		result, err := signers()
+		if err != nil || len(result) != 1 {
+			t.Fatalf("Unexpected result %v, %v", result, err)
+		}
+	}
+	if prompts != 1 {
+		t.Errorf("Expected the passphrase to be asked once, got %d", prompts)
+	}
+
+	failing := NewConnection(&config.Config{}).keyFileSigners("/nonexistent/key")
+	if _, err := failing(); err == nil {
+		t.Error("Expected error for missing key")
+	}
+}
+
+func TestCombineSigners(t *testing.T) {
+	newSigner := func() ssh.Signer {
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signer, err := ssh.NewSignerFromKey(priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signer
+	}
+	agentKey, fileKey := newSigner(), newSigner()
+	source := func(signers ...ssh.Signer) func() ([]ssh.Signer, error) {
+		return func() ([]ssh.Signer, error) { return signers, nil }
+	}
+	failing := func() ([]ssh.Signer, error) { return nil, fmt.Errorf("failed to read SSH key") }
+
+	tests := []struct {
+		name    string
+		sources []func() ([]ssh.Signer, error)
+		want    []ssh.Signer
+		wantErr bool
+	}{
+		{"agent then key file", []func() ([]ssh.Signer, error){source(agentKey), source(fileKey)}, []ssh.Signer{agentKey, fileKey}, false},
+		{"key file also in agent", []func() ([]ssh.Signer, error){source(agentKey, fileKey), source(fileKey)}, []ssh.Signer{agentKey, fileKey}, false},
+		{"missing key file", []func() ([]ssh.Signer, error){source(agentKey), failing}, []ssh.Signer{agentKey}, false},
+		{"empty agent and missing key file", []func() ([]ssh.Signer, error){source(), failing}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signers, err := combineSigners(tt.sources)()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("combineSigners() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(signers) != len(tt.want) {
+				t.Fatalf("Expected %d signers, got %d", len(tt.want), len(signers))
+			}
+			for i := range signers {
+				if string(signers[i].PublicKey().Marshal()) != string(tt.want[i].PublicKey().Marshal()) {
+					t.Errorf("Unexpected signer at %d", i)
+				}
+			}
+		})
+	}
+}
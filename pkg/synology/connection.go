@@ -1,104 +1,267 @@
 package synology
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/docker/docker/client"
 	"github.com/pkg/errors"
 	"golang.org/x/crypto/ssh"
 
 	"github.com/scttfrdmn/syno-docker/pkg/config"
+	"github.com/scttfrdmn/syno-docker/pkg/dsm"
+)
+
+const (
+	// dockerAPIPingTimeout bounds how long Connect waits for the tunneled Docker socket
+	dockerAPIPingTimeout = 5 * time.Second
+	// stderrCaptureLimit bounds how much streamed stderr is kept for error messages
+	stderrCaptureLimit = 4096
 )
 
 // Connection represents a connection to a Synology NAS
 type Connection struct {
-	config    *config.Config
-	sshClient *ssh.Client
-	dockerAPI *client.Client
+	config        *config.Config
+	sshClient     *ssh.Client
+	dockerAPI     *client.Client
+	dsmClient     *dsm.Client
+	nasInfo       *NASInfo
+	hostKeyPrompt HostKeyPrompt
+	jumpClients   []*ssh.Client
+
+	credentialPrompt CredentialPrompt
+
+	// keepaliveInterval, keepaliveStop and lost track the health of sshClient
+	keepaliveInterval time.Duration
+	keepaliveStop     chan struct{}
+	lost              atomic.Bool
+
+	// mu guards sshClient and dockerAPI against Reconnect replacing them;
+	// generation counts reconnects so concurrent retries redial only once
+	mu         sync.RWMutex
+	generation uint64
 }
 
 // NewConnection creates a new connection with the given configuration
 func NewConnection(cfg *config.Config) *Connection {
 	return &Connection{
-		config: cfg,
+		config:            cfg,
+		keepaliveInterval: DefaultKeepaliveInterval,
 	}
 }
 
 // Connect establishes the SSH connection to the Synology NAS
 func (c *Connection) Connect() error {
-	if err := c.connectSSH(); err != nil {
-		return errors.Wrap(err, "failed to establish SSH connection")
+	return c.ConnectContext(context.Background())
+}
+
+// ConnectContext establishes the SSH connection, giving up when ctx is done.
+// Depending on the configured transport, the DSM Web API is used instead of
+// SSH or when SSH fails; see DSMClient.
+func (c *Connection) ConnectContext(ctx context.Context) error {
+	if c.config.Transport == config.TransportDSM {
+		if err := c.connectDSM(ctx); err != nil {
+			return errors.Wrap(err, "failed to log in to the DSM Web API")
+		}
+		return nil
+	}
+
+	if err := c.connectSSH(ctx); err != nil {
+		if !c.dsmFallback(ctx, err) {
+			return errors.Wrap(err, "failed to establish SSH connection")
+		}
+		fmt.Fprintf(os.Stderr, "SSH unavailable (%v), using the DSM Web API at %s\n", err, c.dsmURL())
+		if dsmErr := c.connectDSM(ctx); dsmErr != nil {
+			return fmt.Errorf("failed to establish SSH connection: %v; DSM Web API fallback failed: %w", err, dsmErr)
+		}
+		return nil
+	}
+	c.startKeepalive()
+
+	// Prefer the Docker Engine API over the tunneled socket. If the socket is
+	// not accessible to this user, commands fall back to the docker CLI.
+	if err := c.connectDockerAPI(ctx); err != nil {
+		c.dockerAPI = nil
 	}
 
-	// For v0.1.0, we use SSH commands directly instead of Docker client
-	// This avoids the complex SSH tunneling setup and works reliably
 	return nil
 }
 
-func (c *Connection) connectSSH() error {
-	// Try ssh-agent first if available
-	if hasSSHAgent() {
-		if err := c.connectSSHWithAgent(); err == nil {
-			return nil // Success with agent
-		}
-		// If agent fails, fall back to key file
-		fmt.Printf("Warning: ssh-agent authentication failed, trying key file...\n")
+// connectDockerAPI builds a Docker client that dials SocketPath through the SSH connection
+func (c *Connection) connectDockerAPI(ctx context.Context) error {
+	sshClient := c.sshClient
+	dockerAPI, err := client.NewClientWithOpts(
+		client.WithHost("unix://"+SocketPath),
+		client.WithDialContext(func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := sshClient.Dial("unix", SocketPath)
+			if err != nil && c.lost.Load() {
+				return nil, lostError(err)
+			}
+			return conn, err
+		}),
+		client.WithAPIVersionNegotiation(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create Docker client: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dockerAPIPingTimeout)
+	defer cancel()
+
+	if _, err := dockerAPI.Ping(ctx); err != nil {
+		dockerAPI.Close()
+		return fmt.Errorf("docker socket not accessible: %w", err)
 	}
 
-	// Fallback to key file authentication
-	return c.connectSSHWithKeyFile()
+	c.dockerAPI = dockerAPI
+	return nil
 }
 
-func (c *Connection) connectSSHWithKeyFile() error {
-	// Read SSH private key
-	keyBytes, err := os.ReadFile(c.config.SSHKeyPath)
+func (c *Connection) connectSSH(ctx context.Context) error {
+	if c.config.InsecureSkipHostKeyCheck {
+		fmt.Fprintf(os.Stderr, "Warning: host key verification is disabled for %s\n", c.config.Host)
+	}
+
+	// Resolve aliases, HostName and ProxyJump from ~/.ssh/config
+	sshCfg, err := loadDefaultSSHConfig()
+	if err != nil {
+		return err
+	}
+	target, jumps, err := c.route(sshCfg)
 	if err != nil {
-		return fmt.Errorf("failed to read SSH key: %w", err)
+		return err
+	}
+
+	// Connect through each jump host in turn
+	var via *ssh.Client
+	for _, hop := range jumps {
+		client, err := c.dialHop(ctx, via, hop, c.jumpHostKeyCallback())
+		if err != nil {
+			c.closeJumpClients()
+			return fmt.Errorf("failed to connect to jump host %s: %w", hop.address, err)
+		}
+		c.jumpClients = append(c.jumpClients, client)
+		via = client
 	}
 
-	// Parse private key
-	signer, err := ssh.ParsePrivateKey(keyBytes)
+	client, err := c.dialHop(ctx, via, target, c.hostKeyCallback())
 	if err != nil {
-		return fmt.Errorf("failed to parse SSH key: %w", err)
+		c.closeJumpClients()
+		return fmt.Errorf("failed to dial SSH: %w", err)
+	}
+
+	c.sshClient = client
+	return nil
+}
+
+// dialHop authenticates to hop, connecting through via when it is not nil
+func (c *Connection) dialHop(ctx context.Context, via *ssh.Client, hop sshHop, hostKeyCallback ssh.HostKeyCallback) (*ssh.Client, error) {
+	// Offer every configured auth method in order; the server tries each until one succeeds
+	methods, cleanup := c.authMethods(hop)
+	defer cleanup()
+
+	if len(methods) == 0 {
+		return nil, fmt.Errorf("no usable SSH auth methods (configured: %s)", strings.Join(c.config.GetAuthMethods(), ", "))
 	}
 
 	// Configure SSH client
 	sshConfig := &ssh.ClientConfig{
-		User: c.config.User,
-		Auth: []ssh.AuthMethod{
-			ssh.PublicKeys(signer),
-		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(), // TODO: Implement proper host key verification
+		User:            hop.user,
+		Auth:            methods,
+		HostKeyCallback: hostKeyCallback,
 	}
 
-	// Connect to SSH server
-	address := fmt.Sprintf("%s:%d", c.config.Host, c.config.Port)
-	client, err := ssh.Dial("tcp", address, sshConfig)
+	return dial(ctx, via, hop.address, sshConfig)
+}
+
+// dial opens a TCP connection to address, directly or through via, and
+// performs the SSH handshake, bounded by ctx
+func dial(ctx context.Context, via *ssh.Client, address string, sshConfig *ssh.ClientConfig) (*ssh.Client, error) {
+	var netConn net.Conn
+	var err error
+	if via != nil {
+		netConn, err = via.DialContext(ctx, "tcp", address)
+	} else {
+		var dialer net.Dialer
+		netConn, err = dialer.DialContext(ctx, "tcp", address)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to dial SSH: %w", err)
+		return nil, err
 	}
 
-	c.sshClient = client
+	// Bound the handshake by the context deadline, then clear it
+	if deadline, ok := ctx.Deadline(); ok {
+		netConn.SetDeadline(deadline)
+	}
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(netConn, address, sshConfig)
+	if err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	netConn.SetDeadline(time.Time{})
+
+	return ssh.NewClient(sshConn, chans, reqs), nil
+}
+
+// closeJumpClients closes jump host connections, innermost first
+func (c *Connection) closeJumpClients() error {
+	var errs []string
+	for i := len(c.jumpClients) - 1; i >= 0; i-- {
+		if err := c.jumpClients[i].Close(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	c.jumpClients = nil
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, ", "))
+	}
 	return nil
 }
 
 // Close closes the connection and cleans up resources
 func (c *Connection) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.close()
+}
+
+// close is Close for callers holding mu
+func (c *Connection) close() error {
 	var errs []string
 
+	c.stopKeepalive()
+
 	if c.dockerAPI != nil {
 		if err := c.dockerAPI.Close(); err != nil {
 			errs = append(errs, fmt.Sprintf("Docker client: %v", err))
 		}
+		c.dockerAPI = nil
 	}
 
+	// The keepalive may already have closed a lost connection
 	if c.sshClient != nil {
-		if err := c.sshClient.Close(); err != nil {
+		if err := c.sshClient.Close(); err != nil && !c.lost.Load() {
 			errs = append(errs, fmt.Sprintf("SSH client: %v", err))
 		}
+		c.sshClient = nil
+	}
+
+	if err := c.closeJumpClients(); err != nil {
+		errs = append(errs, fmt.Sprintf("jump hosts: %v", err))
+	}
+
+	if err := c.closeDSM(); err != nil {
+		errs = append(errs, fmt.Sprintf("DSM session: %v", err))
 	}
 
 	if len(errs) > 0 {
@@ -108,51 +271,89 @@ func (c *Connection) Close() error {
 	return nil
 }
 
-// ExecuteCommand executes a command over SSH and returns the output
+// ExecuteCommand executes a command over SSH and returns its stdout. A non-zero
+// exit status is reported as a *RemoteCommandError.
 func (c *Connection) ExecuteCommand(cmd string) (string, error) {
-	if c.sshClient == nil {
-		return "", fmt.Errorf("SSH client not connected")
+	return c.ExecuteCommandContext(context.Background(), cmd)
+}
+
+// ExecuteCommandContext executes a command over SSH and returns the output.
+// If ctx is done before the command finishes, the remote process is signalled
+// and the session is closed.
+func (c *Connection) ExecuteCommandContext(ctx context.Context, cmd string) (string, error) {
+	sshClient := c.currentClient()
+	if sshClient == nil {
+		return "", c.notConnectedError()
 	}
 
-	session, err := c.sshClient.NewSession()
+	session, err := sshClient.NewSession()
 	if err != nil {
-		return "", fmt.Errorf("failed to create SSH session: %w", err)
+		return "", c.openSessionError(err)
 	}
 	defer session.Close()
 
 	// Set up environment to include Docker binary path
 	session.Setenv("PATH", "/usr/local/bin:/usr/bin:/bin")
 
-	output, err := session.CombinedOutput(cmd)
+	var stdout, stderr bytes.Buffer
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+
+	err = runSession(ctx, session, func() error { return session.Run(cmd) })
 	if err != nil {
-		return string(output), fmt.Errorf("command failed: %w", err)
+		return stdout.String(), c.sessionError(ctx, cmd, err, stdout.String(), stderr.String())
 	}
 
-	return string(output), nil
+	return stdout.String(), nil
 }
 
-// ExecuteDockerCommand executes a Docker command with full path
+// ExecuteDockerCommand executes a Docker command with full path, quoting
+// every argument for the remote shell
 func (c *Connection) ExecuteDockerCommand(args []string) (string, error) {
-	// Always use full path to Docker binary
-	cmd := DockerBinary + " " + strings.Join(args, " ")
-	return c.ExecuteCommand(cmd)
+	return c.ExecuteDockerCommandContext(context.Background(), args)
+}
+
+// ExecuteDockerCommandContext is like ExecuteDockerCommand but honors ctx
+func (c *Connection) ExecuteDockerCommandContext(ctx context.Context, args []string) (string, error) {
+	return c.ExecuteCommandContext(ctx, DockerCommand(args))
 }
 
-// GetDockerClient returns the Docker client (nil in v0.1.x)
+// GetDockerClient returns the Docker Engine API client tunneled over SSH, or
+// nil when the Docker socket is not accessible and the CLI must be used
 func (c *Connection) GetDockerClient() *client.Client {
-	// In v0.1.0, we don't use the Docker client API, just SSH commands
-	return nil
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.dockerAPI
+}
+
+// currentClient returns the SSH client, or nil when not connected over SSH
+func (c *Connection) currentClient() *ssh.Client {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.sshClient
 }
 
 // TestConnection tests SSH and Docker connectivity
 func (c *Connection) TestConnection() error {
+	return c.TestConnectionContext(context.Background())
+}
+
+// TestConnectionContext tests SSH and Docker connectivity, honoring ctx
+func (c *Connection) TestConnectionContext(ctx context.Context) error {
+	if c.dsmClient != nil {
+		if _, err := c.dsmClient.ListContainers(ctx); err != nil {
+			return fmt.Errorf("DSM Web API connection test failed: %w", err)
+		}
+		return nil
+	}
+
 	// Test SSH connection
-	if _, err := c.ExecuteCommand("echo 'SSH connection test'"); err != nil {
+	if _, err := c.ExecuteCommandContext(ctx, "echo 'SSH connection test'"); err != nil {
 		return fmt.Errorf("ssh connection test failed: %w", err)
 	}
 
 	// Test Docker command
-	if _, err := c.ExecuteDockerCommand([]string{"version", "--format", "'{{.Server.Version}}'"}); err != nil {
+	if _, err := c.ExecuteDockerCommandContext(ctx, []string{"version", "--format", "{{.Server.Version}}"}); err != nil {
 		return fmt.Errorf("docker connection test failed: %w", err)
 	}
 
@@ -161,27 +362,84 @@ func (c *Connection) TestConnection() error {
 
 // StreamCommand executes a command and streams output to writers
 func (c *Connection) StreamCommand(cmd string, stdout, stderr io.Writer) error {
-	if c.sshClient == nil {
-		return fmt.Errorf("SSH client not connected")
-	}
+	return c.StreamCommandContext(context.Background(), cmd, stdout, stderr)
+}
 
-	session, err := c.sshClient.NewSession()
-	if err != nil {
-		return fmt.Errorf("failed to create SSH session: %w", err)
+// StreamCommandContext executes a command and streams output to writers until
+// the command exits or ctx is done
+func (c *Connection) StreamCommandContext(ctx context.Context, cmd string, stdout, stderr io.Writer) error {
+	return c.RunSessionContext(ctx, cmd, &SessionOptions{Stdout: stdout, Stderr: stderr})
+}
+
+// StreamDockerCommand executes a Docker command with full path and streams
+// output to writers, quoting every argument for the remote shell
+func (c *Connection) StreamDockerCommand(args []string, stdout, stderr io.Writer) error {
+	return c.StreamDockerCommandContext(context.Background(), args, stdout, stderr)
+}
+
+// StreamDockerCommandContext is like StreamDockerCommand but honors ctx
+func (c *Connection) StreamDockerCommandContext(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	return c.StreamCommandContext(ctx, DockerCommand(args), stdout, stderr)
+}
+
+// commandError converts a failed session into a *RemoteCommandError when the
+// remote command exited with a status
+func commandError(cmd string, err error, stdout, stderr string) error {
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return &RemoteCommandError{
+			Command:    cmd,
+			ExitStatus: exitErr.ExitStatus(),
+			Stdout:     stdout,
+			Stderr:     stderr,
+			Err:        err,
+		}
 	}
-	defer session.Close()
+	return fmt.Errorf("command failed: %w", err)
+}
 
-	// Set up environment
-	session.Setenv("PATH", "/usr/local/bin:/usr/bin:/bin")
+// headBuffer keeps the first limit bytes written to it and discards the rest
+type headBuffer struct {
+	bytes.Buffer
+	limit int
+}
 
-	// Connect streams
-	session.Stdout = stdout
-	session.Stderr = stderr
+func (b *headBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.Len(); remaining > 0 {
+		if len(p) > remaining {
+			b.Buffer.Write(p[:remaining])
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+// remoteSession is the part of an SSH session needed to stop a remote command
+type remoteSession interface {
+	Signal(sig ssh.Signal) error
+	Close() error
+}
 
-	// Run command
-	if err := session.Run(cmd); err != nil {
-		return fmt.Errorf("command failed: %w", err)
+// runSession runs fn, which drives session, until it returns or ctx is done.
+// On cancellation the remote process is sent SIGTERM and the session is closed.
+func runSession(ctx context.Context, session remoteSession, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
-	return nil
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		session.Signal(ssh.SIGTERM)
+		session.Close()
+		<-done
+		return ctx.Err()
+	}
 }
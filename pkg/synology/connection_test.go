@@ -1,7 +1,13 @@
 package synology
 
 import (
+	"context"
+	"errors"
+	"io"
 	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
 
 	"github.com/scttfrdmn/syno-docker/pkg/config"
 )
@@ -58,7 +64,7 @@ func TestExecuteDockerCommand(t *testing.T) {
 	}
 
 	// Verify the command would be constructed correctly
-	cmd := DockerBinary + " " + "ps -a"
+	cmd := DockerCommand([]string{"ps", "-a"})
 	if cmd != expectedCmd {
 		t.Errorf("Expected command %s, got %s", expectedCmd, cmd)
 	}
@@ -102,3 +108,96 @@ func TestConstants(t *testing.T) {
 		t.Errorf("Expected DefaultSSHUser to be admin, got %s", DefaultSSHUser)
 	}
 }
+
+type fakeSession struct {
+	signals []ssh.Signal
+	closed  chan struct{}
+}
+
+func newFakeSession() *fakeSession {
+	return &fakeSession{closed: make(chan struct{})}
+}
+
+func (s *fakeSession) Signal(sig ssh.Signal) error {
+	s.signals = append(s.signals, sig)
+	return nil
+}
+
+func (s *fakeSession) Close() error {
+	close(s.closed)
+	return nil
+}
+
+func TestRunSessionCompletes(t *testing.T) {
+	session := newFakeSession()
+
+	err := runSession(context.Background(), session, func() error { return nil })
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(session.signals) != 0 {
+		t.Errorf("Expected no signals, got %v", session.signals)
+	}
+}
+
+func TestRunSessionCancelled(t *testing.T) {
+	session := newFakeSession()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// Simulate a remote command that only returns once the session is closed
+	err := runSession(ctx, session, func() error {
+		<-session.closed
+		return io.EOF
+	})
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if len(session.signals) != 1 || session.signals[0] != ssh.SIGTERM {
+		t.Errorf("Expected SIGTERM to be sent, got %v", session.signals)
+	}
+}
+
+func TestRunSessionAlreadyCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	called := false
+	err := runSession(ctx, newFakeSession(), func() error {
+		called = true
+		return nil
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if called {
+		t.Error("Command should not run when the context is already done")
+	}
+}
+
+func TestConnectContextCancelled(t *testing.T) {
+	cfg := &config.Config{
+		Host:        "192.0.2.1",
+		User:        "admin",
+		Port:        22,
+		AuthMethods: []string{config.AuthPassword},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	conn := NewConnection(cfg)
+	if err := conn.connectSSH(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestExecuteCommandContextNotConnected(t *testing.T) {
+	conn := NewConnection(&config.Config{})
+
+	if _, err := conn.ExecuteCommandContext(context.Background(), "true"); err == nil {
+		t.Error("Expected error when not connected")
+	}
+}
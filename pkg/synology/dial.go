@@ -0,0 +1,36 @@
+package synology
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// DialContext opens a connection from the NAS to addr, tunnelled over the
+// SSH connection. network is "tcp" for a host and port as seen from the NAS,
+// such as a container IP, or "unix" for a socket path such as SocketPath.
+func (c *Connection) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	sshClient := c.currentClient()
+	if sshClient == nil {
+		return nil, c.notConnectedError()
+	}
+
+	conn, err := sshClient.DialContext(ctx, network, addr)
+	if err != nil {
+		var openErr *ssh.OpenChannelError
+		refused := errors.As(err, &openErr)
+		if refused && openErr.Reason == ssh.Prohibited {
+			return nil, fmt.Errorf("the NAS refused to forward to %s (is AllowTcpForwarding, or AllowStreamLocalForwarding for sockets, disabled in /etc/ssh/sshd_config?): %w", addr, err)
+		}
+		err = fmt.Errorf("failed to connect to %s from the NAS: %w", addr, err)
+		// Unless the server refused the channel, the transport is gone
+		if !refused && ctx.Err() == nil {
+			return nil, lostError(err)
+		}
+		return nil, err
+	}
+	return conn, nil
+}
@@ -0,0 +1,16 @@
+package synology
+
+import (
+	"context"
+	"testing"
+
+	"github.com/scttfrdmn/syno-docker/pkg/config"
+)
+
+func TestDialContextNotConnected(t *testing.T) {
+	conn := NewConnection(config.New())
+
+	if _, err := conn.DialContext(context.Background(), "tcp", "172.17.0.2:80"); err == nil {
+		t.Error("Expected error when not connected")
+	}
+}
@@ -0,0 +1,112 @@
+package synology
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+
+	"github.com/scttfrdmn/syno-docker/pkg/config"
+	"github.com/scttfrdmn/syno-docker/pkg/dsm"
+)
+
+// OTPEnv supplies the 2-step verification code for DSM Web API logins
+const OTPEnv = "SYNO_DOCKER_OTP"
+
+// DSMClient returns the DSM Web API client, or nil when the connection uses
+// SSH. Only listing, starting, stopping and restarting containers and
+// reading their logs are available through it.
+func (c *Connection) DSMClient() *dsm.Client {
+	return c.dsmClient
+}
+
+// dsmFallback reports whether the DSM Web API should be tried after SSH
+// failed with err
+func (c *Connection) dsmFallback(ctx context.Context, err error) bool {
+	transport := c.config.Transport
+	return ctx.Err() == nil && c.config.DSM.URL != "" && (transport == "" || transport == config.TransportAuto) && isTransportError(err)
+}
+
+// isTransportError reports whether err means the NAS could not be reached
+// over SSH. A rejected host key or rejected credentials are not transport
+// errors: falling back to the DSM Web API would hide a possible
+// man-in-the-middle or a misconfiguration.
+func isTransportError(err error) bool {
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	return errors.As(err, &opErr) || errors.As(err, &dnsErr) ||
+		errors.As(err, &netErr) && netErr.Timeout() ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// dsmURL returns the configured DSM address, or the default HTTPS port on
+// the configured host
+func (c *Connection) dsmURL() string {
+	if c.config.DSM.URL != "" {
+		return c.config.DSM.URL
+	}
+	return "https://" + net.JoinHostPort(c.config.Host, strconv.Itoa(dsm.DefaultPort))
+}
+
+// connectDSM logs in to the DSM Web API with the account password from
+// PasswordEnv or the prompt, and a 2-step verification code from OTPEnv or
+// the prompt when the account needs one
+func (c *Connection) connectDSM(ctx context.Context) error {
+	client, err := dsm.New(c.dsmURL(), &dsm.Options{InsecureSkipVerify: c.config.DSM.InsecureSkipVerify})
+	if err != nil {
+		return err
+	}
+	if err := client.Discover(ctx); err != nil {
+		return err
+	}
+
+	password, ok := os.LookupEnv(PasswordEnv)
+	if !ok {
+		password, err = c.prompt(fmt.Sprintf("DSM password for %s at %s: ", c.config.User, client.URL()), false)
+		if err != nil {
+			return fmt.Errorf("%w (set %s to provide the password)", err, PasswordEnv)
+		}
+	}
+
+	opts := &dsm.LoginOptions{Account: c.config.User, Password: password, OTPCode: os.Getenv(OTPEnv)}
+	err = client.Login(ctx, opts)
+	if dsm.IsCode(err, dsm.CodeOTPRequired) && opts.OTPCode == "" {
+		opts.OTPCode, err = c.prompt("DSM 2-step verification code: ", true)
+		if err != nil {
+			return fmt.Errorf("%w (set %s to provide the code)", err, OTPEnv)
+		}
+		err = client.Login(ctx, opts)
+	}
+	if err != nil {
+		return err
+	}
+
+	c.dsmClient = client
+	return nil
+}
+
+// closeDSM ends the DSM Web API session. A session the NAS already ended
+// is not an error.
+func (c *Connection) closeDSM() error {
+	if c.dsmClient == nil {
+		return nil
+	}
+	err := c.dsmClient.Logout(context.Background())
+	c.dsmClient = nil
+	if dsm.IsSessionError(err) {
+		return nil
+	}
+	return err
+}
+
+// notConnectedError explains why an operation that needs SSH can't run
+func (c *Connection) notConnectedError() error {
+	if c.dsmClient != nil {
+		return fmt.Errorf("%w: it needs SSH, and only listing, starting, stopping and restarting containers and reading logs work without it", ErrUnsupported)
+	}
+	return fmt.Errorf("SSH client not connected")
+}
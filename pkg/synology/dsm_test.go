@@ -0,0 +1,195 @@
+package synology
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/scttfrdmn/syno-docker/pkg/config"
+	"github.com/scttfrdmn/syno-docker/pkg/dsm/dsmtest"
+)
+
+// dsmTestConfig returns a config for server whose SSH port refuses
+// connections
+func dsmTestConfig(t *testing.T, server *dsmtest.Server) *config.Config {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	cfg := config.New()
+	cfg.Host = "127.0.0.1"
+	cfg.Port = port
+	cfg.User = server.Account
+	cfg.SSHKeyPath = "/nonexistent/key"
+	cfg.DSM.URL = server.URL
+	cfg.DSM.InsecureSkipVerify = true
+	return cfg
+}
+
+func TestConnectDSM(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(PasswordEnv, dsmtest.DefaultPassword)
+	server := dsmtest.NewServer(t)
+	server.AddContainer(dsmtest.FakeContainer{Name: "web", Image: "nginx:latest", Running: true})
+
+	tests := []struct {
+		name      string
+		transport string
+		wantErr   string
+	}{
+		{"dsm only", config.TransportDSM, ""},
+		{"fallback", "", ""},
+		{"explicit auto", config.TransportAuto, ""},
+		{"ssh only", config.TransportSSH, "failed to establish SSH connection"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := dsmTestConfig(t, server)
+			cfg.Transport = tt.transport
+			conn := NewConnection(cfg)
+
+			err := conn.ConnectContext(context.Background())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) || conn.DSMClient() != nil {
+					t.Fatalf("Expected %q without DSM, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ConnectContext failed: %v", err)
+			}
+			if conn.DSMClient() == nil || conn.GetDockerClient() != nil {
+				t.Fatal("Expected the DSM Web API to be used")
+			}
+			if err := conn.TestConnection(); err != nil {
+				t.Errorf("TestConnection failed: %v", err)
+			}
+
+			if err := conn.Close(); err != nil {
+				t.Errorf("Close failed: %v", err)
+			}
+			if server.Sessions() != 0 {
+				t.Errorf("Expected Close to log out, %d sessions left", server.Sessions())
+			}
+		})
+	}
+}
+
+func TestConnectDSMNoFallbackWithoutURL(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	server := dsmtest.NewServer(t)
+	cfg := dsmTestConfig(t, server)
+	cfg.DSM.URL = ""
+
+	conn := NewConnection(cfg)
+	err := conn.ConnectContext(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "failed to establish SSH connection") || len(server.Calls()) != 0 {
+		t.Errorf("Expected the SSH error without trying DSM, got %v", err)
+	}
+}
+
+func TestConnectDSMPrompts(t *testing.T) {
+	os.Unsetenv(PasswordEnv)
+	os.Unsetenv(OTPEnv)
+	server := dsmtest.NewServer(t)
+	server.OTPCode = "654321"
+
+	cfg := dsmTestConfig(t, server)
+	cfg.Transport = config.TransportDSM
+	conn := NewConnection(cfg)
+
+	var prompts []string
+	conn.SetCredentialPrompt(func(prompt string, echo bool) (string, error) {
+		prompts = append(prompts, prompt)
+		if strings.Contains(prompt, "password") {
+			return dsmtest.DefaultPassword, nil
+		}
+		return "654321", nil
+	})
+
+	if err := conn.ConnectContext(context.Background()); err != nil {
+		t.Fatalf("ConnectContext failed: %v", err)
+	}
+	defer conn.Close()
+	if len(prompts) != 2 || !strings.HasPrefix(prompts[1], "DSM 2-step verification code") {
+		t.Errorf("Expected password and code prompts, got %q", prompts)
+	}
+}
+
+func TestConnectDSMLoginFailed(t *testing.T) {
+	t.Setenv(PasswordEnv, "wrong")
+	server := dsmtest.NewServer(t)
+	cfg := dsmTestConfig(t, server)
+	cfg.Transport = config.TransportDSM
+
+	err := NewConnection(cfg).ConnectContext(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "no such account or incorrect password") {
+		t.Errorf("Expected login error, got %v", err)
+	}
+}
+
+func TestDSMUnsupported(t *testing.T) {
+	t.Setenv(PasswordEnv, dsmtest.DefaultPassword)
+	server := dsmtest.NewServer(t)
+	cfg := dsmTestConfig(t, server)
+	cfg.Transport = config.TransportDSM
+	conn := NewConnection(cfg)
+	if err := conn.ConnectContext(context.Background()); err != nil {
+		t.Fatalf("ConnectContext failed: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecuteDockerCommand([]string{"ps"}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported for commands, got %v", err)
+	}
+	if _, err := conn.SFTPClient(); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported for SFTP, got %v", err)
+	}
+}
+
+func TestDSMURL(t *testing.T) {
+	cfg := config.New()
+	cfg.Host = "nas.local"
+	conn := NewConnection(cfg)
+	if got := conn.dsmURL(); got != "https://nas.local:5001" {
+		t.Errorf("dsmURL() = %s", got)
+	}
+
+	cfg.DSM.URL = "http://nas.local:5000"
+	if got := conn.dsmURL(); got != cfg.DSM.URL {
+		t.Errorf("Expected the configured URL, got %s", got)
+	}
+}
+
+func TestIsTransportError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"connection refused", fmt.Errorf("failed to dial SSH: %w", &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}), true},
+		{"unknown host", &net.DNSError{Err: "no such host", Name: "nas.invalid"}, true},
+		{"connection closed during handshake", fmt.Errorf("ssh: handshake failed: %w", io.EOF), true},
+		{"host key mismatch", fmt.Errorf("failed to dial SSH: ssh: handshake failed: %w", &HostKeyMismatchError{Host: "nas.local"}), false},
+		{"unknown host key", fmt.Errorf("failed to dial SSH: ssh: handshake failed: %w", &UnknownHostKeyError{Host: "nas.local"}), false},
+		{"authentication failed", errors.New("ssh: handshake failed: ssh: unable to authenticate, attempted methods [none publickey], no supported methods remain"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransportError(tt.err); got != tt.want {
+				t.Errorf("isTransportError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package synology
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/client"
+
+	"github.com/scttfrdmn/syno-docker/pkg/dsm"
+)
+
+// Sentinel errors for common Docker daemon failures. Use errors.Is to test
+// for them on errors returned by this package and pkg/deploy.
+var (
+	// ErrNotFound means the container, image, volume or network does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrConflict means the object is in use, already exists or is in the wrong state
+	ErrConflict = errors.New("conflict")
+	// ErrDaemonUnavailable means the Docker daemon on the NAS could not be reached
+	ErrDaemonUnavailable = errors.New("docker daemon unavailable")
+	// ErrConnectionLost means the SSH connection to the NAS dropped mid-operation.
+	// Connection.Retry reconnects and repeats operations that fail with it.
+	ErrConnectionLost = errors.New("connection lost")
+	// ErrUnsupported means the operation needs SSH but the connection uses
+	// the DSM Web API
+	ErrUnsupported = errors.New("not supported over the DSM Web API")
+)
+
+// RemoteCommandError is returned when a remote command exits with a non-zero status
+type RemoteCommandError struct {
+	Command    string
+	ExitStatus int
+	Stdout     string
+	Stderr     string
+	Err        error
+}
+
+// Error returns the exit status and the remote stderr
+func (e *RemoteCommandError) Error() string {
+	msg := fmt.Sprintf("command failed with exit status %d", e.ExitStatus)
+	if stderr := strings.TrimSpace(e.Stderr); stderr != "" {
+		msg += ": " + stderr
+	}
+	return msg
+}
+
+// Unwrap exposes the classified sentinel error, if any, and the underlying SSH error
+func (e *RemoteCommandError) Unwrap() []error {
+	errs := []error{}
+	if kind := classifyMessage(e.Stderr); kind != nil {
+		errs = append(errs, kind)
+	}
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	return errs
+}
+
+// classifiedError attaches a sentinel error to an error without changing its message
+type classifiedError struct {
+	kind error
+	err  error
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+// ClassifyError maps Docker API and daemon errors to ErrNotFound, ErrConflict
+// or ErrDaemonUnavailable so callers can test them with errors.Is. Errors that
+// do not match any category are returned unchanged.
+func ClassifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	for _, kind := range []error{ErrNotFound, ErrConflict, ErrDaemonUnavailable, ErrConnectionLost} {
+		if errors.Is(err, kind) {
+			return err
+		}
+	}
+
+	var kind error
+	switch {
+	case dsm.IsSessionError(err):
+		kind = ErrConnectionLost
+	case client.IsErrConnectionFailed(err), cerrdefs.IsUnavailable(err):
+		kind = ErrDaemonUnavailable
+	case cerrdefs.IsNotFound(err):
+		kind = ErrNotFound
+	case cerrdefs.IsConflict(err):
+		kind = ErrConflict
+	default:
+		kind = classifyMessage(err.Error())
+	}
+
+	if kind == nil {
+		return err
+	}
+	return &classifiedError{kind: kind, err: err}
+}
+
+// networkNotFound matches the daemon's error for a missing network, which
+// unlike other objects isn't reported as "No such network"
+var networkNotFound = regexp.MustCompile(`\bnetwork \S+ not found`)
+
+// classifyMessage maps docker CLI and daemon error messages to sentinel
+// errors. Missing commands, such as docker outside $PATH, aren't ErrNotFound.
+func classifyMessage(msg string) error {
+	msg = strings.ToLower(msg)
+
+	switch {
+	case strings.Contains(msg, "cannot connect to the docker daemon"),
+		strings.Contains(msg, "is the docker daemon running"),
+		strings.Contains(msg, "error during connect"):
+		return ErrDaemonUnavailable
+	case strings.Contains(msg, "no such container"),
+		strings.Contains(msg, "no such image"),
+		strings.Contains(msg, "no such volume"),
+		strings.Contains(msg, "no such network"),
+		strings.Contains(msg, "no such object"),
+		strings.Contains(msg, "no such file or directory"),
+		networkNotFound.MatchString(msg),
+		strings.Contains(msg, "does not exist"),
+		strings.Contains(msg, "manifest unknown"):
+		return ErrNotFound
+	case strings.Contains(msg, "conflict"),
+		strings.Contains(msg, "already in use"),
+		strings.Contains(msg, "already exists"),
+		strings.Contains(msg, "is in use"),
+		strings.Contains(msg, "is not running"),
+		strings.Contains(msg, "cannot remove a running container"):
+		return ErrConflict
+	}
+
+	return nil
+}
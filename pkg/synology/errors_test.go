@@ -0,0 +1,134 @@
+package synology
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	cerrdefs "github.com/containerd/errdefs"
+)
+
+func TestClassifyMessage(t *testing.T) {
+	tests := []struct {
+		message  string
+		expected error
+	}{
+		{"Error response from daemon: No such container: web", ErrNotFound},
+		{"Error: No such object: missing", ErrNotFound},
+		{"Error response from daemon: get data: no such volume", ErrNotFound},
+		{"Error response from daemon: network backend not found", ErrNotFound},
+		{"Error response from daemon: manifest for nginx:nope not found: manifest unknown", ErrNotFound},
+		{"Error: No such image: nginx:nope", ErrNotFound},
+		{"cat: /var/packages/ContainerManager/etc/dockerd.json: No such file or directory", ErrNotFound},
+		{`exec: "docker": executable file not found in $PATH`, nil},
+		{"sudo: docker: command not found", nil},
+		{"sh: docker: not found", nil},
+		{`docker: Error response from daemon: Conflict. The container name "/web" is already in use by container "abc".`, ErrConflict},
+		{"Error response from daemon: remove data: volume is in use - [abc]", ErrConflict},
+		{"Error response from daemon: network with name backend already exists", ErrConflict},
+		{"Error response from daemon: container abc is not running", ErrConflict},
+		{"Cannot connect to the Docker daemon at unix:///var/run/docker.sock. Is the docker daemon running?", ErrDaemonUnavailable},
+		{"error during connect: Get http://docker/v1.43/info: EOF", ErrDaemonUnavailable},
+		{"invalid reference format", nil},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			if result := classifyMessage(tt.message); result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestRemoteCommandError(t *testing.T) {
+	err := &RemoteCommandError{
+		Command:    "/usr/local/bin/docker rm web",
+		ExitStatus: 1,
+		Stderr:     "Error response from daemon: No such container: web\n",
+	}
+
+	expected := "command failed with exit status 1: Error response from daemon: No such container: web"
+	if err.Error() != expected {
+		t.Errorf("Expected %q, got %q", expected, err.Error())
+	}
+
+	wrapped := fmt.Errorf("failed to remove container: %w", err)
+	if !errors.Is(wrapped, ErrNotFound) {
+		t.Error("Expected error to match ErrNotFound")
+	}
+	if errors.Is(wrapped, ErrConflict) {
+		t.Error("Expected error not to match ErrConflict")
+	}
+
+	var remoteErr *RemoteCommandError
+	if !errors.As(wrapped, &remoteErr) || remoteErr.ExitStatus != 1 {
+		t.Error("Expected to recover the RemoteCommandError and its exit status")
+	}
+}
+
+func TestRemoteCommandErrorWithoutStderr(t *testing.T) {
+	err := &RemoteCommandError{ExitStatus: 137}
+
+	if err.Error() != "command failed with exit status 137" {
+		t.Errorf("Unexpected message %q", err.Error())
+	}
+	for _, kind := range []error{ErrNotFound, ErrConflict, ErrDaemonUnavailable} {
+		if errors.Is(err, kind) {
+			t.Errorf("Expected no classification, matched %v", kind)
+		}
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected error
+	}{
+		{"typed not found", fmt.Errorf("container web: %w", cerrdefs.ErrNotFound), ErrNotFound},
+		{"typed conflict", fmt.Errorf("name in use: %w", cerrdefs.ErrConflict), ErrConflict},
+		{"typed unavailable", cerrdefs.ErrUnavailable, ErrDaemonUnavailable},
+		{"message", errors.New("Error response from daemon: No such image: foo"), ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ClassifyError(tt.err)
+			if !errors.Is(result, tt.expected) {
+				t.Errorf("Expected %v to match %v", result, tt.expected)
+			}
+			if result.Error() != tt.err.Error() {
+				t.Errorf("Expected message %q to be preserved, got %q", tt.err.Error(), result.Error())
+			}
+			if !errors.Is(result, tt.err) {
+				t.Error("Expected original error to remain in the chain")
+			}
+		})
+	}
+
+	if ClassifyError(nil) != nil {
+		t.Error("Expected nil for nil error")
+	}
+
+	plain := errors.New("invalid reference format")
+	if ClassifyError(plain) != plain {
+		t.Error("Expected unclassified errors to be returned unchanged")
+	}
+}
+
+func TestHeadBuffer(t *testing.T) {
+	buf := &headBuffer{limit: 5}
+
+	for _, chunk := range []string{"abc", "defg", "hij"} {
+		n, err := buf.Write([]byte(chunk))
+		if err != nil || n != len(chunk) {
+			t.Fatalf("Write(%q) = %d, %v", chunk, n, err)
+		}
+	}
+
+	if buf.String() != "abcde" {
+		t.Errorf("Expected abcde, got %q", buf.String())
+	}
+}
@@ -0,0 +1,71 @@
+package synology
+
+import (
+	"context"
+	"io"
+	"net"
+
+	"github.com/docker/docker/client"
+	"github.com/pkg/sftp"
+
+	"github.com/scttfrdmn/syno-docker/pkg/dsm"
+)
+
+// Executor runs commands on the NAS. *Connection is the SSH implementation;
+// package synologytest provides a scriptable fake for tests.
+type Executor interface {
+	ExecuteCommand(cmd string) (string, error)
+	ExecuteCommandContext(ctx context.Context, cmd string) (string, error)
+	ExecuteDockerCommand(args []string) (string, error)
+	ExecuteDockerCommandContext(ctx context.Context, args []string) (string, error)
+	StreamCommand(cmd string, stdout, stderr io.Writer) error
+	StreamCommandContext(ctx context.Context, cmd string, stdout, stderr io.Writer) error
+	RunSessionContext(ctx context.Context, cmd string, opts *SessionOptions) error
+}
+
+// DockerClientProvider is implemented by executors that can also reach the
+// Docker Engine API. GetDockerClient returns nil when the API is unavailable.
+type DockerClientProvider interface {
+	GetDockerClient() *client.Client
+}
+
+// DSMClientProvider is implemented by executors that can reach the DSM Web
+// API. DSMClient returns nil unless SSH is unavailable and the DSM Web API
+// is used instead.
+type DSMClientProvider interface {
+	DSMClient() *dsm.Client
+}
+
+// Reconnector is implemented by executors that can redial a dropped
+// connection and repeat an operation
+type Reconnector interface {
+	Retry(ctx context.Context, fn func() error) error
+}
+
+// FileTransferer is implemented by executors that can open an SFTP session
+// for copying files to and from the NAS
+type FileTransferer interface {
+	SFTPClient() (*sftp.Client, error)
+}
+
+// Dialer is implemented by executors that can tunnel connections through
+// the NAS, to addresses only it can reach
+type Dialer interface {
+	DialContext(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// NASInfoProvider is implemented by executors that know what the NAS runs,
+// so callers can gate features on it
+type NASInfoProvider interface {
+	NASInfo(ctx context.Context) (*NASInfo, error)
+}
+
+var (
+	_ Executor             = (*Connection)(nil)
+	_ DockerClientProvider = (*Connection)(nil)
+	_ DSMClientProvider    = (*Connection)(nil)
+	_ Reconnector          = (*Connection)(nil)
+	_ FileTransferer       = (*Connection)(nil)
+	_ Dialer               = (*Connection)(nil)
+	_ NASInfoProvider      = (*Connection)(nil)
+)
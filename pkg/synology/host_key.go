@@ -0,0 +1,174 @@
+package synology
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/scttfrdmn/syno-docker/pkg/config"
+)
+
+// HostKeyPrompt asks the user whether to trust a host key seen for the first time
+type HostKeyPrompt func(host, fingerprint string) (bool, error)
+
+// HostKeyMismatchError is returned when the NAS presents a different host key
+// than the one previously trusted
+type HostKeyMismatchError struct {
+	Host        string
+	Fingerprint string
+	Expected    string
+}
+
+func (e *HostKeyMismatchError) Error() string {
+	return fmt.Sprintf("host key verification failed for %s: got %s, expected %s. "+
+		"The host key has changed, which may indicate a man-in-the-middle attack. "+
+		"If the change is legitimate, remove the old entry from your known_hosts file and run 'syno-docker init' again",
+		e.Host, e.Fingerprint, e.Expected)
+}
+
+// UnknownHostKeyError is returned when the NAS host key is not trusted yet and
+// no prompt is available to ask the user
+type UnknownHostKeyError struct {
+	Host        string
+	Fingerprint string
+}
+
+func (e *UnknownHostKeyError) Error() string {
+	return fmt.Sprintf("host key for %s is not trusted (fingerprint %s). Run 'syno-docker init' to verify and trust it",
+		e.Host, e.Fingerprint)
+}
+
+// SetHostKeyPrompt sets the trust-on-first-use prompt used for unknown host keys
+func (c *Connection) SetHostKeyPrompt(prompt HostKeyPrompt) {
+	c.hostKeyPrompt = prompt
+}
+
+// knownHostsFiles returns the known_hosts files that exist on this machine
+func knownHostsFiles() []string {
+	var candidates []string
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		candidates = append(candidates, filepath.Join(homeDir, ".ssh", "known_hosts"))
+	}
+	if path, err := config.GetKnownHostsPath(); err == nil {
+		candidates = append(candidates, path)
+	}
+
+	var files []string
+	for _, path := range candidates {
+		if _, err := os.Stat(path); err == nil {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// hostKeyCallback verifies the NAS host key against known_hosts and the
+// fingerprint pinned in the configuration
+func (c *Connection) hostKeyCallback() ssh.HostKeyCallback {
+	return c.newHostKeyCallback(&c.config.HostKeyFingerprint)
+}
+
+// jumpHostKeyCallback verifies a jump host key against known_hosts only;
+// the pinned fingerprint belongs to the NAS
+func (c *Connection) jumpHostKeyCallback() ssh.HostKeyCallback {
+	return c.newHostKeyCallback(nil)
+}
+
+// newHostKeyCallback verifies host keys against known_hosts, asking the user
+// about unknown hosts. When pin is set, its fingerprint is enforced and a newly
+// trusted key is recorded in it.
+func (c *Connection) newHostKeyCallback(pin *string) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		if c.config.InsecureSkipHostKeyCheck {
+			return nil
+		}
+
+		fingerprint := ssh.FingerprintSHA256(key)
+		pinned := ""
+		if pin != nil {
+			pinned = *pin
+		}
+
+		if files := knownHostsFiles(); len(files) > 0 {
+			callback, err := knownhosts.New(files...)
+			if err != nil {
+				return fmt.Errorf("failed to load known_hosts: %w", err)
+			}
+
+			err = callback(hostname, remote, key)
+			var keyErr *knownhosts.KeyError
+			switch {
+			case err == nil:
+				if pinned != "" && pinned != fingerprint {
+					return &HostKeyMismatchError{Host: hostname, Fingerprint: fingerprint, Expected: pinned}
+				}
+				return nil
+			case errors.As(err, &keyErr) && len(keyErr.Want) > 0:
+				return &HostKeyMismatchError{
+					Host:        hostname,
+					Fingerprint: fingerprint,
+					Expected:    ssh.FingerprintSHA256(keyErr.Want[0].Key),
+				}
+			case !errors.As(err, &keyErr):
+				return err
+			}
+		}
+
+		// Host is not in any known_hosts file yet
+		if pinned != "" {
+			if pinned != fingerprint {
+				return &HostKeyMismatchError{Host: hostname, Fingerprint: fingerprint, Expected: pinned}
+			}
+			return addKnownHost(hostname, remote, key)
+		}
+
+		if c.hostKeyPrompt == nil {
+			return &UnknownHostKeyError{Host: hostname, Fingerprint: fingerprint}
+		}
+
+		trusted, err := c.hostKeyPrompt(hostname, fingerprint)
+		if err != nil {
+			return fmt.Errorf("host key prompt failed: %w", err)
+		}
+		if !trusted {
+			return fmt.Errorf("host key for %s was not accepted", hostname)
+		}
+
+		if pin != nil {
+			*pin = fingerprint
+		}
+		return addKnownHost(hostname, remote, key)
+	}
+}
+
+// addKnownHost records a trusted host key in the syno-docker known_hosts file
+func addKnownHost(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	path, err := config.GetKnownHostsPath()
+	if err != nil {
+		return err
+	}
+
+	addresses := []string{knownhosts.Normalize(hostname)}
+	if remote != nil {
+		if remoteAddr := knownhosts.Normalize(remote.String()); remoteAddr != addresses[0] {
+			addresses = append(addresses, remoteAddr)
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open known_hosts file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := fmt.Fprintln(f, knownhosts.Line(addresses, key)); err != nil {
+		return fmt.Errorf("failed to write known_hosts file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package synology
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net"
+	"os"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/scttfrdmn/syno-docker/pkg/config"
+)
+
+func newTestHostKey(t *testing.T) ssh.PublicKey {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate host key: %v", err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatalf("Failed to convert host key: %v", err)
+	}
+	return key
+}
+
+func TestHostKeyCallback(t *testing.T) {
+	tempDir := t.TempDir()
+	oldHome := os.Getenv("HOME")
+	defer os.Setenv("HOME", oldHome)
+	os.Setenv("HOME", tempDir)
+
+	remote := &net.TCPAddr{IP: net.ParseIP("192.168.1.100"), Port: 22}
+	hostKey := newTestHostKey(t)
+	otherKey := newTestHostKey(t)
+
+	cfg := &config.Config{Host: "192.168.1.100", User: "admin", Port: 22}
+	conn := NewConnection(cfg)
+	callback := conn.hostKeyCallback()
+
+	// Unknown host without a prompt must fail
+	err := callback("192.168.1.100:22", remote, hostKey)
+	var unknown *UnknownHostKeyError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("Expected UnknownHostKeyError, got %v", err)
+	}
+
+	// Rejected prompt must fail
+	conn.SetHostKeyPrompt(func(host, fingerprint string) (bool, error) {
+		return false, nil
+	})
+	if err := callback("192.168.1.100:22", remote, hostKey); err == nil {
+		t.Fatal("Expected error when host key is rejected")
+	}
+
+	// Accepted prompt trusts the key and pins the fingerprint
+	conn.SetHostKeyPrompt(func(host, fingerprint string) (bool, error) {
+		if fingerprint != ssh.FingerprintSHA256(hostKey) {
+			t.Errorf("Prompt got fingerprint %s, expected %s", fingerprint, ssh.FingerprintSHA256(hostKey))
+		}
+		return true, nil
+	})
+	if err := callback("192.168.1.100:22", remote, hostKey); err != nil {
+		t.Fatalf("Expected accepted host key, got %v", err)
+	}
+	if cfg.HostKeyFingerprint != ssh.FingerprintSHA256(hostKey) {
+		t.Errorf("Expected pinned fingerprint %s, got %s", ssh.FingerprintSHA256(hostKey), cfg.HostKeyFingerprint)
+	}
+
+	// Later connections succeed without prompting
+	conn.SetHostKeyPrompt(nil)
+	if err := callback("192.168.1.100:22", remote, hostKey); err != nil {
+		t.Errorf("Expected known host key to verify, got %v", err)
+	}
+
+	// A changed key fails hard
+	err = callback("192.168.1.100:22", remote, otherKey)
+	var mismatch *HostKeyMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("Expected HostKeyMismatchError, got %v", err)
+	}
+	if mismatch.Expected != ssh.FingerprintSHA256(hostKey) {
+		t.Errorf("Expected mismatch to report %s, got %s", ssh.FingerprintSHA256(hostKey), mismatch.Expected)
+	}
+
+	// Skipping the check accepts anything
+	cfg.InsecureSkipHostKeyCheck = true
+	if err := callback("192.168.1.100:22", remote, otherKey); err != nil {
+		t.Errorf("Expected insecure mode to accept any key, got %v", err)
+	}
+}
+
+func TestHostKeyCallbackPinnedFingerprint(t *testing.T) {
+	tempDir := t.TempDir()
+	oldHome := os.Getenv("HOME")
+	defer os.Setenv("HOME", oldHome)
+	os.Setenv("HOME", tempDir)
+
+	remote := &net.TCPAddr{IP: net.ParseIP("10.0.0.5"), Port: 2222}
+	hostKey := newTestHostKey(t)
+
+	cfg := &config.Config{
+		Host:               "10.0.0.5",
+		User:               "admin",
+		Port:               2222,
+		HostKeyFingerprint: ssh.FingerprintSHA256(newTestHostKey(t)),
+	}
+	callback := NewConnection(cfg).hostKeyCallback()
+
+	err := callback("10.0.0.5:2222", remote, hostKey)
+	var mismatch *HostKeyMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("Expected HostKeyMismatchError for pinned fingerprint, got %v", err)
+	}
+
+	cfg.HostKeyFingerprint = ssh.FingerprintSHA256(hostKey)
+	if err := callback("10.0.0.5:2222", remote, hostKey); err != nil {
+		t.Errorf("Expected pinned fingerprint to verify, got %v", err)
+	}
+}
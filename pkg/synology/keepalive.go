@@ -0,0 +1,224 @@
+package synology
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// DefaultKeepaliveInterval is how often an idle connection is probed
+	DefaultKeepaliveInterval = 15 * time.Second
+	// keepaliveMaxMissed is how many unanswered probes mark the connection dead
+	keepaliveMaxMissed = 3
+	// keepaliveRequest is the global request OpenSSH uses for ServerAliveInterval
+	keepaliveRequest = "keepalive@openssh.com"
+
+	// reconnectAttempts bounds how often Retry and Reconnect redial
+	reconnectAttempts = 5
+	// reconnectInitialDelay is the first backoff delay, doubled after each failure
+	reconnectInitialDelay = time.Second
+	// reconnectMaxDelay caps the backoff delay
+	reconnectMaxDelay = 30 * time.Second
+)
+
+// SetKeepaliveInterval sets how often the connection is probed once
+// connected. Zero or a negative interval disables keepalives.
+func (c *Connection) SetKeepaliveInterval(interval time.Duration) {
+	c.keepaliveInterval = interval
+}
+
+// startKeepalive probes the SSH connection in the background. After
+// keepaliveMaxMissed unanswered probes the connection is marked lost and
+// closed, so sessions blocked on a dead transport fail instead of hanging.
+func (c *Connection) startKeepalive() {
+	c.lost.Store(false)
+	if c.keepaliveInterval <= 0 || c.sshClient == nil {
+		return
+	}
+
+	stop := make(chan struct{})
+	c.keepaliveStop = stop
+	go keepalive(c.sshClient, c.keepaliveInterval, keepaliveMaxMissed, stop, func() {
+		c.lost.Store(true)
+	})
+}
+
+// stopKeepalive stops the background prober, if running
+func (c *Connection) stopKeepalive() {
+	if c.keepaliveStop != nil {
+		close(c.keepaliveStop)
+		c.keepaliveStop = nil
+	}
+}
+
+// keepaliveClient is the part of an SSH client needed to probe it
+type keepaliveClient interface {
+	SendRequest(name string, wantReply bool, payload []byte) (bool, []byte, error)
+	Close() error
+}
+
+// keepalive sends a keepalive request every interval until stop is closed.
+// A probe that fails or gets no reply within interval counts as missed; once
+// maxMissed are missed in a row, or the transport reports an error, onLost is
+// called and the client is closed.
+func keepalive(client keepaliveClient, interval time.Duration, maxMissed int, stop <-chan struct{}, onLost func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	missed := 0
+	for {
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+
+		reply := make(chan error, 1)
+		go func() {
+			// Servers that do not know the request answer with a failure,
+			// which still proves the transport is alive
+			_, _, err := client.SendRequest(keepaliveRequest, true, nil)
+			reply <- err
+		}()
+
+		select {
+		case err := <-reply:
+			if err != nil {
+				missed = maxMissed
+			} else {
+				missed = 0
+			}
+		case <-time.After(interval):
+			missed++
+		case <-stop:
+			return
+		}
+
+		if missed >= maxMissed {
+			onLost()
+			client.Close()
+			return
+		}
+	}
+}
+
+// Reconnect closes the connection and dials it again, retrying with
+// exponential backoff until it succeeds, the attempts run out or ctx is done
+func (c *Connection) Reconnect(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.reconnect(ctx)
+}
+
+// reconnectSince is Reconnect for a caller whose operation failed on the
+// connection of the given generation. If another caller has reconnected
+// since, the new connection is kept.
+func (c *Connection) reconnectSince(ctx context.Context, generation uint64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation != generation {
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Connection to %s lost, reconnecting...\n", c.config.Host)
+	return c.reconnect(ctx)
+}
+
+// reconnect is Reconnect for callers holding mu
+func (c *Connection) reconnect(ctx context.Context) error {
+	c.close()
+
+	delay := reconnectInitialDelay
+	var err error
+	for attempt := 1; attempt <= reconnectAttempts; attempt++ {
+		if err = c.ConnectContext(ctx); err == nil {
+			c.generation++
+			return nil
+		}
+		if attempt == reconnectAttempts {
+			break
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		delay = min(delay*2, reconnectMaxDelay)
+	}
+
+	return fmt.Errorf("failed to reconnect after %d attempts: %w", reconnectAttempts, err)
+}
+
+// currentGeneration returns the number of reconnects so far
+func (c *Connection) currentGeneration() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.generation
+}
+
+// Retry runs fn and, if it fails because the connection was lost, reconnects
+// and runs it again. Only use it for operations that are safe to repeat.
+// Concurrent calls that lose the same connection reconnect it once.
+func (c *Connection) Retry(ctx context.Context, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		generation := c.currentGeneration()
+		err := fn()
+		if err == nil || ctx.Err() != nil || attempt > reconnectAttempts {
+			return err
+		}
+		if !errors.Is(err, ErrConnectionLost) && !c.lost.Load() {
+			return err
+		}
+
+		if reconnectErr := c.reconnectSince(ctx, generation); reconnectErr != nil {
+			return fmt.Errorf("%w (reconnect failed: %v)", err, reconnectErr)
+		}
+	}
+}
+
+// connectionLost reports whether err from a session means the SSH transport
+// is gone rather than the remote command failing
+func (c *Connection) connectionLost(err error) bool {
+	if c.lost.Load() {
+		return true
+	}
+
+	var exitMissing *ssh.ExitMissingError
+	return errors.As(err, &exitMissing) || errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed)
+}
+
+// sessionError converts a failed session into a *RemoteCommandError, or marks
+// it with ErrConnectionLost when the transport dropped while ctx was live
+func (c *Connection) sessionError(ctx context.Context, cmd string, err error, stdout, stderr string) error {
+	var exitErr *ssh.ExitError
+	if !errors.As(err, &exitErr) && ctx.Err() == nil && c.connectionLost(err) {
+		return lostError(fmt.Errorf("connection lost: %w", err))
+	}
+	return commandError(cmd, err, stdout, stderr)
+}
+
+// openSessionError marks a failure to open a session with ErrConnectionLost
+// unless the server explicitly refused the channel, e.g. for MaxSessions
+func (c *Connection) openSessionError(err error) error {
+	var refused *ssh.OpenChannelError
+	refusedByServer := errors.As(err, &refused)
+
+	err = fmt.Errorf("failed to create SSH session: %w", err)
+	if !refusedByServer {
+		return lostError(err)
+	}
+	return err
+}
+
+// lostError attaches ErrConnectionLost to err without changing its message
+func lostError(err error) error {
+	return &classifiedError{kind: ErrConnectionLost, err: err}
+}
@@ -0,0 +1,170 @@
+package synology
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/scttfrdmn/syno-docker/pkg/config"
+)
+
+// fakeKeepaliveClient answers keepalive requests according to respond
+type fakeKeepaliveClient struct {
+	respond  func() error
+	requests atomic.Int32
+	closed   atomic.Bool
+}
+
+func (f *fakeKeepaliveClient) SendRequest(name string, wantReply bool, payload []byte) (bool, []byte, error) {
+	f.requests.Add(1)
+	if name != keepaliveRequest || !wantReply {
+		return false, nil, fmt.Errorf("unexpected request %s", name)
+	}
+	return true, nil, f.respond()
+}
+
+func (f *fakeKeepaliveClient) Close() error {
+	f.closed.Store(true)
+	return nil
+}
+
+func runKeepalive(client *fakeKeepaliveClient, wait time.Duration) bool {
+	stop := make(chan struct{})
+	lost := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		keepalive(client, 10*time.Millisecond, 3, stop, func() { close(lost) })
+		close(done)
+	}()
+
+	select {
+	case <-lost:
+		<-done
+		return true
+	case <-time.After(wait):
+		close(stop)
+		<-done
+		return false
+	}
+}
+
+func TestKeepaliveHealthy(t *testing.T) {
+	client := &fakeKeepaliveClient{respond: func() error { return nil }}
+
+	if runKeepalive(client, 100*time.Millisecond) {
+		t.Fatal("Expected healthy connection not to be marked lost")
+	}
+	if client.requests.Load() == 0 {
+		t.Error("Expected keepalive requests to be sent")
+	}
+	if client.closed.Load() {
+		t.Error("Expected healthy connection to stay open")
+	}
+}
+
+func TestKeepaliveTransportError(t *testing.T) {
+	client := &fakeKeepaliveClient{respond: func() error { return io.EOF }}
+
+	if !runKeepalive(client, time.Second) {
+		t.Fatal("Expected connection to be marked lost")
+	}
+	if client.requests.Load() != 1 {
+		t.Errorf("Expected a transport error to be fatal at once, sent %d requests", client.requests.Load())
+	}
+	if !client.closed.Load() {
+		t.Error("Expected lost connection to be closed")
+	}
+}
+
+func TestKeepaliveNoReply(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	client := &fakeKeepaliveClient{respond: func() error { <-block; return nil }}
+
+	if !runKeepalive(client, time.Second) {
+		t.Fatal("Expected unanswered keepalives to mark the connection lost")
+	}
+	if !client.closed.Load() {
+		t.Error("Expected lost connection to be closed")
+	}
+}
+
+func TestRetry(t *testing.T) {
+	conn := NewConnection(&config.Config{Host: "nas"})
+	errFailed := errors.New("failed")
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"success", nil},
+		{"other error", errFailed},
+		{"remote command error", &RemoteCommandError{ExitStatus: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := conn.Retry(context.Background(), func() error {
+				calls++
+				return tt.err
+			})
+			if err != tt.err {
+				t.Errorf("Expected %v, got %v", tt.err, err)
+			}
+			if calls != 1 {
+				t.Errorf("Expected one call, got %d", calls)
+			}
+		})
+	}
+}
+
+func TestRetryCancelled(t *testing.T) {
+	conn := NewConnection(&config.Config{Host: "nas"})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls := 0
+	err := conn.Retry(ctx, func() error {
+		calls++
+		return lostError(io.EOF)
+	})
+	if !errors.Is(err, ErrConnectionLost) {
+		t.Errorf("Expected ErrConnectionLost, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected no retry after cancellation, got %d calls", calls)
+	}
+}
+
+func TestSessionError(t *testing.T) {
+	conn := NewConnection(&config.Config{})
+	ctx := context.Background()
+
+	if err := conn.sessionError(ctx, "true", io.EOF, "", ""); !errors.Is(err, ErrConnectionLost) {
+		t.Errorf("Expected EOF to mean a lost connection, got %v", err)
+	}
+	if err := conn.sessionError(ctx, "true", &ssh.ExitMissingError{}, "", ""); !errors.Is(err, ErrConnectionLost) {
+		t.Errorf("Expected a missing exit status to mean a lost connection, got %v", err)
+	}
+	if err := conn.sessionError(ctx, "true", errors.New("boom"), "", ""); errors.Is(err, ErrConnectionLost) {
+		t.Errorf("Expected other errors not to mean a lost connection, got %v", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := conn.sessionError(cancelled, "true", io.EOF, "", ""); errors.Is(err, ErrConnectionLost) {
+		t.Errorf("Expected cancelled sessions not to mean a lost connection, got %v", err)
+	}
+
+	conn.lost.Store(true)
+	if err := conn.sessionError(ctx, "true", errors.New("boom"), "", ""); !errors.Is(err, ErrConnectionLost) {
+		t.Errorf("Expected errors after keepalive failure to mean a lost connection, got %v", err)
+	}
+}
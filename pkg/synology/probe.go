@@ -0,0 +1,233 @@
+package synology
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/scttfrdmn/syno-docker/pkg/config"
+)
+
+// NASInfoTTL is how long probed NAS information is reused before Probe runs
+// again
+const NASInfoTTL = 24 * time.Hour
+
+// Files Probe reads on the NAS
+const (
+	// VersionFile holds the DSM release as shell variables
+	VersionFile = "/etc.defaults/VERSION"
+	// HardwareVersionFile holds the model name, such as DS920+
+	HardwareVersionFile = "/proc/sys/kernel/syno_hw_version"
+)
+
+// NASInfo describes what a NAS runs, so commands can gate features on the
+// DSM release, the CPU architecture or the Docker version
+type NASInfo struct {
+	// Model is the model name, such as DS920+; empty when the NAS does not
+	// report one, as on Virtual DSM
+	Model string `json:"model,omitempty"`
+	// Arch is the machine hardware name from uname -m, such as x86_64 or aarch64
+	Arch string `json:"arch"`
+	// DSMVersion is the DSM product version, such as 7.2.1
+	DSMVersion string `json:"dsm_version"`
+	DSMMajor   int    `json:"dsm_major"`
+	DSMMinor   int    `json:"dsm_minor"`
+	DSMBuild   int    `json:"dsm_build"`
+	// DSMUpdate is the update number of the release, such as 5 for Update 5
+	DSMUpdate int `json:"dsm_update,omitempty"`
+	// DockerVersion and DockerAPIVersion are empty when Container Manager
+	// is not running
+	DockerVersion    string    `json:"docker_version,omitempty"`
+	DockerAPIVersion string    `json:"docker_api_version,omitempty"`
+	ProbedAt         time.Time `json:"probed_at"`
+}
+
+// AtLeastDSM reports whether the NAS runs DSM major.minor or later
+func (i *NASInfo) AtLeastDSM(major, minor int) bool {
+	return i.DSMMajor > major || i.DSMMajor == major && i.DSMMinor >= minor
+}
+
+// Architecture returns the Docker architecture of the NAS, such as amd64
+func (i *NASInfo) Architecture() string {
+	switch i.Arch {
+	case "x86_64":
+		return "amd64"
+	case "aarch64":
+		return "arm64"
+	case "armv7l":
+		return "arm"
+	case "i686":
+		return "386"
+	}
+	return i.Arch
+}
+
+// Platform returns the Docker platform of the NAS, such as linux/amd64
+func (i *NASInfo) Platform() string {
+	if i.Arch == "armv7l" {
+		return "linux/arm/v7"
+	}
+	return "linux/" + i.Architecture()
+}
+
+// DSMRelease returns the DSM release as DSM shows it, such as
+// "7.2.1-69057 Update 5"
+func (i *NASInfo) DSMRelease() string {
+	release := fmt.Sprintf("%s-%d", i.DSMVersion, i.DSMBuild)
+	if i.DSMUpdate > 0 {
+		release += fmt.Sprintf(" Update %d", i.DSMUpdate)
+	}
+	return release
+}
+
+// Probe reads the DSM release, model, CPU architecture and Docker version
+// from the NAS. A missing model or a stopped Docker daemon leaves those
+// fields empty.
+func Probe(ctx context.Context, exec Executor) (*NASInfo, error) {
+	info := &NASInfo{ProbedAt: time.Now().UTC()}
+
+	output, err := exec.ExecuteCommandContext(ctx, ShellJoin("cat", VersionFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the DSM version: %w", err)
+	}
+	if err := info.parseVersion(output); err != nil {
+		return nil, err
+	}
+
+	output, err = exec.ExecuteCommandContext(ctx, "uname -m")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the CPU architecture: %w", err)
+	}
+	info.Arch = strings.TrimSpace(output)
+
+	if output, err := exec.ExecuteCommandContext(ctx, ShellJoin("cat", HardwareVersionFile)); err == nil {
+		info.Model = strings.TrimSpace(output)
+	} else if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	output, err = exec.ExecuteCommandContext(ctx, DockerCommand([]string{"version", "--format", "{{.Server.Version}} {{.Server.APIVersion}}"}))
+	if err == nil {
+		fields := strings.Fields(output)
+		if len(fields) == 2 {
+			info.DockerVersion, info.DockerAPIVersion = fields[0], fields[1]
+		}
+	} else if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	return info, nil
+}
+
+// parseVersion reads the DSM release from the contents of VersionFile
+func (i *NASInfo) parseVersion(contents string) error {
+	values := map[string]string{}
+	for _, line := range strings.Split(contents, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if ok {
+			values[key] = strings.Trim(value, `"`)
+		}
+	}
+
+	var err error
+	if i.DSMMajor, err = strconv.Atoi(values["majorversion"]); err != nil {
+		return fmt.Errorf("failed to parse the DSM version: no majorversion in %s", VersionFile)
+	}
+	i.DSMMinor, _ = strconv.Atoi(values["minorversion"])
+	i.DSMBuild, _ = strconv.Atoi(values["buildnumber"])
+	i.DSMUpdate, _ = strconv.Atoi(values["smallfixnumber"])
+
+	i.DSMVersion = values["productversion"]
+	if i.DSMVersion == "" {
+		i.DSMVersion = fmt.Sprintf("%d.%d", i.DSMMajor, i.DSMMinor)
+	}
+	return nil
+}
+
+// NASInfo returns what the NAS runs, probing it at most once per
+// NASInfoTTL. Results are cached per host in the syno-docker config
+// directory.
+func (c *Connection) NASInfo(ctx context.Context) (*NASInfo, error) {
+	if c.nasInfo != nil {
+		return c.nasInfo, nil
+	}
+	if info := loadNASInfo(c.nasInfoKey()); info != nil && time.Since(info.ProbedAt) < NASInfoTTL {
+		c.nasInfo = info
+		return info, nil
+	}
+	return c.RefreshNASInfo(ctx)
+}
+
+// RefreshNASInfo probes the NAS again and updates the cache
+func (c *Connection) RefreshNASInfo(ctx context.Context) (*NASInfo, error) {
+	info, err := Probe(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	c.nasInfo = info
+	// A cache that can't be written only means probing again next time
+	_ = saveNASInfo(c.nasInfoKey(), info)
+	return info, nil
+}
+
+// nasInfoKey identifies the NAS in the cache
+func (c *Connection) nasInfoKey() string {
+	return net.JoinHostPort(c.config.Host, strconv.Itoa(c.config.Port))
+}
+
+// loadNASInfo returns the cached information for key, or nil
+func loadNASInfo(key string) *NASInfo {
+	cache, err := readNASInfoCache()
+	if err != nil {
+		return nil
+	}
+	return cache[key]
+}
+
+// nasInfoCacheMu serializes updates of the cache file by concurrent connections
+var nasInfoCacheMu sync.Mutex
+
+// saveNASInfo caches info for key
+func saveNASInfo(key string, info *NASInfo) error {
+	nasInfoCacheMu.Lock()
+	defer nasInfoCacheMu.Unlock()
+
+	cache, err := readNASInfoCache()
+	if err != nil {
+		cache = map[string]*NASInfo{}
+	}
+	cache[key] = info
+
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	path, err := config.GetNASInfoPath()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// readNASInfoCache reads the cache file, keyed by host:port
+func readNASInfoCache() (map[string]*NASInfo, error) {
+	path, err := config.GetNASInfoPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cache := map[string]*NASInfo{}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, err
+	}
+	return cache, nil
+}
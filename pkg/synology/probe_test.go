@@ -0,0 +1,145 @@
+package synology
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/scttfrdmn/syno-docker/pkg/config"
+)
+
+// probeExecutor answers the commands Probe runs from outputs, failing the
+// ones it has no output for
+type probeExecutor struct {
+	Executor
+	outputs map[string]string
+}
+
+func (e *probeExecutor) ExecuteCommandContext(ctx context.Context, cmd string) (string, error) {
+	output, ok := e.outputs[cmd]
+	if !ok {
+		return "", &RemoteCommandError{Command: cmd, ExitStatus: 1}
+	}
+	return output, nil
+}
+
+func TestProbe(t *testing.T) {
+	version := "majorversion=\"7\"\nminorversion=\"2\"\nproductversion=\"7.2.1\"\nbuildnumber=\"69057\"\nsmallfixnumber=\"5\"\n"
+	dockerVersion := DockerCommand([]string{"version", "--format", "{{.Server.Version}} {{.Server.APIVersion}}"})
+
+	tests := []struct {
+		name    string
+		outputs map[string]string
+		want    NASInfo
+		wantErr bool
+	}{
+		{
+			name: "complete",
+			outputs: map[string]string{
+				"cat /etc.defaults/VERSION":            version,
+				"uname -m":                             "aarch64\n",
+				"cat /proc/sys/kernel/syno_hw_version": "DS223j\n",
+				dockerVersion:                          "24.0.2 1.43\n",
+			},
+			want: NASInfo{Model: "DS223j", Arch: "aarch64", DSMVersion: "7.2.1", DSMMajor: 7, DSMMinor: 2, DSMBuild: 69057, DSMUpdate: 5, DockerVersion: "24.0.2", DockerAPIVersion: "1.43"},
+		},
+		{
+			name: "docker stopped and no model",
+			outputs: map[string]string{
+				"cat /etc.defaults/VERSION": "majorversion=\"6\"\nminorversion=\"2\"\nbuildnumber=\"25556\"\n",
+				"uname -m":                  "x86_64\n",
+			},
+			want: NASInfo{Arch: "x86_64", DSMVersion: "6.2", DSMMajor: 6, DSMMinor: 2, DSMBuild: 25556},
+		},
+		{
+			name:    "not a NAS",
+			outputs: map[string]string{"uname -m": "x86_64\n"},
+			wantErr: true,
+		},
+		{
+			name:    "unparsable version",
+			outputs: map[string]string{"cat /etc.defaults/VERSION": "garbage\n", "uname -m": "x86_64\n"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := Probe(context.Background(), &probeExecutor{outputs: tt.outputs})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Probe() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			info.ProbedAt = time.Time{}
+			if *info != tt.want {
+				t.Errorf("Probe() = %+v, want %+v", *info, tt.want)
+			}
+		})
+	}
+}
+
+func TestNASInfoHelpers(t *testing.T) {
+	tests := []struct {
+		arch     string
+		platform string
+	}{
+		{"x86_64", "linux/amd64"},
+		{"aarch64", "linux/arm64"},
+		{"armv7l", "linux/arm/v7"},
+		{"riscv64", "linux/riscv64"},
+	}
+	for _, tt := range tests {
+		if got := (&NASInfo{Arch: tt.arch}).Platform(); got != tt.platform {
+			t.Errorf("Platform() for %s = %s, want %s", tt.arch, got, tt.platform)
+		}
+	}
+
+	info := &NASInfo{DSMVersion: "7.2.1", DSMMajor: 7, DSMMinor: 2, DSMBuild: 69057, DSMUpdate: 5}
+	for _, v := range [][2]int{{6, 2}, {7, 0}, {7, 2}} {
+		if !info.AtLeastDSM(v[0], v[1]) {
+			t.Errorf("Expected DSM 7.2 to be at least %d.%d", v[0], v[1])
+		}
+	}
+	if info.AtLeastDSM(7, 3) || info.AtLeastDSM(8, 0) {
+		t.Error("Expected DSM 7.2 to be older than 7.3 and 8.0")
+	}
+	if got := info.DSMRelease(); got != "7.2.1-69057 Update 5" {
+		t.Errorf("DSMRelease() = %s", got)
+	}
+}
+
+func TestNASInfoCache(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := config.New()
+	cfg.Host = "nas.local"
+
+	cached := &NASInfo{Arch: "x86_64", DSMMajor: 7, DSMMinor: 2, ProbedAt: time.Now()}
+	if err := saveNASInfo("nas.local:22", cached); err != nil {
+		t.Fatalf("saveNASInfo failed: %v", err)
+	}
+	if err := saveNASInfo("other.local:22", &NASInfo{Arch: "aarch64", ProbedAt: time.Now()}); err != nil {
+		t.Fatalf("saveNASInfo failed: %v", err)
+	}
+
+	// A fresh entry is used without connecting
+	info, err := NewConnection(cfg).NASInfo(context.Background())
+	if err != nil || info.Arch != "x86_64" {
+		t.Fatalf("Expected the cached entry for the host, got %+v, %v", info, err)
+	}
+
+	// A stale entry is probed again, which needs a connection
+	cached.ProbedAt = time.Now().Add(-NASInfoTTL - time.Minute)
+	if err := saveNASInfo("nas.local:22", cached); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewConnection(cfg).NASInfo(context.Background()); err == nil {
+		t.Error("Expected a stale entry to be probed again")
+	}
+
+	cfg.Port = 2222
+	if _, err := NewConnection(cfg).NASInfo(context.Background()); err == nil {
+		t.Errorf("Expected no cached entry for port %d", cfg.Port)
+	}
+}
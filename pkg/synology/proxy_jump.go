@@ -0,0 +1,134 @@
+package synology
+
+import (
+	"fmt"
+	"net"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"github.com/scttfrdmn/syno-docker/pkg/config"
+)
+
+// sshHop is one SSH server on the way to the NAS: a jump host or the NAS itself
+type sshHop struct {
+	address string
+	user    string
+	keyPath string
+}
+
+// host returns the host part of the hop address
+func (h sshHop) host() string {
+	host, _, err := net.SplitHostPort(h.address)
+	if err != nil {
+		return h.address
+	}
+	return host
+}
+
+// route resolves the configured host through ssh config and returns the NAS
+// hop and any jump hosts to pass through first, in order
+func (c *Connection) route(sshCfg *sshConfig) (sshHop, []sshHop, error) {
+	resolved := sshCfg.resolve(c.config.Host)
+
+	hostName := c.config.Host
+	if resolved.HostName != "" {
+		hostName = resolved.HostName
+	}
+
+	// ssh config provides what the profile leaves unset or at its default
+	port := c.config.Port
+	if (port == 0 || c.config.IsDefault(config.SettingPort)) && resolved.Port != 0 {
+		port = resolved.Port
+	}
+	if port == 0 {
+		port = DefaultSSHPort
+	}
+
+	target := sshHop{
+		address: net.JoinHostPort(hostName, strconv.Itoa(port)),
+		user:    c.config.User,
+		keyPath: c.config.SSHKeyPath,
+	}
+	if (target.user == "" || c.config.IsDefault(config.SettingUser)) && resolved.User != "" {
+		target.user = resolved.User
+	}
+	if (target.keyPath == "" || c.config.IsDefault(config.SettingSSHKeyPath)) && resolved.IdentityFile != "" {
+		target.keyPath = resolved.IdentityFile
+	}
+
+	jumps, err := parseProxyJump(resolved.ProxyJump, sshCfg, c.config.SSHKeyPath)
+	if err != nil {
+		return sshHop{}, nil, fmt.Errorf("invalid ProxyJump for %s: %w", c.config.Host, err)
+	}
+
+	return target, jumps, nil
+}
+
+// parseProxyJump splits a ProxyJump value such as "bastion,admin@10.0.0.5:2222"
+// into hops, resolving each host through ssh config. Jump hosts without an
+// IdentityFile use defaultKeyPath.
+func parseProxyJump(value string, sshCfg *sshConfig, defaultKeyPath string) ([]sshHop, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var hops []sshHop
+	for _, spec := range strings.Split(value, ",") {
+		spec = strings.TrimPrefix(strings.TrimSpace(spec), "ssh://")
+		if spec == "" {
+			return nil, fmt.Errorf("empty jump host")
+		}
+
+		var specUser string
+		if at := strings.LastIndex(spec, "@"); at != -1 {
+			specUser, spec = spec[:at], spec[at+1:]
+		}
+
+		host, specPort := spec, 0
+		if strings.HasPrefix(spec, "[") || strings.Count(spec, ":") == 1 {
+			h, p, err := net.SplitHostPort(spec)
+			if err != nil {
+				return nil, fmt.Errorf("invalid jump host %q: %w", spec, err)
+			}
+			port, err := strconv.Atoi(p)
+			if err != nil {
+				return nil, fmt.Errorf("invalid port in jump host %q", spec)
+			}
+			host, specPort = h, port
+		}
+
+		resolved := sshCfg.resolve(host)
+		hop := sshHop{user: specUser, keyPath: resolved.IdentityFile}
+
+		hostName := host
+		if resolved.HostName != "" {
+			hostName = resolved.HostName
+		}
+		port := specPort
+		if port == 0 {
+			port = resolved.Port
+		}
+		if port == 0 {
+			port = DefaultSSHPort
+		}
+		hop.address = net.JoinHostPort(hostName, strconv.Itoa(port))
+
+		// Like ssh, fall back to the local user name for jump hosts
+		if hop.user == "" {
+			hop.user = resolved.User
+		}
+		if hop.user == "" {
+			if u, err := user.Current(); err == nil {
+				hop.user = u.Username
+			}
+		}
+		if hop.keyPath == "" {
+			hop.keyPath = defaultKeyPath
+		}
+
+		hops = append(hops, hop)
+	}
+
+	return hops, nil
+}
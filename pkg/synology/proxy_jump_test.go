@@ -0,0 +1,141 @@
+package synology
+
+import (
+	"testing"
+
+	"github.com/scttfrdmn/syno-docker/pkg/config"
+)
+
+func TestRoute(t *testing.T) {
+	sshCfg := parseTestSSHConfig(t, testSSHConfig)
+
+	conn := NewConnection(&config.Config{
+		Host:       "nas-prod",
+		Port:       2222,
+		User:       "dsm-admin",
+		SSHKeyPath: "/keys/nas",
+	})
+
+	target, jumps, err := conn.route(sshCfg)
+	if err != nil {
+		t.Fatalf("route failed: %v", err)
+	}
+
+	expectedTarget := sshHop{address: "10.0.0.5:2222", user: "dsm-admin", keyPath: "/keys/nas"}
+	if target != expectedTarget {
+		t.Errorf("Expected target %+v, got %+v", expectedTarget, target)
+	}
+
+	expectedJumps := []sshHop{{address: "bastion.example.com:22", user: "jump", keyPath: "/keys/nas"}}
+	if len(jumps) != len(expectedJumps) || jumps[0] != expectedJumps[0] {
+		t.Errorf("Expected jumps %+v, got %+v", expectedJumps, jumps)
+	}
+}
+
+func TestRouteDirect(t *testing.T) {
+	conn := NewConnection(&config.Config{Host: "192.168.1.100", Port: 22, User: "admin"})
+
+	target, jumps, err := conn.route(&sshConfig{})
+	if err != nil {
+		t.Fatalf("route failed: %v", err)
+	}
+	if target.address != "192.168.1.100:22" || target.user != "admin" {
+		t.Errorf("Unexpected target %+v", target)
+	}
+	if len(jumps) != 0 {
+		t.Errorf("Expected no jump hosts, got %+v", jumps)
+	}
+}
+
+func TestRouteDefaults(t *testing.T) {
+	t.Setenv("HOME", "/home/me")
+	sshCfg := parseTestSSHConfig(t, testSSHConfig)
+	defaults := map[string]string{
+		config.SettingPort:       config.SourceDefault,
+		config.SettingUser:       config.SourceDefault,
+		config.SettingSSHKeyPath: config.SourceDefault,
+	}
+
+	tests := []struct {
+		name     string
+		sources  map[string]string
+		expected sshHop
+	}{
+		{
+			name:     "defaults replaced by ssh config",
+			sources:  defaults,
+			expected: sshHop{address: "10.0.0.5:2222", user: "dsm-admin", keyPath: "/home/me/.ssh/nas_ed25519"},
+		},
+		{
+			name:     "explicit settings kept",
+			sources:  map[string]string{config.SettingPort: "--ssh-port", config.SettingUser: "config.yaml (profile nas)"},
+			expected: sshHop{address: "10.0.0.5:22", user: "admin", keyPath: "/keys/id"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := NewConnection(&config.Config{
+				Host:       "nas-prod",
+				Port:       config.DefaultPort,
+				User:       config.DefaultUser,
+				SSHKeyPath: "/keys/id",
+				Sources:    tt.sources,
+			})
+
+			target, _, err := conn.route(sshCfg)
+			if err != nil {
+				t.Fatalf("route failed: %v", err)
+			}
+			if target != tt.expected {
+				t.Errorf("Expected target %+v, got %+v", tt.expected, target)
+			}
+		})
+	}
+}
+
+func TestParseProxyJump(t *testing.T) {
+	sshCfg := parseTestSSHConfig(t, `
+Host bastion
+    HostName bastion.example.com
+    Port 2200
+    User jump
+    IdentityFile /keys/bastion
+`)
+
+	hops, err := parseProxyJump("bastion, ops@10.0.0.1:2022,ssh://root@[fd00::1]:22", sshCfg, "/keys/default")
+	if err != nil {
+		t.Fatalf("parseProxyJump failed: %v", err)
+	}
+
+	expected := []sshHop{
+		{address: "bastion.example.com:2200", user: "jump", keyPath: "/keys/bastion"},
+		{address: "10.0.0.1:2022", user: "ops", keyPath: "/keys/default"},
+		{address: "[fd00::1]:22", user: "root", keyPath: "/keys/default"},
+	}
+	if len(hops) != len(expected) {
+		t.Fatalf("Expected %d hops, got %+v", len(expected), hops)
+	}
+	for i := range expected {
+		if hops[i] != expected[i] {
+			t.Errorf("Hop %d: expected %+v, got %+v", i, expected[i], hops[i])
+		}
+	}
+}
+
+func TestParseProxyJumpInvalid(t *testing.T) {
+	for _, value := range []string{"bastion,", "host:notaport", "[fd00::1"} {
+		if _, err := parseProxyJump(value, &sshConfig{}, ""); err == nil {
+			t.Errorf("Expected error for %q", value)
+		}
+	}
+}
+
+func TestSSHHopHost(t *testing.T) {
+	if host := (sshHop{address: "10.0.0.5:22"}).host(); host != "10.0.0.5" {
+		t.Errorf("Expected 10.0.0.5, got %s", host)
+	}
+	if host := (sshHop{address: "[fd00::1]:22"}).host(); host != "fd00::1" {
+		t.Errorf("Expected fd00::1, got %s", host)
+	}
+}
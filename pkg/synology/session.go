@@ -0,0 +1,145 @@
+package synology
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// defaultTerm is the terminal type requested when none is given
+const defaultTerm = "xterm"
+
+// TerminalSize is the size of a terminal in character cells
+type TerminalSize struct {
+	Width  int
+	Height int
+}
+
+// SessionOptions configures the streams and terminal of a remote session
+type SessionOptions struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// TTY requests a pseudo-terminal of Size with the given Term type.
+	// Sizes received on Resize are forwarded to the remote terminal.
+	TTY    bool
+	Term   string
+	Size   TerminalSize
+	Resize <-chan TerminalSize
+}
+
+// RunSession runs a command with the given streams and optional pseudo-terminal
+func (c *Connection) RunSession(cmd string, opts *SessionOptions) error {
+	return c.RunSessionContext(context.Background(), cmd, opts)
+}
+
+// RunSessionContext runs a command with the given streams and optional
+// pseudo-terminal until it exits or ctx is done
+func (c *Connection) RunSessionContext(ctx context.Context, cmd string, opts *SessionOptions) error {
+	sshClient := c.currentClient()
+	if sshClient == nil {
+		return c.notConnectedError()
+	}
+
+	session, err := sshClient.NewSession()
+	if err != nil {
+		return c.openSessionError(err)
+	}
+	defer session.Close()
+
+	// Set up environment
+	session.Setenv("PATH", "/usr/local/bin:/usr/bin:/bin")
+
+	if opts.TTY {
+		if err := requestPty(session, opts); err != nil {
+			return err
+		}
+	}
+
+	// Connect streams, keeping the start of stderr for error reporting
+	stderrHead := &headBuffer{limit: stderrCaptureLimit}
+	session.Stdout = opts.Stdout
+	session.Stderr = stderrHead
+	if opts.Stderr != nil {
+		session.Stderr = io.MultiWriter(opts.Stderr, stderrHead)
+	}
+
+	// Copy stdin ourselves: Session.Wait would otherwise block on a reader
+	// such as os.Stdin long after the remote command has exited
+	if opts.Stdin != nil {
+		stdinPipe, err := session.StdinPipe()
+		if err != nil {
+			return fmt.Errorf("failed to open stdin: %w", err)
+		}
+		go func() {
+			io.Copy(stdinPipe, opts.Stdin)
+			stdinPipe.Close()
+		}()
+	}
+
+	if opts.Resize != nil {
+		stopResize := make(chan struct{})
+		defer close(stopResize)
+		go forwardResizes(session, opts.Resize, stopResize)
+	}
+
+	// Run command
+	if err := runSession(ctx, session, func() error { return session.Run(cmd) }); err != nil {
+		return c.sessionError(ctx, cmd, err, "", stderrHead.String())
+	}
+
+	return nil
+}
+
+// RunDockerSession runs a Docker command with the given streams and optional
+// pseudo-terminal, quoting every argument for the remote shell
+func (c *Connection) RunDockerSession(args []string, opts *SessionOptions) error {
+	return c.RunDockerSessionContext(context.Background(), args, opts)
+}
+
+// RunDockerSessionContext is like RunDockerSession but honors ctx
+func (c *Connection) RunDockerSessionContext(ctx context.Context, args []string, opts *SessionOptions) error {
+	return c.RunSessionContext(ctx, DockerCommand(args), opts)
+}
+
+func requestPty(session *ssh.Session, opts *SessionOptions) error {
+	term := opts.Term
+	if term == "" {
+		term = defaultTerm
+	}
+
+	modes := ssh.TerminalModes{
+		ssh.ECHO:          1,
+		ssh.TTY_OP_ISPEED: 14400,
+		ssh.TTY_OP_OSPEED: 14400,
+	}
+
+	if err := session.RequestPty(term, opts.Size.Height, opts.Size.Width, modes); err != nil {
+		return fmt.Errorf("failed to request pseudo-terminal: %w", err)
+	}
+	return nil
+}
+
+// windowChanger is the part of an SSH session that accepts terminal resizes
+type windowChanger interface {
+	WindowChange(h, w int) error
+}
+
+// forwardResizes sends every size received on sizes to the remote terminal
+// until sizes is closed or stop is closed
+func forwardResizes(session windowChanger, sizes <-chan TerminalSize, stop <-chan struct{}) {
+	for {
+		select {
+		case size, ok := <-sizes:
+			if !ok {
+				return
+			}
+			session.WindowChange(size.Height, size.Width)
+		case <-stop:
+			return
+		}
+	}
+}
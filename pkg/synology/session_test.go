@@ -0,0 +1,54 @@
+package synology
+
+import (
+	"context"
+	"testing"
+
+	"github.com/scttfrdmn/syno-docker/pkg/config"
+)
+
+type fakeWindow struct {
+	sizes []TerminalSize
+}
+
+func (w *fakeWindow) WindowChange(h, width int) error {
+	w.sizes = append(w.sizes, TerminalSize{Width: width, Height: h})
+	return nil
+}
+
+func TestForwardResizes(t *testing.T) {
+	window := &fakeWindow{}
+	sizes := make(chan TerminalSize, 2)
+	sizes <- TerminalSize{Width: 120, Height: 40}
+	sizes <- TerminalSize{Width: 80, Height: 24}
+	close(sizes)
+
+	forwardResizes(window, sizes, make(chan struct{}))
+
+	expected := []TerminalSize{{Width: 120, Height: 40}, {Width: 80, Height: 24}}
+	if len(window.sizes) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, window.sizes)
+	}
+	for i := range expected {
+		if window.sizes[i] != expected[i] {
+			t.Errorf("Resize %d: expected %v, got %v", i, expected[i], window.sizes[i])
+		}
+	}
+}
+
+func TestForwardResizesStops(t *testing.T) {
+	stop := make(chan struct{})
+	close(stop)
+
+	// Must return even though the sizes channel is never closed
+	forwardResizes(&fakeWindow{}, make(chan TerminalSize), stop)
+}
+
+func TestRunSessionContextNotConnected(t *testing.T) {
+	conn := NewConnection(&config.Config{})
+
+	err := conn.RunSessionContext(context.Background(), "sh", &SessionOptions{TTY: true})
+	if err == nil {
+		t.Error("Expected error when not connected")
+	}
+}
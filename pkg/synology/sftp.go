@@ -0,0 +1,26 @@
+package synology
+
+import (
+	"fmt"
+
+	"github.com/pkg/sftp"
+)
+
+// SFTPClient opens an SFTP session over the SSH connection. The caller must
+// close the returned client. DSM only offers the SFTP subsystem when SFTP is
+// enabled under Control Panel > File Services > FTP.
+func (c *Connection) SFTPClient() (*sftp.Client, error) {
+	sshClient := c.currentClient()
+	if sshClient == nil {
+		return nil, c.notConnectedError()
+	}
+
+	client, err := sftp.NewClient(sshClient)
+	if err != nil {
+		if c.lost.Load() {
+			return nil, lostError(err)
+		}
+		return nil, fmt.Errorf("failed to start SFTP session (is SFTP enabled in DSM under Control Panel > File Services > FTP?): %w", err)
+	}
+	return client, nil
+}
@@ -0,0 +1,15 @@
+package synology
+
+import (
+	"testing"
+
+	"github.com/scttfrdmn/syno-docker/pkg/config"
+)
+
+func TestSFTPClientNotConnected(t *testing.T) {
+	conn := NewConnection(config.New())
+
+	if _, err := conn.SFTPClient(); err == nil {
+		t.Error("Expected error when not connected")
+	}
+}
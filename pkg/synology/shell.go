@@ -0,0 +1,108 @@
+package synology
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ShellQuote quotes s so that a POSIX shell treats it as a single literal word
+func ShellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+
+	if isShellSafe(s) {
+		return s
+	}
+
+	// Close the quote, emit an escaped single quote, and reopen it
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// ShellJoin quotes each argument and joins them into a single command line
+func ShellJoin(args ...string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = ShellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// DockerCommand builds a shell-safe command line invoking the Docker binary
+func DockerCommand(args []string) string {
+	return ShellJoin(append([]string{DockerBinary}, args...)...)
+}
+
+// isShellSafe reports whether s contains only characters that never need quoting
+func isShellSafe(s string) bool {
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune("_-./:,@%+", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// ShellSplit splits a command line into words using POSIX shell quoting rules.
+// It does not perform expansion of variables, globs or command substitution.
+func ShellSplit(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch == '\\':
+			if i+1 >= len(s) {
+				return nil, fmt.Errorf("unterminated escape in %q", s)
+			}
+			i++
+			if s[i] != '\n' {
+				word.WriteByte(s[i])
+			}
+			inWord = true
+		case ch == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated single quote in %q", s)
+			}
+			word.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case ch == '"':
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("$`\"\\\n", s[i+1]) != -1 {
+					i++
+					if s[i] == '\n' {
+						continue
+					}
+				}
+				word.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, fmt.Errorf("unterminated double quote in %q", s)
+			}
+			inWord = true
+		case ch == ' ' || ch == '\t' || ch == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteByte(ch)
+			inWord = true
+		}
+	}
+
+	if inWord {
+		words = append(words, word.String())
+	}
+
+	return words, nil
+}
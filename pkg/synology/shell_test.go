@@ -0,0 +1,158 @@
+package synology
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", "''"},
+		{"nginx:latest", "nginx:latest"},
+		{"/volume1/docker/web:/data", "/volume1/docker/web:/data"},
+		{"--format", "--format"},
+		{"KEY=value", "'KEY=value'"},
+		{"hello world", "'hello world'"},
+		{"it's", `'it'\''s'`},
+		{"$HOME", "'$HOME'"},
+		{"a;rm -rf /", "'a;rm -rf /'"},
+		{"{{.ID}}\t{{.Names}}", "'{{.ID}}\t{{.Names}}'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if result := ShellQuote(tt.input); result != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestShellJoinRoundTrip(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not available")
+	}
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"plain", []string{"ps", "-a"}},
+		{"spaces", []string{"-e", "GREETING=hello world"}},
+		{"dollar", []string{"-e", "PASSWORD=pa$$word", "$(id)", "${HOME}"}},
+		{"semicolon", []string{"-e", "X=1; touch /tmp/pwned"}},
+		{"pipes and redirects", []string{"a | b", "c > /etc/passwd", "d < e", "f && g || h"}},
+		{"quotes", []string{`it's`, `"double"`, `'single'`, `mixed "and' quotes`}},
+		{"backticks", []string{"`id`", "\\`escaped\\`"}},
+		{"globs", []string{"*", "?", "[abc]", "~", "~root"}},
+		{"whitespace", []string{"tab\there", "new\nline", " leading", "trailing "}},
+		{"empty", []string{"", "after-empty"}},
+		{"go template", []string{"--format", "table {{.ID}}\t{{.Names}}"}},
+		{"assignment first", []string{"FOO=bar", "baz"}},
+		{"comment", []string{"#not-a-comment", "a #b"}},
+		{"backslashes", []string{`C:\path\to`, `\\`, `\'`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := "printf '%s\\0' " + ShellJoin(tt.args...)
+			output, err := exec.Command(sh, "-c", script).Output()
+			if err != nil {
+				t.Fatalf("Shell failed for %q: %v", script, err)
+			}
+
+			got := strings.Split(strings.TrimSuffix(string(output), "\x00"), "\x00")
+			if len(got) != len(tt.args) {
+				t.Fatalf("Expected %d args %q, got %d args %q", len(tt.args), tt.args, len(got), got)
+			}
+			for i := range tt.args {
+				if got[i] != tt.args[i] {
+					t.Errorf("Arg %d: expected %q, got %q", i, tt.args[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDockerCommand(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"ps", "-a"}, "/usr/local/bin/docker ps -a"},
+		{[]string{"run", "-e", "MSG=hi there", "alpine"}, "/usr/local/bin/docker run -e 'MSG=hi there' alpine"},
+		{[]string{"exec", "web", "sh", "-c", "echo $HOME"}, "/usr/local/bin/docker exec web sh -c 'echo $HOME'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			if result := DockerCommand(tt.args); result != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestShellSplit(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+		hasError bool
+	}{
+		{"npm start", []string{"npm", "start"}, false},
+		{"  spaced   out  ", []string{"spaced", "out"}, false},
+		{`sh -c 'echo "hi there"'`, []string{"sh", "-c", `echo "hi there"`}, false},
+		{`echo "a \"quoted\" $word"`, []string{"echo", `a "quoted" $word`}, false},
+		{`one\ word`, []string{"one word"}, false},
+		{`''`, []string{""}, false},
+		{`'it'\''s'`, []string{"it's"}, false},
+		{`'unterminated`, nil, true},
+		{`"unterminated`, nil, true},
+		{`trailing\`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := ShellSplit(tt.input)
+			if tt.hasError {
+				if err == nil {
+					t.Errorf("Expected error but got %q", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(result) != len(tt.expected) {
+				t.Fatalf("Expected %q, got %q", tt.expected, result)
+			}
+			for i := range tt.expected {
+				if result[i] != tt.expected[i] {
+					t.Errorf("Word %d: expected %q, got %q", i, tt.expected[i], result[i])
+				}
+			}
+		})
+	}
+}
+
+func TestShellSplitJoinRoundTrip(t *testing.T) {
+	args := []string{"run", "-e", "A=b c", "$(id)", "it's", "", "tab\t", `back\slash`, `"dq"`}
+
+	result, err := ShellSplit(ShellJoin(args...))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(result) != len(args) {
+		t.Fatalf("Expected %q, got %q", args, result)
+	}
+	for i := range args {
+		if result[i] != args[i] {
+			t.Errorf("Arg %d: expected %q, got %q", i, args[i], result[i])
+		}
+	}
+}
@@ -0,0 +1,350 @@
+package synology
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// maxSSHConfigIncludeDepth bounds nested Include directives, as OpenSSH does
+const maxSSHConfigIncludeDepth = 16
+
+// SSHHostConfig holds the settings ~/.ssh/config gives for a host alias.
+// Fields are empty when the config does not set them.
+type SSHHostConfig struct {
+	Alias        string
+	HostName     string
+	Port         int
+	User         string
+	IdentityFile string
+	ProxyJump    string
+	// Found reports whether any Host block other than "Host *" matched
+	Found bool
+}
+
+// sshConfigBlock is a Host block and its options in file order
+type sshConfigBlock struct {
+	patterns []string
+	options  [][2]string
+}
+
+// sshConfig is a parsed ssh_config with Include directives expanded
+type sshConfig struct {
+	blocks []sshConfigBlock
+}
+
+// ResolveSSHHost looks alias up in ~/.ssh/config and /etc/ssh/ssh_config
+func ResolveSSHHost(alias string) (*SSHHostConfig, error) {
+	cfg, err := loadDefaultSSHConfig()
+	if err != nil {
+		return nil, err
+	}
+	return cfg.resolve(alias), nil
+}
+
+// loadDefaultSSHConfig parses the user and system ssh config files
+func loadDefaultSSHConfig() (*sshConfig, error) {
+	var files []string
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		files = append(files, filepath.Join(homeDir, ".ssh", "config"))
+	}
+	files = append(files, "/etc/ssh/ssh_config")
+
+	return loadSSHConfig(files...)
+}
+
+// loadSSHConfig parses the given files in order, skipping files that do not exist
+func loadSSHConfig(files ...string) (*sshConfig, error) {
+	cfg := &sshConfig{}
+	for _, file := range files {
+		if err := cfg.parseFile(file, 0); err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+func (c *sshConfig) parseFile(file string, depth int) error {
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open ssh config: %w", err)
+	}
+	defer f.Close()
+
+	if err := c.parse(f, depth); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+	return nil
+}
+
+// parse reads ssh_config directives. Options before the first Host block
+// apply to every host. Match blocks are not supported and are skipped.
+func (c *sshConfig) parse(r io.Reader, depth int) error {
+	current := &sshConfigBlock{patterns: []string{"*"}}
+	skipping := false
+
+	flush := func() {
+		if !skipping && len(current.options) > 0 {
+			c.blocks = append(c.blocks, *current)
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		keyword, args, err := splitSSHConfigLine(scanner.Text())
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		if keyword == "" {
+			continue
+		}
+
+		switch keyword {
+		case "host":
+			flush()
+			current = &sshConfigBlock{patterns: args}
+			skipping = false
+		case "match":
+			flush()
+			current = &sshConfigBlock{}
+			skipping = true
+		case "include":
+			if skipping {
+				continue
+			}
+			// Included options belong to the enclosing block, so flush first
+			flush()
+			patterns := current.patterns
+			if depth >= maxSSHConfigIncludeDepth {
+				return fmt.Errorf("line %d: too many nested includes", lineNo)
+			}
+			for _, arg := range args {
+				if err := c.include(arg, patterns, depth+1); err != nil {
+					return err
+				}
+			}
+			current = &sshConfigBlock{patterns: patterns}
+		default:
+			if !skipping && len(args) > 0 {
+				current.options = append(current.options, [2]string{keyword, strings.Join(args, " ")})
+			}
+		}
+	}
+	flush()
+
+	return scanner.Err()
+}
+
+// include parses the files matching pattern. Relative patterns are resolved
+// against ~/.ssh, as OpenSSH does for the user config.
+func (c *sshConfig) include(pattern string, patterns []string, depth int) error {
+	pattern = expandHome(pattern)
+	if !filepath.IsAbs(pattern) {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		pattern = filepath.Join(homeDir, ".ssh", pattern)
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid Include pattern %q: %w", pattern, err)
+	}
+
+	for _, match := range matches {
+		included := &sshConfig{}
+		if err := included.parseFile(match, depth); err != nil {
+			return err
+		}
+		// Included blocks only apply within the Host block that included them
+		for _, block := range included.blocks {
+			if !isMatchAll(patterns) {
+				if isMatchAll(block.patterns) {
+					block.patterns = patterns
+				} else {
+					block.patterns = append(append([]string(nil), block.patterns...), patterns...)
+				}
+			}
+			c.blocks = append(c.blocks, block)
+		}
+	}
+	return nil
+}
+
+// resolve returns the settings for alias. The first value found wins.
+func (c *sshConfig) resolve(alias string) *SSHHostConfig {
+	result := &SSHHostConfig{Alias: alias}
+
+	for _, block := range c.blocks {
+		if !matchHostPatterns(alias, block.patterns) {
+			continue
+		}
+		if !isMatchAll(block.patterns) {
+			result.Found = true
+		}
+
+		for _, option := range block.options {
+			value := option[1]
+			switch option[0] {
+			case "hostname":
+				if result.HostName == "" {
+					result.HostName = value
+				}
+			case "port":
+				if result.Port == 0 {
+					if port, err := strconv.Atoi(value); err == nil {
+						result.Port = port
+					}
+				}
+			case "user":
+				if result.User == "" {
+					result.User = value
+				}
+			case "identityfile":
+				if result.IdentityFile == "" {
+					result.IdentityFile = value
+				}
+			case "proxyjump":
+				if result.ProxyJump == "" {
+					result.ProxyJump = value
+				}
+			}
+		}
+	}
+
+	if result.HostName != "" {
+		result.HostName = expandSSHTokens(result.HostName, alias, result)
+	}
+	if result.IdentityFile != "" {
+		result.IdentityFile = expandHome(expandSSHTokens(result.IdentityFile, alias, result))
+	}
+	if strings.EqualFold(result.ProxyJump, "none") {
+		result.ProxyJump = ""
+	}
+
+	return result
+}
+
+// splitSSHConfigLine splits a line into a lowercased keyword and its
+// arguments, honoring "keyword=value" and double-quoted arguments
+func splitSSHConfigLine(line string) (string, []string, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", nil, nil
+	}
+
+	end := strings.IndexAny(line, " \t=")
+	if end == -1 {
+		return strings.ToLower(line), nil, nil
+	}
+	keyword := strings.ToLower(line[:end])
+	rest := strings.TrimLeft(line[end:], " \t")
+	rest = strings.TrimLeft(strings.TrimPrefix(rest, "="), " \t")
+
+	var args []string
+	for rest != "" {
+		if rest[0] == '#' {
+			break
+		}
+		if rest[0] == '"' {
+			closing := strings.IndexByte(rest[1:], '"')
+			if closing == -1 {
+				return "", nil, fmt.Errorf("unterminated quote")
+			}
+			args = append(args, rest[1:closing+1])
+			rest = strings.TrimLeft(rest[closing+2:], " \t")
+			continue
+		}
+		next := strings.IndexAny(rest, " \t")
+		if next == -1 {
+			args = append(args, rest)
+			break
+		}
+		args = append(args, rest[:next])
+		rest = strings.TrimLeft(rest[next:], " \t")
+	}
+
+	return keyword, args, nil
+}
+
+// matchHostPatterns reports whether host matches a Host line. A negated
+// pattern that matches excludes the host regardless of other patterns.
+func matchHostPatterns(host string, patterns []string) bool {
+	matched := false
+	for _, pattern := range patterns {
+		negated := strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+
+		ok, err := path.Match(strings.ToLower(pattern), strings.ToLower(host))
+		if err != nil || !ok {
+			continue
+		}
+		if negated {
+			return false
+		}
+		matched = true
+	}
+	return matched
+}
+
+func isMatchAll(patterns []string) bool {
+	return len(patterns) == 1 && patterns[0] == "*"
+}
+
+// expandSSHTokens expands the %h, %n, %p, %r, %u, %d and %% tokens
+func expandSSHTokens(value, alias string, host *SSHHostConfig) string {
+	if !strings.Contains(value, "%") {
+		return value
+	}
+
+	hostName := host.HostName
+	if hostName == "" || strings.Contains(hostName, "%") {
+		hostName = alias
+	}
+	port := host.Port
+	if port == 0 {
+		port = DefaultSSHPort
+	}
+	localUser := ""
+	if u, err := user.Current(); err == nil {
+		localUser = u.Username
+	}
+	remoteUser := host.User
+	if remoteUser == "" {
+		remoteUser = localUser
+	}
+	homeDir, _ := os.UserHomeDir()
+
+	replacer := strings.NewReplacer(
+		"%%", "%",
+		"%h", hostName,
+		"%n", alias,
+		"%p", strconv.Itoa(port),
+		"%r", remoteUser,
+		"%u", localUser,
+		"%d", homeDir,
+	)
+	return replacer.Replace(value)
+}
+
+// expandHome replaces a leading ~ with the user's home directory
+func expandHome(p string) string {
+	if p != "~" && !strings.HasPrefix(p, "~/") {
+		return p
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return p
+	}
+	return filepath.Join(homeDir, strings.TrimPrefix(p, "~"))
+}
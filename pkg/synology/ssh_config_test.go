@@ -0,0 +1,205 @@
+package synology
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func parseTestSSHConfig(t *testing.T, content string) *sshConfig {
+	cfg := &sshConfig{}
+	if err := cfg.parse(strings.NewReader(content), 0); err != nil {
+		t.Fatalf("Failed to parse ssh config: %v", err)
+	}
+	return cfg
+}
+
+const testSSHConfig = `
+# Global defaults
+ServerAliveInterval 30
+
+Host nas-prod
+    HostName 10.0.0.5
+    Port 2222
+    User dsm-admin
+    IdentityFile ~/.ssh/nas_ed25519
+    ProxyJump bastion
+
+Host bastion
+    HostName bastion.example.com
+    User jump
+
+Host *.lan !printer.lan
+    User lanuser
+
+Match host nas-prod
+    User ignored
+
+Host *
+    User fallback
+    Port 22
+`
+
+func TestResolveSSHConfig(t *testing.T) {
+	homeDir, _ := os.UserHomeDir()
+	cfg := parseTestSSHConfig(t, testSSHConfig)
+
+	tests := []struct {
+		alias    string
+		expected SSHHostConfig
+	}{
+		{"nas-prod", SSHHostConfig{
+			Alias:        "nas-prod",
+			HostName:     "10.0.0.5",
+			Port:         2222,
+			User:         "dsm-admin",
+			IdentityFile: filepath.Join(homeDir, ".ssh", "nas_ed25519"),
+			ProxyJump:    "bastion",
+			Found:        true,
+		}},
+		{"bastion", SSHHostConfig{Alias: "bastion", HostName: "bastion.example.com", Port: 22, User: "jump", Found: true}},
+		{"nas.lan", SSHHostConfig{Alias: "nas.lan", Port: 22, User: "lanuser", Found: true}},
+		{"printer.lan", SSHHostConfig{Alias: "printer.lan", Port: 22, User: "fallback"}},
+		{"192.168.1.100", SSHHostConfig{Alias: "192.168.1.100", Port: 22, User: "fallback"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.alias, func(t *testing.T) {
+			result := cfg.resolve(tt.alias)
+			if *result != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, *result)
+			}
+		})
+	}
+}
+
+func TestSplitSSHConfigLine(t *testing.T) {
+	tests := []struct {
+		line     string
+		keyword  string
+		args     []string
+		hasError bool
+	}{
+		{"HostName 10.0.0.5", "hostname", []string{"10.0.0.5"}, false},
+		{"  Port=2222", "port", []string{"2222"}, false},
+		{"Port = 2222", "port", []string{"2222"}, false},
+		{`IdentityFile "~/My Keys/id_ed25519"`, "identityfile", []string{"~/My Keys/id_ed25519"}, false},
+		{"Host a b  c", "host", []string{"a", "b", "c"}, false},
+		{"User admin # comment", "user", []string{"admin"}, false},
+		{"# just a comment", "", nil, false},
+		{"", "", nil, false},
+		{`IdentityFile "unterminated`, "", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			keyword, args, err := splitSSHConfigLine(tt.line)
+			if tt.hasError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if keyword != tt.keyword {
+				t.Errorf("Expected keyword %q, got %q", tt.keyword, keyword)
+			}
+			if strings.Join(args, "|") != strings.Join(tt.args, "|") {
+				t.Errorf("Expected args %q, got %q", tt.args, args)
+			}
+		})
+	}
+}
+
+func TestSSHConfigTokens(t *testing.T) {
+	cfg := parseTestSSHConfig(t, `
+Host nas-*
+    HostName %h.internal.example.com
+    IdentityFile /keys/%n/%r
+    User admin
+`)
+
+	result := cfg.resolve("nas-1")
+	if result.HostName != "nas-1.internal.example.com" {
+		t.Errorf("Expected expanded HostName, got %s", result.HostName)
+	}
+	if result.IdentityFile != "/keys/nas-1/admin" {
+		t.Errorf("Expected expanded IdentityFile, got %s", result.IdentityFile)
+	}
+}
+
+func TestSSHConfigProxyJumpNone(t *testing.T) {
+	cfg := parseTestSSHConfig(t, `
+Host direct
+    ProxyJump none
+Host *
+    ProxyJump bastion
+`)
+
+	if result := cfg.resolve("direct"); result.ProxyJump != "" {
+		t.Errorf("Expected ProxyJump none to disable jumping, got %q", result.ProxyJump)
+	}
+	if result := cfg.resolve("other"); result.ProxyJump != "bastion" {
+		t.Errorf("Expected ProxyJump bastion, got %q", result.ProxyJump)
+	}
+}
+
+func TestSSHConfigInclude(t *testing.T) {
+	tempDir := t.TempDir()
+	oldHome := os.Getenv("HOME")
+	defer os.Setenv("HOME", oldHome)
+	os.Setenv("HOME", tempDir)
+
+	sshDir := filepath.Join(tempDir, ".ssh")
+	if err := os.MkdirAll(filepath.Join(sshDir, "config.d"), 0700); err != nil {
+		t.Fatalf("Failed to create ssh dir: %v", err)
+	}
+	files := map[string]string{
+		"config":             "Include config.d/*\n\nHost *\n    User fallback\n",
+		"config.d/nas.conf":  "Host nas-prod\n    HostName 10.0.0.5\n",
+		"config.d/jump.conf": "Host bastion\n    User jump\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(sshDir, name), []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	result, err := ResolveSSHHost("nas-prod")
+	if err != nil {
+		t.Fatalf("ResolveSSHHost failed: %v", err)
+	}
+	if result.HostName != "10.0.0.5" || !result.Found {
+		t.Errorf("Expected included HostName, got %+v", result)
+	}
+	if result.User != "fallback" {
+		t.Errorf("Expected fallback user, got %s", result.User)
+	}
+}
+
+func TestMatchHostPatterns(t *testing.T) {
+	tests := []struct {
+		host     string
+		patterns []string
+		expected bool
+	}{
+		{"nas", []string{"nas"}, true},
+		{"NAS", []string{"nas"}, true},
+		{"nas-1", []string{"nas-?"}, true},
+		{"nas-10", []string{"nas-?"}, false},
+		{"nas.lan", []string{"*.lan", "!printer.lan"}, true},
+		{"printer.lan", []string{"*.lan", "!printer.lan"}, false},
+		{"other", []string{"!nas"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if result := matchHostPatterns(tt.host, tt.patterns); result != tt.expected {
+				t.Errorf("matchHostPatterns(%q, %v) = %v, expected %v", tt.host, tt.patterns, result, tt.expected)
+			}
+		})
+	}
+}
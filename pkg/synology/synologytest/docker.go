@@ -0,0 +1,628 @@
+package synologytest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/scttfrdmn/syno-docker/pkg/synology"
+)
+
+const (
+	// FakeDockerVersion is the server version reported by FakeDocker
+	FakeDockerVersion = "24.0.2"
+	// FakeDockerAPIVersion is the API version reported by FakeDocker
+	FakeDockerAPIVersion = "1.43"
+	// MissingImage is an image reference that FakeDocker fails to pull
+	MissingImage = "synologytest/does-not-exist"
+)
+
+// FakeContainer is a container known to FakeDocker
+type FakeContainer struct {
+	ID       string
+	Name     string
+	Image    string
+	Command  []string
+	Status   string // created, running or exited
+	Ports    []string
+	Volumes  []string
+	Env      []string
+	Restart  string
+	User     string
+	Networks []string
+	Labels   []string
+	Logs     []string
+	// Files holds the contents of files written by docker cp, by absolute path
+	Files map[string][]byte
+}
+
+// FakeImage is an image known to FakeDocker
+type FakeImage struct {
+	ID         string
+	Repository string
+	Tag        string
+	// Layers holds the diff IDs of the image's layers, base layer first
+	Layers []string
+}
+
+// Ref returns the image reference as repository:tag
+func (i FakeImage) Ref() string {
+	return i.Repository + ":" + i.Tag
+}
+
+// FakeVolume is a volume known to FakeDocker
+type FakeVolume struct {
+	Name      string
+	Driver    string
+	Labels    []string
+	Anonymous bool
+}
+
+// FakeNetwork is a network known to FakeDocker
+type FakeNetwork struct {
+	ID       string
+	Name     string
+	Driver   string
+	Labels   []string
+	Internal bool
+	builtin  bool
+}
+
+// FakeDocker emulates the docker CLI of a Synology NAS against in-memory
+// containers, images, volumes and networks. It implements the commands and
+// flags that pkg/deploy uses. Listings use single-word values, such as "Up"
+// for a running container, so column-based parsing works. It is safe for
+// concurrent use.
+type FakeDocker struct {
+	mu         sync.Mutex
+	containers []*FakeContainer
+	images     []*FakeImage
+	volumes    []*FakeVolume
+	networks   []*FakeNetwork
+	serial     int
+	stopped    bool
+
+	// Now returns the time used for log timestamps. It defaults to time.Now.
+	Now func() time.Time
+	// HostRoot is the local directory standing in for the NAS filesystem in
+	// docker cp, which fails when it is empty
+	HostRoot string
+}
+
+// NewFakeDocker returns a FakeDocker with the predefined bridge, host and
+// none networks and no containers, images or volumes
+func NewFakeDocker() *FakeDocker {
+	d := &FakeDocker{Now: time.Now}
+	for _, name := range []string{"bridge", "host", "none"} {
+		driver := name
+		if name == "none" {
+			driver = "null"
+		}
+		d.networks = append(d.networks, &FakeNetwork{ID: d.newID("network", name), Name: name, Driver: driver, builtin: true})
+	}
+	return d
+}
+
+// AddImage makes ref available locally, as if it had been pulled
+func (d *FakeDocker) AddImage(ref string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.addImage(ref)
+}
+
+// AddImageLayers makes ref available locally with the given layer diff IDs,
+// base layer first
+func (d *FakeDocker) AddImageLayers(ref string, layers ...string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.addImage(ref).Layers = layers
+}
+
+// Containers returns a copy of every container, oldest first
+func (d *FakeDocker) Containers() []FakeContainer {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var containers []FakeContainer
+	for _, c := range d.containers {
+		copied := *c
+		copied.Networks = append([]string(nil), c.Networks...)
+		copied.Logs = append([]string(nil), c.Logs...)
+		copied.Files = map[string][]byte{}
+		for name, data := range c.Files {
+			copied.Files[name] = data
+		}
+		containers = append(containers, copied)
+	}
+	return containers
+}
+
+// Container returns a copy of the container with the given name or ID
+func (d *FakeDocker) Container(nameOrID string) (FakeContainer, bool) {
+	for _, c := range d.Containers() {
+		if c.Name == nameOrID || strings.HasPrefix(c.ID, nameOrID) {
+			return c, true
+		}
+	}
+	return FakeContainer{}, false
+}
+
+// Images returns a copy of every image
+func (d *FakeDocker) Images() []FakeImage {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var images []FakeImage
+	for _, image := range d.images {
+		copied := *image
+		copied.Layers = append([]string(nil), image.Layers...)
+		images = append(images, copied)
+	}
+	return images
+}
+
+// Volumes returns a copy of every volume
+func (d *FakeDocker) Volumes() []FakeVolume {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var volumes []FakeVolume
+	for _, volume := range d.volumes {
+		volumes = append(volumes, *volume)
+	}
+	return volumes
+}
+
+// Networks returns a copy of every network
+func (d *FakeDocker) Networks() []FakeNetwork {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var networks []FakeNetwork
+	for _, network := range d.networks {
+		networks = append(networks, *network)
+	}
+	return networks
+}
+
+// Run executes a docker command line, without the docker binary, and
+// returns its exit status
+func (d *FakeDocker) Run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if stdin == nil {
+		stdin = strings.NewReader("")
+	}
+	cmd := &dockerCmd{d: d, stdin: stdin, stdout: stdout, stderr: stderr}
+
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "Usage:  docker [OPTIONS] COMMAND")
+		return 1
+	}
+	if d.stopped {
+		fmt.Fprintf(stderr, "Cannot connect to the Docker daemon at unix://%s. Is the docker daemon running?\n", synology.SocketPath)
+		return 1
+	}
+	return cmd.dispatch(args[0], args[1:])
+}
+
+// SetRunning starts or stops the daemon. Every command fails while it is
+// stopped, as when Container Manager fails to start.
+func (d *FakeDocker) SetRunning(running bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stopped = !running
+}
+
+// dockerCmd is one docker invocation
+type dockerCmd struct {
+	d      *FakeDocker
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func (c *dockerCmd) dispatch(name string, args []string) int {
+	handlers := map[string]func([]string) int{
+		"version": c.version,
+		"pull":    c.pull,
+		"run":     c.run,
+		"ps":      c.ps,
+		"rm":      c.rm,
+		"start":   c.start,
+		"stop":    c.stop,
+		"restart": c.restart,
+		"logs":    c.logs,
+		"exec":    c.exec,
+		"attach":  c.attach,
+		"inspect": c.inspect,
+		"stats":   c.stats,
+		"images":  c.images,
+		"rmi":     c.rmi,
+		"export":  c.export,
+		"cp":      c.cp,
+		"import":  c.importImage,
+		"build":   c.build,
+		"save":    c.save,
+		"load":    c.load,
+		"system":  c.system,
+		"volume":  c.volume,
+		"network": c.network,
+		"container": func(args []string) int {
+			if len(args) == 0 {
+				return c.usage("container")
+			}
+			switch args[0] {
+			case "ls", "list":
+				return c.ps(args[1:])
+			case "inspect":
+				return c.inspect(append([]string{"--type", "container"}, args[1:]...))
+			}
+			return c.dispatch(args[0], args[1:])
+		},
+		"image": func(args []string) int {
+			if len(args) == 0 {
+				return c.usage("image")
+			}
+			switch args[0] {
+			case "ls", "list":
+				return c.images(args[1:])
+			case "rm":
+				return c.rmi(args[1:])
+			case "inspect":
+				return c.inspect(append([]string{"--type", "image"}, args[1:]...))
+			}
+			return c.dispatch(args[0], args[1:])
+		},
+	}
+
+	handler, ok := handlers[name]
+	if !ok {
+		fmt.Fprintf(c.stderr, "docker: '%s' is not a docker command.\nSee 'docker --help'\n", name)
+		return 1
+	}
+	return handler(args)
+}
+
+// fail reports a daemon error the way the docker CLI does
+func (c *dockerCmd) fail(status int, format string, args ...any) int {
+	fmt.Fprintf(c.stderr, "Error response from daemon: "+format+"\n", args...)
+	return status
+}
+
+func (c *dockerCmd) usage(command string) int {
+	fmt.Fprintf(c.stderr, "\"docker %s\" requires at least 1 argument.\n", command)
+	return 1
+}
+
+// dockerFlags is a parsed command line. Flags end at the first positional
+// argument, as with docker run and docker exec.
+type dockerFlags struct {
+	values map[string][]string
+	args   []string
+}
+
+// parseDockerFlags parses args. valueFlags lists flags that take a value,
+// with aliases separated by "|", e.g. "e|env". Other flags are booleans.
+func parseDockerFlags(args []string, valueFlags ...string) (*dockerFlags, error) {
+	canonical := map[string]string{}
+	for _, spec := range valueFlags {
+		names := strings.Split(spec, "|")
+		for _, name := range names {
+			canonical[name] = names[len(names)-1]
+		}
+	}
+
+	flags := &dockerFlags{values: map[string][]string{}}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			flags.args = append(flags.args, args[i+1:]...)
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			flags.args = append(flags.args, args[i:]...)
+			break
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		long, isValue := canonical[name]
+		switch {
+		case isValue && hasValue:
+			flags.values[long] = append(flags.values[long], value)
+		case isValue:
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("flag needs an argument: %s", arg)
+			}
+			i++
+			flags.values[long] = append(flags.values[long], args[i])
+		case !strings.HasPrefix(arg, "--") && len(name) > 1:
+			// Combined short booleans such as -it
+			for _, r := range name {
+				flags.values[string(r)] = append(flags.values[string(r)], "true")
+			}
+		default:
+			flags.values[name] = append(flags.values[name], "true")
+		}
+	}
+	return flags, nil
+}
+
+// has reports whether any of the named boolean flags was set
+func (f *dockerFlags) has(names ...string) bool {
+	for _, name := range names {
+		if values := f.values[name]; len(values) > 0 && values[len(values)-1] != "false" {
+			return true
+		}
+	}
+	return false
+}
+
+// get returns the last value of a value flag, by its long name
+func (f *dockerFlags) get(name string) string {
+	values := f.values[name]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
+
+// all returns every value of a value flag, by its long name
+func (f *dockerFlags) all(name string) []string {
+	return f.values[name]
+}
+
+// parse parses args, reporting flag errors on stderr. ok is false on error.
+func (c *dockerCmd) parse(args []string, valueFlags ...string) (*dockerFlags, bool) {
+	flags, err := parseDockerFlags(args, valueFlags...)
+	if err != nil {
+		fmt.Fprintln(c.stderr, err)
+		return nil, false
+	}
+	return flags, true
+}
+
+var templateField = regexp.MustCompile(`{{\s*\.(\w+)\s*}}`)
+
+// render writes one line per row using a docker --format template. A
+// "table " prefix adds a header line built from the field names.
+func (c *dockerCmd) render(format string, rows []map[string]any) int {
+	table := strings.HasPrefix(format, "table")
+	if table {
+		format = strings.TrimSpace(strings.TrimPrefix(format, "table"))
+	}
+
+	tmpl, err := parseTemplate(format)
+	if err != nil {
+		fmt.Fprintf(c.stderr, "template parsing error: %v\n", err)
+		return 1
+	}
+
+	if table {
+		header := templateField.ReplaceAllStringFunc(format, func(field string) string {
+			return headerName(templateField.FindStringSubmatch(field)[1])
+		})
+		fmt.Fprintln(c.stdout, header)
+	}
+	for _, row := range rows {
+		if err := tmpl.Execute(c.stdout, row); err != nil {
+			fmt.Fprintf(c.stderr, "template: %v\n", err)
+			return 1
+		}
+		fmt.Fprintln(c.stdout)
+	}
+	return 0
+}
+
+// inspectObjects writes objects as an indented JSON array, or one line per
+// object using format
+func (c *dockerCmd) inspectObjects(format string, objects []map[string]any) int {
+	if format != "" {
+		return c.render(format, objects)
+	}
+	data, _ := json.MarshalIndent(objects, "", "    ")
+	fmt.Fprintln(c.stdout, string(data))
+	return 0
+}
+
+func parseTemplate(format string) (*template.Template, error) {
+	return template.New("format").Funcs(template.FuncMap{
+		"json": func(v any) string {
+			data, _ := json.Marshal(v)
+			return string(data)
+		},
+		"join": strings.Join,
+	}).Parse(format)
+}
+
+func headerName(field string) string {
+	switch field {
+	case "CreatedSince":
+		return "CREATED"
+	case "CPUPerc":
+		return "CPU %"
+	case "MemUsage":
+		return "MEM USAGE / LIMIT"
+	}
+	return strings.ToUpper(field)
+}
+
+// newID returns a deterministic 64 character hex ID
+func (d *FakeDocker) newID(kind, name string) string {
+	d.serial++
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s/%s/%d", kind, name, d.serial)))
+	return hex.EncodeToString(sum[:])
+}
+
+// timestamp returns the current time as docker formats log timestamps
+func (d *FakeDocker) timestamp() string {
+	return d.Now().UTC().Format(time.RFC3339Nano)
+}
+
+// normalizeImageRef turns "nginx" into "nginx:latest" and drops the default
+// registry and library prefixes
+func normalizeImageRef(ref string) (string, string) {
+	ref = strings.TrimPrefix(ref, "docker.io/")
+	ref = strings.TrimPrefix(ref, "library/")
+	repository, tag := ref, "latest"
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		repository, tag = ref[:i], ref[i+1:]
+	}
+	return repository, tag
+}
+
+func (d *FakeDocker) findImage(ref string) *FakeImage {
+	repository, tag := normalizeImageRef(ref)
+	for _, image := range d.images {
+		if (image.Repository == repository && image.Tag == tag) || strings.HasPrefix(image.ID, strings.TrimPrefix(ref, "sha256:")) {
+			return image
+		}
+	}
+	return nil
+}
+
+func (d *FakeDocker) addImage(ref string) *FakeImage {
+	if image := d.findImage(ref); image != nil {
+		return image
+	}
+	repository, tag := normalizeImageRef(ref)
+	image := &FakeImage{ID: d.newID("image", ref), Repository: repository, Tag: tag}
+	d.images = append(d.images, image)
+	return image
+}
+
+// hasLayerChain reports whether an image has chain as its first layers
+func (d *FakeDocker) hasLayerChain(chain []string) bool {
+	for _, image := range d.images {
+		if len(image.Layers) < len(chain) {
+			continue
+		}
+		matches := true
+		for i := range chain {
+			if image.Layers[i] != chain[i] {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *FakeDocker) findContainer(nameOrID string) *FakeContainer {
+	nameOrID = strings.TrimPrefix(nameOrID, "/")
+	for _, c := range d.containers {
+		if c.Name == nameOrID {
+			return c
+		}
+	}
+	for _, c := range d.containers {
+		if len(nameOrID) >= 3 && strings.HasPrefix(c.ID, nameOrID) {
+			return c
+		}
+	}
+	return nil
+}
+
+func (d *FakeDocker) findVolume(name string) *FakeVolume {
+	for _, v := range d.volumes {
+		if v.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+func (d *FakeDocker) findNetwork(nameOrID string) *FakeNetwork {
+	for _, n := range d.networks {
+		if n.Name == nameOrID {
+			return n
+		}
+	}
+	for _, n := range d.networks {
+		if len(nameOrID) >= 3 && strings.HasPrefix(n.ID, nameOrID) {
+			return n
+		}
+	}
+	return nil
+}
+
+// imageInUse reports whether any container uses image
+func (d *FakeDocker) imageInUse(image *FakeImage) *FakeContainer {
+	for _, c := range d.containers {
+		if found := d.findImage(c.Image); found == image {
+			return c
+		}
+	}
+	return nil
+}
+
+// volumeUsers returns the IDs of containers that mount volume
+func (d *FakeDocker) volumeUsers(volume *FakeVolume) []string {
+	var ids []string
+	for _, c := range d.containers {
+		for _, mount := range c.Volumes {
+			if source, _, _ := strings.Cut(mount, ":"); source == volume.Name {
+				ids = append(ids, c.ID)
+				break
+			}
+		}
+	}
+	return ids
+}
+
+// networkUsers returns the containers connected to network
+func (d *FakeDocker) networkUsers(network *FakeNetwork) []*FakeContainer {
+	var users []*FakeContainer
+	for _, c := range d.containers {
+		for _, name := range c.Networks {
+			if name == network.Name {
+				users = append(users, c)
+				break
+			}
+		}
+	}
+	return users
+}
+
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// sortedKeys returns the keys of m in order, for stable output
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// hasLabel reports whether labels, as KEY=value pairs, match the docker
+// ps filter value label, either a key or KEY=value
+func hasLabel(labels []string, label string) bool {
+	key, value, withValue := strings.Cut(label, "=")
+	got, ok := keyValues(labels)[key]
+	return ok && (!withValue || got == value)
+}
+
+// keyValues turns ["a=b"] into {"a": "b"}
+func keyValues(pairs []string) map[string]string {
+	m := map[string]string{}
+	for _, pair := range pairs {
+		key, value, _ := strings.Cut(pair, "=")
+		m[key] = value
+	}
+	return m
+}
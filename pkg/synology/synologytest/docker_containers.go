@@ -0,0 +1,656 @@
+package synologytest
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (c *dockerCmd) version(args []string) int {
+	flags, ok := c.parse(args, "f|format")
+	if !ok {
+		return 1
+	}
+
+	info := map[string]any{
+		"Client": map[string]any{"Version": FakeDockerVersion},
+		"Server": map[string]any{"Version": FakeDockerVersion, "APIVersion": FakeDockerAPIVersion},
+	}
+	if format := flags.get("format"); format != "" {
+		return c.render(format, []map[string]any{info})
+	}
+	fmt.Fprintf(c.stdout, "Client:\n Version: %s\n\nServer:\n Engine:\n  Version: %s\n", FakeDockerVersion, FakeDockerVersion)
+	return 0
+}
+
+func (c *dockerCmd) pull(args []string) int {
+	flags, ok := c.parse(args, "platform")
+	if !ok {
+		return 1
+	}
+	if len(flags.args) != 1 {
+		return c.usage("pull")
+	}
+
+	ref := flags.args[0]
+	repository, tag := normalizeImageRef(ref)
+	if repository == MissingImage {
+		return c.fail(1, "manifest for %s:%s not found: manifest unknown: manifest unknown", repository, tag)
+	}
+
+	existing := c.d.findImage(ref)
+	image := c.d.addImage(ref)
+	if flags.has("q", "quiet") {
+		fmt.Fprintf(c.stdout, "docker.io/%s\n", image.Ref())
+		return 0
+	}
+
+	fmt.Fprintf(c.stdout, "%s: Pulling from %s\n", tag, repository)
+	fmt.Fprintf(c.stdout, "Digest: sha256:%s\n", image.ID)
+	if existing != nil {
+		fmt.Fprintf(c.stdout, "Status: Image is up to date for %s\n", image.Ref())
+	} else {
+		fmt.Fprintf(c.stdout, "Status: Downloaded newer image for %s\n", image.Ref())
+	}
+	fmt.Fprintf(c.stdout, "docker.io/%s\n", image.Ref())
+	return 0
+}
+
+func (c *dockerCmd) run(args []string) int {
+	flags, ok := c.parse(args,
+		"name", "p|publish", "v|volume", "e|env", "restart", "net|network",
+		"u|user", "w|workdir", "l|label", "entrypoint", "hostname", "env-file",
+		"platform", "cpus", "m|memory", "mount", "log-driver", "log-opt")
+	if !ok {
+		return 125
+	}
+	if len(flags.args) == 0 {
+		fmt.Fprintln(c.stderr, "\"docker run\" requires at least 1 argument.")
+		return 125
+	}
+
+	ref := flags.args[0]
+	if c.d.findImage(ref) == nil {
+		repository, tag := normalizeImageRef(ref)
+		fmt.Fprintf(c.stderr, "Unable to find image '%s:%s' locally\n", repository, tag)
+		if repository == MissingImage {
+			fmt.Fprintf(c.stderr, "docker: Error response from daemon: manifest for %s:%s not found: manifest unknown: manifest unknown.\n", repository, tag)
+			return 125
+		}
+		c.d.addImage(ref)
+	}
+
+	name := flags.get("name")
+	if name == "" {
+		name = fmt.Sprintf("fake_container_%d", c.d.serial+1)
+	}
+	if existing := c.d.findContainer(name); existing != nil && existing.Name == name {
+		fmt.Fprintf(c.stderr, "docker: Error response from daemon: Conflict. The container name \"/%s\" is already in use by container \"%s\". You have to remove (or rename) that container to be able to reuse that name.\n", name, existing.ID)
+		return 125
+	}
+
+	network := flags.get("network")
+	if network == "" {
+		network = "bridge"
+	}
+	if c.d.findNetwork(network) == nil {
+		fmt.Fprintf(c.stderr, "docker: Error response from daemon: network %s not found.\n", network)
+		return 125
+	}
+
+	for _, mount := range flags.all("volume") {
+		source, _, _ := strings.Cut(mount, ":")
+		if !strings.HasPrefix(source, "/") && c.d.findVolume(source) == nil {
+			c.d.volumes = append(c.d.volumes, &FakeVolume{Name: source, Driver: "local"})
+		}
+	}
+
+	container := &FakeContainer{
+		ID:       c.d.newID("container", name),
+		Name:     name,
+		Image:    ref,
+		Command:  flags.args[1:],
+		Status:   "running",
+		Ports:    flags.all("publish"),
+		Volumes:  flags.all("volume"),
+		Env:      flags.all("env"),
+		Restart:  flags.get("restart"),
+		User:     flags.get("user"),
+		Networks: []string{network},
+		Labels:   flags.all("label"),
+	}
+	c.d.containers = append(c.d.containers, container)
+
+	// Commands that finish right away, like echo, exit and leave their output
+	output, status := emulateProcess(container, container.Command, nil, c.stdin)
+	for _, line := range strings.Split(strings.TrimSuffix(output, "\n"), "\n") {
+		if line != "" {
+			container.Logs = append(container.Logs, c.d.timestamp()+" "+line)
+		}
+	}
+	if status >= 0 {
+		container.Status = "exited"
+	}
+
+	if flags.has("d", "detach") {
+		fmt.Fprintln(c.stdout, container.ID)
+		return 0
+	}
+	io.WriteString(c.stdout, output)
+	if status > 0 {
+		return status
+	}
+	return 0
+}
+
+// containerRow is a container as docker ps formats it
+func containerRow(container *FakeContainer) map[string]any {
+	status := map[string]string{"running": "Up", "exited": "Exited", "created": "Created"}[container.Status]
+	return map[string]any{
+		"ID":       shortID(container.ID),
+		"Names":    container.Name,
+		"Image":    container.Image,
+		"Command":  strconv.Quote(strings.Join(container.Command, " ")),
+		"Status":   status,
+		"State":    container.Status,
+		"Ports":    strings.Join(container.Ports, ","),
+		"Networks": strings.Join(container.Networks, ","),
+		"Labels":   strings.Join(container.Labels, ","),
+	}
+}
+
+func (c *dockerCmd) ps(args []string) int {
+	flags, ok := c.parse(args, "format", "f|filter", "n|last")
+	if !ok {
+		return 1
+	}
+
+	var rows []map[string]any
+containers:
+	for i := len(c.d.containers) - 1; i >= 0; i-- {
+		container := c.d.containers[i]
+		if container.Status != "running" && !flags.has("a", "all") {
+			continue
+		}
+		for _, filter := range flags.all("filter") {
+			key, value, _ := strings.Cut(filter, "=")
+			if key == "label" && !hasLabel(container.Labels, value) || key == "name" && !strings.Contains(container.Name, value) {
+				continue containers
+			}
+		}
+		rows = append(rows, containerRow(container))
+	}
+
+	format := flags.get("format")
+	switch {
+	case flags.has("q", "quiet"):
+		format = "{{.ID}}"
+	case format == "":
+		format = "table {{.ID}}\t{{.Image}}\t{{.Command}}\t{{.Status}}\t{{.Ports}}\t{{.Names}}"
+	}
+	return c.render(format, rows)
+}
+
+// eachContainer runs fn for every named container, reporting missing ones,
+// and returns 1 if any failed
+func (c *dockerCmd) eachContainer(names []string, fn func(*FakeContainer) bool) int {
+	status := 0
+	for _, name := range names {
+		container := c.d.findContainer(name)
+		if container == nil {
+			c.fail(1, "No such container: %s", name)
+			status = 1
+			continue
+		}
+		if !fn(container) {
+			status = 1
+			continue
+		}
+		fmt.Fprintln(c.stdout, name)
+	}
+	return status
+}
+
+func (c *dockerCmd) rm(args []string) int {
+	flags, ok := c.parse(args)
+	if !ok {
+		return 1
+	}
+	if len(flags.args) == 0 {
+		return c.usage("rm")
+	}
+
+	return c.eachContainer(flags.args, func(container *FakeContainer) bool {
+		if container.Status == "running" && !flags.has("f", "force") {
+			c.fail(1, "You cannot remove a running container %s. Stop the container before attempting removal or force remove", container.ID)
+			return false
+		}
+		for i, existing := range c.d.containers {
+			if existing == container {
+				c.d.containers = append(c.d.containers[:i], c.d.containers[i+1:]...)
+				break
+			}
+		}
+		return true
+	})
+}
+
+func (c *dockerCmd) start(args []string) int {
+	flags, ok := c.parse(args, "detach-keys")
+	if !ok {
+		return 1
+	}
+	if len(flags.args) == 0 {
+		return c.usage("start")
+	}
+
+	return c.eachContainer(flags.args, func(container *FakeContainer) bool {
+		if container.Status != "running" {
+			container.Status = "running"
+			container.Logs = append(container.Logs, c.d.timestamp()+" Container started")
+		}
+		return true
+	})
+}
+
+func (c *dockerCmd) stop(args []string) int {
+	flags, ok := c.parse(args, "t|time", "s|signal")
+	if !ok {
+		return 1
+	}
+	if len(flags.args) == 0 {
+		return c.usage("stop")
+	}
+
+	return c.eachContainer(flags.args, func(container *FakeContainer) bool {
+		if container.Status == "running" {
+			container.Status = "exited"
+			container.Logs = append(container.Logs, c.d.timestamp()+" Container stopped")
+		}
+		return true
+	})
+}
+
+func (c *dockerCmd) restart(args []string) int {
+	flags, ok := c.parse(args, "t|time", "s|signal")
+	if !ok {
+		return 1
+	}
+	if len(flags.args) == 0 {
+		return c.usage("restart")
+	}
+
+	return c.eachContainer(flags.args, func(container *FakeContainer) bool {
+		container.Status = "running"
+		container.Logs = append(container.Logs, c.d.timestamp()+" Container restarted")
+		return true
+	})
+}
+
+func (c *dockerCmd) logs(args []string) int {
+	flags, ok := c.parse(args, "n|tail", "since", "until")
+	if !ok {
+		return 1
+	}
+	if len(flags.args) != 1 {
+		return c.usage("logs")
+	}
+
+	container := c.d.findContainer(flags.args[0])
+	if container == nil {
+		return c.fail(1, "No such container: %s", flags.args[0])
+	}
+
+	var since time.Time
+	if value := flags.get("since"); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			since = c.d.Now().Add(-duration)
+		} else if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			since = t
+		} else {
+			fmt.Fprintf(c.stderr, "invalid value for \"since\": %s\n", value)
+			return 1
+		}
+	}
+
+	var lines []string
+	for _, line := range container.Logs {
+		stamp, message, _ := strings.Cut(line, " ")
+		if t, err := time.Parse(time.RFC3339Nano, stamp); err == nil && t.Before(since) {
+			continue
+		}
+		if flags.has("t", "timestamps") {
+			lines = append(lines, line)
+		} else {
+			lines = append(lines, message)
+		}
+	}
+
+	if tail := flags.get("tail"); tail != "" && tail != "all" {
+		n, err := strconv.Atoi(tail)
+		if err == nil && n >= 0 && n < len(lines) {
+			lines = lines[len(lines)-n:]
+		}
+	}
+
+	for _, line := range lines {
+		fmt.Fprintln(c.stdout, line)
+	}
+	return 0
+}
+
+func (c *dockerCmd) exec(args []string) int {
+	flags, ok := c.parse(args, "u|user", "w|workdir", "e|env", "env-file", "detach-keys")
+	if !ok {
+		return 1
+	}
+	if len(flags.args) < 2 {
+		fmt.Fprintln(c.stderr, "\"docker exec\" requires at least 2 arguments.")
+		return 1
+	}
+
+	container := c.d.findContainer(flags.args[0])
+	if container == nil {
+		return c.fail(1, "No such container: %s", flags.args[0])
+	}
+	if container.Status != "running" {
+		return c.fail(1, "container %s is not running", container.ID)
+	}
+
+	var stdin io.Reader = strings.NewReader("")
+	if flags.has("i", "interactive") {
+		stdin = c.stdin
+	}
+
+	output, status := emulateProcess(container, flags.args[1:], flags.all("env"), stdin)
+	io.WriteString(c.stdout, output)
+	if status == 127 {
+		fmt.Fprintf(c.stderr, "OCI runtime exec failed: exec failed: unable to start container process: exec: %q: executable file not found in $PATH: unknown\n", flags.args[1])
+		return 126
+	}
+	return max(status, 0)
+}
+
+func (c *dockerCmd) attach(args []string) int {
+	flags, ok := c.parse(args, "detach-keys")
+	if !ok {
+		return 1
+	}
+	if len(flags.args) != 1 {
+		return c.usage("attach")
+	}
+
+	container := c.d.findContainer(flags.args[0])
+	if container == nil {
+		return c.fail(1, "No such container: %s", flags.args[0])
+	}
+	if container.Status != "running" {
+		fmt.Fprintln(c.stderr, "You cannot attach to a stopped container, start it first")
+		return 1
+	}
+	if !flags.has("no-stdin") {
+		io.Copy(io.Discard, c.stdin)
+	}
+	return 0
+}
+
+func (c *dockerCmd) stats(args []string) int {
+	flags, ok := c.parse(args, "format")
+	if !ok {
+		return 1
+	}
+
+	var rows []map[string]any
+	for _, container := range c.d.containers {
+		if len(flags.args) > 0 {
+			found := false
+			for _, name := range flags.args {
+				found = found || c.d.findContainer(name) == container
+			}
+			if !found {
+				continue
+			}
+		} else if container.Status != "running" && !flags.has("a", "all") {
+			continue
+		}
+
+		rows = append(rows, map[string]any{
+			"ID":        shortID(container.ID),
+			"Container": shortID(container.ID),
+			"Name":      container.Name,
+			"CPUPerc":   "0.00%",
+			"MemUsage":  "8MiB/1GiB",
+			"MemPerc":   "0.78%",
+			"NetIO":     "0B/0B",
+			"BlockIO":   "0B/0B",
+			"PIDs":      "1",
+		})
+	}
+	for _, name := range flags.args {
+		if c.d.findContainer(name) == nil {
+			return c.fail(1, "No such container: %s", name)
+		}
+	}
+
+	format := flags.get("format")
+	if format == "" {
+		format = "table {{.Container}}\t{{.Name}}\t{{.CPUPerc}}\t{{.MemUsage}}\t{{.MemPerc}}\t{{.NetIO}}\t{{.BlockIO}}\t{{.PIDs}}"
+	}
+	// Streaming stats print a single sample here rather than running forever
+	return c.render(format, rows)
+}
+
+func (c *dockerCmd) export(args []string) int {
+	flags, ok := c.parse(args, "o|output")
+	if !ok {
+		return 1
+	}
+	if len(flags.args) != 1 {
+		return c.usage("export")
+	}
+
+	container := c.d.findContainer(flags.args[0])
+	if container == nil {
+		return c.fail(1, "No such container: %s", flags.args[0])
+	}
+	if flags.get("output") != "" {
+		// The file would be written on the NAS, which has no filesystem here
+		return 0
+	}
+
+	tw := tar.NewWriter(c.stdout)
+	hostname := []byte(shortID(container.ID) + "\n")
+	tw.WriteHeader(&tar.Header{Name: "etc/hostname", Mode: 0644, Size: int64(len(hostname))})
+	tw.Write(hostname)
+	tw.Close()
+	return 0
+}
+
+func (c *dockerCmd) cp(args []string) int {
+	flags, ok := c.parse(args)
+	if !ok {
+		return 1
+	}
+	if len(flags.args) != 2 {
+		fmt.Fprintln(c.stderr, "\"docker cp\" requires exactly 2 arguments.")
+		return 1
+	}
+	if c.d.HostRoot == "" {
+		fmt.Fprintln(c.stderr, "synologytest: docker cp needs FakeDocker.HostRoot")
+		return 1
+	}
+
+	srcName, srcPath, srcInContainer := splitCopyArg(flags.args[0])
+	dstName, dstPath, dstInContainer := splitCopyArg(flags.args[1])
+	if srcInContainer == dstInContainer {
+		fmt.Fprintln(c.stderr, "copying between containers or between host paths is not supported")
+		return 1
+	}
+
+	name := srcName
+	if dstInContainer {
+		name = dstName
+	}
+	container := c.d.findContainer(name)
+	if container == nil {
+		return c.fail(1, "No such container: %s", name)
+	}
+	if container.Files == nil {
+		container.Files = map[string][]byte{}
+	}
+
+	if dstInContainer {
+		return c.copyIn(container, srcPath, dstPath)
+	}
+	return c.copyOut(container, srcPath, dstPath)
+}
+
+// splitCopyArg splits a docker cp argument into a container name and path
+func splitCopyArg(arg string) (string, string, bool) {
+	if strings.HasPrefix(arg, "/") || strings.HasPrefix(arg, ".") {
+		return "", arg, false
+	}
+	name, p, found := strings.Cut(arg, ":")
+	if !found {
+		return "", arg, false
+	}
+	return name, p, true
+}
+
+// copyIn copies a file or directory from the NAS into container
+func (c *dockerCmd) copyIn(container *FakeContainer, src, dst string) int {
+	source := hostPath(c.d.HostRoot, src)
+	if _, err := os.Stat(source); err != nil {
+		fmt.Fprintf(c.stderr, "lstat %s: no such file or directory\n", src)
+		return 1
+	}
+
+	// As with cp, an existing directory receives the source by name
+	target := path.Clean(dst)
+	if strings.HasSuffix(dst, "/") || containerDir(container, target) {
+		target = path.Join(target, path.Base(path.Clean(src)))
+	}
+
+	err := filepath.WalkDir(source, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.Type().IsRegular() {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(source, p)
+		container.Files[path.Join(target, filepath.ToSlash(rel))] = data
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintln(c.stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// copyOut copies a file or directory from container to the NAS
+func (c *dockerCmd) copyOut(container *FakeContainer, src, dst string) int {
+	src = path.Clean(src)
+	target := hostPath(c.d.HostRoot, dst)
+	if info, err := os.Stat(target); err == nil && info.IsDir() {
+		target = filepath.Join(target, path.Base(src))
+	}
+
+	found := false
+	for _, name := range sortedKeys(container.Files) {
+		if name != src && !strings.HasPrefix(name, src+"/") {
+			continue
+		}
+		found = true
+
+		p := filepath.Join(target, filepath.FromSlash(strings.TrimPrefix(name, src)))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			fmt.Fprintln(c.stderr, err)
+			return 1
+		}
+		if err := os.WriteFile(p, container.Files[name], 0644); err != nil {
+			fmt.Fprintln(c.stderr, err)
+			return 1
+		}
+	}
+	if !found {
+		return c.fail(1, "Could not find the file %s in container %s", src, container.Name)
+	}
+	return 0
+}
+
+// containerDir reports whether any file of container is below dir
+func containerDir(container *FakeContainer, dir string) bool {
+	for name := range container.Files {
+		if strings.HasPrefix(name, strings.TrimSuffix(dir, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// emulateProcess runs a few common commands inside container and returns
+// their output and exit status. Status -1 means the process keeps running;
+// 127 means the command is unknown.
+func emulateProcess(container *FakeContainer, command, env []string, stdin io.Reader) (string, int) {
+	if len(command) == 0 {
+		return "", -1
+	}
+
+	switch command[0] {
+	case "echo":
+		return strings.Join(command[1:], " ") + "\n", 0
+	case "true":
+		return "", 0
+	case "false":
+		return "", 1
+	case "whoami":
+		if container.User != "" {
+			return container.User + "\n", 0
+		}
+		return "root\n", 0
+	case "hostname":
+		return shortID(container.ID) + "\n", 0
+	case "env":
+		vars := append([]string{"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin", "HOSTNAME=" + shortID(container.ID)}, container.Env...)
+		return strings.Join(append(vars, env...), "\n") + "\n", 0
+	case "cat":
+		if len(command) == 1 && stdin != nil {
+			data, _ := io.ReadAll(stdin)
+			return string(data), 0
+		}
+		var out strings.Builder
+		for _, name := range command[1:] {
+			data, ok := container.Files[name]
+			if !ok {
+				return out.String(), 1
+			}
+			out.Write(data)
+		}
+		return out.String(), 0
+	case "sleep", "nginx", "redis-server", "postgres":
+		return "", -1
+	case "sh", "/bin/sh", "bash", "/bin/bash":
+		if len(command) == 3 && command[1] == "-c" {
+			script := strings.Fields(command[2])
+			if len(script) == 2 && script[0] == "exit" {
+				status, err := strconv.Atoi(script[1])
+				if err == nil {
+					return "", status
+				}
+			}
+			return emulateProcess(container, script, env, stdin)
+		}
+		return "", 0
+	}
+	return "", 127
+}
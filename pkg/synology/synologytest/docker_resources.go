@@ -0,0 +1,1009 @@
+package synologytest
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+func (d *FakeDocker) containerObject(container *FakeContainer) map[string]any {
+	networks := map[string]any{}
+	for _, name := range container.Networks {
+		networks[name] = map[string]any{"NetworkID": d.findNetwork(name).ID, "IPAddress": d.containerIP(container, name)}
+	}
+	return map[string]any{
+		"Id":    container.ID,
+		"Name":  "/" + container.Name,
+		"Image": container.Image,
+		"State": map[string]any{
+			"Status":  container.Status,
+			"Running": container.Status == "running",
+		},
+		"Config": map[string]any{
+			"Image":  container.Image,
+			"Cmd":    container.Command,
+			"Env":    container.Env,
+			"User":   container.User,
+			"Labels": keyValues(container.Labels),
+			"Tty":    false,
+		},
+		"HostConfig": map[string]any{
+			"Binds":         container.Volumes,
+			"RestartPolicy": map[string]any{"Name": container.Restart},
+		},
+		"NetworkSettings": map[string]any{"Networks": networks},
+	}
+}
+
+func imageObject(image *FakeImage) map[string]any {
+	return map[string]any{
+		"Id":       "sha256:" + image.ID,
+		"RepoTags": []string{image.Ref()},
+		"Size":     int64(len(image.ID)) << 20,
+		"RootFS":   map[string]any{"Type": "layers", "Layers": image.Layers},
+	}
+}
+
+// containerIP returns the address of a running container on the named
+// network, with a subnet per network, or "" as docker reports for stopped
+// containers and the host network
+func (d *FakeDocker) containerIP(container *FakeContainer, network string) string {
+	if container.Status != "running" || network == "host" {
+		return ""
+	}
+	subnet := 0
+	for i, n := range d.networks {
+		if n.Name == network {
+			subnet = i
+		}
+	}
+	for i, c := range d.containers {
+		if c == container {
+			return fmt.Sprintf("172.%d.0.%d", 17+subnet, i+2)
+		}
+	}
+	return ""
+}
+
+func volumeObject(volume *FakeVolume) map[string]any {
+	return map[string]any{
+		"Name":       volume.Name,
+		"Driver":     volume.Driver,
+		"Mountpoint": "/volume1/@docker/volumes/" + volume.Name + "/_data",
+		"Labels":     keyValues(volume.Labels),
+		"Scope":      "local",
+	}
+}
+
+func (d *FakeDocker) networkObject(network *FakeNetwork) map[string]any {
+	containers := map[string]any{}
+	for _, container := range d.networkUsers(network) {
+		containers[container.ID] = map[string]any{"Name": container.Name}
+	}
+	return map[string]any{
+		"Name":       network.Name,
+		"Id":         network.ID,
+		"Driver":     network.Driver,
+		"Scope":      "local",
+		"Internal":   network.Internal,
+		"Labels":     keyValues(network.Labels),
+		"Containers": containers,
+	}
+}
+
+func (c *dockerCmd) inspect(args []string) int {
+	flags, ok := c.parse(args, "f|format", "type")
+	if !ok {
+		return 1
+	}
+	if len(flags.args) == 0 {
+		return c.usage("inspect")
+	}
+
+	kind := flags.get("type")
+	var objects []map[string]any
+	for _, name := range flags.args {
+		object := c.lookup(kind, name)
+		if object == nil {
+			fmt.Fprintf(c.stderr, "Error: No such object: %s\n", name)
+			return 1
+		}
+		objects = append(objects, object)
+	}
+	return c.inspectObjects(flags.get("format"), objects)
+}
+
+// lookup finds name among objects of kind, or of any kind if kind is empty
+func (c *dockerCmd) lookup(kind, name string) map[string]any {
+	if kind == "" || kind == "container" {
+		if container := c.d.findContainer(name); container != nil {
+			return c.d.containerObject(container)
+		}
+	}
+	if kind == "" || kind == "image" {
+		if image := c.d.findImage(name); image != nil {
+			return imageObject(image)
+		}
+	}
+	if kind == "" || kind == "volume" {
+		if volume := c.d.findVolume(name); volume != nil {
+			return volumeObject(volume)
+		}
+	}
+	if kind == "" || kind == "network" {
+		if network := c.d.findNetwork(name); network != nil {
+			return c.d.networkObject(network)
+		}
+	}
+	return nil
+}
+
+func (c *dockerCmd) images(args []string) int {
+	flags, ok := c.parse(args, "f|filter", "format")
+	if !ok {
+		return 1
+	}
+
+	filters := keyValues(flags.all("filter"))
+	var rows []map[string]any
+	for i := len(c.d.images) - 1; i >= 0; i-- {
+		image := c.d.images[i]
+		if len(flags.args) > 0 {
+			repository, _ := normalizeImageRef(flags.args[0])
+			if image.Repository != repository {
+				continue
+			}
+		}
+		if filters["dangling"] == "true" && image.Repository != "<none>" {
+			continue
+		}
+
+		id := shortID(image.ID)
+		if flags.has("no-trunc") {
+			id = "sha256:" + image.ID
+		}
+		rows = append(rows, map[string]any{
+			"Repository":   image.Repository,
+			"Tag":          image.Tag,
+			"ID":           id,
+			"Digest":       "<none>",
+			"CreatedSince": "now",
+			"Size":         fmt.Sprintf("%dMB", len(image.ID)),
+		})
+	}
+
+	format := flags.get("format")
+	switch {
+	case flags.has("q", "quiet"):
+		format = "{{.ID}}"
+	case format == "":
+		format = "table {{.Repository}}\t{{.Tag}}\t{{.ID}}\t{{.CreatedSince}}\t{{.Size}}"
+	}
+	return c.render(format, rows)
+}
+
+func (c *dockerCmd) rmi(args []string) int {
+	flags, ok := c.parse(args)
+	if !ok {
+		return 1
+	}
+	if len(flags.args) == 0 {
+		return c.usage("rmi")
+	}
+
+	status := 0
+	for _, ref := range flags.args {
+		image := c.d.findImage(ref)
+		if image == nil {
+			status = c.fail(1, "No such image: %s", ref)
+			continue
+		}
+		if container := c.d.imageInUse(image); container != nil && !flags.has("f", "force") {
+			status = c.fail(1, "conflict: unable to remove repository reference %q (must force) - container %s is using its referenced image %s", ref, shortID(container.ID), shortID(image.ID))
+			continue
+		}
+		for i, existing := range c.d.images {
+			if existing == image {
+				c.d.images = append(c.d.images[:i], c.d.images[i+1:]...)
+				break
+			}
+		}
+		fmt.Fprintf(c.stdout, "Untagged: %s\nDeleted: sha256:%s\n", image.Ref(), image.ID)
+	}
+	return status
+}
+
+func (c *dockerCmd) importImage(args []string) int {
+	flags, ok := c.parse(args, "c|change", "m|message", "platform")
+	if !ok {
+		return 1
+	}
+	if len(flags.args) == 0 || len(flags.args) > 2 {
+		return c.usage("import")
+	}
+
+	source := flags.args[0]
+	switch {
+	case source == "-":
+		if n, _ := io.Copy(io.Discard, c.stdin); n == 0 {
+			return c.fail(1, "EOF")
+		}
+	case strings.HasPrefix(source, "http://"), strings.HasPrefix(source, "https://"):
+	case c.d.HostRoot != "":
+		if _, err := os.Stat(hostPath(c.d.HostRoot, source)); err != nil {
+			fmt.Fprintf(c.stderr, "open %s: no such file or directory\n", source)
+			return 1
+		}
+	}
+
+	var image *FakeImage
+	if len(flags.args) == 2 {
+		image = c.d.addImage(flags.args[1])
+	} else {
+		image = &FakeImage{ID: c.d.newID("image", flags.args[0]), Repository: "<none>", Tag: "<none>"}
+		c.d.images = append(c.d.images, image)
+	}
+	fmt.Fprintf(c.stdout, "sha256:%s\n", image.ID)
+	return 0
+}
+
+func (c *dockerCmd) save(args []string) int {
+	flags, ok := c.parse(args, "o|output", "platform")
+	if !ok {
+		return 1
+	}
+	if len(flags.args) == 0 {
+		return c.usage("save")
+	}
+
+	var images []*FakeImage
+	for _, ref := range flags.args {
+		image := c.d.findImage(ref)
+		if image == nil {
+			return c.fail(1, "No such image: %s", ref)
+		}
+		images = append(images, image)
+	}
+	if flags.get("output") != "" {
+		// The file would be written on the NAS, which has no filesystem here
+		return 0
+	}
+
+	// Layers are written as <diff ID>/layer.tar, the config as <image ID>.json
+	// and manifest.json last, as docker does
+	tw := tar.NewWriter(c.stdout)
+	written := map[string]bool{}
+	var manifest []map[string]any
+	for _, image := range images {
+		layers := []string{}
+		for _, diffID := range image.Layers {
+			name := strings.TrimPrefix(diffID, "sha256:") + "/layer.tar"
+			if !written[name] {
+				writeTarFile(tw, name, []byte("layer "+diffID))
+				written[name] = true
+			}
+			layers = append(layers, name)
+		}
+		config, _ := json.Marshal(map[string]any{
+			"rootfs": map[string]any{"type": "layers", "diff_ids": append([]string{}, image.Layers...)},
+		})
+		writeTarFile(tw, image.ID+".json", config)
+		manifest = append(manifest, map[string]any{
+			"Config":   image.ID + ".json",
+			"RepoTags": []string{image.Ref()},
+			"Layers":   layers,
+		})
+	}
+	data, _ := json.Marshal(manifest)
+	writeTarFile(tw, "manifest.json", data)
+	tw.Close()
+	return 0
+}
+
+// writeTarFile adds a regular file to tw
+func writeTarFile(tw *tar.Writer, name string, data []byte) {
+	tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data))})
+	tw.Write(data)
+}
+
+func (c *dockerCmd) load(args []string) int {
+	flags, ok := c.parse(args, "i|input", "platform")
+	if !ok {
+		return 1
+	}
+
+	archive, err := decompress(c.stdin)
+	if err != nil {
+		return c.fail(1, "%v", err)
+	}
+
+	var manifest []struct {
+		Config   string
+		RepoTags []string
+		Layers   []string
+	}
+	files := map[string]bool{}
+	configs := map[string][]byte{}
+	tr := tar.NewReader(archive)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return c.fail(1, "unexpected EOF")
+		}
+		files[header.Name] = true
+		switch {
+		case header.Name == "manifest.json":
+			if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+				return c.fail(1, "invalid manifest: %v", err)
+			}
+		case strings.HasSuffix(header.Name, ".json"):
+			configs[header.Name], _ = io.ReadAll(tr)
+		}
+	}
+	if manifest == nil {
+		return c.fail(1, "open /var/lib/docker/tmp/docker-import/repositories: no such file or directory")
+	}
+
+	for _, entry := range manifest {
+		var config struct {
+			RootFS struct {
+				DiffIDs []string `json:"diff_ids"`
+			} `json:"rootfs"`
+		}
+		json.Unmarshal(configs[entry.Config], &config)
+		diffIDs := config.RootFS.DiffIDs
+
+		// Like docker, a layer file may be left out of the archive when the
+		// daemon already has the layer with the same parent chain
+		for i, layer := range entry.Layers {
+			if !files[layer] && (i >= len(diffIDs) || !c.d.hasLayerChain(diffIDs[:i+1])) {
+				return c.fail(1, "open /var/lib/docker/tmp/docker-import/%s: no such file or directory", layer)
+			}
+		}
+
+		for _, ref := range entry.RepoTags {
+			c.d.addImage(ref).Layers = diffIDs
+			if !flags.has("q", "quiet") {
+				fmt.Fprintf(c.stdout, "Loaded image: %s\n", ref)
+			}
+		}
+	}
+	return 0
+}
+
+// decompress returns r, unpacked if it is gzip compressed
+func decompress(r io.Reader) (io.Reader, error) {
+	input := bufio.NewReader(r)
+	if magic, _ := input.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(input)
+	}
+	return input, nil
+}
+
+// build emulates building from a context sent on stdin, as "docker build -"
+// does. Instructions are echoed as steps, and COPY and ADD sources must be
+// in the context.
+func (c *dockerCmd) build(args []string) int {
+	flags, ok := c.parse(args, "f|file", "t|tag", "build-arg", "target", "platform", "progress")
+	if !ok {
+		return 1
+	}
+	if len(flags.args) != 1 {
+		fmt.Fprintln(c.stderr, "\"docker build\" requires exactly 1 argument.")
+		return 1
+	}
+	if flags.args[0] != "-" {
+		fmt.Fprintf(c.stderr, "unable to prepare context: path %q not found\n", flags.args[0])
+		return 1
+	}
+
+	archive, err := decompress(c.stdin)
+	if err != nil {
+		return c.fail(1, "%v", err)
+	}
+	files := map[string][]byte{}
+	tr := tar.NewReader(archive)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return c.fail(1, "unexpected EOF")
+		}
+		data, _ := io.ReadAll(tr)
+		files[strings.TrimSuffix(header.Name, "/")] = data
+	}
+
+	dockerfile := flags.get("file")
+	if dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+	data, ok := files[dockerfile]
+	if !ok {
+		return c.fail(1, "Cannot locate specified Dockerfile: %s", dockerfile)
+	}
+
+	var instructions []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			instructions = append(instructions, line)
+		}
+	}
+
+	declared := map[string]bool{}
+	for i, line := range instructions {
+		fmt.Fprintf(c.stdout, "Step %d/%d : %s\n", i+1, len(instructions), line)
+		words := strings.Fields(line)
+		switch strings.ToUpper(words[0]) {
+		case "ARG":
+			if len(words) > 1 {
+				name, _, _ := strings.Cut(words[1], "=")
+				declared[name] = true
+			}
+		case "COPY", "ADD":
+			var sources []string
+			for _, word := range words[1:] {
+				if !strings.HasPrefix(word, "--") {
+					sources = append(sources, word)
+				}
+			}
+			for _, source := range sources[:max(len(sources)-1, 0)] {
+				if !inContext(files, source) {
+					fmt.Fprintf(c.stderr, "COPY failed: file not found in build context or excluded by .dockerignore: stat %s: file does not exist\n", source)
+					return 1
+				}
+			}
+		}
+	}
+
+	var unused []string
+	for _, arg := range flags.all("build-arg") {
+		if name, _, _ := strings.Cut(arg, "="); !declared[name] {
+			unused = append(unused, name)
+		}
+	}
+	if len(unused) > 0 {
+		fmt.Fprintf(c.stderr, "[Warning] One or more build-args %v were not consumed\n", unused)
+	}
+
+	tags := flags.all("tag")
+	if len(tags) == 0 {
+		image := &FakeImage{ID: c.d.newID("image", dockerfile), Repository: "<none>", Tag: "<none>"}
+		c.d.images = append(c.d.images, image)
+		fmt.Fprintf(c.stdout, "Successfully built %s\n", shortID(image.ID))
+		return 0
+	}
+	for i, tag := range tags {
+		image := c.d.addImage(tag)
+		if i == 0 {
+			fmt.Fprintf(c.stdout, "Successfully built %s\n", shortID(image.ID))
+		}
+		fmt.Fprintf(c.stdout, "Successfully tagged %s\n", image.Ref())
+	}
+	return 0
+}
+
+// inContext reports whether source names a file or directory in files
+func inContext(files map[string][]byte, source string) bool {
+	source = strings.TrimSuffix(strings.TrimPrefix(path.Clean("/"+source), "/"), "/")
+	if source == "" {
+		return true
+	}
+	for name := range files {
+		if name == source || strings.HasPrefix(name, source+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *dockerCmd) system(args []string) int {
+	if len(args) == 0 {
+		return c.usage("system")
+	}
+
+	switch args[0] {
+	case "df":
+		return c.systemDf(args[1:])
+	case "info":
+		return c.systemInfo(args[1:])
+	case "prune":
+		return c.systemPrune(args[1:])
+	}
+	fmt.Fprintf(c.stderr, "docker: 'system %s' is not a docker command.\n", args[0])
+	return 1
+}
+
+func (c *dockerCmd) systemDf(args []string) int {
+	flags, ok := c.parse(args, "format")
+	if !ok {
+		return 1
+	}
+
+	running := 0
+	for _, container := range c.d.containers {
+		if container.Status == "running" {
+			running++
+		}
+	}
+	activeVolumes := 0
+	for _, volume := range c.d.volumes {
+		if len(c.d.volumeUsers(volume)) > 0 {
+			activeVolumes++
+		}
+	}
+
+	rows := []map[string]any{
+		{"Type": "Images", "TotalCount": len(c.d.images), "Active": len(c.d.images), "Size": "0B", "Reclaimable": "0B"},
+		{"Type": "Containers", "TotalCount": len(c.d.containers), "Active": running, "Size": "0B", "Reclaimable": "0B"},
+		{"Type": "Local Volumes", "TotalCount": len(c.d.volumes), "Active": activeVolumes, "Size": "0B", "Reclaimable": "0B"},
+		{"Type": "Build Cache", "TotalCount": 0, "Active": 0, "Size": "0B", "Reclaimable": "0B"},
+	}
+
+	format := flags.get("format")
+	if format == "" {
+		format = "table {{.Type}}\t{{.TotalCount}}\t{{.Active}}\t{{.Size}}\t{{.Reclaimable}}"
+	}
+	return c.render(format, rows)
+}
+
+func (c *dockerCmd) systemInfo(args []string) int {
+	flags, ok := c.parse(args, "f|format")
+	if !ok {
+		return 1
+	}
+
+	info := map[string]any{
+		"ServerVersion":   FakeDockerVersion,
+		"Containers":      len(c.d.containers),
+		"Images":          len(c.d.images),
+		"OperatingSystem": "Synology DSM",
+		"Architecture":    "x86_64",
+		"Driver":          "btrfs",
+	}
+	if format := flags.get("format"); format != "" {
+		return c.render(format, []map[string]any{info})
+	}
+	for _, key := range sortedKeys(info) {
+		fmt.Fprintf(c.stdout, " %s: %v\n", key, info[key])
+	}
+	return 0
+}
+
+// confirmed reports whether a prune may go ahead, asking on stdin like docker
+func (c *dockerCmd) confirmed(flags *dockerFlags, warning string) bool {
+	if flags.has("f", "force") {
+		return true
+	}
+	fmt.Fprintf(c.stdout, "WARNING! This will remove:\n%sAre you sure you want to continue? [y/N] ", warning)
+	var answer string
+	fmt.Fscanln(c.stdin, &answer)
+	return answer == "y" || answer == "Y"
+}
+
+func (c *dockerCmd) systemPrune(args []string) int {
+	flags, ok := c.parse(args, "filter")
+	if !ok {
+		return 1
+	}
+	if !c.confirmed(flags, "  - all stopped containers\n  - all networks not used by at least one container\n") {
+		return 0
+	}
+
+	var kept []*FakeContainer
+	fmt.Fprintln(c.stdout, "Deleted Containers:")
+	for _, container := range c.d.containers {
+		if container.Status == "running" {
+			kept = append(kept, container)
+			continue
+		}
+		fmt.Fprintln(c.stdout, container.ID)
+	}
+	c.d.containers = kept
+
+	fmt.Fprintln(c.stdout, "\nDeleted Networks:")
+	c.pruneNetworks()
+
+	fmt.Fprintln(c.stdout, "\nDeleted Images:")
+	var images []*FakeImage
+	for _, image := range c.d.images {
+		unused := c.d.imageInUse(image) == nil
+		if unused && (image.Repository == "<none>" || flags.has("a", "all")) {
+			fmt.Fprintf(c.stdout, "deleted: sha256:%s\n", image.ID)
+			continue
+		}
+		images = append(images, image)
+	}
+	c.d.images = images
+
+	if flags.has("volumes") {
+		fmt.Fprintln(c.stdout, "\nDeleted Volumes:")
+		c.pruneVolumes(false)
+	}
+
+	fmt.Fprintln(c.stdout, "\nTotal reclaimed space: 0B")
+	return 0
+}
+
+func (c *dockerCmd) volume(args []string) int {
+	if len(args) == 0 {
+		return c.usage("volume")
+	}
+
+	switch args[0] {
+	case "ls", "list":
+		return c.volumeList(args[1:])
+	case "create":
+		return c.volumeCreate(args[1:])
+	case "rm", "remove":
+		return c.volumeRemove(args[1:])
+	case "inspect":
+		return c.volumeInspect(args[1:])
+	case "prune":
+		return c.volumePrune(args[1:])
+	}
+	fmt.Fprintf(c.stderr, "docker: 'volume %s' is not a docker command.\n", args[0])
+	return 1
+}
+
+func (c *dockerCmd) volumeList(args []string) int {
+	flags, ok := c.parse(args, "f|filter", "format")
+	if !ok {
+		return 1
+	}
+
+	var rows []map[string]any
+	for _, volume := range c.d.volumes {
+		rows = append(rows, map[string]any{"Driver": volume.Driver, "Name": volume.Name, "Scope": "local"})
+	}
+
+	format := flags.get("format")
+	switch {
+	case flags.has("q", "quiet"):
+		format = "{{.Name}}"
+	case format == "":
+		format = "table {{.Driver}}\t{{.Name}}"
+	}
+	return c.render(format, rows)
+}
+
+func (c *dockerCmd) volumeCreate(args []string) int {
+	flags, ok := c.parse(args, "d|driver", "label", "o|opt", "name")
+	if !ok {
+		return 1
+	}
+
+	name := flags.get("name")
+	if len(flags.args) > 0 {
+		name = flags.args[0]
+	}
+	anonymous := name == ""
+	if anonymous {
+		name = c.d.newID("volume", "")
+	}
+
+	// Creating an existing volume is not an error, as with docker
+	if c.d.findVolume(name) == nil {
+		driver := flags.get("driver")
+		if driver == "" {
+			driver = "local"
+		}
+		c.d.volumes = append(c.d.volumes, &FakeVolume{Name: name, Driver: driver, Labels: flags.all("label"), Anonymous: anonymous})
+	}
+	fmt.Fprintln(c.stdout, name)
+	return 0
+}
+
+func (c *dockerCmd) volumeRemove(args []string) int {
+	flags, ok := c.parse(args)
+	if !ok {
+		return 1
+	}
+	if len(flags.args) == 0 {
+		return c.usage("volume rm")
+	}
+
+	status := 0
+	for _, name := range flags.args {
+		volume := c.d.findVolume(name)
+		if volume == nil {
+			if !flags.has("f", "force") {
+				status = c.fail(1, "get %s: no such volume", name)
+			}
+			continue
+		}
+		if users := c.d.volumeUsers(volume); len(users) > 0 {
+			status = c.fail(1, "remove %s: volume is in use - [%s]", name, strings.Join(users, ", "))
+			continue
+		}
+		c.removeVolume(volume)
+		fmt.Fprintln(c.stdout, name)
+	}
+	return status
+}
+
+func (c *dockerCmd) removeVolume(volume *FakeVolume) {
+	for i, existing := range c.d.volumes {
+		if existing == volume {
+			c.d.volumes = append(c.d.volumes[:i], c.d.volumes[i+1:]...)
+			return
+		}
+	}
+}
+
+func (c *dockerCmd) volumeInspect(args []string) int {
+	flags, ok := c.parse(args, "f|format")
+	if !ok {
+		return 1
+	}
+	if len(flags.args) == 0 {
+		return c.usage("volume inspect")
+	}
+
+	var objects []map[string]any
+	for _, name := range flags.args {
+		volume := c.d.findVolume(name)
+		if volume == nil {
+			return c.fail(1, "get %s: no such volume", name)
+		}
+		objects = append(objects, volumeObject(volume))
+	}
+	return c.inspectObjects(flags.get("format"), objects)
+}
+
+func (c *dockerCmd) volumePrune(args []string) int {
+	flags, ok := c.parse(args, "filter")
+	if !ok {
+		return 1
+	}
+	if !c.confirmed(flags, "  - all anonymous local volumes not used by at least one container.\n") {
+		return 0
+	}
+
+	fmt.Fprintln(c.stdout, "Deleted Volumes:")
+	c.pruneVolumes(flags.has("a", "all"))
+	fmt.Fprintln(c.stdout, "\nTotal reclaimed space: 0B")
+	return 0
+}
+
+// pruneVolumes removes unused volumes; named ones only when all is set
+func (c *dockerCmd) pruneVolumes(all bool) {
+	var kept []*FakeVolume
+	for _, volume := range c.d.volumes {
+		if len(c.d.volumeUsers(volume)) == 0 && (volume.Anonymous || all) {
+			fmt.Fprintln(c.stdout, volume.Name)
+			continue
+		}
+		kept = append(kept, volume)
+	}
+	c.d.volumes = kept
+}
+
+func (c *dockerCmd) network(args []string) int {
+	if len(args) == 0 {
+		return c.usage("network")
+	}
+
+	switch args[0] {
+	case "ls", "list":
+		return c.networkList(args[1:])
+	case "create":
+		return c.networkCreate(args[1:])
+	case "rm", "remove":
+		return c.networkRemove(args[1:])
+	case "inspect":
+		return c.networkInspect(args[1:])
+	case "connect":
+		return c.networkConnect(args[1:])
+	case "disconnect":
+		return c.networkDisconnect(args[1:])
+	case "prune":
+		return c.networkPrune(args[1:])
+	}
+	fmt.Fprintf(c.stderr, "docker: 'network %s' is not a docker command.\n", args[0])
+	return 1
+}
+
+func (c *dockerCmd) networkList(args []string) int {
+	flags, ok := c.parse(args, "f|filter", "format")
+	if !ok {
+		return 1
+	}
+
+	var rows []map[string]any
+	for _, network := range c.d.networks {
+		id := shortID(network.ID)
+		if flags.has("no-trunc") {
+			id = network.ID
+		}
+		rows = append(rows, map[string]any{"ID": id, "Name": network.Name, "Driver": network.Driver, "Scope": "local"})
+	}
+
+	format := flags.get("format")
+	switch {
+	case flags.has("q", "quiet"):
+		format = "{{.ID}}"
+	case format == "":
+		format = "table {{.ID}}\t{{.Name}}\t{{.Driver}}\t{{.Scope}}"
+	}
+	return c.render(format, rows)
+}
+
+func (c *dockerCmd) networkCreate(args []string) int {
+	flags, ok := c.parse(args, "d|driver", "o|opt", "gateway", "ip-range", "ipam-driver", "subnet", "label")
+	if !ok {
+		return 1
+	}
+	if len(flags.args) != 1 {
+		return c.usage("network create")
+	}
+
+	name := flags.args[0]
+	if c.d.findNetwork(name) != nil {
+		return c.fail(1, "network with name %s already exists", name)
+	}
+
+	driver := flags.get("driver")
+	if driver == "" {
+		driver = "bridge"
+	}
+	network := &FakeNetwork{
+		ID:       c.d.newID("network", name),
+		Name:     name,
+		Driver:   driver,
+		Labels:   flags.all("label"),
+		Internal: flags.has("internal"),
+	}
+	c.d.networks = append(c.d.networks, network)
+	fmt.Fprintln(c.stdout, network.ID)
+	return 0
+}
+
+func (c *dockerCmd) networkRemove(args []string) int {
+	flags, ok := c.parse(args)
+	if !ok {
+		return 1
+	}
+	if len(flags.args) == 0 {
+		return c.usage("network rm")
+	}
+
+	status := 0
+	for _, name := range flags.args {
+		network := c.d.findNetwork(name)
+		switch {
+		case network == nil:
+			if !flags.has("f", "force") {
+				status = c.fail(1, "network %s not found", name)
+			}
+		case network.builtin:
+			status = c.fail(1, "%s is a pre-defined network and cannot be removed", name)
+		case len(c.d.networkUsers(network)) > 0:
+			status = c.fail(1, "error while removing network: network %s id %s has active endpoints", name, network.ID)
+		default:
+			c.removeNetwork(network)
+			fmt.Fprintln(c.stdout, name)
+		}
+	}
+	return status
+}
+
+func (c *dockerCmd) removeNetwork(network *FakeNetwork) {
+	for i, existing := range c.d.networks {
+		if existing == network {
+			c.d.networks = append(c.d.networks[:i], c.d.networks[i+1:]...)
+			return
+		}
+	}
+}
+
+func (c *dockerCmd) networkInspect(args []string) int {
+	flags, ok := c.parse(args, "f|format")
+	if !ok {
+		return 1
+	}
+	if len(flags.args) == 0 {
+		return c.usage("network inspect")
+	}
+
+	var objects []map[string]any
+	for _, name := range flags.args {
+		network := c.d.findNetwork(name)
+		if network == nil {
+			return c.fail(1, "network %s not found", name)
+		}
+		objects = append(objects, c.d.networkObject(network))
+	}
+	return c.inspectObjects(flags.get("format"), objects)
+}
+
+func (c *dockerCmd) networkConnect(args []string) int {
+	flags, ok := c.parse(args, "alias", "ip", "ip6", "link-local", "driver-opt")
+	if !ok {
+		return 1
+	}
+	if len(flags.args) != 2 {
+		return c.usage("network connect")
+	}
+
+	network := c.d.findNetwork(flags.args[0])
+	if network == nil {
+		return c.fail(1, "network %s not found", flags.args[0])
+	}
+	container := c.d.findContainer(flags.args[1])
+	if container == nil {
+		return c.fail(1, "No such container: %s", flags.args[1])
+	}
+	for _, name := range container.Networks {
+		if name == network.Name {
+			return c.fail(1, "endpoint with name %s already exists in network %s", container.Name, network.Name)
+		}
+	}
+
+	container.Networks = append(container.Networks, network.Name)
+	return 0
+}
+
+func (c *dockerCmd) networkDisconnect(args []string) int {
+	flags, ok := c.parse(args)
+	if !ok {
+		return 1
+	}
+	if len(flags.args) != 2 {
+		return c.usage("network disconnect")
+	}
+
+	network := c.d.findNetwork(flags.args[0])
+	if network == nil {
+		return c.fail(1, "network %s not found", flags.args[0])
+	}
+	container := c.d.findContainer(flags.args[1])
+	if container == nil {
+		return c.fail(1, "No such container: %s", flags.args[1])
+	}
+
+	for i, name := range container.Networks {
+		if name == network.Name {
+			container.Networks = append(container.Networks[:i], container.Networks[i+1:]...)
+			return 0
+		}
+	}
+	return c.fail(1, "container %s is not connected to network %s", container.ID, network.Name)
+}
+
+func (c *dockerCmd) networkPrune(args []string) int {
+	flags, ok := c.parse(args, "filter")
+	if !ok {
+		return 1
+	}
+	if !c.confirmed(flags, "  - all custom networks not used by at least one container\n") {
+		return 0
+	}
+
+	fmt.Fprintln(c.stdout, "Deleted Networks:")
+	c.pruneNetworks()
+	return 0
+}
+
+// pruneNetworks removes custom networks without containers
+func (c *dockerCmd) pruneNetworks() {
+	var kept []*FakeNetwork
+	for _, network := range c.d.networks {
+		if !network.builtin && len(c.d.networkUsers(network)) == 0 {
+			fmt.Fprintln(c.stdout, network.Name)
+			continue
+		}
+		kept = append(kept, network)
+	}
+	c.d.networks = kept
+}
@@ -0,0 +1,361 @@
+package synologytest
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// runDocker runs args against d and returns stdout, stderr and the exit status
+func runDocker(d *FakeDocker, args ...string) (string, string, int) {
+	var stdout, stderr bytes.Buffer
+	status := d.Run(args, nil, &stdout, &stderr)
+	return stdout.String(), stderr.String(), status
+}
+
+func TestParseDockerFlags(t *testing.T) {
+	flags, err := parseDockerFlags(
+		[]string{"-it", "--name=web", "-e", "A=1", "--env", "B=2", "--rm", "nginx", "-e", "ignored"},
+		"name", "e|env",
+	)
+	if err != nil {
+		t.Fatalf("parseDockerFlags failed: %v", err)
+	}
+
+	if !flags.has("i") || !flags.has("t") || !flags.has("rm") {
+		t.Errorf("Expected boolean flags i, t and rm, got %v", flags.values)
+	}
+	if flags.get("name") != "web" {
+		t.Errorf("Expected name web, got %q", flags.get("name"))
+	}
+	if env := strings.Join(flags.all("env"), ","); env != "A=1,B=2" {
+		t.Errorf("Expected env A=1,B=2, got %q", env)
+	}
+	if args := strings.Join(flags.args, " "); args != "nginx -e ignored" {
+		t.Errorf("Expected flags to stop at the image, got %q", args)
+	}
+
+	if _, err := parseDockerFlags([]string{"--name"}, "name"); err == nil {
+		t.Error("Expected error for a missing flag value")
+	}
+}
+
+func TestFakeDockerContainerLifecycle(t *testing.T) {
+	d := NewFakeDocker()
+
+	id, _, status := runDocker(d, "run", "-d", "--name", "web", "-p", "8080:80", "nginx:alpine")
+	if status != 0 || len(strings.TrimSpace(id)) != 64 {
+		t.Fatalf("Expected a container ID, got %q (status %d)", id, status)
+	}
+
+	_, stderr, status := runDocker(d, "run", "-d", "--name", "web", "nginx:alpine")
+	if status != 125 || !strings.Contains(stderr, "is already in use") {
+		t.Errorf("Expected name conflict, got %q (status %d)", stderr, status)
+	}
+
+	output, _, _ := runDocker(d, "ps", "--format", "{{.Names}} {{.Status}} {{.Ports}}")
+	if output != "web Up 8080:80\n" {
+		t.Errorf("Unexpected ps output: %q", output)
+	}
+
+	_, stderr, status = runDocker(d, "rm", "web")
+	if status != 1 || !strings.Contains(stderr, "cannot remove a running container") {
+		t.Errorf("Expected running container removal to fail, got %q", stderr)
+	}
+
+	runDocker(d, "stop", "web")
+	if output, _, _ := runDocker(d, "ps", "-q"); output != "" {
+		t.Errorf("Expected no running containers, got %q", output)
+	}
+	if output, _, _ := runDocker(d, "ps", "-a", "--format", "{{.Names}} {{.Status}}"); output != "web Exited\n" {
+		t.Errorf("Expected stopped container with -a, got %q", output)
+	}
+
+	if _, _, status := runDocker(d, "rm", "web"); status != 0 {
+		t.Errorf("Expected stopped container to be removed, got status %d", status)
+	}
+	if len(d.Containers()) != 0 {
+		t.Errorf("Expected no containers, got %+v", d.Containers())
+	}
+}
+
+func TestFakeDockerLabels(t *testing.T) {
+	d := NewFakeDocker()
+	runDocker(d, "run", "-d", "--name", "web", "--label", "project=site", "--label", "tier=front", "nginx:alpine")
+	runDocker(d, "run", "-d", "--name", "db", "--label", "project=site", "postgres:16")
+	runDocker(d, "run", "-d", "--name", "other", "nginx:alpine")
+
+	tests := []struct {
+		filter string
+		want   string
+	}{
+		{"label=project", "db\nweb\n"},
+		{"label=project=site", "db\nweb\n"},
+		{"label=tier=back", ""},
+		{"name=we", "web\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			output, _, _ := runDocker(d, "ps", "-a", "--filter", tt.filter, "--format", "{{.Names}}")
+			if output != tt.want {
+				t.Errorf("ps --filter %s = %q, want %q", tt.filter, output, tt.want)
+			}
+		})
+	}
+
+	output, _, _ := runDocker(d, "inspect", "--format", "{{json .Config.Labels}}", "web")
+	if output != `{"project":"site","tier":"front"}`+"\n" {
+		t.Errorf("Unexpected labels %q", output)
+	}
+}
+
+func TestFakeDockerStopped(t *testing.T) {
+	d := NewFakeDocker()
+	d.SetRunning(false)
+	if _, stderr, status := runDocker(d, "ps"); status != 1 || !strings.Contains(stderr, "Is the docker daemon running?") {
+		t.Errorf("Expected a daemon error, got %q (status %d)", stderr, status)
+	}
+	d.SetRunning(true)
+	if _, _, status := runDocker(d, "ps"); status != 0 {
+		t.Errorf("Expected ps to work again, got status %d", status)
+	}
+}
+
+func TestFakeDockerExecAndLogs(t *testing.T) {
+	d := NewFakeDocker()
+	runDocker(d, "run", "--name", "hello", "alpine", "echo", "hello world")
+	runDocker(d, "run", "-d", "--name", "app", "-e", "MODE=test", "alpine", "sleep", "3600")
+
+	if output, _, _ := runDocker(d, "logs", "hello"); output != "hello world\n" {
+		t.Errorf("Expected echo output in logs, got %q", output)
+	}
+	if container, _ := d.Container("hello"); container.Status != "exited" {
+		t.Errorf("Expected echo container to exit, got %s", container.Status)
+	}
+
+	output, _, status := runDocker(d, "exec", "app", "env")
+	if status != 0 || !strings.Contains(output, "MODE=test") {
+		t.Errorf("Expected container env, got %q (status %d)", output, status)
+	}
+	if _, _, status := runDocker(d, "exec", "app", "sh", "-c", "exit 3"); status != 3 {
+		t.Errorf("Expected exit status 3, got %d", status)
+	}
+	if _, _, status := runDocker(d, "exec", "app", "nonexistent-binary"); status != 126 {
+		t.Errorf("Expected exit status 126, got %d", status)
+	}
+	if _, stderr, _ := runDocker(d, "exec", "hello", "true"); !strings.Contains(stderr, "is not running") {
+		t.Errorf("Expected exec in stopped container to fail, got %q", stderr)
+	}
+}
+
+func TestFakeDockerImages(t *testing.T) {
+	d := NewFakeDocker()
+
+	if _, _, status := runDocker(d, "pull", "nginx"); status != 0 {
+		t.Fatalf("Expected pull to succeed, got status %d", status)
+	}
+	if _, stderr, status := runDocker(d, "pull", MissingImage); status != 1 || !strings.Contains(stderr, "manifest unknown") {
+		t.Errorf("Expected missing image pull to fail, got %q", stderr)
+	}
+
+	output, _, _ := runDocker(d, "images", "--format", "{{.Repository}}:{{.Tag}}")
+	if output != "nginx:latest\n" {
+		t.Errorf("Unexpected images: %q", output)
+	}
+
+	runDocker(d, "run", "-d", "--name", "web", "nginx")
+	if _, stderr, status := runDocker(d, "rmi", "nginx"); status != 1 || !strings.Contains(stderr, "conflict") {
+		t.Errorf("Expected image in use to conflict, got %q", stderr)
+	}
+	if _, _, status := runDocker(d, "rmi", "-f", "nginx:latest"); status != 0 {
+		t.Errorf("Expected forced removal to succeed, got status %d", status)
+	}
+}
+
+func TestFakeDockerVolumesAndNetworks(t *testing.T) {
+	d := NewFakeDocker()
+
+	runDocker(d, "volume", "create", "data")
+	runDocker(d, "network", "create", "backend")
+	runDocker(d, "run", "-d", "--name", "db", "--network", "backend", "-v", "data:/var/lib/data", "postgres")
+
+	if _, stderr, _ := runDocker(d, "volume", "rm", "data"); !strings.Contains(stderr, "volume is in use") {
+		t.Errorf("Expected volume in use, got %q", stderr)
+	}
+	if _, stderr, _ := runDocker(d, "network", "rm", "backend"); !strings.Contains(stderr, "active endpoints") {
+		t.Errorf("Expected network with endpoints, got %q", stderr)
+	}
+	if _, stderr, _ := runDocker(d, "network", "rm", "bridge"); !strings.Contains(stderr, "pre-defined") {
+		t.Errorf("Expected predefined network, got %q", stderr)
+	}
+
+	output, _, _ := runDocker(d, "inspect", "--format", "{{.State.Status}} {{.HostConfig.RestartPolicy.Name}}", "db")
+	if output != "running \n" {
+		t.Errorf("Unexpected inspect output: %q", output)
+	}
+
+	runDocker(d, "rm", "-f", "db")
+	runDocker(d, "volume", "prune", "--force", "--all")
+	runDocker(d, "network", "prune", "--force")
+	if len(d.Volumes()) != 0 {
+		t.Errorf("Expected volumes pruned, got %+v", d.Volumes())
+	}
+	if len(d.Networks()) != 3 {
+		t.Errorf("Expected only predefined networks, got %+v", d.Networks())
+	}
+}
+
+func TestFakeDockerTableFormat(t *testing.T) {
+	d := NewFakeDocker()
+	runDocker(d, "volume", "create", "data")
+
+	output, _, _ := runDocker(d, "volume", "ls", "--format", "table {{.Driver}}\t{{.Name}}")
+	if output != "DRIVER\tNAME\nlocal\tdata\n" {
+		t.Errorf("Unexpected table output: %q", output)
+	}
+}
+
+func TestFakeDockerCopy(t *testing.T) {
+	d := NewFakeDocker()
+	d.HostRoot = t.TempDir()
+	runDocker(d, "run", "-d", "--name", "web", "nginx")
+
+	if err := os.MkdirAll(hostPath(d.HostRoot, "/volume1/site/css"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(hostPath(d.HostRoot, "/volume1/site/index.html"), []byte("<h1>hi</h1>"), 0644)
+	os.WriteFile(hostPath(d.HostRoot, "/volume1/site/css/app.css"), []byte("body {}"), 0644)
+
+	if _, stderr, status := runDocker(d, "cp", "/volume1/site", "web:/usr/share/nginx/html"); status != 0 {
+		t.Fatalf("cp into container failed: %s", stderr)
+	}
+	// An existing directory in the container receives the source by name
+	if _, stderr, status := runDocker(d, "cp", "/volume1/site/index.html", "web:/usr/share/nginx/html"); status != 0 {
+		t.Fatalf("cp of a file failed: %s", stderr)
+	}
+
+	container, _ := d.Container("web")
+	for _, name := range []string{"/usr/share/nginx/html/index.html", "/usr/share/nginx/html/css/app.css"} {
+		if _, ok := container.Files[name]; !ok {
+			t.Errorf("Expected %s in container, got %v", name, sortedKeys(container.Files))
+		}
+	}
+	if stdout, _, _ := runDocker(d, "exec", "web", "cat", "/usr/share/nginx/html/css/app.css"); stdout != "body {}" {
+		t.Errorf("Expected exec cat to read the copied file, got %q", stdout)
+	}
+
+	os.MkdirAll(hostPath(d.HostRoot, "/volume1/backup"), 0755)
+	if _, stderr, status := runDocker(d, "cp", "web:/usr/share/nginx/html", "/volume1/backup"); status != 0 {
+		t.Fatalf("cp from container failed: %s", stderr)
+	}
+	data, err := os.ReadFile(hostPath(d.HostRoot, "/volume1/backup/html/css/app.css"))
+	if err != nil || string(data) != "body {}" {
+		t.Errorf("Expected copied file on the NAS, got %q, %v", data, err)
+	}
+
+	if _, stderr, status := runDocker(d, "cp", "web:/missing", "/volume1/backup"); status != 1 || !strings.Contains(stderr, "Could not find the file /missing") {
+		t.Errorf("Expected missing file error, got %d %q", status, stderr)
+	}
+}
+
+func TestFakeDockerSaveLoad(t *testing.T) {
+	source := NewFakeDocker()
+	source.AddImage("nginx:alpine")
+
+	archive, stderr, status := runDocker(source, "save", "nginx")
+	if status != 1 || !strings.Contains(stderr, "No such image: nginx") {
+		t.Errorf("Expected missing image error, got %d %q", status, stderr)
+	}
+	archive, stderr, status = runDocker(source, "save", "nginx:alpine")
+	if status != 0 {
+		t.Fatalf("save failed: %s", stderr)
+	}
+
+	target := NewFakeDocker()
+	var stdout, errOut bytes.Buffer
+	if status := target.Run([]string{"load"}, strings.NewReader(archive), &stdout, &errOut); status != 0 {
+		t.Fatalf("load failed: %s", errOut.String())
+	}
+	if stdout.String() != "Loaded image: nginx:alpine\n" || len(target.Images()) != 1 {
+		t.Errorf("Unexpected load result %q, %+v", stdout.String(), target.Images())
+	}
+
+	if status := target.Run([]string{"load"}, strings.NewReader("not a tar"), &stdout, &errOut); status != 1 {
+		t.Errorf("Expected load of garbage to fail, got %d", status)
+	}
+}
+
+func TestFakeDockerLoadLayers(t *testing.T) {
+	source := NewFakeDocker()
+	source.AddImageLayers("my-app:1.0", "sha256:base", "sha256:app")
+	archive, stderr, status := runDocker(source, "save", "my-app:1.0")
+	if status != 0 {
+		t.Fatalf("save failed: %s", stderr)
+	}
+
+	// The same archive without the base layer's file
+	var trimmed bytes.Buffer
+	tr := tar.NewReader(strings.NewReader(archive))
+	tw := tar.NewWriter(&trimmed)
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			break
+		}
+		if header.Name == "base/layer.tar" {
+			continue
+		}
+		data, _ := io.ReadAll(tr)
+		tw.WriteHeader(header)
+		tw.Write(data)
+	}
+	tw.Close()
+
+	target := NewFakeDocker()
+	var stdout, errOut bytes.Buffer
+	if status := target.Run([]string{"load"}, bytes.NewReader(trimmed.Bytes()), &stdout, &errOut); status != 1 || !strings.Contains(errOut.String(), "base/layer.tar: no such file or directory") {
+		t.Errorf("Expected missing layer error, got %d %q", status, errOut.String())
+	}
+
+	target.AddImageLayers("debian:12", "sha256:base")
+	errOut.Reset()
+	if status := target.Run([]string{"load"}, bytes.NewReader(trimmed.Bytes()), &stdout, &errOut); status != 0 {
+		t.Fatalf("load with the base layer present failed: %s", errOut.String())
+	}
+	if stdout, _, _ := runDocker(target, "image", "inspect", "--format", "{{json .RootFS.Layers}}", "my-app:1.0"); stdout != "[\"sha256:base\",\"sha256:app\"]\n" {
+		t.Errorf("Unexpected loaded layers %q", stdout)
+	}
+}
+
+func TestFakeDockerBuild(t *testing.T) {
+	var context bytes.Buffer
+	tw := tar.NewWriter(&context)
+	for name, data := range map[string]string{"Dockerfile": "FROM alpine\nARG VERSION\nCOPY app /app\n", "app/main.sh": "echo hi"} {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data))})
+		tw.Write([]byte(data))
+	}
+	tw.Close()
+
+	d := NewFakeDocker()
+	var stdout, stderr bytes.Buffer
+	status := d.Run([]string{"build", "-t", "my-app", "--build-arg", "VERSION=1", "--build-arg", "OTHER=2", "-"}, bytes.NewReader(context.Bytes()), &stdout, &stderr)
+	if status != 0 {
+		t.Fatalf("build failed: %s", stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Step 3/3 : COPY app /app") || !strings.Contains(stdout.String(), "Successfully tagged my-app:latest") {
+		t.Errorf("Unexpected build output %q", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "build-args [OTHER] were not consumed") {
+		t.Errorf("Expected a warning for the unused build arg, got %q", stderr.String())
+	}
+
+	stderr.Reset()
+	status = d.Run([]string{"build", "-f", "Missing.Dockerfile", "-"}, bytes.NewReader(context.Bytes()), &stdout, &stderr)
+	if status != 1 || !strings.Contains(stderr.String(), "Cannot locate specified Dockerfile: Missing.Dockerfile") {
+		t.Errorf("Expected missing Dockerfile error, got %d %q", status, stderr.String())
+	}
+}
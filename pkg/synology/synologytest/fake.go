@@ -0,0 +1,284 @@
+// Package synologytest provides a scriptable in-memory NAS for testing code
+// that runs commands through a synology.Executor.
+package synologytest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/scttfrdmn/syno-docker/pkg/synology"
+)
+
+// Response is the canned result of a command
+type Response struct {
+	Stdout     string
+	Stderr     string
+	ExitStatus int
+	// Err is returned as is instead of an exit status, e.g. to simulate a
+	// dropped connection with synology.ErrConnectionLost
+	Err error
+}
+
+// Rule answers every command that starts with its prefix
+type Rule struct {
+	prefix    []string
+	responses []Response
+	calls     int
+}
+
+// Return answers with stdout and exit status 0
+func (r *Rule) Return(stdout string) *Rule {
+	return r.Respond(Response{Stdout: stdout})
+}
+
+// Fail answers with stderr and a non-zero exit status
+func (r *Rule) Fail(exitStatus int, stderr string) *Rule {
+	return r.Respond(Response{Stderr: stderr, ExitStatus: exitStatus})
+}
+
+// Respond queues resp. Responses are used in order and the last one repeats.
+func (r *Rule) Respond(resp Response) *Rule {
+	r.responses = append(r.responses, resp)
+	return r
+}
+
+// next returns the response for the next matching call
+func (r *Rule) next() Response {
+	if len(r.responses) == 0 {
+		return Response{}
+	}
+	resp := r.responses[min(r.calls, len(r.responses)-1)]
+	r.calls++
+	return resp
+}
+
+// Call is a command run against the fake
+type Call struct {
+	// Command is the command line as sent to the remote shell
+	Command string
+	// Args are the words of Command, without the docker binary for docker commands
+	Args   []string
+	Docker bool
+	// Stdin is what was written to the command's standard input
+	Stdin string
+}
+
+// FakeNAS is a synology.Executor that answers commands from scripted rules
+// and records every call. Commands that match no rule fail with exit status
+// 127. It is safe for concurrent use.
+type FakeNAS struct {
+	mu        sync.Mutex
+	rules     []*Rule
+	calls     []Call
+	connected bool
+	closed    bool
+}
+
+var _ synology.Executor = (*FakeNAS)(nil)
+
+// New returns a FakeNAS with no rules
+func New() *FakeNAS {
+	return &FakeNAS{}
+}
+
+// On adds a rule for commands whose words start with prefix. When several
+// rules match, the one with the longest prefix wins, then the latest added.
+func (f *FakeNAS) On(prefix ...string) *Rule {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	rule := &Rule{prefix: prefix}
+	f.rules = append(f.rules, rule)
+	return rule
+}
+
+// OnDocker adds a rule for docker commands whose arguments start with prefix
+func (f *FakeNAS) OnDocker(prefix ...string) *Rule {
+	return f.On(append([]string{synology.DockerBinary}, prefix...)...)
+}
+
+// Calls returns every command run so far, in order
+func (f *FakeNAS) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// DockerCalls returns the arguments of every docker command run so far
+func (f *FakeNAS) DockerCalls() [][]string {
+	var calls [][]string
+	for _, call := range f.Calls() {
+		if call.Docker {
+			calls = append(calls, call.Args)
+		}
+	}
+	return calls
+}
+
+// ConnectContext marks the fake as connected
+func (f *FakeNAS) ConnectContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.connected = true
+	return nil
+}
+
+// Close marks the fake as closed
+func (f *FakeNAS) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+// Connected reports whether ConnectContext was called
+func (f *FakeNAS) Connected() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.connected
+}
+
+// Closed reports whether Close was called
+func (f *FakeNAS) Closed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.closed
+}
+
+// ExecuteCommand runs cmd and returns its stdout
+func (f *FakeNAS) ExecuteCommand(cmd string) (string, error) {
+	return f.ExecuteCommandContext(context.Background(), cmd)
+}
+
+// ExecuteCommandContext runs cmd and returns its stdout
+func (f *FakeNAS) ExecuteCommandContext(ctx context.Context, cmd string) (string, error) {
+	resp, err := f.run(ctx, cmd, "")
+	return resp.Stdout, err
+}
+
+// ExecuteDockerCommand runs a docker command and returns its stdout
+func (f *FakeNAS) ExecuteDockerCommand(args []string) (string, error) {
+	return f.ExecuteDockerCommandContext(context.Background(), args)
+}
+
+// ExecuteDockerCommandContext runs a docker command and returns its stdout
+func (f *FakeNAS) ExecuteDockerCommandContext(ctx context.Context, args []string) (string, error) {
+	return f.ExecuteCommandContext(ctx, synology.DockerCommand(args))
+}
+
+// StreamCommand runs cmd and writes its output to stdout and stderr
+func (f *FakeNAS) StreamCommand(cmd string, stdout, stderr io.Writer) error {
+	return f.StreamCommandContext(context.Background(), cmd, stdout, stderr)
+}
+
+// StreamCommandContext runs cmd and writes its output to stdout and stderr
+func (f *FakeNAS) StreamCommandContext(ctx context.Context, cmd string, stdout, stderr io.Writer) error {
+	return f.RunSessionContext(ctx, cmd, &synology.SessionOptions{Stdout: stdout, Stderr: stderr})
+}
+
+// RunSessionContext runs cmd, recording its stdin and writing its output to
+// the session streams. Terminal options are ignored.
+func (f *FakeNAS) RunSessionContext(ctx context.Context, cmd string, opts *synology.SessionOptions) error {
+	var stdin string
+	if opts.Stdin != nil {
+		data, err := io.ReadAll(opts.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read stdin: %w", err)
+		}
+		stdin = string(data)
+	}
+
+	resp, err := f.run(ctx, cmd, stdin)
+	if opts.Stdout != nil {
+		io.WriteString(opts.Stdout, resp.Stdout)
+	}
+	if opts.Stderr != nil {
+		io.WriteString(opts.Stderr, resp.Stderr)
+	}
+	return err
+}
+
+// run records cmd and returns the scripted response and the error a real
+// connection would return for it
+func (f *FakeNAS) run(ctx context.Context, cmd, stdin string) (Response, error) {
+	if err := ctx.Err(); err != nil {
+		return Response{}, err
+	}
+
+	words, err := synology.ShellSplit(cmd)
+	if err != nil {
+		return Response{}, fmt.Errorf("command failed: %w", err)
+	}
+
+	f.mu.Lock()
+	call := Call{Command: cmd, Args: words, Stdin: stdin}
+	if len(words) > 0 && words[0] == synology.DockerBinary {
+		call.Docker = true
+		call.Args = words[1:]
+	}
+	f.calls = append(f.calls, call)
+
+	var resp Response
+	if rule := f.match(words); rule != nil {
+		resp = rule.next()
+	} else {
+		resp = Response{ExitStatus: 127, Stderr: fmt.Sprintf("synologytest: no response scripted for %q", cmd)}
+	}
+	f.mu.Unlock()
+
+	if resp.Err != nil {
+		return resp, resp.Err
+	}
+	if resp.ExitStatus != 0 {
+		return resp, &synology.RemoteCommandError{
+			Command:    cmd,
+			ExitStatus: resp.ExitStatus,
+			Stdout:     resp.Stdout,
+			Stderr:     resp.Stderr,
+		}
+	}
+	return resp, nil
+}
+
+// match returns the rule with the longest prefix of words, preferring the
+// latest added. The caller must hold f.mu.
+func (f *FakeNAS) match(words []string) *Rule {
+	var best *Rule
+	for _, rule := range f.rules {
+		if !hasPrefix(words, rule.prefix) {
+			continue
+		}
+		if best == nil || len(rule.prefix) >= len(best.prefix) {
+			best = rule
+		}
+	}
+	return best
+}
+
+func hasPrefix(words, prefix []string) bool {
+	if len(prefix) > len(words) {
+		return false
+	}
+	for i, word := range prefix {
+		if words[i] != word {
+			return false
+		}
+	}
+	return true
+}
+
+// String lists the recorded commands, one per line, for test failure messages
+func (f *FakeNAS) String() string {
+	var b strings.Builder
+	for _, call := range f.Calls() {
+		b.WriteString(call.Command)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
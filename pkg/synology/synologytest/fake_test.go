@@ -0,0 +1,110 @@
+package synologytest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/scttfrdmn/syno-docker/pkg/synology"
+)
+
+func TestFakeNASRules(t *testing.T) {
+	fake := New()
+	fake.OnDocker("ps").Return("generic\n")
+	fake.OnDocker("ps", "-a").Return("all\n")
+	fake.OnDocker("rm").Fail(1, "Error response from daemon: No such container: web")
+
+	output, err := fake.ExecuteDockerCommand([]string{"ps", "-a"})
+	if err != nil || output != "all\n" {
+		t.Errorf("Expected longest prefix to win, got %q, %v", output, err)
+	}
+
+	output, err = fake.ExecuteDockerCommand([]string{"ps", "--format", "{{.ID}}"})
+	if err != nil || output != "generic\n" {
+		t.Errorf("Expected prefix match, got %q, %v", output, err)
+	}
+
+	_, err = fake.ExecuteDockerCommand([]string{"rm", "web"})
+	var remoteErr *synology.RemoteCommandError
+	if !errors.As(err, &remoteErr) || remoteErr.ExitStatus != 1 {
+		t.Fatalf("Expected *RemoteCommandError with status 1, got %v", err)
+	}
+	if !errors.Is(err, synology.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	if _, err := fake.ExecuteCommand("uname -a"); !errors.As(err, &remoteErr) || remoteErr.ExitStatus != 127 {
+		t.Errorf("Expected unscripted command to fail with 127, got %v", err)
+	}
+}
+
+func TestFakeNASResponseSequence(t *testing.T) {
+	fake := New()
+	fake.On("cat", "/etc/VERSION").
+		Respond(Response{Err: synology.ErrConnectionLost}).
+		Return("majorversion=\"7\"\n")
+
+	if _, err := fake.ExecuteCommand("cat /etc/VERSION"); !errors.Is(err, synology.ErrConnectionLost) {
+		t.Errorf("Expected first response error, got %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if output, err := fake.ExecuteCommand("cat /etc/VERSION"); err != nil || !strings.Contains(output, "7") {
+			t.Errorf("Expected last response to repeat, got %q, %v", output, err)
+		}
+	}
+}
+
+func TestFakeNASRecordsCalls(t *testing.T) {
+	fake := New()
+	fake.OnDocker().Return("")
+
+	fake.ExecuteDockerCommand([]string{"run", "-e", "GREETING=hello world", "nginx"})
+	err := fake.RunSessionContext(context.Background(), synology.DockerCommand([]string{"load"}), &synology.SessionOptions{
+		Stdin: strings.NewReader("image data"),
+	})
+	if err != nil {
+		t.Fatalf("RunSessionContext failed: %v", err)
+	}
+
+	calls := fake.DockerCalls()
+	if len(calls) != 2 {
+		t.Fatalf("Expected 2 docker calls, got %d:\n%s", len(calls), fake)
+	}
+	if strings.Join(calls[0], "|") != "run|-e|GREETING=hello world|nginx" {
+		t.Errorf("Expected unquoted arguments, got %q", calls[0])
+	}
+	if stdin := fake.Calls()[1].Stdin; stdin != "image data" {
+		t.Errorf("Expected recorded stdin, got %q", stdin)
+	}
+}
+
+func TestFakeNASStreams(t *testing.T) {
+	fake := New()
+	fake.OnDocker("logs").Respond(Response{Stdout: "out\n", Stderr: "err\n"})
+
+	var stdout, stderr bytes.Buffer
+	if err := fake.StreamCommand(synology.DockerCommand([]string{"logs", "web"}), &stdout, &stderr); err != nil {
+		t.Fatalf("StreamCommand failed: %v", err)
+	}
+	if stdout.String() != "out\n" || stderr.String() != "err\n" {
+		t.Errorf("Unexpected streams: %q, %q", stdout.String(), stderr.String())
+	}
+}
+
+func TestFakeNASCancelled(t *testing.T) {
+	fake := New()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := fake.ExecuteCommandContext(ctx, "true"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if err := fake.ConnectContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if len(fake.Calls()) != 0 {
+		t.Errorf("Expected no recorded calls, got %d", len(fake.Calls()))
+	}
+}
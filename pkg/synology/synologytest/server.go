@@ -0,0 +1,607 @@
+package synologytest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/scttfrdmn/syno-docker/pkg/config"
+	"github.com/scttfrdmn/syno-docker/pkg/synology"
+)
+
+const (
+	// FakeDSMVersion is the synology.VersionFile of SSHServer
+	FakeDSMVersion = `majorversion="7"
+minorversion="2"
+major="7"
+minor="2"
+micro="1"
+productversion="7.2.1"
+buildphase="GM"
+buildnumber="69057"
+smallfixnumber="5"
+builddate="2024/04/17"
+`
+	// FakeModel is the model SSHServer reports
+	FakeModel = "DS920+"
+)
+
+// checksumNameEscaper escapes names in sha256sum output as coreutils does
+var checksumNameEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`)
+
+// SSHServer is an in-process SSH server that stands in for a Synology NAS.
+// It runs exec requests through a small shell emulation that handles common
+// utilities and routes the docker binary to a FakeDocker. The NAS
+// filesystem is a temporary directory, served over SFTP and shared with the
+// shell and docker cp.
+type SSHServer struct {
+	// Docker receives every docker invocation
+	Docker *FakeDocker
+	// User is the only login accepted
+	User string
+	// Arch is what uname -m prints. The DSM release and model are read from
+	// synology.VersionFile and synology.HardwareVersionFile, which tests
+	// can overwrite under Path.
+	Arch string
+	// Password enables password authentication when set before connecting
+	Password string
+	// Systemctl, when set, runs for systemctl commands with their
+	// arguments and returns the exit status; by default they succeed
+	Systemctl func(args []string) int
+	// Forward, when set before connecting, connects tunnels that clients
+	// open to network ("tcp" or "unix") and addr as seen from the NAS. When
+	// nil, tunnels are refused as by sshd with forwarding disabled, so the
+	// Docker socket is unavailable and clients use the docker CLI.
+	Forward func(network, addr string) (net.Conn, error)
+
+	listener   net.Listener
+	hostKey    ssh.Signer
+	clientKey  ssh.PublicKey
+	keyPath    string
+	root       string
+	mu         sync.Mutex
+	commands   []string
+	conns      []net.Conn
+	wg         sync.WaitGroup
+	closeOnce  sync.Once
+	listenAddr *net.TCPAddr
+}
+
+// NewSSHServer starts an SSH server on a loopback port, backed by docker or
+// by a new FakeDocker when docker is nil. A client key is written to a
+// temporary directory. docker uses the server filesystem for docker cp
+// unless its HostRoot is already set. The server is stopped when the test
+// finishes.
+func NewSSHServer(t testing.TB, docker *FakeDocker) *SSHServer {
+	t.Helper()
+
+	if docker == nil {
+		docker = NewFakeDocker()
+	}
+	// Connections record trusted host keys in known_hosts under HOME, and a
+	// reused port with a new key would fail verification, so keep them in a
+	// throwaway directory rather than the user's
+	t.Setenv("HOME", t.TempDir())
+
+	root := t.TempDir()
+	if err := os.MkdirAll(hostPath(root, synology.DefaultVolume), 0755); err != nil {
+		t.Fatalf("Failed to create volume directory: %v", err)
+	}
+	for nasPath, contents := range map[string]string{
+		"/etc/VERSION":               FakeDSMVersion,
+		synology.VersionFile:         FakeDSMVersion,
+		synology.HardwareVersionFile: FakeModel + "\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(hostPath(root, nasPath)), 0755); err != nil {
+			t.Fatalf("Failed to create system directory: %v", err)
+		}
+		if err := os.WriteFile(hostPath(root, nasPath), []byte(contents), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", nasPath, err)
+		}
+	}
+	if docker.HostRoot == "" {
+		docker.HostRoot = root
+	}
+
+	_, hostPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate host key: %v", err)
+	}
+	hostKey, err := ssh.NewSignerFromKey(hostPriv)
+	if err != nil {
+		t.Fatalf("Failed to create host key signer: %v", err)
+	}
+
+	clientPub, clientPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate client key: %v", err)
+	}
+	block, err := ssh.MarshalPrivateKey(clientPriv, "")
+	if err != nil {
+		t.Fatalf("Failed to marshal client key: %v", err)
+	}
+	keyPath := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatalf("Failed to write client key: %v", err)
+	}
+	clientKey, err := ssh.NewPublicKey(clientPub)
+	if err != nil {
+		t.Fatalf("Failed to create client public key: %v", err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	s := &SSHServer{
+		Docker:     docker,
+		User:       synology.DefaultSSHUser,
+		Arch:       "x86_64",
+		listener:   listener,
+		hostKey:    hostKey,
+		clientKey:  clientKey,
+		keyPath:    keyPath,
+		root:       root,
+		listenAddr: listener.Addr().(*net.TCPAddr),
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// Addr returns the host:port the server listens on
+func (s *SSHServer) Addr() string {
+	return s.listenAddr.String()
+}
+
+// KeyPath returns the path of the private key the server accepts
+func (s *SSHServer) KeyPath() string {
+	return s.keyPath
+}
+
+// Path returns where the NAS path nasPath is stored on the local filesystem
+func (s *SSHServer) Path(nasPath string) string {
+	return hostPath(s.root, nasPath)
+}
+
+// HostKeyFingerprint returns the SHA256 fingerprint of the server host key
+func (s *SSHServer) HostKeyFingerprint() string {
+	return ssh.FingerprintSHA256(s.hostKey.PublicKey())
+}
+
+// Config returns a configuration that connects to the server with the
+// client key and a pinned host key
+func (s *SSHServer) Config() *config.Config {
+	cfg := config.New()
+	cfg.Host = s.listenAddr.IP.String()
+	cfg.Port = s.listenAddr.Port
+	cfg.User = s.User
+	cfg.SSHKeyPath = s.keyPath
+	cfg.HostKeyFingerprint = s.HostKeyFingerprint()
+	cfg.AuthMethods = []string{config.AuthPublicKey}
+	return cfg
+}
+
+// Commands returns every command line executed so far, in order
+func (s *SSHServer) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.commands...)
+}
+
+// DropConnections closes every open client connection, as a network
+// failure would, while the server keeps accepting new ones
+func (s *SSHServer) DropConnections() {
+	s.mu.Lock()
+	conns := s.conns
+	s.conns = nil
+	s.mu.Unlock()
+
+	for _, conn := range conns {
+		conn.Close()
+	}
+}
+
+// Close stops the server and closes every connection
+func (s *SSHServer) Close() {
+	s.closeOnce.Do(func() {
+		s.listener.Close()
+		s.DropConnections()
+		s.wg.Wait()
+	})
+}
+
+func (s *SSHServer) serverConfig() *ssh.ServerConfig {
+	cfg := &ssh.ServerConfig{
+		PublicKeyCallback: func(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if meta.User() == s.User && bytes.Equal(key.Marshal(), s.clientKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("unknown public key for %s", meta.User())
+		},
+	}
+	if s.Password != "" {
+		cfg.PasswordCallback = func(meta ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if meta.User() == s.User && string(password) == s.Password {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("password rejected for %s", meta.User())
+		}
+	}
+	cfg.AddHostKey(s.hostKey)
+	return cfg
+}
+
+func (s *SSHServer) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.conns = append(s.conns, conn)
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handleConn(conn)
+		}()
+	}
+}
+
+func (s *SSHServer) handleConn(conn net.Conn) {
+	defer conn.Close()
+
+	sshConn, chans, reqs, err := ssh.NewServerConn(conn, s.serverConfig())
+	if err != nil {
+		return
+	}
+	defer sshConn.Close()
+
+	// Keepalives and other global requests are answered with a failure, as
+	// OpenSSH does for requests it does not know
+	go ssh.DiscardRequests(reqs)
+
+	var sessions sync.WaitGroup
+	for newChannel := range chans {
+		switch newChannel.ChannelType() {
+		case "session":
+			channel, requests, err := newChannel.Accept()
+			if err != nil {
+				continue
+			}
+			sessions.Add(1)
+			go func() {
+				defer sessions.Done()
+				s.handleSession(channel, requests)
+			}()
+		case "direct-tcpip", "direct-streamlocal@openssh.com":
+			sessions.Add(1)
+			go func() {
+				defer sessions.Done()
+				s.handleForward(newChannel)
+			}()
+		default:
+			newChannel.Reject(ssh.UnknownChannelType, "unsupported channel type")
+		}
+	}
+	sessions.Wait()
+}
+
+// handleForward connects a tunnel channel through Forward
+func (s *SSHServer) handleForward(newChannel ssh.NewChannel) {
+	if s.Forward == nil {
+		newChannel.Reject(ssh.Prohibited, "forwarding is disabled")
+		return
+	}
+
+	var network, addr string
+	if newChannel.ChannelType() == "direct-tcpip" {
+		var payload struct {
+			Host       string
+			Port       uint32
+			OriginAddr string
+			OriginPort uint32
+		}
+		if err := ssh.Unmarshal(newChannel.ExtraData(), &payload); err != nil {
+			newChannel.Reject(ssh.ConnectionFailed, "invalid payload")
+			return
+		}
+		network, addr = "tcp", net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port)))
+	} else {
+		var payload struct {
+			SocketPath string
+			Reserved0  string
+			Reserved1  uint32
+		}
+		if err := ssh.Unmarshal(newChannel.ExtraData(), &payload); err != nil {
+			newChannel.Reject(ssh.ConnectionFailed, "invalid payload")
+			return
+		}
+		network, addr = "unix", payload.SocketPath
+	}
+
+	conn, err := s.Forward(network, addr)
+	if err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(requests)
+
+	// Pass on half-closes so request-response exchanges complete
+	go func() {
+		io.Copy(conn, channel)
+		if hc, ok := conn.(interface{ CloseWrite() error }); ok {
+			hc.CloseWrite()
+		} else {
+			conn.Close()
+		}
+	}()
+	io.Copy(channel, conn)
+	channel.Close()
+	conn.Close()
+}
+
+func (s *SSHServer) handleSession(channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer channel.Close()
+
+	for req := range requests {
+		switch req.Type {
+		case "env", "pty-req", "window-change", "signal":
+			req.Reply(req.WantReply, nil)
+		case "exec":
+			var payload struct{ Command string }
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+				req.Reply(false, nil)
+				continue
+			}
+			req.Reply(true, nil)
+
+			status := s.runShell(payload.Command, channel, channel, channel.Stderr())
+			channel.CloseWrite()
+			channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{uint32(status)}))
+			return
+		case "subsystem":
+			var payload struct{ Name string }
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil || payload.Name != "sftp" {
+				req.Reply(false, nil)
+				continue
+			}
+			req.Reply(true, nil)
+
+			server := sftp.NewRequestServer(channel, newRootHandlers(s.root))
+			server.Serve()
+			server.Close()
+			return
+		default:
+			req.Reply(false, nil)
+		}
+	}
+}
+
+// runShell emulates the NAS shell for cmd: commands joined with && run in
+// turn, | builds pipelines, set -o pipefail is honored, sudo is ignored, and
+// the docker binary runs on Docker. There are no redirections; tee writes
+// files.
+func (s *SSHServer) runShell(cmd string, stdin io.Reader, stdout, stderr io.Writer) int {
+	s.mu.Lock()
+	s.commands = append(s.commands, cmd)
+	s.mu.Unlock()
+
+	words, err := synology.ShellSplit(cmd)
+	if err != nil {
+		fmt.Fprintf(stderr, "sh: %v\n", err)
+		return 2
+	}
+
+	status := 0
+	pipefail := false
+	for _, command := range splitWords(words, "&&") {
+		if strings.Join(command, " ") == "set -o pipefail" {
+			pipefail = true
+			continue
+		}
+		if status = s.runPipeline(splitWords(command, "|"), pipefail, stdin, stdout, stderr); status != 0 {
+			return status
+		}
+	}
+	return status
+}
+
+// runPipeline runs commands with the output of each as the input of the
+// next. The status is that of the last command or, with pipefail, of the
+// last command that failed.
+func (s *SSHServer) runPipeline(commands [][]string, pipefail bool, stdin io.Reader, stdout, stderr io.Writer) int {
+	status := 0
+	input := stdin
+	for i, command := range commands {
+		out := stdout
+		var buffer bytes.Buffer
+		if i < len(commands)-1 {
+			out = &buffer
+		}
+
+		result := s.runCommand(command, input, out, stderr)
+		if result != 0 || !pipefail || i == len(commands)-1 && status == 0 {
+			status = result
+		}
+		input = &buffer
+	}
+	return status
+}
+
+// splitWords splits words at each separator word
+func splitWords(words []string, separator string) [][]string {
+	var parts [][]string
+	start := 0
+	for i, word := range words {
+		if word == separator {
+			parts = append(parts, words[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, words[start:])
+}
+
+func (s *SSHServer) runCommand(words []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(words) > 0 && words[0] == "sudo" {
+		words = words[1:]
+	}
+	if len(words) == 0 {
+		return 0
+	}
+
+	switch words[0] {
+	case synology.DockerBinary, "docker":
+		return s.Docker.Run(words[1:], stdin, stdout, stderr)
+	case "echo":
+		fmt.Fprintln(stdout, strings.Join(words[1:], " "))
+	case "systemctl":
+		if s.Systemctl != nil {
+			return s.Systemctl(words[1:])
+		}
+	case "true", "chmod", "chown", "touch":
+	case "mkdir":
+		return s.fileCommand(words, stderr, func(p string) error { return os.MkdirAll(p, 0755) })
+	case "cp":
+		var paths []string
+		for _, word := range words[1:] {
+			if !strings.HasPrefix(word, "-") {
+				paths = append(paths, word)
+			}
+		}
+		if len(paths) != 2 {
+			fmt.Fprintln(stderr, "cp: missing file operand")
+			return 1
+		}
+		data, err := os.ReadFile(s.Path(paths[0]))
+		if err == nil {
+			err = os.WriteFile(s.Path(paths[1]), data, 0644)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "cp: %v\n", err)
+			return 1
+		}
+	case "rm":
+		return s.fileCommand(words, stderr, os.RemoveAll)
+	case "sha256sum":
+		return s.fileCommand(words, stderr, func(p string) error {
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			// Like coreutils, escape names with a backslash, newline or
+			// carriage return and mark their line with a leading backslash
+			name, prefix := strings.TrimPrefix(p, s.root), ""
+			if strings.ContainsAny(name, "\\\n\r") {
+				name, prefix = checksumNameEscaper.Replace(name), `\`
+			}
+			fmt.Fprintf(stdout, "%s%x  %s\n", prefix, sha256.Sum256(data), name)
+			return nil
+		})
+	case "false":
+		return 1
+	case "whoami":
+		fmt.Fprintln(stdout, s.User)
+	case "uname":
+		if len(words) == 2 && words[1] == "-m" {
+			fmt.Fprintln(stdout, s.Arch)
+			return 0
+		}
+		fmt.Fprintf(stdout, "Linux synologytest 4.4.302+ #72806 SMP %s GNU/Linux synology_geminilake_920+\n", s.Arch)
+	case "cat":
+		if len(words) == 1 {
+			io.Copy(stdout, stdin)
+			return 0
+		}
+		return s.fileCommand(words, stderr, func(p string) error {
+			data, err := os.ReadFile(p)
+			stdout.Write(data)
+			return err
+		})
+	case "tee":
+		var files []*os.File
+		status := s.fileCommand(words, stderr, func(p string) error {
+			f, err := os.Create(p)
+			if err == nil {
+				files = append(files, f)
+			}
+			return err
+		})
+		writers := []io.Writer{stdout}
+		for _, f := range files {
+			defer f.Close()
+			writers = append(writers, f)
+		}
+		io.Copy(io.MultiWriter(writers...), stdin)
+		return status
+	case "gzip":
+		gz := gzip.NewWriter(stdout)
+		io.Copy(gz, stdin)
+		gz.Close()
+	case "exit":
+		if len(words) > 1 {
+			status, _ := strconv.Atoi(words[1])
+			return status
+		}
+	default:
+		fmt.Fprintf(stderr, "sh: %s: command not found\n", words[0])
+		return 127
+	}
+	return 0
+}
+
+// fileCommand applies fn to the location of each path argument of words,
+// ignoring flags. Errors are reported like coreutils does and make the
+// status 1.
+func (s *SSHServer) fileCommand(words []string, stderr io.Writer, fn func(string) error) int {
+	status := 0
+	flags := true
+	for _, word := range words[1:] {
+		if flags && word == "--" {
+			flags = false
+			continue
+		}
+		if flags && strings.HasPrefix(word, "-") {
+			continue
+		}
+		if err := fn(s.Path(word)); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				err = errors.New("No such file or directory")
+			}
+			fmt.Fprintf(stderr, "%s: %s: %v\n", words[0], word, err)
+			status = 1
+		}
+	}
+	return status
+}
@@ -0,0 +1,241 @@
+package synologytest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/scttfrdmn/syno-docker/pkg/config"
+	"github.com/scttfrdmn/syno-docker/pkg/synology"
+)
+
+func connectTestServer(t *testing.T, server *SSHServer, cfg *config.Config) *synology.Connection {
+	conn := synology.NewConnection(cfg)
+	if err := conn.ConnectContext(context.Background()); err != nil {
+		t.Fatalf("Failed to connect to test server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestSSHServerExec(t *testing.T) {
+	server := NewSSHServer(t, nil)
+	conn := connectTestServer(t, server, server.Config())
+
+	if err := conn.TestConnection(); err != nil {
+		t.Fatalf("TestConnection failed: %v", err)
+	}
+
+	output, err := conn.ExecuteCommand("mkdir -p /volume1/docker/test && echo 'done here'")
+	if err != nil || output != "done here\n" {
+		t.Errorf("Expected chained commands to run, got %q, %v", output, err)
+	}
+
+	if conn.GetDockerClient() != nil {
+		t.Error("Expected the Docker socket to be unavailable so the CLI is used")
+	}
+
+	commands := server.Commands()
+	if len(commands) != 3 || !strings.HasPrefix(commands[1], synology.DockerBinary+" version") {
+		t.Errorf("Unexpected recorded commands: %q", commands)
+	}
+}
+
+func TestSSHServerExitStatus(t *testing.T) {
+	server := NewSSHServer(t, nil)
+	conn := connectTestServer(t, server, server.Config())
+
+	_, err := conn.ExecuteDockerCommand([]string{"rm", "missing"})
+	var remoteErr *synology.RemoteCommandError
+	if !errors.As(err, &remoteErr) || remoteErr.ExitStatus != 1 {
+		t.Fatalf("Expected exit status 1, got %v", err)
+	}
+	if !errors.Is(err, synology.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	_, err = conn.ExecuteCommand("no-such-tool")
+	if !errors.As(err, &remoteErr) || remoteErr.ExitStatus != 127 {
+		t.Errorf("Expected exit status 127, got %v", err)
+	}
+}
+
+func TestSSHServerStdin(t *testing.T) {
+	server := NewSSHServer(t, nil)
+	conn := connectTestServer(t, server, server.Config())
+
+	var stdout strings.Builder
+	err := conn.RunSession("cat", &synology.SessionOptions{
+		Stdin:  strings.NewReader("piped input"),
+		Stdout: &stdout,
+	})
+	if err != nil {
+		t.Fatalf("RunSession failed: %v", err)
+	}
+	if stdout.String() != "piped input" {
+		t.Errorf("Expected stdin to be echoed, got %q", stdout.String())
+	}
+}
+
+func TestSSHServerTee(t *testing.T) {
+	server := NewSSHServer(t, nil)
+	conn := connectTestServer(t, server, server.Config())
+
+	var stdout strings.Builder
+	err := conn.RunSession("mkdir -p /volume1/docker/app && tee /volume1/docker/app/compose.yaml", &synology.SessionOptions{
+		Stdin:  strings.NewReader("services: {}\n"),
+		Stdout: &stdout,
+	})
+	if err != nil {
+		t.Fatalf("RunSession failed: %v", err)
+	}
+	data, err := os.ReadFile(server.Path("/volume1/docker/app/compose.yaml"))
+	if err != nil || string(data) != "services: {}\n" || stdout.String() != string(data) {
+		t.Errorf("Expected tee to write the file and stdout, got %q, %q, %v", data, stdout.String(), err)
+	}
+}
+
+func TestSSHServerCopyAndSystemctl(t *testing.T) {
+	server := NewSSHServer(t, nil)
+	var restarted []string
+	server.Systemctl = func(args []string) int {
+		restarted = args
+		return 3
+	}
+	conn := connectTestServer(t, server, server.Config())
+
+	if _, err := conn.ExecuteCommand("echo hi | tee /volume1/docker/a && sudo cp -p /volume1/docker/a /volume1/docker/b"); err != nil {
+		t.Fatalf("cp failed: %v", err)
+	}
+	if data, err := os.ReadFile(server.Path("/volume1/docker/b")); err != nil || string(data) != "hi\n" {
+		t.Errorf("Expected the copy, got %q, %v", data, err)
+	}
+
+	_, err := conn.ExecuteCommand(synology.RestartCommand)
+	var remoteErr *synology.RemoteCommandError
+	if !errors.As(err, &remoteErr) || remoteErr.ExitStatus != 3 || strings.Join(restarted, " ") != "restart "+synology.ServiceName {
+		t.Errorf("Expected the hook's status for %q, got %v", restarted, err)
+	}
+}
+
+func TestSSHServerProbe(t *testing.T) {
+	server := NewSSHServer(t, nil)
+	server.Arch = "aarch64"
+	conn := connectTestServer(t, server, server.Config())
+
+	info, err := conn.NASInfo(context.Background())
+	if err != nil {
+		t.Fatalf("NASInfo failed: %v", err)
+	}
+	if info.Model != FakeModel || info.Platform() != "linux/arm64" || info.DSMRelease() != "7.2.1-69057 Update 5" || info.DockerAPIVersion != FakeDockerAPIVersion {
+		t.Errorf("Unexpected NAS info %+v", info)
+	}
+}
+
+func TestSSHServerPassword(t *testing.T) {
+	server := NewSSHServer(t, nil)
+	server.Password = "secret"
+
+	cfg := server.Config()
+	cfg.AuthMethods = []string{config.AuthPassword}
+
+	oldPassword := os.Getenv(synology.PasswordEnv)
+	defer os.Setenv(synology.PasswordEnv, oldPassword)
+	os.Setenv(synology.PasswordEnv, "secret")
+
+	conn := connectTestServer(t, server, cfg)
+	if _, err := conn.ExecuteCommand("true"); err != nil {
+		t.Errorf("Expected password login to work, got %v", err)
+	}
+}
+
+func TestSSHServerRejectsUnknownKey(t *testing.T) {
+	server := NewSSHServer(t, nil)
+	other := NewSSHServer(t, nil)
+
+	cfg := server.Config()
+	cfg.SSHKeyPath = other.KeyPath()
+
+	conn := synology.NewConnection(cfg)
+	if err := conn.Connect(); err == nil {
+		conn.Close()
+		t.Fatal("Expected connection with an unknown key to fail")
+	}
+}
+
+func TestSSHServerReconnect(t *testing.T) {
+	server := NewSSHServer(t, nil)
+	conn := connectTestServer(t, server, server.Config())
+
+	server.DropConnections()
+
+	calls := 0
+	err := conn.Retry(context.Background(), func() error {
+		calls++
+		_, err := conn.ExecuteDockerCommand([]string{"ps"})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Expected Retry to reconnect, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected one retry after the drop, got %d calls", calls)
+	}
+}
+
+func TestSSHServerSFTP(t *testing.T) {
+	server := NewSSHServer(t, nil)
+	conn := connectTestServer(t, server, server.Config())
+
+	client, err := conn.SFTPClient()
+	if err != nil {
+		t.Fatalf("SFTPClient failed: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.MkdirAll("/volume1/docker/app"); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+	file, err := client.Create("/volume1/docker/app/hello")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	file.Write([]byte("hello"))
+	file.Close()
+
+	// Files written over SFTP are visible to the shell
+	output, err := conn.ExecuteCommand("sha256sum /volume1/docker/app/hello")
+	if err != nil || output != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824  /volume1/docker/app/hello\n" {
+		t.Errorf("Unexpected sha256sum output %q, %v", output, err)
+	}
+
+	if _, err := conn.ExecuteCommand("rm -rf /volume1/docker/app"); err != nil {
+		t.Fatalf("rm failed: %v", err)
+	}
+	if _, err := os.Stat(server.Path("/volume1/docker/app")); !os.IsNotExist(err) {
+		t.Errorf("Expected directory to be removed, got %v", err)
+	}
+}
+
+func TestSSHServerPipeline(t *testing.T) {
+	server := NewSSHServer(t, nil)
+	conn := connectTestServer(t, server, server.Config())
+
+	output, err := conn.ExecuteCommand("echo hello | cat")
+	if err != nil || output != "hello\n" {
+		t.Errorf("Expected output through the pipe, got %q, %v", output, err)
+	}
+
+	// Without pipefail the last command decides the status
+	if _, err := conn.ExecuteCommand("false | cat"); err != nil {
+		t.Errorf("Expected success without pipefail, got %v", err)
+	}
+
+	var remoteErr *synology.RemoteCommandError
+	_, err = conn.ExecuteCommand("set -o pipefail && false | cat")
+	if !errors.As(err, &remoteErr) || remoteErr.ExitStatus != 1 {
+		t.Errorf("Expected exit status 1 with pipefail, got %v", err)
+	}
+}
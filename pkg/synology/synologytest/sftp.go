@@ -0,0 +1,141 @@
+package synologytest
+
+import (
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/pkg/sftp"
+)
+
+// hostPath maps an absolute NAS path to its location under root
+func hostPath(root, nasPath string) string {
+	return filepath.Join(root, filepath.FromSlash(path.Clean("/"+nasPath)))
+}
+
+// rootFS serves SFTP requests from a local directory standing in for the
+// root of the NAS filesystem
+type rootFS struct {
+	root string
+}
+
+func newRootHandlers(root string) sftp.Handlers {
+	fs := &rootFS{root: root}
+	return sftp.Handlers{FileGet: fs, FilePut: fs, FileCmd: fs, FileList: fs}
+}
+
+func (fs *rootFS) path(p string) string {
+	return hostPath(fs.root, p)
+}
+
+// Fileread opens a file for reading
+func (fs *rootFS) Fileread(r *sftp.Request) (io.ReaderAt, error) {
+	return os.Open(fs.path(r.Filepath))
+}
+
+// Filewrite opens a file for writing with the requested flags
+func (fs *rootFS) Filewrite(r *sftp.Request) (io.WriterAt, error) {
+	pflags := r.Pflags()
+	flags := os.O_WRONLY
+	if pflags.Creat {
+		flags |= os.O_CREATE
+	}
+	if pflags.Trunc {
+		flags |= os.O_TRUNC
+	}
+	if pflags.Excl {
+		flags |= os.O_EXCL
+	}
+	return os.OpenFile(fs.path(r.Filepath), flags, 0644)
+}
+
+// Filecmd runs commands that change the filesystem
+func (fs *rootFS) Filecmd(r *sftp.Request) error {
+	p := fs.path(r.Filepath)
+	switch r.Method {
+	case "Setstat":
+		attrs, flags := r.Attributes(), r.AttrFlags()
+		if flags.Size {
+			if err := os.Truncate(p, int64(attrs.Size)); err != nil {
+				return err
+			}
+		}
+		if flags.Permissions {
+			if err := os.Chmod(p, attrs.FileMode().Perm()); err != nil {
+				return err
+			}
+		}
+		if flags.Acmodtime {
+			return os.Chtimes(p, attrs.AccessTime(), attrs.ModTime())
+		}
+		return nil
+	case "Rename":
+		return os.Rename(p, fs.path(r.Target))
+	case "Rmdir", "Remove":
+		return os.Remove(p)
+	case "Mkdir":
+		return os.Mkdir(p, 0755)
+	case "Symlink":
+		// Filepath is the link target, kept as given, and Target the new link
+		return os.Symlink(r.Filepath, fs.path(r.Target))
+	}
+	return sftp.ErrSSHFxOpUnsupported
+}
+
+// Filelist lists directories and stats files
+func (fs *rootFS) Filelist(r *sftp.Request) (sftp.ListerAt, error) {
+	p := fs.path(r.Filepath)
+	switch r.Method {
+	case "List":
+		entries, err := os.ReadDir(p)
+		if err != nil {
+			return nil, err
+		}
+		var infos listerAt
+		for _, entry := range entries {
+			info, err := entry.Info()
+			if err != nil {
+				return nil, err
+			}
+			infos = append(infos, info)
+		}
+		return infos, nil
+	case "Stat":
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		return listerAt{info}, nil
+	}
+	return nil, sftp.ErrSSHFxOpUnsupported
+}
+
+// Lstat stats a file without following symlinks
+func (fs *rootFS) Lstat(r *sftp.Request) (sftp.ListerAt, error) {
+	info, err := os.Lstat(fs.path(r.Filepath))
+	if err != nil {
+		return nil, err
+	}
+	return listerAt{info}, nil
+}
+
+// Readlink returns the target of a symlink
+func (fs *rootFS) Readlink(p string) (string, error) {
+	return os.Readlink(fs.path(p))
+}
+
+// listerAt serves a fixed list of file infos
+type listerAt []os.FileInfo
+
+// ListAt copies entries starting at offset into ls
+func (l listerAt) ListAt(ls []os.FileInfo, offset int64) (int, error) {
+	if offset >= int64(len(l)) {
+		return 0, io.EOF
+	}
+	n := copy(ls, l[offset:])
+	if n < len(ls) {
+		return n, io.EOF
+	}
+	return n, nil
+}
@@ -0,0 +1,213 @@
+package e2e
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCLIContainerLifecycle(t *testing.T) {
+	nas := newTestNAS(t)
+
+	output := nas.mustRun(t, "run", "nginx:alpine", "--name", "web", "--port", "8080:80", "--env", "TITLE=my site")
+	if !strings.Contains(output, "web") {
+		t.Errorf("Expected run output to mention the container, got:\n%s", output)
+	}
+
+	container, ok := nas.docker.Container("web")
+	if !ok {
+		t.Fatal("Expected container web to exist on the NAS")
+	}
+	if container.Status != "running" || container.Env[0] != "TITLE=my site" || container.Restart != "unless-stopped" {
+		t.Errorf("Unexpected container: %+v", container)
+	}
+
+	if output := nas.mustRun(t, "ps"); !strings.Contains(output, "web") {
+		t.Errorf("Expected ps to list web, got:\n%s", output)
+	}
+
+	nas.mustRun(t, "stop", "web")
+	if output := nas.mustRun(t, "ps"); strings.Contains(output, "nginx:alpine") {
+		t.Errorf("Expected stopped container to be hidden, got:\n%s", output)
+	}
+	if output := nas.mustRun(t, "ps", "--all"); !strings.Contains(output, "web") {
+		t.Errorf("Expected ps --all to list web, got:\n%s", output)
+	}
+
+	nas.mustRun(t, "start", "web")
+	nas.mustRun(t, "restart", "web")
+	if output := nas.mustRun(t, "logs", "web"); !strings.Contains(output, "Container restarted") {
+		t.Errorf("Expected restart in logs, got:\n%s", output)
+	}
+
+	nas.mustRun(t, "rm", "--force", "web")
+	if _, ok := nas.docker.Container("web"); ok {
+		t.Error("Expected container web to be removed")
+	}
+}
+
+func TestCLIExecExitCode(t *testing.T) {
+	nas := newTestNAS(t)
+	nas.mustRun(t, "run", "alpine", "--name", "app", "--command", "sleep,3600")
+
+	if output := nas.mustRun(t, "exec", "app", "whoami"); !strings.Contains(output, "root") {
+		t.Errorf("Expected whoami output, got:\n%s", output)
+	}
+
+	result := nas.run(t, "exec", "app", "--", "sh", "-c", "exit 3")
+	if result.exitCode != 3 {
+		t.Errorf("Expected exit code 3 to be forwarded, got %d\n%s", result.exitCode, result.stderr)
+	}
+}
+
+func TestCLIMissingContainer(t *testing.T) {
+	nas := newTestNAS(t)
+
+	result := nas.run(t, "rm", "ghost")
+	if result.exitCode == 0 {
+		t.Fatal("Expected removing a missing container to fail")
+	}
+	if !strings.Contains(result.stderr, "No such container: ghost") {
+		t.Errorf("Expected the daemon error, got:\n%s", result.stderr)
+	}
+}
+
+func TestCLIVolumesAndNetworks(t *testing.T) {
+	nas := newTestNAS(t)
+
+	nas.mustRun(t, "volume", "create", "app-data")
+	if output := nas.mustRun(t, "volume", "ls", "--quiet"); !strings.Contains(output, "app-data") {
+		t.Errorf("Expected volume in listing, got:\n%s", output)
+	}
+
+	nas.mustRun(t, "network", "create", "backend")
+	if output := nas.mustRun(t, "network", "ls"); !strings.Contains(output, "backend") {
+		t.Errorf("Expected network in listing, got:\n%s", output)
+	}
+
+	nas.mustRun(t, "volume", "rm", "app-data")
+	nas.mustRun(t, "network", "rm", "backend")
+	if len(nas.docker.Volumes()) != 0 || len(nas.docker.Networks()) != 3 {
+		t.Errorf("Expected volume and network removed, got %+v %+v", nas.docker.Volumes(), nas.docker.Networks())
+	}
+}
+
+func TestCLIConnectionFailure(t *testing.T) {
+	nas := newTestNAS(t)
+	nas.server.Close()
+
+	result := nas.run(t, "ps")
+	if result.exitCode == 0 || !strings.Contains(result.stderr, "connection failed") {
+		t.Errorf("Expected connection failure, got %d:\n%s", result.exitCode, result.stderr)
+	}
+}
+
+func TestCLICopy(t *testing.T) {
+	nas := newTestNAS(t)
+	nas.mustRun(t, "run", "nginx:alpine", "--name", "web")
+
+	local := filepath.Join(t.TempDir(), "index.html")
+	if err := os.WriteFile(local, []byte("<h1>hello</h1>"), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	output := nas.mustRun(t, "cp", local, "nas:/volume1/docker/index.html")
+	if !strings.Contains(output, "Copied 1 file(s)") {
+		t.Errorf("Expected copy summary, got:\n%s", output)
+	}
+	info, err := os.Stat(nas.server.Path("/volume1/docker/index.html"))
+	if err != nil || info.Mode().Perm() != 0640 {
+		t.Errorf("Expected file with mode 0640 on the NAS, got %v, %v", info, err)
+	}
+
+	nas.mustRun(t, "cp", local, "web:/usr/share/nginx/html/index.html")
+	if output := nas.mustRun(t, "exec", "web", "cat", "/usr/share/nginx/html/index.html"); !strings.Contains(output, "<h1>hello</h1>") {
+		t.Errorf("Expected the file inside the container, got:\n%s", output)
+	}
+
+	back := filepath.Join(t.TempDir(), "back.html")
+	nas.mustRun(t, "cp", "web:/usr/share/nginx/html/index.html", back)
+	if data, _ := os.ReadFile(back); string(data) != "<h1>hello</h1>" {
+		t.Errorf("Expected the file back from the container, got %q", data)
+	}
+
+	if result := nas.run(t, "cp", "nas:/volume1/docker/missing", back); result.exitCode == 0 {
+		t.Error("Expected copying a missing file to fail")
+	}
+}
+
+func TestCLIExport(t *testing.T) {
+	nas := newTestNAS(t)
+	nas.mustRun(t, "run", "nginx:alpine", "--name", "web")
+
+	output := filepath.Join(t.TempDir(), "web.tar.gz")
+	nas.mustRun(t, "export", "web", "--output", output)
+	data, err := os.ReadFile(output)
+	if err != nil || len(data) < 2 || data[0] != 0x1f || data[1] != 0x8b {
+		t.Errorf("Expected a gzip file selected by the extension, got %d bytes, %v", len(data), err)
+	}
+
+	// Writing to STDOUT keeps status messages out of the archive
+	result := nas.run(t, "export", "web")
+	if result.exitCode != 0 {
+		t.Fatalf("export to STDOUT failed:\n%s", result.stderr)
+	}
+	if !strings.HasPrefix(result.stdout, "etc/hostname") {
+		t.Errorf("Expected only the tar archive on STDOUT, got %q", result.stdout[:min(len(result.stdout), 40)])
+	}
+	if !strings.Contains(result.stderr, "exported successfully") {
+		t.Errorf("Expected status messages on STDERR, got:\n%s", result.stderr)
+	}
+}
+
+func TestCLISaveLoadImport(t *testing.T) {
+	nas := newTestNAS(t)
+	nas.mustRun(t, "pull", "nginx:alpine")
+
+	archive := filepath.Join(t.TempDir(), "nginx.tar")
+	nas.mustRun(t, "save", "nginx:alpine", "--output", archive)
+
+	offline := newTestNAS(t)
+	output := offline.mustRun(t, "load", "--input", archive)
+	if !strings.Contains(output, "Loaded image: nginx:alpine") {
+		t.Errorf("Expected the image to be loaded, got:\n%s", output)
+	}
+	if len(offline.docker.Images()) != 1 {
+		t.Errorf("Expected one image on the offline NAS, got %+v", offline.docker.Images())
+	}
+
+	// A local tarball is streamed to the NAS rather than looked up there
+	output = offline.mustRun(t, "import", archive, "rootfs:latest")
+	if !strings.Contains(output, "Image imported as rootfs:latest") {
+		t.Errorf("Expected the import to succeed, got:\n%s", output)
+	}
+}
+
+func TestCLIBuildAndDeploy(t *testing.T) {
+	nas := newTestNAS(t)
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"web/Dockerfile":     "FROM nginx:alpine\nCOPY index.html /usr/share/nginx/html/\n",
+		"web/index.html":     "<h1>hello</h1>",
+		"web/.dockerignore":  "*.log\n",
+		"docker-compose.yml": "services:\n  web:\n    build: ./web\n    ports:\n      - \"8080:80\"\n",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	output := nas.mustRun(t, "build", "-t", "my-site:1.0", filepath.Join(dir, "web"))
+	if !strings.Contains(output, "Step 2/2 : COPY index.html") || !strings.Contains(output, "Successfully tagged my-site:1.0") {
+		t.Errorf("Expected the build output, got:\n%s", output)
+	}
+
+	nas.mustRun(t, "deploy", filepath.Join(dir, "docker-compose.yml"), "--project", "site")
+	container, ok := nas.docker.Container("site_web_1")
+	if !ok || container.Image != "site_web" {
+		t.Errorf("Expected the built image to be deployed, got %+v", container)
+	}
+}
@@ -0,0 +1,135 @@
+package e2e
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	"github.com/scttfrdmn/syno-docker/pkg/config"
+	"github.com/scttfrdmn/syno-docker/pkg/dsm/dsmtest"
+	"github.com/scttfrdmn/syno-docker/pkg/synology"
+)
+
+// startAgent serves an ssh-agent holding a key the emulated NAS doesn't
+// accept, and points SSH_AUTH_SOCK at it
+func startAgent(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: priv}); err != nil {
+		t.Fatal(err)
+	}
+
+	sock := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				agent.ServeAgent(keyring, conn)
+			}()
+		}
+	}()
+	t.Setenv("SSH_AUTH_SOCK", sock)
+}
+
+func TestConnectAgentKeyRejectedOverSSH(t *testing.T) {
+	nas := newTestNAS(t)
+	startAgent(t)
+
+	// The agent's key alone is rejected
+	cfg := nas.server.Config()
+	cfg.AuthMethods = []string{config.AuthAgent}
+	if err := synology.NewConnection(cfg).Connect(); err == nil {
+		t.Fatal("Expected the agent's key to be rejected")
+	}
+
+	// With the default methods the key file is offered after the agent's key
+	cfg.AuthMethods = nil
+	conn := synology.NewConnection(cfg)
+	if err := conn.Connect(); err != nil {
+		t.Fatalf("Expected the key file to be offered after the agent's key, got %v", err)
+	}
+	conn.Close()
+}
+
+func TestConnectNoDSMFallbackOnRejectionOverSSH(t *testing.T) {
+	nas := newTestNAS(t)
+	dsmServer := dsmtest.NewServer(t)
+	t.Setenv(synology.PasswordEnv, dsmtest.DefaultPassword)
+	t.Setenv("SSH_AUTH_SOCK", "")
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKeyPath := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(otherKeyPath, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(cfg *config.Config)
+		check  func(err error) bool
+	}{
+		{
+			name:   "host key mismatch",
+			modify: func(cfg *config.Config) { cfg.HostKeyFingerprint = ssh.FingerprintSHA256(otherKey) },
+			check: func(err error) bool {
+				var mismatch *synology.HostKeyMismatchError
+				return errors.As(err, &mismatch)
+			},
+		},
+		{
+			name:   "key rejected",
+			modify: func(cfg *config.Config) { cfg.SSHKeyPath = otherKeyPath },
+			check:  func(err error) bool { return err != nil },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := nas.server.Config()
+			cfg.DSM.URL = dsmServer.URL
+			cfg.DSM.InsecureSkipVerify = true
+			tt.modify(cfg)
+
+			conn := synology.NewConnection(cfg)
+			err := conn.ConnectContext(context.Background())
+			if !tt.check(err) {
+				t.Fatalf("Expected the SSH error to be returned, got %v", err)
+			}
+			if conn.DSMClient() != nil || len(dsmServer.Calls()) != 0 {
+				t.Errorf("Expected the DSM Web API not to be tried, got %d calls", len(dsmServer.Calls()))
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package e2e
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/scttfrdmn/syno-docker/pkg/deploy"
+	"github.com/scttfrdmn/syno-docker/pkg/synology"
+	"github.com/scttfrdmn/syno-docker/pkg/synology/synologytest"
+)
+
+func TestDeployContainerOverSSH(t *testing.T) {
+	nas := newTestNAS(t)
+	conn := nas.connect(t)
+
+	opts := deploy.NewContainerOptions("nginx:alpine")
+	opts.Name = "web"
+	opts.Ports = []string{"8080:80"}
+	if _, err := deploy.Container(conn, opts); err != nil {
+		t.Fatalf("Container failed: %v", err)
+	}
+
+	containers, err := deploy.ListContainers(conn, false)
+	if err != nil {
+		t.Fatalf("ListContainers failed: %v", err)
+	}
+	if len(containers) != 1 || containers[0].Name != "web" {
+		t.Fatalf("Expected container web, got %+v", containers)
+	}
+
+	output, err := deploy.ExecCommand(conn, "web", []string{"echo", "hello"}, &deploy.ExecOptions{})
+	if err != nil {
+		t.Fatalf("ExecCommand failed: %v", err)
+	}
+	if strings.TrimSpace(output) != "hello" {
+		t.Errorf("Expected hello, got %q", output)
+	}
+
+	if err := deploy.RemoveContainer(conn, "web", true); err != nil {
+		t.Fatalf("RemoveContainer failed: %v", err)
+	}
+	if err := deploy.RemoveContainer(conn, "web", true); !errors.Is(err, synology.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound removing twice, got %v", err)
+	}
+}
+
+func TestDeployImagesOverSSH(t *testing.T) {
+	nas := newTestNAS(t)
+	conn := nas.connect(t)
+
+	if err := deploy.PullImage(conn, "redis:7", &deploy.PullOptions{Quiet: true}); err != nil {
+		t.Fatalf("PullImage failed: %v", err)
+	}
+	if err := deploy.PullImage(conn, synologytest.MissingImage, &deploy.PullOptions{Quiet: true}); !errors.Is(err, synology.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound pulling a missing image, got %v", err)
+	}
+
+	images, err := deploy.ListImages(conn, "", &deploy.ImagesOptions{})
+	if err != nil {
+		t.Fatalf("ListImages failed: %v", err)
+	}
+	if len(images) != 1 || images[0].Repository != "redis" || images[0].Tag != "7" {
+		t.Fatalf("Expected redis:7, got %+v", images)
+	}
+
+	if err := deploy.RemoveImage(conn, "redis:7", &deploy.RmiOptions{}); err != nil {
+		t.Fatalf("RemoveImage failed: %v", err)
+	}
+	if len(nas.docker.Images()) != 0 {
+		t.Errorf("Expected no images left, got %+v", nas.docker.Images())
+	}
+}
+
+func TestDeployReconnectOverSSH(t *testing.T) {
+	nas := newTestNAS(t)
+	conn := nas.connect(t)
+
+	nas.server.DropConnections()
+
+	if _, err := deploy.ListContainers(conn, true); err != nil {
+		t.Fatalf("Expected ListContainers to reconnect, got %v", err)
+	}
+}
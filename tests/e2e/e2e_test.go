@@ -0,0 +1,118 @@
+// Package e2e runs syno-docker end to end against an in-process SSH server
+// that emulates a Synology NAS, so it needs no hardware and runs in plain
+// go test.
+package e2e
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/scttfrdmn/syno-docker/pkg/synology"
+	"github.com/scttfrdmn/syno-docker/pkg/synology/synologytest"
+)
+
+// binary is the syno-docker CLI built for the tests
+var binary string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "syno-docker-e2e")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create build directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	binary = filepath.Join(dir, "syno-docker")
+	build := exec.Command("go", "build", "-o", binary, "github.com/scttfrdmn/syno-docker")
+	build.Stdout = os.Stdout
+	build.Stderr = os.Stderr
+	if err := build.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to build syno-docker: %v\n", err)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// testNAS is an emulated NAS with a home directory configured to use it
+type testNAS struct {
+	server *synologytest.SSHServer
+	docker *synologytest.FakeDocker
+	home   string
+}
+
+// newTestNAS starts an emulated NAS and saves a syno-docker configuration
+// for it in a fresh home directory
+func newTestNAS(t *testing.T) *testNAS {
+	docker := synologytest.NewFakeDocker()
+	server := synologytest.NewSSHServer(t, docker)
+	home := t.TempDir()
+
+	oldHome := os.Getenv("HOME")
+	defer os.Setenv("HOME", oldHome)
+	os.Setenv("HOME", home)
+
+	if err := server.Config().Save(); err != nil {
+		t.Fatalf("Failed to save configuration: %v", err)
+	}
+
+	return &testNAS{server: server, docker: docker, home: home}
+}
+
+// connect opens an SSH connection to the emulated NAS
+func (n *testNAS) connect(t *testing.T) *synology.Connection {
+	conn := synology.NewConnection(n.server.Config())
+	if err := conn.Connect(); err != nil {
+		t.Fatalf("Failed to connect to emulated NAS: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// cliResult is the outcome of running the CLI
+type cliResult struct {
+	stdout   string
+	stderr   string
+	exitCode int
+}
+
+// run runs the syno-docker CLI against the emulated NAS
+func (n *testNAS) run(t *testing.T, args ...string) cliResult {
+	cmd := exec.Command(binary, args...)
+	cmd.Env = append(os.Environ(), "HOME="+n.home, "SSH_AUTH_SOCK=")
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	result := cliResult{}
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		result.exitCode = exitErr.ExitCode()
+	case err != nil:
+		t.Fatalf("Failed to run syno-docker: %v", err)
+	}
+	result.stdout = stdout.String()
+	result.stderr = stderr.String()
+	return result
+}
+
+// mustRun runs the CLI and fails the test if it exits with an error
+func (n *testNAS) mustRun(t *testing.T, args ...string) string {
+	t.Helper()
+	result := n.run(t, args...)
+	if result.exitCode != 0 {
+		t.Fatalf("syno-docker %s exited with %d:\n%s%s", strings.Join(args, " "), result.exitCode, result.stdout, result.stderr)
+	}
+	return result.stdout
+}
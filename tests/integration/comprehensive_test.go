@@ -380,7 +380,7 @@ func testVolumeManagement(t *testing.T, runner *TestRunner) {
 		}
 
 		// Test with format
-		opts.Format = "'{{.Name}}'"
+		opts.Format = "{{.Name}}"
 		formattedInfo, err := deploy.InspectVolume(runner.Connection, volumeName, opts)
 		if err != nil {
 			t.Fatalf("Failed to inspect volume with format: %v", err)
@@ -416,12 +416,12 @@ func testVolumeManagement(t *testing.T, runner *TestRunner) {
 		// Test volume usage with a one-shot container using working command pattern
 		containerName := "test-vol-usage-" + helpers.RandomString(6)
 
-		// Use the exact command pattern that works manually with proper escaping
+		// Arguments are quoted for the remote shell by ExecuteDockerCommand
 		dockerArgs := []string{
 			"run", "--rm", "--name", containerName,
 			"-v", fmt.Sprintf("%s:/data", volumeName),
 			"alpine:latest",
-			"sh", "-c", "\"echo 'Testing volume mount...' && ls -la /data && touch /data/test-file.txt && ls -la /data && echo 'Volume test completed successfully'\"",
+			"sh", "-c", "echo 'Testing volume mount...' && ls -la /data && touch /data/test-file.txt && ls -la /data && echo 'Volume test completed successfully'",
 		}
 
 		t.Logf("Executing: docker %s", strings.Join(dockerArgs, " "))
@@ -725,7 +725,7 @@ func testSystemOperations(t *testing.T, runner *TestRunner) {
 		}
 
 		// Test with format template
-		inspectOpts.Format = "'{{.State.Status}}'"
+		inspectOpts.Format = "{{.State.Status}}"
 		statusInfo, err := deploy.InspectObject(runner.Connection, containerName, inspectOpts)
 		if err != nil {
 			t.Fatalf("Failed to inspect container with format: %v", err)
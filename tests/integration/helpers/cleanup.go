@@ -141,7 +141,7 @@ func (cm *CleanupManager) CleanupFiles() error {
 	for _, filePath := range files {
 		fmt.Printf("Cleaning up file: %s\n", filePath)
 
-		_, err := cm.conn.ExecuteCommand(fmt.Sprintf("rm -f %s", filePath))
+		_, err := cm.conn.ExecuteCommand(synology.ShellJoin("rm", "-f", filePath))
 		if err != nil {
 			errors = append(errors, fmt.Sprintf("failed to remove file %s: %v", filePath, err))
 			continue
@@ -172,7 +172,7 @@ func (cm *CleanupManager) CleanupDirectories() error {
 	for _, dirPath := range dirs {
 		fmt.Printf("Cleaning up directory: %s\n", dirPath)
 
-		_, err := cm.conn.ExecuteCommand(fmt.Sprintf("rm -rf %s", dirPath))
+		_, err := cm.conn.ExecuteCommand(synology.ShellJoin("rm", "-rf", dirPath))
 		if err != nil {
 			errors = append(errors, fmt.Sprintf("failed to remove directory %s: %v", dirPath, err))
 			continue
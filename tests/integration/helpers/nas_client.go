@@ -23,7 +23,7 @@ func RandomString(length int) string {
 
 // CreateTestDirectory creates a directory on the NAS for testing
 func CreateTestDirectory(conn *synology.Connection, path string) error {
-	cmd := fmt.Sprintf("mkdir -p %s && chmod 755 %s", path, path)
+	cmd := synology.ShellJoin("mkdir", "-p", path) + " && " + synology.ShellJoin("chmod", "755", path)
 	_, err := conn.ExecuteCommand(cmd)
 	if err != nil {
 		return fmt.Errorf("failed to create directory %s: %w", path, err)
@@ -40,7 +40,7 @@ func CreateTestFile(conn *synology.Connection, filePath, content string) error {
 	}
 
 	// Create file with content
-	cmd := fmt.Sprintf("cat > %s << 'EOF'\n%s\nEOF", filePath, content)
+	cmd := fmt.Sprintf("cat > %s << 'EOF'\n%s\nEOF", synology.ShellQuote(filePath), content)
 	_, err := conn.ExecuteCommand(cmd)
 	if err != nil {
 		return fmt.Errorf("failed to create file %s: %w", filePath, err)
@@ -134,13 +134,13 @@ func CheckContainerLogs(conn *synology.Connection, containerName, expectedLogCon
 // GetContainerIP retrieves the IP address of a container
 func GetContainerIP(conn *synology.Connection, containerName string) (string, error) {
 	output, err := conn.ExecuteDockerCommand([]string{
-		"inspect", "--format", "'{{range .NetworkSettings.Networks}}{{.IPAddress}}{{end}}'", containerName,
+		"inspect", "--format", "{{range .NetworkSettings.Networks}}{{.IPAddress}}{{end}}", containerName,
 	})
 	if err != nil {
 		return "", fmt.Errorf("failed to get IP for container %s: %w", containerName, err)
 	}
 
-	ip := strings.Trim(strings.TrimSpace(output), "'")
+	ip := strings.TrimSpace(output)
 	if ip == "" {
 		return "", fmt.Errorf("no IP address found for container %s", containerName)
 	}
@@ -246,7 +246,7 @@ func CreateTestFileInContainer(conn *synology.Connection, containerName, filePat
 		TTY:         false,
 	}
 
-	cmd := []string{"sh", "-c", fmt.Sprintf("echo '%s' > %s", content, filePath)}
+	cmd := []string{"sh", "-c", "echo " + synology.ShellQuote(content) + " > " + synology.ShellQuote(filePath)}
 	_, err := deploy.ExecCommand(conn, containerName, cmd, opts)
 	if err != nil {
 		return fmt.Errorf("failed to create file %s in container %s: %w", filePath, containerName, err)